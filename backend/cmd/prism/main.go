@@ -2,40 +2,65 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // tefas.timezone works without the system zone database
 
 	"github.com/ferhatkunduraci/prism/internal/api"
 	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/notify"
 	"github.com/ferhatkunduraci/prism/internal/providers"
 	"github.com/ferhatkunduraci/prism/internal/providers/binance"
 	"github.com/ferhatkunduraci/prism/internal/providers/coingecko"
+	"github.com/ferhatkunduraci/prism/internal/providers/frankfurter"
+	"github.com/ferhatkunduraci/prism/internal/providers/mock"
 	"github.com/ferhatkunduraci/prism/internal/providers/tefas"
 	"github.com/ferhatkunduraci/prism/internal/storage"
 )
 
+// logLevel is shared by the log handler so a reload can change it in place
+var logLevel = new(slog.LevelVar)
+
+// loadOptions are the config parsing options given on the command line,
+// applied again on reload
+var loadOptions config.LoadOptions
+
 func main() {
-	// Initialize structured logger
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
-	slog.SetDefault(logger)
+	flag.BoolVar(&loadOptions.AllowUnknownKeys, "allow-unknown", false, "ignore unknown keys in config.yaml instead of refusing to start")
+	flag.Parse()
+
+	// Initialize structured logger; text until the config picks a format
+	slog.SetDefault(slog.New(newLogHandler("text")))
 
 	// Load configuration
-	cfg, err := config.Load("config.yaml")
+	cfg, err := config.Load("config.yaml", loadOptions)
 	if err != nil {
 		slog.Error("failed to load config", "error", err)
 		os.Exit(1)
 	}
+	if err := cfg.Validate(); err != nil {
+		slog.Error("invalid config", "error", err)
+		os.Exit(1)
+	}
+	setLogLevel(cfg.Logging.Level)
+	slog.SetDefault(slog.New(newLogHandler(cfg.Logging.Format)))
 
 	slog.Info("starting Prism server", "port", cfg.Server.Port)
 
 	// Initialize storage
-	store, err := storage.New(cfg.Database.Path)
+	store, err := storage.New(cfg.Database.Path, storage.Options{
+		MaxOpenConns: cfg.Database.MaxOpenConns,
+		MaxIdleConns: cfg.Database.MaxIdleConns,
+		BusyTimeout:  cfg.Database.BusyTimeout,
+
+		BackupBeforeMigrate: backupBeforeMigrate(cfg),
+	})
 	if err != nil {
 		slog.Error("failed to initialize storage", "error", err)
 		os.Exit(1)
@@ -43,7 +68,8 @@ func main() {
 	defer store.Close()
 
 	// Migrate holdings from config to database if database is empty
-	if err := migrateHoldingsFromConfig(store, cfg); err != nil {
+	migrated, err := migrateHoldingsFromConfig(store, cfg)
+	if err != nil {
 		slog.Error("failed to migrate holdings from config", "error", err)
 		// Continue anyway - this is not fatal
 	}
@@ -51,56 +77,95 @@ func main() {
 	// Initialize providers
 	var tefasProvider providers.Provider
 	var cryptoProvider providers.Provider
+	var fxProvider providers.ExchangeRateProvider
 
-	// TEFAS Provider
-	fundCodes := cfg.TEFAS.GetFundCodes()
-	if len(fundCodes) > 0 {
-		slog.Info("initializing TEFAS provider", "funds", fundCodes)
-		tefasProvider = tefas.NewProvider(tefas.Config{
-			Headless: cfg.TEFAS.Headless,
-			Funds:    fundCodes,
-		})
-	}
-
-	// Crypto Providers (Binance with CoinGecko fallback)
-	cryptoSymbols := cfg.Crypto.Binance.GetCryptoSymbols()
-	if cfg.Crypto.Binance.Enabled && len(cryptoSymbols) > 0 {
-		slog.Info("initializing crypto providers", "symbols", cryptoSymbols)
+	// Concrete providers are kept for runtime reconfiguration on SIGHUP
+	var rp reloadableProviders
 
-		binanceProvider := binance.NewProvider(binance.Config{
-			Symbols: cryptoSymbols,
+	if cfg.Mock.Enabled {
+		slog.Warn("mock providers enabled; all prices are synthetic", "seed", cfg.Mock.Seed)
+		tefasProvider = mock.NewProvider(mock.Config{
+			Name:       "tefas",
+			Seed:       cfg.Mock.Seed,
+			Volatility: cfg.Mock.Volatility,
+		})
+		cryptoProvider = mock.NewProvider(mock.Config{
+			Name:       "crypto",
+			Seed:       cfg.Mock.Seed + 1,
+			Volatility: cfg.Mock.Volatility,
 		})
+	} else {
+		built, err := newProviderRegistry(cfg, store, &rp).Build(context.Background(), cfg.EnabledProviders())
+		if err != nil {
+			slog.Error("failed to initialize providers", "error", err)
+			os.Exit(1)
+		}
+		tefasProvider, cryptoProvider, fxProvider = built.Funds, built.Crypto, built.FX
+
+		// Warm provider caches with prices persisted by the previous run
+		rp.loadCaches(context.Background())
 
-		if cfg.Crypto.CoinGecko.Enabled {
-			coingeckoProvider := coingecko.NewProvider(coingecko.Config{
-				APIKey: cfg.Crypto.CoinGecko.APIKey,
-			})
-			// Use fallback wrapper: Binance -> CoinGecko
-			cryptoProvider = providers.NewFallbackProvider(binanceProvider, coingeckoProvider)
-		} else {
-			cryptoProvider = binanceProvider
+		// Launch the browser now so a missing install is reported at startup
+		// rather than on the first fund price request
+		if rp.tefas != nil {
+			go startTEFAS(rp.tefas)
 		}
-	} else if cfg.Crypto.CoinGecko.Enabled {
-		// Only CoinGecko enabled
-		cryptoProvider = coingecko.NewProvider(coingecko.Config{
-			APIKey: cfg.Crypto.CoinGecko.APIKey,
-		})
 	}
 
+	cfgHolder := config.NewHolder(cfg)
+
 	// Initialize router with providers
 	router := api.NewRouter(&api.RouterConfig{
-		Config:         cfg,
+		Config:         cfgHolder,
 		TEFASProvider:  tefasProvider,
 		CryptoProvider: cryptoProvider,
+		FXProvider:     fxProvider,
 		Storage:        store,
 	})
 
+	// Background jobs stop when this context is cancelled on shutdown
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+
+	jobs := api.NewHandler(cfgHolder, tefasProvider, cryptoProvider, fxProvider, store)
+
+	// Notifications
+	if cfg.Notify.Telegram.Enabled() {
+		telegram := notify.NewTelegram(cfg.Notify.Telegram.Token, cfg.Notify.Telegram.ChatID)
+		if cfg.Notify.DailySummaryTime != "" {
+			go jobs.RunDailySummary(bgCtx, telegram, cfg.Notify.DailySummaryTime)
+		}
+		// Runs even without alerts so ones added on reload are checked
+		go jobs.RunPortfolioAlerts(bgCtx, telegram, cfg.Notify.AlertInterval)
+	}
+
+	// Migrated holdings without a cost basis start at break-even; prices
+	// come from the providers, so this waits until they exist
+	if migrated && cfg.InferCostBasis {
+		go jobs.InferMissingCostBases(bgCtx)
+	}
+
+	// Snapshot retention; a no-op until snapshot.retention_days is set
+	go jobs.RunSnapshotPruning(bgCtx)
+
+	if rp.tefas != nil && cfg.TEFAS.KeepaliveInterval > 0 {
+		go rp.tefas.RunKeepAlive(bgCtx, cfg.TEFAS.KeepaliveInterval)
+	}
+
+	if cfg.Database.Backup.Interval > 0 {
+		go store.RunBackups(bgCtx, backupPolicy(cfg), cfg.Database.Backup.Interval)
+	}
+
+	if rp.binance != nil && cfg.Crypto.Binance.BackgroundRefresh {
+		go rp.binance.RunRefresher(bgCtx)
+	}
+
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
 		Handler:      router,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 60 * time.Second, // Longer timeout for TEFAS (Playwright can be slow)
+		WriteTimeout: config.MaxRequestTimeout + 30*time.Second, // Outlast any handler deadline a reload can set, so timeouts are reported, not dropped
 		IdleTimeout:  60 * time.Second,
 	}
 
@@ -114,12 +179,25 @@ func main() {
 
 	slog.Info("server started", "addr", srv.Addr)
 
+	// Reload config on SIGHUP until asked to shut down
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+
+	for running := true; running; {
+		select {
+		case <-hup:
+			reloadConfig(cfgHolder, &rp)
+		case <-quit:
+			running = false
+		}
+	}
 
 	slog.Info("shutting down server...")
+	bgCancel()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -143,19 +221,20 @@ func main() {
 	slog.Info("server stopped")
 }
 
-// migrateHoldingsFromConfig migrates holdings from config.yaml to SQLite if the database is empty
-func migrateHoldingsFromConfig(store *storage.Storage, cfg *config.Config) error {
+// migrateHoldingsFromConfig migrates holdings from config.yaml to SQLite if
+// the database is empty, and reports whether it migrated any
+func migrateHoldingsFromConfig(store *storage.Storage, cfg *config.Config) (bool, error) {
 	ctx := context.Background()
 
 	// Check if database is empty
 	empty, err := store.IsEmpty(ctx)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	if !empty {
 		slog.Info("holdings already exist in database, skipping migration")
-		return nil
+		return false, nil
 	}
 
 	var holdings []storage.CreateHoldingRequest
@@ -167,14 +246,19 @@ func migrateHoldingsFromConfig(store *storage.Storage, cfg *config.Config) error
 			Symbol:    h.Code,
 			Quantity:  h.Quantity,
 			CostBasis: h.CostBasis,
+			FundType:  h.FundType,
 		})
 	}
 
-	// Add crypto holdings
+	// Add crypto holdings, stored in canonical form like those created via the API
 	for _, h := range cfg.Crypto.Binance.Holdings {
+		symbol, err := binance.NormalizeSymbol(h.Symbol, cfg.Crypto.Binance.Quote)
+		if err != nil {
+			return false, err
+		}
 		holdings = append(holdings, storage.CreateHoldingRequest{
 			Type:      storage.HoldingTypeCrypto,
-			Symbol:    h.Symbol,
+			Symbol:    symbol,
 			Quantity:  h.Quantity,
 			CostBasis: h.CostBasis,
 		})
@@ -182,13 +266,180 @@ func migrateHoldingsFromConfig(store *storage.Storage, cfg *config.Config) error
 
 	if len(holdings) == 0 {
 		slog.Info("no holdings in config to migrate")
-		return nil
+		return false, nil
 	}
 
 	if err := store.BulkCreateHoldings(ctx, holdings); err != nil {
-		return err
+		return false, err
 	}
 
 	slog.Info("migrated holdings from config", "count", len(holdings))
-	return nil
+	return true, nil
+}
+
+// reloadableProviders holds the concrete providers whose settings can change at runtime
+type reloadableProviders struct {
+	tefas       *tefas.Provider
+	binance     *binance.Provider
+	coingecko   *coingecko.Provider
+	frankfurter *frankfurter.Provider
+}
+
+// loadCaches restores persisted last-known prices into each provider cache
+func (rp *reloadableProviders) loadCaches(ctx context.Context) {
+	if rp.tefas != nil {
+		if err := rp.tefas.LoadCache(ctx); err != nil {
+			slog.Warn("failed to load persisted TEFAS prices", "error", err)
+		}
+	}
+	if rp.binance != nil {
+		if err := rp.binance.LoadCache(ctx); err != nil {
+			slog.Warn("failed to load persisted Binance prices", "error", err)
+		}
+	}
+	if rp.coingecko != nil {
+		if err := rp.coingecko.LoadCache(ctx); err != nil {
+			slog.Warn("failed to load persisted CoinGecko prices", "error", err)
+		}
+	}
+}
+
+// startTEFAS starts the TEFAS browser, warning once if it can't. The server
+// keeps running either way, serving crypto prices and cached fund prices.
+func startTEFAS(p *tefas.Provider) {
+	err := p.Start()
+	switch {
+	case err == nil:
+	case errors.Is(err, tefas.ErrBrowserNotInstalled):
+		slog.Warn("TEFAS disabled until the browser is installed and prism restarted; serving crypto and last-known fund prices", "error", err)
+	default:
+		slog.Warn("failed to start TEFAS browser; will retry on the next fund price request", "error", err)
+	}
+}
+
+// storedFundTypes returns the TEFAS fund type recorded for each fund holding
+func storedFundTypes(ctx context.Context, store *storage.Storage) map[string]tefas.FundType {
+	holdings, err := store.GetHoldingsByType(ctx, storage.HoldingTypeFund)
+	if err != nil {
+		slog.Warn("failed to load fund types from storage", "error", err)
+		return nil
+	}
+
+	types := make(map[string]tefas.FundType)
+	for _, h := range holdings {
+		if h.FundType != "" {
+			types[h.Symbol] = tefas.FundType(h.FundType)
+		}
+	}
+	return types
+}
+
+// toFundTypes converts configured fund types to the provider's type
+func toFundTypes(types map[string]string) map[string]tefas.FundType {
+	out := make(map[string]tefas.FundType, len(types))
+	for code, t := range types {
+		out[code] = tefas.FundType(t)
+	}
+	return out
+}
+
+// backupPolicy returns where and how many database backups are kept
+func backupPolicy(cfg *config.Config) storage.BackupPolicy {
+	return storage.BackupPolicy{Dir: cfg.Database.Backup.Dir, Keep: cfg.Database.Backup.Keep}
+}
+
+// backupBeforeMigrate returns the policy for backups taken before schema
+// migrations, or nil when they are off
+func backupBeforeMigrate(cfg *config.Config) *storage.BackupPolicy {
+	if !cfg.Database.Backup.BeforeMigrate {
+		return nil
+	}
+	policy := backupPolicy(cfg)
+	return &policy
+}
+
+// binanceSymbols returns the configured crypto holdings as Binance pairs,
+// skipping symbols that can't be read as one
+func binanceSymbols(cfg *config.BinanceConfig) []string {
+	symbols := make([]string, 0, len(cfg.Holdings))
+	for _, symbol := range cfg.GetCryptoSymbols() {
+		if pair, err := binance.NormalizeSymbol(symbol, cfg.Quote); err == nil {
+			symbols = append(symbols, pair)
+		}
+	}
+	return symbols
+}
+
+// newLogHandler returns a stdout handler in the given format ("text" or
+// "json") that filters by logLevel
+func newLogHandler(format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: logLevel}
+	if format == "json" {
+		return slog.NewJSONHandler(os.Stdout, opts)
+	}
+	return slog.NewTextHandler(os.Stdout, opts)
+}
+
+// setLogLevel applies a validated logging.level
+func setLogLevel(level string) {
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		slog.Warn("ignoring invalid log level", "level", level, "error", err)
+	}
+}
+
+// reloadConfig re-reads config.yaml and applies hot-reloadable settings.
+// Holdings are not re-migrated; port and database settings require a restart.
+func reloadConfig(holder *config.Holder, rp *reloadableProviders) {
+	slog.Info("reloading config")
+
+	newCfg, err := config.Load("config.yaml", loadOptions)
+	if err != nil {
+		slog.Error("failed to reload config, keeping current", "error", err)
+		return
+	}
+	if err := newCfg.Validate(); err != nil {
+		slog.Error("invalid config on reload, keeping current", "error", err)
+		return
+	}
+
+	oldCfg := holder.Get()
+	if newCfg.Server.Port != oldCfg.Server.Port {
+		slog.Warn("server.port changed; restart required to apply", "current", oldCfg.Server.Port, "new", newCfg.Server.Port)
+	}
+	if newCfg.Server.CORSAllowCredentials != oldCfg.Server.CORSAllowCredentials || newCfg.Server.CORSMaxAge != oldCfg.Server.CORSMaxAge {
+		slog.Warn("server.cors_allow_credentials or cors_max_age changed; restart required to apply")
+	}
+	if newCfg.Logging.Format != oldCfg.Logging.Format {
+		slog.Warn("logging.format changed; restart required to apply", "current", oldCfg.Logging.Format, "new", newCfg.Logging.Format)
+	}
+	setLogLevel(newCfg.Logging.Level)
+	if newCfg.Database.Path != oldCfg.Database.Path {
+		slog.Warn("database.path changed; restart required to apply", "current", oldCfg.Database.Path, "new", newCfg.Database.Path)
+	} else if newCfg.Database != oldCfg.Database {
+		slog.Warn("database pool settings changed; restart required to apply")
+	}
+
+	if rp.tefas != nil {
+		rp.tefas.SetSymbols(newCfg.TEFAS.GetFundCodes())
+		rp.tefas.SetFundTypes(toFundTypes(newCfg.TEFAS.GetFundTypes()))
+		rp.tefas.SetCacheTTL(newCfg.TEFAS.CacheTTL)
+		rp.tefas.SetStaleAfter(newCfg.TEFAS.StaleAfter)
+	} else if len(newCfg.TEFAS.Holdings) > 0 {
+		slog.Warn("TEFAS holdings added but provider was not started; restart required")
+	}
+	if rp.binance != nil {
+		rp.binance.SetSymbols(binanceSymbols(&newCfg.Crypto.Binance))
+		rp.binance.SetCacheTTL(newCfg.Crypto.Binance.CacheTTL)
+		rp.binance.SetStaleAfter(newCfg.Crypto.Binance.StaleAfter)
+	}
+	if rp.coingecko != nil {
+		rp.coingecko.SetCacheTTL(newCfg.Crypto.CoinGecko.CacheTTL)
+		rp.coingecko.SetStaleAfter(newCfg.Crypto.CoinGecko.StaleAfter)
+	}
+	if rp.frankfurter != nil {
+		rp.frankfurter.SetCacheTTL(newCfg.FX.Frankfurter.CacheTTL)
+	}
+
+	holder.Set(newCfg)
+	slog.Info("config reloaded")
 }
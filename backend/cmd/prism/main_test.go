@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+)
+
+func TestReloadConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		wantTimeout time.Duration
+	}{
+		{"valid config applied", "mock:\n  enabled: true\nserver:\n  request_timeout: 45s\n", 45 * time.Second},
+		{"invalid config kept out", "mock:\n  enabled: true\nserver:\n  request_timeout: 6m\n", 30 * time.Second},
+		{"unknown key kept out", "mock:\n  enabled: true\nserver:\n  requst_timeout: 45s\n", 30 * time.Second},
+		{"unparsable config kept out", "server: [\n", 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatalf("writing config: %v", err)
+			}
+			t.Setenv("PRISM_CONFIG", path)
+
+			current := &config.Config{}
+			current.Server.RequestTimeout = 30 * time.Second
+			holder := config.NewHolder(current)
+			reloadConfig(holder, &reloadableProviders{})
+
+			if got := holder.Get().Server.RequestTimeout; got != tt.wantTimeout {
+				t.Errorf("request timeout after reload = %v, want %v", got, tt.wantTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/providers/binance"
+	"github.com/ferhatkunduraci/prism/internal/providers/coingecko"
+	"github.com/ferhatkunduraci/prism/internal/providers/frankfurter"
+	"github.com/ferhatkunduraci/prism/internal/providers/tefas"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+)
+
+// newProviderRegistry registers a factory for every provider type the
+// config can enable. Built providers are also kept in rp so a reload can
+// reconfigure them.
+func newProviderRegistry(cfg *config.Config, store *storage.Storage, rp *reloadableProviders) *providers.Registry {
+	reg := providers.NewRegistry()
+	reg.SetCircuitBreaker(providers.BreakerConfig{
+		FailureThreshold: cfg.Crypto.CircuitBreaker.FailureThreshold,
+		Cooldown:         cfg.Crypto.CircuitBreaker.Cooldown,
+	})
+
+	reg.Register(providers.ProviderTypeTEFAS, providers.RoleFunds, func(ctx context.Context) (any, error) {
+		fundCodes := cfg.TEFAS.GetFundCodes()
+		slog.Info("initializing TEFAS provider", "funds", fundCodes)
+		rp.tefas = tefas.NewProvider(tefas.Config{
+			Headless:  cfg.TEFAS.Headless,
+			Funds:     fundCodes,
+			FundTypes: storedFundTypes(ctx, store),
+			CacheTTL:  cfg.TEFAS.CacheTTL,
+			Timeout:   cfg.TEFAS.Timeout,
+			Store:     store,
+			NameStore: store,
+
+			StaleAfter:   cfg.TEFAS.StaleAfter,
+			RestartAfter: cfg.TEFAS.RestartAfterFailures,
+			LookbackDays: cfg.TEFAS.MaxLookbackDays,
+
+			FetchConcurrency: cfg.TEFAS.FetchConcurrency,
+			Proxy: tefas.Proxy{
+				Server:   cfg.TEFAS.Proxy.Server,
+				Username: cfg.TEFAS.Proxy.Username,
+				Password: cfg.TEFAS.Proxy.Password,
+			},
+			LaunchArgs: cfg.TEFAS.LaunchArgs,
+			UserAgents: cfg.TEFAS.UserAgents,
+			Holidays:   cfg.TEFAS.GetHolidays(),
+			Location:   cfg.TEFAS.GetLocation(),
+		})
+		// Types set in config take precedence over stored ones
+		rp.tefas.SetFundTypes(toFundTypes(cfg.TEFAS.GetFundTypes()))
+		return rp.tefas, nil
+	})
+
+	reg.Register(providers.ProviderTypeBinance, providers.RoleCrypto, func(ctx context.Context) (any, error) {
+		cryptoSymbols := binanceSymbols(&cfg.Crypto.Binance)
+		slog.Info("initializing Binance provider", "symbols", cryptoSymbols)
+		rp.binance = binance.NewProvider(binance.Config{
+			Symbols:  cryptoSymbols,
+			CacheTTL: cfg.Crypto.Binance.CacheTTL,
+			Timeout:  cfg.Crypto.Binance.Timeout,
+			Store:    store,
+			Proxy:    cfg.Crypto.GetHTTPProxy(),
+
+			StaleAfter: cfg.Crypto.Binance.StaleAfter,
+		})
+		return rp.binance, nil
+	})
+
+	// Registered after Binance in EnabledProviders, so it backs Binance up
+	// when both are enabled
+	reg.Register(providers.ProviderTypeCoinGecko, providers.RoleCrypto, func(ctx context.Context) (any, error) {
+		slog.Info("initializing CoinGecko provider")
+		rp.coingecko = coingecko.NewProvider(coingecko.Config{
+			APIKey:   cfg.Crypto.CoinGecko.APIKey,
+			CacheTTL: cfg.Crypto.CoinGecko.CacheTTL,
+			Timeout:  cfg.Crypto.CoinGecko.Timeout,
+			Store:    store,
+			Proxy:    cfg.Crypto.GetHTTPProxy(),
+
+			StaleAfter: cfg.Crypto.CoinGecko.StaleAfter,
+		})
+		return rp.coingecko, nil
+	})
+
+	// Fiat exchange rates (preferred over the USDT-based crypto rate)
+	reg.Register(providers.ProviderTypeFrankfurter, providers.RoleFX, func(ctx context.Context) (any, error) {
+		slog.Info("initializing Frankfurter exchange-rate provider")
+		rp.frankfurter = frankfurter.NewProvider(frankfurter.Config{
+			BaseURL:  cfg.FX.Frankfurter.BaseURL,
+			CacheTTL: cfg.FX.Frankfurter.CacheTTL,
+			Timeout:  cfg.FX.Frankfurter.Timeout,
+		})
+		return rp.frankfurter, nil
+	})
+
+	return reg
+}
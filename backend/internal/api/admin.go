@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// resetConfirmation must be sent as confirm for POST /api/admin/reset to go ahead
+const resetConfirmation = "RESET"
+
+// CacheFlushResponse lists the providers whose caches were flushed
+type CacheFlushResponse struct {
+	Flushed []string `json:"flushed"`
+}
+
+// FlushCaches handles POST /api/admin/cache/flush
+//
+// Expires every provider cache and the cached summary, so the next request
+// fetches fresh prices instead of waiting for the TTLs.
+func (h *Handler) FlushCaches(c *gin.Context) {
+	flushed := []string{}
+	for _, p := range []any{h.tefasProvider, h.cryptoProvider, h.fxProvider} {
+		flusher, ok := p.(providers.CacheFlusher)
+		if !ok {
+			continue
+		}
+		flusher.FlushCache()
+		if named, ok := p.(interface{ Name() string }); ok {
+			flushed = append(flushed, named.Name())
+		}
+	}
+	h.invalidateSummary()
+
+	slog.Info("provider caches flushed", "providers", flushed)
+	c.JSON(http.StatusOK, CacheFlushResponse{Flushed: flushed})
+}
+
+// ResetRequest is the body of POST /api/admin/reset
+type ResetRequest struct {
+	Confirm string `json:"confirm"` // Must be "RESET"
+	Reseed  bool   `json:"reseed"`  // Recreate the holdings listed in the config afterwards
+}
+
+// Reset handles POST /api/admin/reset
+//
+// Permanently deletes every holding, including those in the trash, and
+// every portfolio snapshot. With reseed, the holdings listed in the current
+// config are created again, as on a first start; invalid ones are skipped.
+// Nothing happens unless the body confirms with {"confirm": "RESET"}.
+func (h *Handler) Reset(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req ResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidBody(c, err)
+		return
+	}
+	if req.Confirm != resetConfirmation {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, `Send {"confirm": "RESET"} to delete all holdings and snapshots`)
+		return
+	}
+
+	var seed []storage.CreateHoldingRequest
+	if req.Reseed {
+		reqs, err := h.configHoldings(h.cfg.Get())
+		if err != nil {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+			return
+		}
+		for i, v := range storage.CheckHoldings(reqs) {
+			if v.Status == storage.ValidationNew {
+				seed = append(seed, reqs[i])
+			}
+		}
+	}
+
+	result, err := h.storage.Reset(ctx, seed)
+	if err != nil {
+		slog.Error("failed to reset database", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to reset database")
+		return
+	}
+	h.invalidateSummary()
+
+	// Like on a first start, seeded holdings without a cost basis start at
+	// break-even once prices are in
+	if result.HoldingsSeeded > 0 && h.cfg.Get().InferCostBasis {
+		go h.InferMissingCostBases(context.WithoutCancel(ctx))
+	}
+
+	slog.Warn("database reset", "holdings_removed", result.HoldingsRemoved, "snapshots_removed", result.SnapshotsRemoved, "holdings_seeded", result.HoldingsSeeded)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestReset(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.TEFAS.Holdings = []config.FundHolding{
+		{Code: "KUT", Quantity: 100, CostBasis: 1200},
+		{Code: "TI2", Quantity: -1}, // Invalid, so skipped
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantResult  storage.ResetResult
+		wantSymbols []string
+	}{
+		{
+			name:       "not confirmed",
+			body:       `{"confirm": "reset"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty body",
+			body:       ``,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "reset",
+			body:       `{"confirm": "RESET"}`,
+			wantStatus: http.StatusOK,
+			wantResult: storage.ResetResult{HoldingsRemoved: 2, SnapshotsRemoved: 1},
+		},
+		{
+			name:        "reseed from config",
+			body:        `{"confirm": "RESET", "reseed": true}`,
+			wantStatus:  http.StatusOK,
+			wantResult:  storage.ResetResult{HoldingsRemoved: 2, SnapshotsRemoved: 1, HoldingsSeeded: 1},
+			wantSymbols: []string{"KUT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "AFT", Quantity: 10},
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 1},
+			)
+			store.UpsertSnapshot(context.Background(), storage.Snapshot{Date: time.Now(), TotalValue: 10})
+			h := NewHandler(config.NewHolder(cfg), nil, nil, nil, store)
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/admin/reset", h.Reset)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/reset", strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			holdings, _ := store.GetAllHoldings(context.Background())
+			if tt.wantStatus != http.StatusOK {
+				if len(holdings) != 2 {
+					t.Errorf("%d holdings left after a refused reset, want 2", len(holdings))
+				}
+				return
+			}
+
+			var result storage.ResetResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if result != tt.wantResult {
+				t.Errorf("result = %+v, want %+v", result, tt.wantResult)
+			}
+			var symbols []string
+			for _, holding := range holdings {
+				symbols = append(symbols, holding.Symbol)
+			}
+			if strings.Join(symbols, ",") != strings.Join(tt.wantSymbols, ",") {
+				t.Errorf("holdings after reset = %v, want %v", symbols, tt.wantSymbols)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/notify"
+)
+
+// RunPortfolioAlerts checks the notify.alerts conditions against the
+// portfolio summary every interval until ctx is cancelled, sending a message
+// through n for each one met. Alerts are read from the current config on
+// every check, so a reload applies to the next one. Delivery failures are
+// logged and never stop the loop.
+func (h *Handler) RunPortfolioAlerts(ctx context.Context, n notify.Notifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	state := newAlertState()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		alerts := h.cfg.Get().Notify.Alerts
+		if len(alerts) == 0 {
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		summary, ok := h.alertSummary(checkCtx)
+		if ok {
+			for _, message := range state.check(alerts, summary, time.Now()) {
+				if err := n.Send(checkCtx, message); err != nil {
+					slog.Warn("failed to send portfolio alert", "error", err)
+				} else {
+					slog.Info("sent portfolio alert", "message", message)
+				}
+			}
+		}
+		cancel()
+	}
+}
+
+// alertSummary returns the summary to check alerts against, or false when
+// a holding has no price or the totals couldn't be converted to the display
+// currency, as such a total would set off alerts falsely
+func (h *Handler) alertSummary(ctx context.Context) (PortfolioSummary, bool) {
+	holdings, err := h.storage.GetAllHoldings(ctx)
+	if err != nil {
+		slog.Warn("skipping alert check", "error", err)
+		return PortfolioSummary{}, false
+	}
+	summary, err := h.cachedPortfolioSummary(ctx)
+	if err != nil {
+		slog.Warn("skipping alert check", "error", err)
+		return PortfolioSummary{}, false
+	}
+	if symbol := unpricedHolding(summary, holdings); symbol != "" {
+		slog.Warn("skipping alert check; holding has no current price", "symbol", symbol)
+		return PortfolioSummary{}, false
+	}
+	if !summary.TotalsConverted {
+		slog.Warn("skipping alert check; totals aren't converted to the display currency", "display_currency", summary.DisplayCurrency)
+		return PortfolioSummary{}, false
+	}
+	return summary, true
+}
+
+// alertState remembers which alerts have fired. A threshold alert fires
+// when its condition starts to hold and again only after it stopped
+// holding; a day-drop alert fires at most once per day.
+type alertState struct {
+	active  map[config.AlertConfig]bool   // Threshold alerts whose condition held at the last check
+	firedOn map[config.AlertConfig]string // Day each day-drop alert last fired
+}
+
+func newAlertState() *alertState {
+	return &alertState{
+		active:  make(map[config.AlertConfig]bool),
+		firedOn: make(map[config.AlertConfig]string),
+	}
+}
+
+// check returns the messages for the alerts that fire on summary as of now
+func (s *alertState) check(alerts []config.AlertConfig, summary PortfolioSummary, now time.Time) []string {
+	var messages []string
+	for _, a := range alerts {
+		switch {
+		case a.Above > 0:
+			if s.crossed(a, summary.TotalValue >= a.Above) {
+				messages = append(messages, fmt.Sprintf("Prism alert: portfolio value %.2f is at or above %.2f", summary.TotalValue, a.Above))
+			}
+		case a.Below > 0:
+			if s.crossed(a, summary.TotalValue <= a.Below) {
+				messages = append(messages, fmt.Sprintf("Prism alert: portfolio value %.2f is at or below %.2f", summary.TotalValue, a.Below))
+			}
+		case a.DayDropPct > 0:
+			today := now.Format(time.DateOnly)
+			if summary.DayChangePct == nil || -*summary.DayChangePct <= a.DayDropPct || s.firedOn[a] == today {
+				continue
+			}
+			s.firedOn[a] = today
+			messages = append(messages, fmt.Sprintf("Prism alert: portfolio value %.2f is down %.2f%% since %s (threshold %.2f%%)",
+				summary.TotalValue, -*summary.DayChangePct, *summary.DayChangeFrom, a.DayDropPct))
+		}
+	}
+	return messages
+}
+
+// crossed records whether a's condition holds and reports whether it has
+// just started to
+func (s *alertState) crossed(a config.AlertConfig, holds bool) bool {
+	was := s.active[a]
+	s.active[a] = holds
+	return holds && !was
+}
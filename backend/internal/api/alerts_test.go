@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+)
+
+func TestAlertStateCheck(t *testing.T) {
+	above := config.AlertConfig{Scope: config.AlertScopePortfolio, Above: 1000}
+	below := config.AlertConfig{Scope: config.AlertScopePortfolio, Below: 500}
+	drop := config.AlertConfig{Scope: config.AlertScopePortfolio, DayDropPct: 5}
+	alerts := []config.AlertConfig{above, below, drop}
+
+	summary := func(value, dayChangePct float64) PortfolioSummary {
+		from := "2024-03-12"
+		return PortfolioSummary{TotalValue: value, DayChangePct: &dayChangePct, DayChangeFrom: &from}
+	}
+	day1 := time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	steps := []struct {
+		name    string
+		summary PortfolioSummary
+		now     time.Time
+		want    int
+	}{
+		{"nothing met", summary(800, -1), day1, 0},
+		{"crosses above", summary(1200, 2), day1, 1},
+		{"stays above", summary(1300, 3), day1, 0},
+		{"falls back", summary(900, 0), day1, 0},
+		{"crosses above again", summary(1100, 1), day1, 1},
+		{"drops and crosses below", summary(400, -8), day1, 2},
+		{"drop already sent today", summary(390, -10), day1, 0},
+		{"drop on the next day", summary(380, -6), day2, 1},
+		{"no day change", PortfolioSummary{TotalValue: 380}, day2, 0},
+	}
+
+	state := newAlertState()
+	for _, step := range steps {
+		if got := state.check(alerts, step.summary, step.now); len(got) != step.want {
+			t.Errorf("%s: sent %q, want %d messages", step.name, got, step.want)
+		}
+	}
+}
+
+func TestAlertSummary(t *testing.T) {
+	kut := storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20}
+	btc := storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5}
+
+	tests := []struct {
+		name      string
+		holdings  []storage.CreateHoldingRequest
+		fx        providers.ExchangeRateProvider
+		wantOK    bool
+		wantValue float64
+	}{
+		{"funds only", []storage.CreateHoldingRequest{kut}, nil, true, 30},
+		{"converted", []storage.CreateHoldingRequest{kut, btc}, staticFX{"USD": 40}, true, 430},
+		// 30 TRY and 10 USD would add up to 40 of neither
+		{"unconverted", []storage.CreateHoldingRequest{kut, btc}, nil, false, 0},
+		{"unpriced holding", []storage.CreateHoldingRequest{kut, {Type: storage.HoldingTypeFund, Symbol: "TI2", Quantity: 1}}, nil, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(config.NewHolder(&config.Config{}),
+				&staticProvider{prices: map[string]float64{"KUT": 3}},
+				&staticProvider{prices: map[string]float64{"BTCUSDT": 5}},
+				tt.fx, newFakeStore(tt.holdings...))
+
+			summary, ok := h.alertSummary(context.Background())
+			if ok != tt.wantOK {
+				t.Fatalf("alertSummary() ok = %v, want %v (totals converted: %v)", ok, tt.wantOK, summary.TotalsConverted)
+			}
+			if ok && summary.TotalValue != tt.wantValue {
+				t.Errorf("total value = %v, want %v", summary.TotalValue, tt.wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// targetSumTolerance is how far target percentages may stray from 100 before warning
+const targetSumTolerance = 0.01
+
+// AllocationEntry compares a holding's current weight against its target
+type AllocationEntry struct {
+	Type            storage.HoldingType `json:"type"`
+	Symbol          string              `json:"symbol"`
+	Value           float64             `json:"value"`
+	CurrentPct      float64             `json:"current_pct"`
+	TargetPct       *float64            `json:"target_pct"`
+	DriftPct        *float64            `json:"drift_pct,omitempty"`        // current_pct - target_pct
+	RebalanceAmount *float64            `json:"rebalance_amount,omitempty"` // Positive = buy, negative = sell
+}
+
+// AllocationResponse represents the target allocation drift report. Values
+// and rebalance amounts are in Currency, the summary's display currency,
+// or added as they are when the summary couldn't convert its totals.
+type AllocationResponse struct {
+	TotalValue     float64           `json:"total_value"`
+	Currency       string            `json:"currency,omitempty"`
+	TotalTargetPct float64           `json:"total_target_pct"`
+	Warning        string            `json:"warning,omitempty"`
+	Holdings       []AllocationEntry `json:"holdings"`
+	LastUpdated    time.Time         `json:"last_updated"`
+}
+
+// GetAllocation handles GET /api/portfolio/allocation
+func (h *Handler) GetAllocation(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	holdings, err := h.storage.GetAllHoldings(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+
+	summary, err := h.cachedPortfolioSummary(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+
+	c.JSON(http.StatusOK, buildAllocation(summary, holdings))
+}
+
+// buildAllocation computes each holding's weight and rebalance amount using
+// the same values and total as the portfolio summary, each value converted
+// to the currency of the total first
+func buildAllocation(summary PortfolioSummary, holdings []storage.Holding) AllocationResponse {
+	targets := make(map[storage.HoldingType]map[string]*float64)
+	for _, h := range holdings {
+		if targets[h.Type] == nil {
+			targets[h.Type] = make(map[string]*float64)
+		}
+		targets[h.Type][h.Symbol] = h.TargetPct
+	}
+
+	resp := AllocationResponse{
+		TotalValue:  summary.TotalValue,
+		Currency:    summary.totalsCurrency(),
+		Holdings:    make([]AllocationEntry, 0, len(summary.Funds)+len(summary.Cryptos)),
+		LastUpdated: summary.LastUpdated,
+	}
+
+	hasTargets := false
+	add := func(holdingType storage.HoldingType, symbol string, value float64) {
+		entry := AllocationEntry{
+			Type:      holdingType,
+			Symbol:    symbol,
+			Value:     value,
+			TargetPct: targets[holdingType][symbol],
+		}
+		if summary.TotalValue > 0 {
+			entry.CurrentPct = value / summary.TotalValue * 100
+		}
+		if entry.TargetPct != nil {
+			hasTargets = true
+			resp.TotalTargetPct += *entry.TargetPct
+			drift := entry.CurrentPct - *entry.TargetPct
+			rebalance := *entry.TargetPct/100*summary.TotalValue - value
+			entry.DriftPct = &drift
+			entry.RebalanceAmount = &rebalance
+		}
+		resp.Holdings = append(resp.Holdings, entry)
+	}
+
+	for _, f := range summary.Funds {
+		add(storage.HoldingTypeFund, f.Code, summary.inTotalsCurrency(f.Value, "TRY"))
+	}
+	for _, cr := range summary.Cryptos {
+		add(storage.HoldingTypeCrypto, cr.Symbol, summary.inTotalsCurrency(cr.Value, cr.Currency))
+	}
+
+	// Report rather than reject targets that don't add up
+	if hasTargets && math.Abs(resp.TotalTargetPct-100) > targetSumTolerance {
+		resp.Warning = fmt.Sprintf("target percentages sum to %.2f%%, not 100%%", resp.TotalTargetPct)
+	}
+
+	return resp
+}
+
+// topMoversCount is how many holdings are listed in each movers section
+const topMoversCount = 5
+
+// BreakdownItem represents one holding's share of the portfolio
+type BreakdownItem struct {
+	Type   storage.HoldingType `json:"type"`
+	Symbol string              `json:"symbol"`
+	Name   string              `json:"name"`
+	Value  float64             `json:"value"`
+	Pct    float64             `json:"pct"` // Share of total portfolio value
+	PnL    float64             `json:"pnl"`
+	PnLPct float64             `json:"pnl_pct"`
+	Tags   []string            `json:"tags,omitempty"`
+}
+
+// Breakdown groupings, chosen with GET /api/portfolio/breakdown?by=
+const (
+	breakdownByType = "type"
+	breakdownByTag  = "tag"
+)
+
+// untaggedGroup collects the holdings without tags in a breakdown by tag
+const untaggedGroup = "untagged"
+
+// BreakdownGroup aggregates holdings that share a group key
+type BreakdownGroup struct {
+	Group    string          `json:"group"`
+	Value    float64         `json:"value"`
+	Pct      float64         `json:"pct"`
+	Holdings []BreakdownItem `json:"holdings"`
+}
+
+// BreakdownResponse represents the portfolio breakdown by asset type or tag.
+// Values and P&L are in Currency, as in AllocationResponse.
+type BreakdownResponse struct {
+	TotalValue  float64          `json:"total_value"`
+	Currency    string           `json:"currency,omitempty"`
+	By          string           `json:"by"`
+	Groups      []BreakdownGroup `json:"groups"`
+	TopGainers  []BreakdownItem  `json:"top_gainers"`
+	TopLosers   []BreakdownItem  `json:"top_losers"`
+	LastUpdated time.Time        `json:"last_updated"`
+}
+
+// GetBreakdown handles GET /api/portfolio/breakdown?by=type|tag
+//
+// Groups by type unless by=tag. A holding with several tags counts toward
+// each of their groups, so tag groups can add up to more than the total.
+func (h *Handler) GetBreakdown(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	by := c.DefaultQuery("by", breakdownByType)
+	if by != breakdownByType && by != breakdownByTag {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("by must be %q or %q, got %q", breakdownByType, breakdownByTag, by))
+		return
+	}
+
+	summary, err := h.cachedPortfolioSummary(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+
+	c.JSON(http.StatusOK, buildBreakdown(summary, by))
+}
+
+// buildBreakdown groups summary holdings by type or tag and picks the top
+// movers, with each value converted to the currency of the total
+func buildBreakdown(summary PortfolioSummary, by string) BreakdownResponse {
+	share := func(value float64) float64 {
+		if summary.TotalValue == 0 {
+			return 0
+		}
+		return value / summary.TotalValue * 100
+	}
+
+	items := make([]BreakdownItem, 0, len(summary.Funds)+len(summary.Cryptos))
+	for _, f := range summary.Funds {
+		value := summary.inTotalsCurrency(f.Value, "TRY")
+		items = append(items, BreakdownItem{
+			Type: storage.HoldingTypeFund, Symbol: f.Code, Name: f.Name,
+			Value: value, Pct: share(value), PnL: summary.inTotalsCurrency(f.PnL, "TRY"), PnLPct: f.PnLPct, Tags: f.Tags,
+		})
+	}
+	for _, cr := range summary.Cryptos {
+		value := summary.inTotalsCurrency(cr.Value, cr.Currency)
+		items = append(items, BreakdownItem{
+			Type: storage.HoldingTypeCrypto, Symbol: cr.Symbol, Name: cr.Name,
+			Value: value, Pct: share(value), PnL: summary.inTotalsCurrency(cr.PnL, cr.Currency), PnLPct: cr.PnLPct, Tags: cr.Tags,
+		})
+	}
+
+	// Group in order of first appearance: fund then crypto by type
+	groups := make([]BreakdownGroup, 0, 2)
+	index := make(map[string]int)
+	for _, item := range items {
+		for _, key := range breakdownKeys(item, by) {
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, BreakdownGroup{Group: key})
+			}
+			groups[i].Value += item.Value
+			groups[i].Holdings = append(groups[i].Holdings, item)
+		}
+	}
+	for i := range groups {
+		groups[i].Pct = share(groups[i].Value)
+	}
+	// Tags have no natural order, so the largest come first and untagged last
+	if by == breakdownByTag {
+		sort.SliceStable(groups, func(i, j int) bool {
+			if (groups[i].Group == untaggedGroup) != (groups[j].Group == untaggedGroup) {
+				return groups[j].Group == untaggedGroup
+			}
+			return groups[i].Value > groups[j].Value
+		})
+	}
+
+	gainers := make([]BreakdownItem, 0, len(items))
+	losers := make([]BreakdownItem, 0, len(items))
+	for _, item := range items {
+		switch {
+		case item.PnLPct > 0:
+			gainers = append(gainers, item)
+		case item.PnLPct < 0:
+			losers = append(losers, item)
+		}
+	}
+	sort.SliceStable(gainers, func(i, j int) bool { return gainers[i].PnLPct > gainers[j].PnLPct })
+	sort.SliceStable(losers, func(i, j int) bool { return losers[i].PnLPct < losers[j].PnLPct })
+
+	return BreakdownResponse{
+		TotalValue:  summary.TotalValue,
+		Currency:    summary.totalsCurrency(),
+		By:          by,
+		Groups:      groups,
+		TopGainers:  gainers[:min(len(gainers), topMoversCount)],
+		TopLosers:   losers[:min(len(losers), topMoversCount)],
+		LastUpdated: summary.LastUpdated,
+	}
+}
+
+// breakdownKeys returns the groups item belongs to when grouping by by
+func breakdownKeys(item BreakdownItem, by string) []string {
+	if by != breakdownByTag {
+		return []string{string(item.Type)}
+	}
+	if len(item.Tags) == 0 {
+		return []string{untaggedGroup}
+	}
+	return item.Tags
+}
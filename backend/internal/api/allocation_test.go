@@ -0,0 +1,278 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// allocationSummary is a portfolio of KUT worth 3000 TRY (cost 2000) and
+// BTCUSDT worth 25 USD (cost 20), converted at usdTRY when it is positive
+func allocationSummary(usdTRY float64) PortfolioSummary {
+	summary := summarize(
+		[]FundPrice{{Code: "KUT", Name: "KUT", Value: 3000, CostBasis: 2000, PnL: 1000, PnLPct: 50, Tags: []string{"retirement"}}},
+		[]CryptoPrice{{Symbol: "BTCUSDT", Currency: "USD", Name: "BTCUSDT", Value: 25, CostBasis: 20, PnL: 5, PnLPct: 25}},
+	)
+	rates := tryRates{}
+	if usdTRY > 0 {
+		rates.rates = map[string]float64{"TRY": 1, "USD": usdTRY}
+	}
+	summary.convertTotals(rates, summaryCurrencies{fund: "TRY", crypto: "USD", display: "TRY"})
+	return summary
+}
+
+func TestBuildAllocation(t *testing.T) {
+	targets := func(kut, btc *float64) []storage.Holding {
+		return []storage.Holding{
+			{Type: storage.HoldingTypeFund, Symbol: "KUT", TargetPct: kut},
+			{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", TargetPct: btc},
+		}
+	}
+
+	type entry struct {
+		value, pct       float64
+		drift, rebalance *float64
+	}
+	tests := []struct {
+		name         string
+		usdTRY       float64
+		holdings     []storage.Holding
+		wantTotal    float64
+		wantCurrency string
+		wantKUT      entry
+		wantBTC      entry
+		wantWarning  string
+	}{
+		{
+			name:         "converted to the display currency",
+			usdTRY:       40,
+			holdings:     targets(ptrFloat(50), ptrFloat(50)),
+			wantTotal:    4000,
+			wantCurrency: "TRY",
+			wantKUT:      entry{3000, 75, ptrFloat(25), ptrFloat(-1000)},
+			wantBTC:      entry{1000, 25, ptrFloat(-25), ptrFloat(1000)},
+		},
+		{
+			name:         "no targets",
+			usdTRY:       40,
+			holdings:     targets(nil, nil),
+			wantTotal:    4000,
+			wantCurrency: "TRY",
+			wantKUT:      entry{value: 3000, pct: 75},
+			wantBTC:      entry{value: 1000, pct: 25},
+		},
+		{
+			name:         "targets not adding up",
+			usdTRY:       40,
+			holdings:     targets(ptrFloat(60), ptrFloat(30)),
+			wantTotal:    4000,
+			wantCurrency: "TRY",
+			wantKUT:      entry{3000, 75, ptrFloat(15), ptrFloat(-600)},
+			wantBTC:      entry{1000, 25, ptrFloat(-5), ptrFloat(200)},
+			wantWarning:  "target percentages sum to 90.00%, not 100%",
+		},
+		{
+			name:         "targets within tolerance",
+			usdTRY:       40,
+			holdings:     targets(ptrFloat(50.005), ptrFloat(50)),
+			wantTotal:    4000,
+			wantCurrency: "TRY",
+			wantKUT:      entry{3000, 75, ptrFloat(24.995), ptrFloat(-999.8)},
+			wantBTC:      entry{1000, 25, ptrFloat(-25), ptrFloat(1000)},
+		},
+		{
+			name:         "targets over 100%",
+			usdTRY:       40,
+			holdings:     targets(ptrFloat(80), ptrFloat(40)),
+			wantTotal:    4000,
+			wantCurrency: "TRY",
+			wantKUT:      entry{3000, 75, ptrFloat(-5), ptrFloat(200)},
+			wantBTC:      entry{1000, 25, ptrFloat(-15), ptrFloat(600)},
+			wantWarning:  "target percentages sum to 120.00%, not 100%",
+		},
+		{
+			name:         "one target",
+			usdTRY:       40,
+			holdings:     targets(nil, ptrFloat(100)),
+			wantTotal:    4000,
+			wantCurrency: "TRY",
+			wantKUT:      entry{value: 3000, pct: 75},
+			wantBTC:      entry{1000, 25, ptrFloat(-75), ptrFloat(3000)},
+		},
+		{
+			// Without a rate the total adds TRY and USD as they are, and so
+			// do the weights
+			name:      "unconverted",
+			holdings:  targets(nil, nil),
+			wantTotal: 3025,
+			wantKUT:   entry{value: 3000, pct: 3000.0 / 3025 * 100},
+			wantBTC:   entry{value: 25, pct: 25.0 / 3025 * 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := buildAllocation(allocationSummary(tt.usdTRY), tt.holdings)
+
+			if resp.TotalValue != tt.wantTotal || resp.Currency != tt.wantCurrency {
+				t.Errorf("total = %v %q, want %v %q", resp.TotalValue, resp.Currency, tt.wantTotal, tt.wantCurrency)
+			}
+			if resp.Warning != tt.wantWarning {
+				t.Errorf("warning = %q, want %q", resp.Warning, tt.wantWarning)
+			}
+			if len(resp.Holdings) != 2 {
+				t.Fatalf("got %d entries, want 2", len(resp.Holdings))
+			}
+			pctSum := 0.0
+			for i, want := range []entry{tt.wantKUT, tt.wantBTC} {
+				got := resp.Holdings[i]
+				pctSum += got.CurrentPct
+				if got.Value != want.value || !closeTo(got.CurrentPct, want.pct) {
+					t.Errorf("%s value/pct = %v/%v, want %v/%v", got.Symbol, got.Value, got.CurrentPct, want.value, want.pct)
+				}
+				if !closeToPtr(got.DriftPct, want.drift) || !closeToPtr(got.RebalanceAmount, want.rebalance) {
+					t.Errorf("%s drift/rebalance = %v/%v, want %v/%v", got.Symbol, fmtPtr(got.DriftPct), fmtPtr(got.RebalanceAmount), fmtPtr(want.drift), fmtPtr(want.rebalance))
+				}
+			}
+			if !closeTo(pctSum, 100) {
+				t.Errorf("weights add up to %v%%, want 100%%", pctSum)
+			}
+		})
+	}
+}
+
+func TestGetAllocation(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, TargetPct: ptrFloat(60)},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "TI2", Quantity: 10, TargetPct: ptrFloat(40)},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "AFT", Quantity: 10},
+	)
+	tefas := &staticProvider{prices: map[string]float64{"KUT": 3, "TI2": 5, "AFT": 2}}
+	h := NewHandler(config.NewHolder(&config.Config{}), tefas, &staticProvider{}, nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/portfolio/allocation", h.GetAllocation)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/portfolio/allocation", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var resp AllocationResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	if resp.TotalValue != 100 || resp.TotalTargetPct != 100 || resp.Warning != "" {
+		t.Errorf("total = %v, targets = %v%%, warning %q; want 100, 100%%, none", resp.TotalValue, resp.TotalTargetPct, resp.Warning)
+	}
+	want := map[string]struct {
+		pct              float64
+		drift, rebalance *float64
+	}{
+		"KUT": {30, ptrFloat(-30), ptrFloat(30)},
+		"TI2": {50, ptrFloat(10), ptrFloat(-10)},
+		"AFT": {20, nil, nil},
+	}
+	if len(resp.Holdings) != len(want) {
+		t.Fatalf("holdings = %+v, want %d", resp.Holdings, len(want))
+	}
+	for _, got := range resp.Holdings {
+		w := want[got.Symbol]
+		if !closeTo(got.CurrentPct, w.pct) || !closeToPtr(got.DriftPct, w.drift) || !closeToPtr(got.RebalanceAmount, w.rebalance) {
+			t.Errorf("%s pct/drift/rebalance = %v/%v/%v, want %v/%v/%v", got.Symbol,
+				got.CurrentPct, fmtPtr(got.DriftPct), fmtPtr(got.RebalanceAmount), w.pct, fmtPtr(w.drift), fmtPtr(w.rebalance))
+		}
+	}
+}
+
+func TestBuildBreakdown(t *testing.T) {
+	tests := []struct {
+		name         string
+		usdTRY       float64
+		by           string
+		wantCurrency string
+		wantGroups   map[string][2]float64 // value, pct
+		wantBTCPnL   float64
+	}{
+		{
+			name:         "by type, converted",
+			usdTRY:       40,
+			by:           breakdownByType,
+			wantCurrency: "TRY",
+			wantGroups:   map[string][2]float64{"fund": {3000, 75}, "crypto": {1000, 25}},
+			wantBTCPnL:   200,
+		},
+		{
+			name:         "by tag, converted",
+			usdTRY:       40,
+			by:           breakdownByTag,
+			wantCurrency: "TRY",
+			wantGroups:   map[string][2]float64{"retirement": {3000, 75}, untaggedGroup: {1000, 25}},
+			wantBTCPnL:   200,
+		},
+		{
+			name:       "by type, unconverted",
+			by:         breakdownByType,
+			wantGroups: map[string][2]float64{"fund": {3000, 3000.0 / 3025 * 100}, "crypto": {25, 25.0 / 3025 * 100}},
+			wantBTCPnL: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := buildBreakdown(allocationSummary(tt.usdTRY), tt.by)
+
+			if resp.Currency != tt.wantCurrency {
+				t.Errorf("currency = %q, want %q", resp.Currency, tt.wantCurrency)
+			}
+			if len(resp.Groups) != len(tt.wantGroups) {
+				t.Fatalf("groups = %+v, want %v", resp.Groups, tt.wantGroups)
+			}
+			pctSum := 0.0
+			for _, g := range resp.Groups {
+				want, ok := tt.wantGroups[g.Group]
+				if !ok || g.Value != want[0] || !closeTo(g.Pct, want[1]) {
+					t.Errorf("group %s = %v (%v%%), want %v (%v%%)", g.Group, g.Value, g.Pct, want[0], want[1])
+				}
+				pctSum += g.Pct
+			}
+			if !closeTo(pctSum, 100) {
+				t.Errorf("group shares add up to %v%%, want 100%%", pctSum)
+			}
+			if len(resp.TopGainers) != 2 || resp.TopGainers[0].Symbol != "KUT" {
+				t.Fatalf("top gainers = %+v, want KUT then BTCUSDT", resp.TopGainers)
+			}
+			if btc := resp.TopGainers[1]; btc.PnL != tt.wantBTCPnL || btc.PnLPct != 25 {
+				t.Errorf("BTCUSDT pnl = %v (%v%%), want %v (25%%)", btc.PnL, btc.PnLPct, tt.wantBTCPnL)
+			}
+		})
+	}
+}
+
+// closeTo reports whether two percentages agree to well within rounding
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// closeToPtr is closeTo for optional values, which must both be set or unset
+func closeToPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return closeTo(*a, *b)
+}
+
+// fmtPtr prints an optional value for a test failure
+func fmtPtr(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/providers/binance"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCandleLimit is the number of candles returned when no limit is given
+const defaultCandleLimit = 30
+
+// CryptoHistoryResponse is the candlestick history of one crypto pair
+type CryptoHistoryResponse struct {
+	Symbol   string             `json:"symbol"`
+	Interval string             `json:"interval"`
+	Candles  []providers.Candle `json:"candles"`
+}
+
+// GetCryptoHistory handles GET /api/crypto/:symbol/history?interval=1d&limit=30
+//
+// Limits above binance.MaxKlineLimit are capped.
+func (h *Handler) GetCryptoHistory(c *gin.Context) {
+	symbol, err := h.normalizeSymbol(storage.HoldingTypeCrypto, c.Param("symbol"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+
+	interval := c.DefaultQuery("interval", "1d")
+	if !slices.Contains(binance.KlineIntervals, interval) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed,
+			fmt.Sprintf("interval must be one of %s, got %q", strings.Join(binance.KlineIntervals, ", "), interval))
+		return
+	}
+
+	limit := defaultCandleLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("limit must be a positive integer, got %q", s))
+			return
+		}
+		limit = min(n, binance.MaxKlineLimit)
+	}
+
+	kp, ok := h.cryptoProvider.(providers.KlineProvider)
+	if !ok {
+		respondError(c, http.StatusNotImplemented, CodeNotSupported, "configured crypto providers don't support price history")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	candles, err := kp.FetchKlines(ctx, symbol, interval, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, providers.ErrNotSupported):
+			respondError(c, http.StatusNotImplemented, CodeNotSupported, "configured crypto providers don't support price history")
+		case errors.Is(err, providers.ErrSymbolNotFound):
+			respondError(c, http.StatusNotFound, CodeSymbolNotFound, "Crypto not found")
+		default:
+			slog.Error("failed to fetch crypto history", "symbol", symbol, "interval", interval, "error", err)
+			respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Failed to fetch crypto history")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, CryptoHistoryResponse{
+		Symbol:   symbol,
+		Interval: interval,
+		Candles:  candles,
+	})
+}
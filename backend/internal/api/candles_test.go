@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/gin-gonic/gin"
+)
+
+// klineProvider serves limit candles for known symbols and records each call
+type klineProvider struct {
+	staticProvider
+	calls []string
+}
+
+func (p *klineProvider) FetchKlines(ctx context.Context, symbol, interval string, limit int) ([]providers.Candle, error) {
+	p.calls = append(p.calls, symbol+" "+interval)
+	if _, ok := p.prices[symbol]; !ok {
+		return nil, providers.ErrSymbolNotFound
+	}
+	candles := make([]providers.Candle, limit)
+	for i := range candles {
+		candles[i] = providers.Candle{OpenTime: time.Unix(int64(i)*86400, 0).UTC(), Close: p.prices[symbol]}
+	}
+	return candles, nil
+}
+
+func TestGetCryptoHistory(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    providers.Provider
+		path        string
+		wantStatus  int
+		wantCandles int
+		wantCall    string
+	}{
+		{"defaults", &klineProvider{staticProvider: staticProvider{prices: map[string]float64{"BTCUSDT": 5}}}, "/api/crypto/btc/history", http.StatusOK, defaultCandleLimit, "BTCUSDT 1d"},
+		{"interval and limit", &klineProvider{staticProvider: staticProvider{prices: map[string]float64{"BTCUSDT": 5}}}, "/api/crypto/BTCUSDT/history?interval=4h&limit=5", http.StatusOK, 5, "BTCUSDT 4h"},
+		{"limit capped", &klineProvider{staticProvider: staticProvider{prices: map[string]float64{"BTCUSDT": 5}}}, "/api/crypto/BTCUSDT/history?limit=5000", http.StatusOK, 1000, "BTCUSDT 1d"},
+		{"invalid interval", &klineProvider{}, "/api/crypto/BTCUSDT/history?interval=2d", http.StatusBadRequest, 0, ""},
+		{"invalid limit", &klineProvider{}, "/api/crypto/BTCUSDT/history?limit=0", http.StatusBadRequest, 0, ""},
+		{"unknown symbol", &klineProvider{}, "/api/crypto/NOPEUSDT/history", http.StatusNotFound, 0, "NOPEUSDT 1d"},
+		{"provider without candles", &staticProvider{}, "/api/crypto/BTCUSDT/history", http.StatusNotImplemented, 0, ""},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(config.NewHolder(&config.Config{}), nil, tt.provider, nil, newFakeStore())
+			r := gin.New()
+			r.GET("/api/crypto/:symbol/history", h.GetCryptoHistory)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if kp, ok := tt.provider.(*klineProvider); ok && tt.wantCall != "" {
+				if len(kp.calls) != 1 || kp.calls[0] != tt.wantCall {
+					t.Errorf("FetchKlines calls = %v, want [%s]", kp.calls, tt.wantCall)
+				}
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp CryptoHistoryResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if len(resp.Candles) != tt.wantCandles {
+				t.Errorf("got %d candles, want %d", len(resp.Candles), tt.wantCandles)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// CashflowResponse represents the money put into the portfolio over time
+type CashflowResponse struct {
+	Months           []CashflowMonth `json:"months"`
+	TotalNetDeposits float64         `json:"total_net_deposits"`
+	Currency         string          `json:"currency"`     // display_currency, which the deposits are converted to
+	LimitedData      bool            `json:"limited_data"` // True when months is a single point built from the current cost basis
+	Note             string          `json:"note,omitempty"`
+	LastUpdated      time.Time       `json:"last_updated"`
+}
+
+// CashflowMonth is the net amount deposited in one month
+type CashflowMonth struct {
+	Month       string  `json:"month"`        // "2006-01"
+	NetDeposits float64 `json:"net_deposits"` // Buys minus sells at transaction prices
+}
+
+// GetCashflow handles GET /api/portfolio/cashflow
+//
+// Holdings only record a lump-sum cost basis with no purchase dates, so the
+// stored cost bases, converted to display_currency, are reported as one
+// deposit in the current month. No prices are fetched.
+func (h *Handler) GetCashflow(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	holdings, err := h.storage.GetAllHoldings(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+	h.newCostConverter().convertAll(ctx, holdings)
+
+	var cryptoSymbols []string
+	amounts := make([]pricedAmount, 0, len(holdings))
+	for _, holding := range holdings {
+		if holding.Type == storage.HoldingTypeCrypto {
+			cryptoSymbols = append(cryptoSymbols, holding.Symbol)
+		}
+		amounts = append(amounts, pricedAmount{currency: priceCurrency(holding), costBasis: holding.CostBasis})
+	}
+	currencies := h.summaryCurrencies()
+	var rates tryRates
+	if h.fxProvider != nil || h.cryptoProvider != nil {
+		rates = h.fetchTRYRates(ctx, cryptoSymbols, currencies)
+	}
+	_, costBasis, _, ok := rates.convertedTotal(amounts, currencies.display)
+	if !ok {
+		respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable,
+			"Exchange rates unavailable; cost bases can't be converted to "+currencies.display)
+		return
+	}
+
+	c.JSON(http.StatusOK, buildCashflow(costBasis.InexactFloat64(), currencies.display, time.Now()))
+}
+
+// buildCashflow reports costBasis, in currency, as one deposit in the month
+// of now
+func buildCashflow(costBasis float64, currency string, now time.Time) CashflowResponse {
+	return CashflowResponse{
+		Months: []CashflowMonth{{
+			Month:       now.Format("2006-01"),
+			NetDeposits: costBasis,
+		}},
+		TotalNetDeposits: costBasis,
+		Currency:         currency,
+		LimitedData:      true,
+		Note:             "No transaction history is recorded, so deposits can't be split by month; showing the current total cost basis",
+		LastUpdated:      now,
+	}
+}
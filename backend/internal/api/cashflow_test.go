@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetCashflow(t *testing.T) {
+	kut := storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20}
+	btc := storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5}
+
+	tests := []struct {
+		name         string
+		display      string
+		holdings     []storage.CreateHoldingRequest
+		fx           providers.ExchangeRateProvider
+		wantStatus   int
+		wantDeposits float64
+	}{
+		// 20 TRY and 5 USD at 40 TRY
+		{"converted", "", []storage.CreateHoldingRequest{kut, btc}, staticFX{"USD": 40}, http.StatusOK, 220},
+		{"display currency", "USD", []storage.CreateHoldingRequest{kut, btc}, staticFX{"USD": 40}, http.StatusOK, 5.5},
+		{"funds without rates", "", []storage.CreateHoldingRequest{kut}, nil, http.StatusOK, 20},
+		{"no holdings", "", nil, nil, http.StatusOK, 0},
+		{"no rates", "", []storage.CreateHoldingRequest{kut, btc}, nil, http.StatusServiceUnavailable, 0},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DisplayCurrency: tt.display}
+			h := NewHandler(config.NewHolder(cfg), nil, nil, tt.fx, newFakeStore(tt.holdings...))
+			r := gin.New()
+			r.GET("/api/portfolio/cashflow", h.GetCashflow)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/portfolio/cashflow", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp CashflowResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			wantCurrency := tt.display
+			if wantCurrency == "" {
+				wantCurrency = "TRY"
+			}
+			if resp.Currency != wantCurrency || resp.TotalNetDeposits != tt.wantDeposits {
+				t.Errorf("total net deposits = %v %s, want %v %s", resp.TotalNetDeposits, resp.Currency, tt.wantDeposits, wantCurrency)
+			}
+			month := time.Now().Format("2006-01")
+			if len(resp.Months) != 1 || resp.Months[0].Month != month || resp.Months[0].NetDeposits != tt.wantDeposits {
+				t.Errorf("months = %+v, want one %s point of %v", resp.Months, month, tt.wantDeposits)
+			}
+			if !resp.LimitedData || resp.Note == "" {
+				t.Errorf("limited_data/note = %v/%q, want true with a note", resp.LimitedData, resp.Note)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressMinLength is the smallest body worth compressing; below it the
+// gzip framing eats most of the savings
+const compressMinLength = 1024
+
+// Compression gzips response bodies for clients that accept it. Bodies
+// under compressMinLength, responses that already carry a Content-Encoding
+// or an already-compressed content type, and streams (text/event-stream, or
+// any response flushed before it reaches compressMinLength) are sent as is.
+func Compression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if c.Request.Method == http.MethodHead || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		w := &gzipWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		if weight, err := strconv.ParseFloat(q, 64); err == nil && weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter holds back the start of a body until it knows whether to
+// compress it: once compressMinLength bytes arrive it switches to gzip,
+// while a flush, an unsuitable response or the handler finishing first
+// sends the body unchanged
+type gzipWriter struct {
+	gin.ResponseWriter
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool // Decided against compressing; writes go straight through
+}
+
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(p)
+	case w.passthrough:
+		return w.ResponseWriter.Write(p)
+	case !w.compressible():
+		w.passthrough = true
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= compressMinLength {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers as they are, so a body that follows can
+// no longer be compressed
+func (w *gzipWriter) WriteHeaderNow() {
+	if w.gz == nil {
+		w.pass()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush sends everything written so far. A stream flushes early and often,
+// so a body flushed before it was compressed stays uncompressed.
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	} else {
+		w.pass()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Written reports whether any of the body has been written, held back or not
+func (w *gzipWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// compressible reports whether the response is worth compressing, judging
+// by the status and headers set before the first write
+func (w *gzipWriter) compressible() bool {
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	switch {
+	case mediaType == "text/event-stream",
+		mediaType == "application/gzip",
+		mediaType == "application/zip",
+		mediaType == "application/octet-stream",
+		strings.HasPrefix(mediaType, "image/") && mediaType != "image/svg+xml",
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"):
+		return false
+	}
+	return true
+}
+
+// startGzip switches the response to gzip and compresses the held-back bytes
+func (w *gzipWriter) startGzip() error {
+	header := w.Header()
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	// The compressed body is a different representation, so a strong ETag
+	// must not be shared with the plain one; If-None-Match matching is weak
+	// and still recognizes it
+	if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		header.Set("ETag", "W/"+etag)
+	}
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	buf := w.buf
+	w.buf = nil
+	_, err := w.gz.Write(buf)
+	return err
+}
+
+// pass gives up on compressing and sends any held-back bytes unchanged
+func (w *gzipWriter) pass() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// finish sends what the handler left behind: the rest of the gzip stream,
+// or a body too short to compress
+func (w *gzipWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		return
+	}
+	w.pass()
+}
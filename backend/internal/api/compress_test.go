@@ -0,0 +1,95 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("prism ", 500)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Compression())
+	r.GET("/large", func(c *gin.Context) { c.String(http.StatusOK, large) })
+	r.GET("/tiny", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) })
+	r.GET("/etag", func(c *gin.Context) { respondWithETag(c, large, large) })
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, large)
+	})
+	r.GET("/flushed", func(c *gin.Context) {
+		c.String(http.StatusOK, "data: first\n\n")
+		c.Writer.Flush()
+		c.String(http.StatusOK, large)
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{"large body", "/large", "gzip, deflate", true},
+		{"no Accept-Encoding", "/large", "", false},
+		{"gzip refused", "/large", "gzip;q=0, br", false},
+		{"any encoding", "/large", "*", true},
+		{"tiny body", "/tiny", "gzip", false},
+		{"compressed type", "/image", "gzip", false},
+		{"event stream", "/stream", "gzip", false},
+		{"flushed early", "/flushed", "gzip", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			gzipped := w.Header().Get("Content-Encoding") == "gzip"
+			if gzipped != tt.wantGzip {
+				t.Fatalf("Content-Encoding = %q, want gzip: %v", w.Header().Get("Content-Encoding"), tt.wantGzip)
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", w.Header().Get("Vary"))
+			}
+			if !gzipped {
+				return
+			}
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("body is not gzip: %v", err)
+			}
+			body, err := io.ReadAll(zr)
+			if err != nil || string(body) != large {
+				t.Errorf("decompressed body is %d bytes (err %v), want the %d byte original", len(body), err, len(large))
+			}
+		})
+	}
+
+	// A compressed response gets a weak ETag, which If-None-Match still matches
+	req := httptest.NewRequest(http.MethodGet, "/etag", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("ETag of a gzipped response = %q, want a weak tag", etag)
+	}
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("revalidation: status %d with %d byte body, want 304 and no body", w.Code, w.Body.Len())
+	}
+}
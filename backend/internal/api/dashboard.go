@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DashboardResponse is everything the dashboard shows on load. Summary and
+// ExchangeRate are null when they couldn't be fetched, with the reason in
+// Errors keyed by field name ("summary", "exchange_rate").
+type DashboardResponse struct {
+	Summary      *PortfolioSummary     `json:"summary"`
+	ExchangeRate *ExchangeRateResponse `json:"exchange_rate"` // USD/TRY
+	Version      VersionResponse       `json:"version"`
+	Health       HealthResponse        `json:"health"`
+	Errors       map[string]string     `json:"errors,omitempty"`
+}
+
+// GetDashboard handles GET /api/dashboard
+//
+// The summary, exchange rate and provider health are fetched concurrently.
+// A part that fails is reported in Errors rather than failing the request.
+func (h *Handler) GetDashboard(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	resp := DashboardResponse{Version: versionInfo()}
+	var summaryErr, rateErr error
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		summary, err := h.cachedPortfolioSummary(ctx)
+		if err != nil {
+			summaryErr = err
+			return
+		}
+		resp.Summary = &summary
+	}()
+	go func() {
+		defer wg.Done()
+		rate, err := h.usdTRYRate(ctx)
+		if err != nil {
+			rateErr = err
+			return
+		}
+		resp.ExchangeRate = &rate
+	}()
+	go func() {
+		defer wg.Done()
+		resp.Health = h.health(ctx)
+	}()
+	wg.Wait()
+
+	if summaryErr != nil {
+		slog.Error("dashboard: failed to build portfolio summary", "error", summaryErr)
+		resp.addError("summary", "Failed to fetch holdings")
+	}
+	if rateErr != nil {
+		slog.Warn("dashboard: failed to fetch exchange rate", "error", rateErr)
+		resp.addError("exchange_rate", "Failed to fetch exchange rate: "+rateErr.Error())
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// addError records why part of the dashboard is missing
+func (d *DashboardResponse) addError(part, msg string) {
+	if d.Errors == nil {
+		d.Errors = make(map[string]string)
+	}
+	d.Errors[part] = msg
+}
+
+// exchangeRateShare is the part of the dashboard's remaining time budget the
+// USD/TRY lookup may use, so a slow rate doesn't hold up the other parts
+const exchangeRateShare = 1.0 / 3
+
+// usdTRYRate returns the USD/TRY rate shown by default on the dashboard
+func (h *Handler) usdTRYRate(ctx context.Context) (ExchangeRateResponse, error) {
+	if h.fxProvider == nil && h.cryptoProvider == nil {
+		return ExchangeRateResponse{}, errors.New("exchange rate provider not available")
+	}
+
+	ctx, cancel := withBudgetShare(ctx, exchangeRateShare)
+	defer cancel()
+	rate, lastUpdated, err := h.exchangeRate(ctx, "USD", "TRY")
+	if err != nil {
+		return ExchangeRateResponse{}, err
+	}
+	return ExchangeRateResponse{
+		From:        "USD",
+		To:          "TRY",
+		Rate:        rate,
+		LastUpdated: lastUpdated,
+	}, nil
+}
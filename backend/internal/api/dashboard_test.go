@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetDashboard(t *testing.T) {
+	tests := []struct {
+		name       string
+		storeErr   error
+		fx         providers.ExchangeRateProvider
+		wantValue  float64 // Summary total, 0 if the summary should be missing
+		wantRate   float64 // 0 if the rate should be missing
+		wantErrors []string
+	}{
+		{"everything available", nil, staticFX{"USD": 40}, 30, 40, nil},
+		{"summary fails", errors.New("database is locked"), staticFX{"USD": 40}, 0, 40, []string{"summary"}},
+		{"no exchange rate", nil, nil, 30, 0, []string{"exchange_rate"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20})
+			store.err = tt.storeErr
+			h := NewHandler(config.NewHolder(&config.Config{}),
+				&staticProvider{prices: map[string]float64{"KUT": 3}},
+				&staticProvider{},
+				tt.fx, store)
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/dashboard", h.GetDashboard)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+			var resp DashboardResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+
+			switch {
+			case tt.wantValue == 0 && resp.Summary != nil:
+				t.Errorf("summary = %+v, want null", resp.Summary)
+			case tt.wantValue != 0 && (resp.Summary == nil || resp.Summary.TotalValue != tt.wantValue):
+				t.Errorf("summary = %+v, want total value %v", resp.Summary, tt.wantValue)
+			}
+			switch {
+			case tt.wantRate == 0 && resp.ExchangeRate != nil:
+				t.Errorf("exchange rate = %+v, want null", resp.ExchangeRate)
+			case tt.wantRate != 0 && (resp.ExchangeRate == nil || resp.ExchangeRate.Rate != tt.wantRate):
+				t.Errorf("exchange rate = %+v, want %v", resp.ExchangeRate, tt.wantRate)
+			}
+			if resp.Version.Version != Version {
+				t.Errorf("version = %q, want %q", resp.Version.Version, Version)
+			}
+			if resp.Health.Status != "ok" {
+				t.Errorf("health status = %q, want ok", resp.Health.Status)
+			}
+			if len(resp.Errors) != len(tt.wantErrors) {
+				t.Fatalf("errors = %v, want %v", resp.Errors, tt.wantErrors)
+			}
+			for _, part := range tt.wantErrors {
+				if resp.Errors[part] == "" {
+					t.Errorf("errors = %v, missing %s", resp.Errors, part)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an API error.
+// Clients should branch on it rather than on the message.
+type ErrorCode string
+
+const (
+	CodeValidationFailed    ErrorCode = "VALIDATION_FAILED"
+	CodeHoldingNotFound     ErrorCode = "HOLDING_NOT_FOUND"
+	CodeHoldingExists       ErrorCode = "HOLDING_EXISTS"
+	CodeHoldingInTrash      ErrorCode = "HOLDING_IN_TRASH"
+	CodeSymbolNotFound      ErrorCode = "SYMBOL_NOT_FOUND"
+	CodePriceNotFound       ErrorCode = "PRICE_NOT_FOUND"
+	CodeWatchItemNotFound   ErrorCode = "WATCH_ITEM_NOT_FOUND"
+	CodeWatchItemExists     ErrorCode = "WATCH_ITEM_EXISTS"
+	CodeUnsupportedCurrency ErrorCode = "UNSUPPORTED_CURRENCY"
+	CodeProviderUnavailable ErrorCode = "PROVIDER_UNAVAILABLE"
+	CodeUpstreamBlocked     ErrorCode = "UPSTREAM_BLOCKED"
+	CodeNotSupported        ErrorCode = "NOT_SUPPORTED"
+	CodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	CodeForbidden           ErrorCode = "FORBIDDEN"
+	CodeRateLimited         ErrorCode = "RATE_LIMITED"
+	CodeBodyTooLarge        ErrorCode = "BODY_TOO_LARGE"
+	CodeIdempotencyKeyUsed  ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	CodeTimeout             ErrorCode = "REQUEST_TIMEOUT"
+	CodeInternal            ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes one error. Details is optional, code-specific data
+// such as the fields that failed validation.
+type ErrorDetail struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Details any       `json:"details,omitempty"`
+}
+
+// FieldError is one failed validation rule, reported in the details of
+// VALIDATION_FAILED errors for invalid request bodies
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+}
+
+// newError builds an error response body
+func newError(code ErrorCode, message string) ErrorResponse {
+	return ErrorResponse{Error: ErrorDetail{Code: code, Message: message}}
+}
+
+// respondError aborts the request with an error response. A server error
+// raised after the request ran out of time is reported as REQUEST_TIMEOUT,
+// since the deadline is what most likely caused it.
+func respondError(c *gin.Context, status int, code ErrorCode, message string) {
+	if status >= http.StatusInternalServerError && errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		status, code, message = http.StatusServiceUnavailable, CodeTimeout, "Request timed out"
+	}
+	c.AbortWithStatusJSON(status, newError(code, message))
+}
+
+// respondTimeout aborts the request with 503 for one that ran out of time
+func respondTimeout(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, newError(CodeTimeout, "Request timed out"))
+}
+
+// respondInvalidBody aborts the request with VALIDATION_FAILED for a body
+// that failed to bind, listing the failed fields when there are any. A body
+// cut off by BodyLimit gets BODY_TOO_LARGE instead.
+func respondInvalidBody(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondBodyTooLarge(c, tooLarge.Limit)
+		return
+	}
+
+	resp := newError(CodeValidationFailed, "Invalid request body: "+err.Error())
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make([]FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, FieldError{Field: jsonFieldPath(fe.Namespace()), Rule: fe.Tag()})
+		}
+		resp.Error.Details = fields
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, resp)
+}
+
+// respondBodyTooLarge aborts the request with 413 for a body over limit bytes
+func respondBodyTooLarge(c *gin.Context, limit int64) {
+	respondError(c, http.StatusRequestEntityTooLarge, CodeBodyTooLarge,
+		fmt.Sprintf("Request body must be at most %d bytes", limit))
+}
+
+// jsonFieldPath turns a validator namespace such as
+// "SimulateRequest.Adjustments[0].DeltaQuantity" into the JSON path
+// "adjustments[0].delta_quantity". Request fields are tagged with the
+// snake_case form of their Go names.
+func jsonFieldPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		path = namespace
+	}
+
+	var b strings.Builder
+	prev := '.'
+	for _, r := range path {
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+		prev = r
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(config.NewHolder(&config.Config{}), nil, nil, nil, newFakeStore())
+	r := gin.New()
+	r.GET("/api/holdings/:id", h.GetHolding)
+	r.POST("/api/portfolio/simulate", h.SimulatePortfolio)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		wantStatus  int
+		wantCode    ErrorCode
+		wantDetails []FieldError
+	}{
+		{"unknown holding", http.MethodGet, "/api/holdings/9", "", http.StatusNotFound, CodeHoldingNotFound, nil},
+		{"invalid id", http.MethodGet, "/api/holdings/abc", "", http.StatusBadRequest, CodeValidationFailed, nil},
+		{
+			"failed field validation", http.MethodPost, "/api/portfolio/simulate",
+			`{"adjustments":[{"symbol":"KUT","delta_quantity":1,"price":-1}]}`,
+			http.StatusBadRequest, CodeValidationFailed,
+			[]FieldError{{Field: "adjustments[0].price", Rule: "gt"}},
+		},
+		{"malformed JSON", http.MethodPost, "/api/portfolio/simulate", `{`, http.StatusBadRequest, CodeValidationFailed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			var resp struct {
+				Error struct {
+					Code    ErrorCode    `json:"code"`
+					Message string       `json:"message"`
+					Details []FieldError `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.Error.Code != tt.wantCode || resp.Error.Message == "" {
+				t.Errorf("error = %+v, want code %s with a message", resp.Error, tt.wantCode)
+			}
+			if len(resp.Error.Details) != len(tt.wantDetails) {
+				t.Fatalf("details = %+v, want %+v", resp.Error.Details, tt.wantDetails)
+			}
+			for i := range tt.wantDetails {
+				if resp.Error.Details[i] != tt.wantDetails[i] {
+					t.Errorf("details[%d] = %+v, want %+v", i, resp.Error.Details[i], tt.wantDetails[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondWithETag responds with body as JSON, tagged with a hash of tagged:
+// the parts of the response that matter to a poller. A request whose
+// If-None-Match already holds the tag gets 304 Not Modified and no body.
+func respondWithETag(c *gin.Context, body, tagged any) {
+	etag, err := computeETag(tagged)
+	if err != nil {
+		c.JSON(http.StatusOK, body)
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache") // Cache, but revalidate every time
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// computeETag returns a strong ETag for the JSON encoding of v
+func computeETag(v any) (string, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header lists etag or is "*".
+// Comparison is weak, as RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{``, false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifNoneMatch, `"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.ifNoneMatch, got, tt.want)
+		}
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	store := newFakeStore(storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 100})
+	tefas := &staticProvider{prices: map[string]float64{"KUT": 12}}
+	cfg := &config.Config{}
+	cfg.Server.SummaryCacheTTL = time.Minute
+	h := NewHandler(config.NewHolder(cfg), tefas, nil, nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/holdings", h.GetHoldings)
+	r.GET("/summary", h.GetPortfolioSummary)
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tags := make(map[string]string)
+	for _, path := range []string{"/holdings", "/summary"} {
+		first := get(path, "")
+		tags[path] = first.Header().Get("ETag")
+		if first.Code != http.StatusOK || tags[path] == "" {
+			t.Fatalf("%s: status %d, ETag %q; want 200 with an ETag", path, first.Code, tags[path])
+		}
+		if w := get(path, tags[path]); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s unchanged: status %d with %d byte body, want 304 and no body", path, w.Code, w.Body.Len())
+		}
+	}
+
+	quantity := 20.0
+	store.UpdateHolding(context.Background(), 1, storage.UpdateHoldingRequest{Quantity: &quantity})
+	h.invalidateSummary()
+	for _, path := range []string{"/holdings", "/summary"} {
+		w := get(path, tags[path])
+		if w.Code != http.StatusOK || w.Header().Get("ETag") == tags[path] {
+			t.Errorf("%s after an update: status %d, ETag %q; want 200 with a new ETag", path, w.Code, w.Header().Get("ETag"))
+		}
+	}
+}
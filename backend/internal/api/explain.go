@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// PnLExplanation lays out the inputs of a holding's P&L and the arithmetic
+// from them to the result, as the summary computes it
+type PnLExplanation struct {
+	HoldingID    int64               `json:"holding_id"`
+	Type         storage.HoldingType `json:"type"`
+	Symbol       string              `json:"symbol"`
+	Currency     string              `json:"currency"` // Currency of the price, value, cost basis and P&L
+	Price        float64             `json:"price"`
+	PriceSource  string              `json:"price_source,omitempty"`  // Provider that quoted the price
+	PriceUpdated *time.Time          `json:"price_updated,omitempty"` // When the price was fetched
+	PriceDate    string              `json:"price_date,omitempty"`    // Day a daily price was published (YYYY-MM-DD)
+	PriceStale   bool                `json:"price_stale"`
+	Quantity     float64             `json:"quantity"`
+	CostBasis    float64             `json:"cost_basis"`                // In Currency, after any conversion
+	EnteredCost  float64             `json:"entered_cost_basis"`        // As stored on the holding
+	CostCurrency string              `json:"cost_currency,omitempty"`   // Currency EnteredCost is in, when not Currency
+	Rate         *float64            `json:"conversion_rate,omitempty"` // CostCurrency to Currency rate applied to EnteredCost
+	RateUpdated  *time.Time          `json:"rate_updated,omitempty"`
+	Value        float64             `json:"value"`
+	PnL          float64             `json:"pnl"`
+	PnLPct       float64             `json:"pnl_pct"`
+	Steps        []string            `json:"steps"`           // The arithmetic, in order
+	Notes        []string            `json:"notes,omitempty"` // Why an input is missing or was left out
+}
+
+// ExplainPnL handles GET /api/holdings/:id/pnl/explain
+func (h *Handler) ExplainPnL(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid holding ID")
+		return
+	}
+
+	holding, err := h.storage.GetHoldingByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrHoldingNotFound) {
+			respondError(c, http.StatusNotFound, CodeHoldingNotFound, "Holding not found")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holding")
+		return
+	}
+
+	explained := PnLExplanation{
+		HoldingID:   holding.ID,
+		Type:        holding.Type,
+		Symbol:      holding.Symbol,
+		Currency:    priceCurrency(*holding),
+		Quantity:    holding.Quantity,
+		CostBasis:   holding.CostBasis,
+		EnteredCost: holding.CostBasis,
+		Steps:       []string{},
+	}
+
+	// Cost basis, converted as costConverter does
+	if from := holding.CostCurrency; from != "" && from != explained.Currency {
+		explained.CostCurrency = from
+		rate, updated, err := h.exchangeRate(ctx, from, explained.Currency)
+		if err != nil || rate <= 0 {
+			explained.Notes = append(explained.Notes, fmt.Sprintf("No %s/%s rate is available, so the cost basis is used as entered in %s", from, explained.Currency, from))
+		} else {
+			explained.CostBasis = convertCost(holding.CostBasis, rate)
+			explained.Rate, explained.RateUpdated = &rate, &updated
+			explained.Steps = append(explained.Steps, fmt.Sprintf("cost_basis = entered_cost_basis × conversion_rate = %s %s × %s = %s %s",
+				formatNumber(holding.CostBasis), from, formatNumber(rate), formatNumber(explained.CostBasis), explained.Currency))
+		}
+	}
+
+	// Price, fetched as GetHoldingDetail does
+	provider := h.cryptoProvider
+	if holding.Type == storage.HoldingTypeFund {
+		provider = h.tefasProvider
+	}
+	var price *providers.Price
+	if provider != nil {
+		for _, p := range fetchPrices(ctx, provider, []string{holding.Symbol}).prices {
+			if p.Symbol == holding.Symbol {
+				price = &p
+				explained.PriceSource = p.Source
+				if p.Source == "" {
+					explained.PriceSource = provider.Name()
+				}
+				break
+			}
+		}
+	}
+	if price == nil {
+		explained.PriceStale = true
+		explained.Notes = append(explained.Notes, "No price is available, so the holding is valued at 0 as in the summary")
+		c.JSON(http.StatusOK, explained)
+		return
+	}
+	explained.Price = price.Price
+	explained.PriceUpdated = &price.LastUpdated
+	explained.PriceDate = price.PriceDate
+	explained.PriceStale = price.Stale
+
+	explained.Value, explained.PnL, explained.PnLPct = positionPnL(price.Price, holding.Quantity, explained.CostBasis)
+	explained.Steps = append(explained.Steps,
+		fmt.Sprintf("value = price × quantity = %s × %s = %s, rounded to %d decimal places = %s",
+			formatNumber(price.Price), formatNumber(holding.Quantity),
+			decimal.NewFromFloat(price.Price).Mul(decimal.NewFromFloat(holding.Quantity)).String(), moneyPlaces, formatNumber(explained.Value)),
+		fmt.Sprintf("pnl = value − cost_basis = %s − %s = %s",
+			formatNumber(explained.Value), formatNumber(explained.CostBasis), formatNumber(explained.PnL)),
+	)
+	if explained.CostBasis > 0 {
+		explained.Steps = append(explained.Steps, fmt.Sprintf("pnl_pct = pnl ÷ cost_basis × 100 = %s ÷ %s × 100 = %s",
+			formatNumber(explained.PnL), formatNumber(explained.CostBasis), formatNumber(explained.PnLPct)))
+	} else if explained.CostBasis < 0 {
+		explained.Steps = append(explained.Steps, fmt.Sprintf("pnl_pct = pnl ÷ |cost_basis| × 100 = %s ÷ %s × 100 = %s",
+			formatNumber(explained.PnL), formatNumber(-explained.CostBasis), formatNumber(explained.PnLPct)))
+	} else {
+		explained.Steps = append(explained.Steps, "pnl_pct = 0, as there is no cost basis to compare with")
+	}
+
+	c.JSON(http.StatusOK, explained)
+}
+
+// formatNumber writes x in the shortest form that reads back as x, without
+// an exponent
+func formatNumber(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
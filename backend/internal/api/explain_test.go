@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestExplainPnL(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCTRY", Quantity: 2, CostBasis: 0.125, CostCurrency: "USD"},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "ETHUSDT", Quantity: 1, CostBasis: 100, CostCurrency: "TRY"},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 3}},
+		&staticProvider{prices: map[string]float64{"BTCTRY": 5}},
+		staticFX{"USD": 40}, store)
+	down := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{err: errors.New("upstream down")}, nil, nil, store)
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		handler    *Handler
+		path       string
+		wantStatus int
+		want       PnLExplanation
+		wantSteps  int
+		wantNotes  int
+	}{
+		{
+			name:       "fund",
+			handler:    h,
+			path:       "/api/holdings/1/pnl/explain",
+			wantStatus: http.StatusOK,
+			want:       PnLExplanation{Currency: "TRY", Price: 3, PriceSource: "static", Quantity: 10, CostBasis: 20, EnteredCost: 20, Value: 30, PnL: 10, PnLPct: 50},
+			wantSteps:  3,
+		},
+		{
+			name:       "converted cost basis",
+			handler:    h,
+			path:       "/api/holdings/2/pnl/explain",
+			wantStatus: http.StatusOK,
+			want:       PnLExplanation{Currency: "TRY", Price: 5, PriceSource: "static", Quantity: 2, CostBasis: 5, EnteredCost: 0.125, CostCurrency: "USD", Value: 10, PnL: 5, PnLPct: 100},
+			wantSteps:  4,
+		},
+		{
+			name:       "no rate and no price",
+			handler:    h,
+			path:       "/api/holdings/3/pnl/explain",
+			wantStatus: http.StatusOK,
+			want:       PnLExplanation{Currency: "USD", PriceStale: true, Quantity: 1, CostBasis: 100, EnteredCost: 100, CostCurrency: "TRY"},
+			wantNotes:  2,
+		},
+		{
+			name:       "provider down",
+			handler:    down,
+			path:       "/api/holdings/1/pnl/explain",
+			wantStatus: http.StatusOK,
+			want:       PnLExplanation{Currency: "TRY", PriceStale: true, Quantity: 10, CostBasis: 20, EnteredCost: 20},
+			wantNotes:  1,
+		},
+		{name: "unknown holding", handler: h, path: "/api/holdings/9/pnl/explain", wantStatus: http.StatusNotFound},
+		{name: "invalid id", handler: h, path: "/api/holdings/abc/pnl/explain", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/api/holdings/:id/pnl/explain", tt.handler.ExplainPnL)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var got PnLExplanation
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if len(got.Steps) != tt.wantSteps || len(got.Notes) != tt.wantNotes {
+				t.Errorf("steps/notes = %q/%q, want %d/%d", got.Steps, got.Notes, tt.wantSteps, tt.wantNotes)
+			}
+			if (got.Rate != nil) != (tt.want.CostCurrency != "" && tt.wantNotes == 0) {
+				t.Errorf("conversion_rate = %v, want one only when the cost basis was converted", got.Rate)
+			}
+			got.HoldingID, got.Type, got.Symbol = 0, "", ""
+			got.PriceUpdated, got.Rate, got.RateUpdated = nil, nil, nil
+			got.Steps, got.Notes = nil, nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("explanation = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/gin-gonic/gin"
+)
+
+// FundSearchResponse represents the fund search response
+type FundSearchResponse struct {
+	Query   string               `json:"query"`
+	Results []providers.FundInfo `json:"results"`
+}
+
+// FundDetailsResponse represents a fund's price plus TEFAS metadata
+type FundDetailsResponse struct {
+	Code              string    `json:"code"`
+	Name              string    `json:"name"`
+	Price             float64   `json:"price"`
+	PortfolioSize     float64   `json:"portfolio_size"`
+	Investors         int       `json:"investors"`
+	SharesOutstanding float64   `json:"shares_outstanding"`
+	LastUpdated       time.Time `json:"last_updated"`
+	Stale             bool      `json:"stale"`
+	PriceDate         string    `json:"price_date,omitempty"`
+	Category          string    `json:"category,omitempty"`
+}
+
+// SearchFunds handles GET /api/funds/search?q=
+func (h *Handler) SearchFunds(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Query parameter q is required")
+		return
+	}
+
+	searcher, ok := h.tefasProvider.(providers.FundSearcher)
+	if !ok {
+		respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Fund search not available")
+		return
+	}
+
+	results, err := searcher.SearchFunds(ctx, query)
+	if err != nil {
+		if errors.Is(err, providers.ErrUpstreamBlocked) {
+			respondError(c, http.StatusServiceUnavailable, CodeUpstreamBlocked, "TEFAS is blocking automated requests right now; try again later")
+			return
+		}
+		slog.Error("fund search failed", "query", query, "error", err)
+		respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Failed to search funds")
+		return
+	}
+
+	if results == nil {
+		results = []providers.FundInfo{}
+	}
+
+	c.JSON(http.StatusOK, FundSearchResponse{
+		Query:   query,
+		Results: results,
+	})
+}
+
+// GetFundDetails handles GET /api/funds/:code/details
+func (h *Handler) GetFundDetails(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	code := c.Param("code")
+
+	detailer, ok := h.tefasProvider.(providers.FundDetailer)
+	if !ok {
+		respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Fund details not available")
+		return
+	}
+
+	details, err := detailer.FetchFundDetails(ctx, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, providers.ErrSymbolNotFound):
+			respondError(c, http.StatusNotFound, CodeSymbolNotFound, "Fund not found")
+		case errors.Is(err, providers.ErrUpstreamBlocked):
+			respondError(c, http.StatusServiceUnavailable, CodeUpstreamBlocked, "TEFAS is blocking automated requests right now; try again later")
+		default:
+			slog.Error("failed to fetch fund details", "code", code, "error", err)
+			respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Failed to fetch fund details")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, FundDetailsResponse{
+		Code:              details.Price.Symbol,
+		Name:              details.Price.Name,
+		Price:             details.Price.Price,
+		PortfolioSize:     details.PortfolioSize,
+		Investors:         details.Investors,
+		SharesOutstanding: details.SharesOutstanding,
+		LastUpdated:       details.Price.LastUpdated,
+		Stale:             details.Price.Stale,
+		PriceDate:         details.Price.PriceDate,
+		Category:          details.Price.Category,
+	})
+}
+
+// FundNAVResponse is a fund's price (net asset value per share) on one date
+type FundNAVResponse struct {
+	Code     string  `json:"code"`
+	Date     string  `json:"date"`
+	Price    float64 `json:"price"`
+	Name     string  `json:"name,omitempty"`
+	Category string  `json:"category,omitempty"`
+}
+
+// AdjacentDates are the weekdays either side of a date without a price,
+// suggested in PRICE_NOT_FOUND details. After is omitted when in the future.
+type AdjacentDates struct {
+	Before string `json:"before"`
+	After  string `json:"after,omitempty"`
+}
+
+// cachedNAV is a fetched fund NAV and when it was last served
+type cachedNAV struct {
+	price  providers.Price
+	usedAt time.Time
+}
+
+const (
+	// maxCachedNAVs bounds the NAVs and, separately, the misses cached;
+	// once full, the least recently served NAV makes way for a new one,
+	// while further misses go uncached
+	maxCachedNAVs = 10000
+
+	// navMissTTL is how long a date without a NAV is answered from the
+	// cache, so made-up codes can't pin entries
+	navMissTTL = time.Hour
+)
+
+// GetFundPrice handles GET /api/funds/:code/price?date=YYYY-MM-DD
+//
+// Fetches the fund's NAV on that date alone from TEFAS and caches it, as
+// published prices never change. Responds 404 PRICE_NOT_FOUND, with the
+// adjacent weekdays as details, when the fund has no record that day.
+func (h *Handler) GetFundPrice(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	code := strings.ToUpper(strings.TrimSpace(c.Param("code")))
+	date, err := time.Parse(time.DateOnly, c.Query("date"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "date must be a date like 2024-03-15")
+		return
+	}
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	if date.After(today) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "date must not be in the future")
+		return
+	}
+
+	history, ok := h.tefasProvider.(providers.PriceHistoryProvider)
+	if !ok {
+		respondError(c, http.StatusNotImplemented, CodeNotSupported, "Configured fund provider doesn't support price history")
+		return
+	}
+
+	nav, found, err := h.fundNAV(ctx, history, code, date, today)
+	if err != nil {
+		if errors.Is(err, providers.ErrUpstreamBlocked) {
+			respondError(c, http.StatusServiceUnavailable, CodeUpstreamBlocked, "TEFAS is blocking automated requests right now; try again later")
+			return
+		}
+		slog.Error("failed to fetch fund price", "code", code, "date", date.Format(time.DateOnly), "error", err)
+		respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Failed to fetch fund price")
+		return
+	}
+
+	if !found {
+		adjacent := AdjacentDates{Before: adjacentWeekday(date, -1).Format(time.DateOnly)}
+		if after := adjacentWeekday(date, 1); !after.After(today) {
+			adjacent.After = after.Format(time.DateOnly)
+		}
+		resp := newError(CodePriceNotFound, fmt.Sprintf(
+			"%s has no price for %s; TEFAS publishes none on weekends and holidays, so try an adjacent date",
+			code, date.Format(time.DateOnly)))
+		resp.Error.Details = adjacent
+		c.AbortWithStatusJSON(http.StatusNotFound, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, FundNAVResponse{
+		Code:     code,
+		Date:     nav.PriceDate,
+		Price:    nav.Price,
+		Name:     nav.Name,
+		Category: nav.Category,
+	})
+}
+
+// fundNAV returns code's NAV on date from the cache, or fetches it.
+// Concurrent requests for the same NAV share one fetch, which is detached
+// from the first caller so its disconnect doesn't fail the rest.
+func (h *Handler) fundNAV(ctx context.Context, history providers.PriceHistoryProvider, code string, date, today time.Time) (providers.Price, bool, error) {
+	key := code + "/" + date.Format(time.DateOnly)
+	if nav, found, ok := h.cachedFundNAV(key, time.Now()); ok {
+		return nav, found, nil
+	}
+
+	type result struct {
+		nav   providers.Price
+		found bool
+	}
+	res, err, _ := h.navGroup.Do(key, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.requestTimeout())
+		defer cancel()
+		prices, err := history.FetchPriceHistory(fetchCtx, code, date, date)
+		if err != nil {
+			return nil, err
+		}
+		var res result
+		for _, p := range prices {
+			if p.Symbol == code && p.PriceDate == date.Format(time.DateOnly) {
+				res = result{nav: p, found: true}
+			}
+		}
+		// Today's price may still be published later
+		if res.found || date.Before(today) {
+			h.cacheFundNAV(key, res.nav, res.found, time.Now())
+		}
+		return res, nil
+	})
+	if err != nil {
+		return providers.Price{}, false, err
+	}
+	return res.(result).nav, res.(result).found, nil
+}
+
+// cachedFundNAV looks key up at now, reporting whether the cache answers
+// for it and, if so, whether a NAV was found
+func (h *Handler) cachedFundNAV(key string, now time.Time) (nav providers.Price, found, ok bool) {
+	h.navMu.Lock()
+	defer h.navMu.Unlock()
+	if cached, hit := h.navs[key]; hit {
+		cached.usedAt = now
+		h.navs[key] = cached
+		return cached.price, true, true
+	}
+	if missedAt, hit := h.navMisses[key]; hit && now.Sub(missedAt) < navMissTTL {
+		return providers.Price{}, false, true
+	}
+	return providers.Price{}, false, false
+}
+
+// cacheFundNAV caches the NAV fetched for key at now, or that there was
+// none. A NAV evicts the least recently served one once the cache is full;
+// a miss is dropped if expired misses don't make room for it.
+func (h *Handler) cacheFundNAV(key string, nav providers.Price, found bool, now time.Time) {
+	h.navMu.Lock()
+	defer h.navMu.Unlock()
+
+	if !found {
+		if len(h.navMisses) >= maxCachedNAVs {
+			for k, missedAt := range h.navMisses {
+				if now.Sub(missedAt) >= navMissTTL {
+					delete(h.navMisses, k)
+				}
+			}
+		}
+		if len(h.navMisses) < maxCachedNAVs {
+			h.navMisses[key] = now
+		}
+		return
+	}
+
+	delete(h.navMisses, key)
+	if _, ok := h.navs[key]; !ok && len(h.navs) >= maxCachedNAVs {
+		var oldest string
+		for k, cached := range h.navs {
+			if oldest == "" || cached.usedAt.Before(h.navs[oldest].usedAt) {
+				oldest = k
+			}
+		}
+		delete(h.navs, oldest)
+	}
+	h.navs[key] = cachedNAV{price: nav, usedAt: now}
+}
+
+// adjacentWeekday returns the closest weekday to date in direction step (1
+// for later, -1 for earlier)
+func adjacentWeekday(date time.Time, step int) time.Time {
+	day := date.AddDate(0, 0, step)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, step)
+	}
+	return day
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetFundsCategory(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 1},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "TI2", Quantity: 1},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "AFT", Quantity: 1},
+	)
+	tefas := &staticProvider{
+		prices:     map[string]float64{"KUT": 1, "TI2": 2, "AFT": 3},
+		categories: map[string]string{"KUT": "Kıymetli Madenler Fonu", "TI2": "Hisse Senedi Fonu"},
+	}
+	h := NewHandler(config.NewHolder(&config.Config{}), tefas, nil, nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/funds", h.GetFunds)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"no filter", "", []string{"AFT", "KUT", "TI2"}},
+		{"category", "?category=Hisse%20Senedi%20Fonu", []string{"TI2"}},
+		{"case-insensitive", "?category=hisse%20senedi%20fonu", []string{"TI2"}},
+		{"unknown category", "?category=Serbest%20Fon", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/funds"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+
+			var resp struct {
+				Funds []FundPrice `json:"funds"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			got := []string{}
+			for _, f := range resp.Funds {
+				got = append(got, f.Code)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("funds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// navHistory publishes price on weekdays and counts the calls made to it
+type navHistory struct {
+	staticProvider
+	price float64
+	calls int
+}
+
+func (p *navHistory) FetchPriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]providers.Price, error) {
+	p.calls++
+	var prices []providers.Price
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			prices = append(prices, providers.Price{Symbol: symbol, Price: p.price, PriceDate: day.Format(time.DateOnly)})
+		}
+	}
+	return prices, nil
+}
+
+func TestGetFundPrice(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	tests := []struct {
+		name        string
+		provider    providers.Provider
+		path        string
+		wantStatus  int
+		wantCode    ErrorCode
+		wantDetails *AdjacentDates
+	}{
+		{name: "weekday", provider: &navHistory{price: 1.5}, path: "/api/funds/kut/price?date=2024-03-15", wantStatus: http.StatusOK},
+		{name: "weekend", provider: &navHistory{price: 1.5}, path: "/api/funds/KUT/price?date=2024-03-16", wantStatus: http.StatusNotFound,
+			wantCode: CodePriceNotFound, wantDetails: &AdjacentDates{Before: "2024-03-15", After: "2024-03-18"}},
+		{name: "missing date", provider: &navHistory{}, path: "/api/funds/KUT/price", wantStatus: http.StatusBadRequest, wantCode: CodeValidationFailed},
+		{name: "invalid date", provider: &navHistory{}, path: "/api/funds/KUT/price?date=15.03.2024", wantStatus: http.StatusBadRequest, wantCode: CodeValidationFailed},
+		{name: "future date", provider: &navHistory{}, path: "/api/funds/KUT/price?date=" + tomorrow, wantStatus: http.StatusBadRequest, wantCode: CodeValidationFailed},
+		{name: "provider without history", provider: &staticProvider{}, path: "/api/funds/KUT/price?date=2024-03-15", wantStatus: http.StatusNotImplemented, wantCode: CodeNotSupported},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(config.NewHolder(&config.Config{}), tt.provider, nil, nil, newFakeStore())
+			r := gin.New()
+			r.GET("/api/funds/:code/price", h.GetFundPrice)
+
+			// The second request is answered from the cache
+			for range 2 {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+				if w.Code != tt.wantStatus {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+				}
+
+				if w.Code == http.StatusOK {
+					var got FundNAVResponse
+					if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+						t.Fatalf("decoding response: %v", err)
+					}
+					if want := (FundNAVResponse{Code: "KUT", Date: "2024-03-15", Price: 1.5}); got != want {
+						t.Errorf("response = %+v, want %+v", got, want)
+					}
+					continue
+				}
+
+				var got struct {
+					Error struct {
+						Code    ErrorCode      `json:"code"`
+						Details *AdjacentDates `json:"details"`
+					} `json:"error"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("decoding response: %v", err)
+				}
+				if got.Error.Code != tt.wantCode {
+					t.Errorf("error code = %s, want %s", got.Error.Code, tt.wantCode)
+				}
+				if !reflect.DeepEqual(got.Error.Details, tt.wantDetails) {
+					t.Errorf("details = %+v, want %+v", got.Error.Details, tt.wantDetails)
+				}
+			}
+
+			if p, ok := tt.provider.(*navHistory); ok && p.calls > 1 {
+				t.Errorf("FetchPriceHistory called %d times, want at most once", p.calls)
+			}
+		})
+	}
+}
+
+func TestFundNAVCache(t *testing.T) {
+	h := NewHandler(config.NewHolder(&config.Config{}), nil, nil, nil, newFakeStore())
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	nav := providers.Price{Symbol: "KUT", Price: 1.5, PriceDate: "2024-03-15"}
+
+	h.cacheFundNAV("KUT/2024-03-16", providers.Price{}, false, now)
+	if _, found, ok := h.cachedFundNAV("KUT/2024-03-16", now.Add(navMissTTL-time.Second)); !ok || found {
+		t.Errorf("fresh miss: found %v, cached %v; want a cached miss", found, ok)
+	}
+	if _, _, ok := h.cachedFundNAV("KUT/2024-03-16", now.Add(navMissTTL)); ok {
+		t.Error("miss answered from the cache past navMissTTL")
+	}
+
+	// Misses filling up don't keep NAVs out, and expired ones make room
+	for i := range maxCachedNAVs {
+		h.cacheFundNAV(fmt.Sprintf("X%d/2024-03-16", i), providers.Price{}, false, now)
+	}
+	h.cacheFundNAV("KUT/2024-03-15", nav, true, now)
+	if got, found, ok := h.cachedFundNAV("KUT/2024-03-15", now); !ok || !found || got != nav {
+		t.Errorf("NAV after the misses filled up = %+v (found %v, cached %v), want %+v", got, found, ok, nav)
+	}
+	h.cacheFundNAV("Y/2024-03-16", providers.Price{}, false, now)
+	if _, _, ok := h.cachedFundNAV("Y/2024-03-16", now); ok {
+		t.Error("miss cached past maxCachedNAVs")
+	}
+	later := now.Add(navMissTTL)
+	h.cacheFundNAV("Y/2024-03-16", providers.Price{}, false, later)
+	if _, _, ok := h.cachedFundNAV("Y/2024-03-16", later); !ok {
+		t.Error("expired misses didn't make room for a new one")
+	}
+
+	// A full cache makes way for new NAVs by evicting the least recently served
+	for i := 1; i < maxCachedNAVs; i++ {
+		h.cacheFundNAV(fmt.Sprintf("N%d/2024-03-15", i), nav, true, now.Add(time.Duration(i)))
+	}
+	h.cachedFundNAV("KUT/2024-03-15", now.Add(maxCachedNAVs))
+	h.cacheFundNAV("NEW/2024-03-15", nav, true, now.Add(maxCachedNAVs+1))
+	if _, _, ok := h.cachedFundNAV("NEW/2024-03-15", now); !ok {
+		t.Error("new NAV not cached in a full cache")
+	}
+	if _, _, ok := h.cachedFundNAV("KUT/2024-03-15", now); !ok {
+		t.Error("recently served NAV evicted")
+	}
+	if _, _, ok := h.cachedFundNAV("N1/2024-03-15", now); ok {
+		t.Error("least recently served NAV kept")
+	}
+	if len(h.navs) != maxCachedNAVs {
+		t.Errorf("cached %d NAVs, want %d", len(h.navs), maxCachedNAVs)
+	}
+}
+
+// blockingNAVHistory holds every fetch until release is closed
+type blockingNAVHistory struct {
+	staticProvider
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (p *blockingNAVHistory) FetchPriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]providers.Price, error) {
+	p.calls.Add(1)
+	<-p.release
+	return []providers.Price{{Symbol: symbol, Price: 1.5, PriceDate: from.Format(time.DateOnly)}}, nil
+}
+
+func TestGetFundPriceSharesFetches(t *testing.T) {
+	provider := &blockingNAVHistory{release: make(chan struct{})}
+	h := NewHandler(config.NewHolder(&config.Config{}), provider, nil, nil, newFakeStore())
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/funds/:code/price", h.GetFundPrice)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/funds/KUT/price?date=2024-03-15", nil))
+			codes[i] = w.Code
+		}()
+	}
+	for provider.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond) // Let the other requests join the fetch
+	close(provider.release)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: status %d, want 200", i+1, code)
+		}
+	}
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("FetchPriceHistory called %d times for concurrent requests, want once", calls)
+	}
+}
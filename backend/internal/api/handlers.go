@@ -2,15 +2,28 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/metrics"
 	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/providers/binance"
 	"github.com/ferhatkunduraci/prism/internal/storage"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
 )
 
 // Version information (set at build time)
@@ -21,66 +34,236 @@ var (
 
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
-	cfg            *config.Config
+	cfg            *config.Holder
 	tefasProvider  providers.Provider
 	cryptoProvider providers.Provider
-	storage        *storage.Storage
+	fxProvider     providers.ExchangeRateProvider // Optional, preferred over the crypto provider's rate
+	storage        HoldingsStore
+
+	// Short-lived portfolio summary cache shared across requests. The
+	// generation counts invalidations, so a summary built from holdings
+	// read before one isn't cached or shared after it.
+	summaryGroup singleflight.Group
+	summaryMu    sync.Mutex
+	summary      *PortfolioSummary
+	summaryAt    time.Time
+	summaryGen   uint64
+
+	// Collapses concurrent retries sharing an Idempotency-Key
+	idempotencyGroup singleflight.Group
+
+	// Holding stats keyed by type and symbol, recomputed once a day
+	statsMu sync.Mutex
+	stats   map[string]cachedStats
+
+	// Past fund NAVs keyed by code and date, which never change, and the
+	// dates found without one, which are asked about again after navMissTTL
+	navMu     sync.Mutex
+	navs      map[string]cachedNAV
+	navMisses map[string]time.Time
+	navGroup  singleflight.Group
 }
 
 // NewHandler creates a new Handler instance
-func NewHandler(cfg *config.Config, tefas, crypto providers.Provider, store *storage.Storage) *Handler {
+func NewHandler(cfg *config.Holder, tefas, crypto providers.Provider, fx providers.ExchangeRateProvider, store HoldingsStore) *Handler {
 	return &Handler{
 		cfg:            cfg,
 		tefasProvider:  tefas,
 		cryptoProvider: crypto,
+		fxProvider:     fx,
 		storage:        store,
+		stats:          make(map[string]cachedStats),
+		navs:           make(map[string]cachedNAV),
+		navMisses:      make(map[string]time.Time),
 	}
 }
 
+// defaultRequestTimeout applies when the config doesn't set server.request_timeout
+const defaultRequestTimeout = 30 * time.Second
+
+// requestTimeout returns server.request_timeout, or the default when unset
+func (h *Handler) requestTimeout() time.Duration {
+	timeout := h.cfg.Get().Server.RequestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	return timeout
+}
+
+// withBudgetShare bounds ctx to share of the time left before its deadline,
+// so one step of a request can't use up the whole budget RequestTimeout set.
+// A ctx without a deadline is left unbounded.
+func withBudgetShare(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(float64(time.Until(deadline))*share))
+}
+
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status    string            `json:"status"`
-	Timestamp time.Time         `json:"timestamp"`
-	Providers map[string]string `json:"providers,omitempty"`
+	Status          string                    `json:"status"`
+	Timestamp       time.Time                 `json:"timestamp"`
+	Providers       map[string]string         `json:"providers,omitempty"`
+	ProviderDetails map[string]ProviderHealth `json:"provider_details,omitempty"`
+}
+
+// ProviderHealth describes what a provider can still serve. A provider can be
+// unhealthy yet still have stale prices to show.
+type ProviderHealth struct {
+	Provider        string     `json:"provider"`
+	Healthy         bool       `json:"healthy"`
+	Serving         string     `json:"serving,omitempty"` // For fallback providers, the one answering now
+	LastSuccess     *time.Time `json:"last_success"`      // Null if no fetch has succeeded yet
+	CacheAgeSeconds *float64   `json:"cache_age_seconds"` // Null if nothing is cached
+	StaleAvailable  bool       `json:"stale_data_available"`
+
+	// Underlying gives "healthy" or "unhealthy" per provider for fallback
+	// chains, whose Healthy only says whether any of them is
+	Underlying map[string]string `json:"underlying,omitempty"`
+
+	// Circuits gives the circuit breaker state ("closed", "open" or
+	// "half_open") of each provider that has one
+	Circuits map[string]providers.CircuitState `json:"circuits,omitempty"`
 }
 
 // Health handles GET /api/health
 func (h *Handler) Health(c *gin.Context) {
+	health := h.health(c.Request.Context())
+	status := http.StatusOK
+	if health.Status != "ok" {
+		status = http.StatusPartialContent
+	}
+	c.JSON(status, health)
+}
+
+// ReadinessResponse reports whether the server can serve portfolio data
+type ReadinessResponse struct {
+	Status    string            `json:"status"`  // "ready" or "not_ready"
+	Storage   string            `json:"storage"` // "ok" or "unreachable"
+	Providers map[string]string `json:"providers,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Liveness handles GET /api/health/live. It answers 200 as long as the
+// process serves requests, without checking storage or providers, so a
+// provider outage doesn't get the process restarted.
+func (h *Handler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: "ok", Timestamp: time.Now()})
+}
+
+// Readiness handles GET /api/health/ready. It answers 200 when storage is
+// reachable and at least one provider is healthy, and 503 otherwise.
+func (h *Handler) Readiness(c *gin.Context) {
 	ctx := c.Request.Context()
-	providerStatus := make(map[string]string)
-	allHealthy := true
 
-	if h.tefasProvider != nil {
-		if h.tefasProvider.IsHealthy(ctx) {
-			providerStatus["tefas"] = "healthy"
+	resp := ReadinessResponse{
+		Status:    "ready",
+		Storage:   "ok",
+		Providers: make(map[string]string),
+		Timestamp: time.Now(),
+	}
+	if err := h.storage.Ping(ctx); err != nil {
+		slog.Warn("readiness check: storage unreachable", "error", err)
+		resp.Status = "not_ready"
+		resp.Storage = "unreachable"
+	}
+
+	anyHealthy := false
+	for key, provider := range map[string]providers.Provider{"tefas": h.tefasProvider, "crypto": h.cryptoProvider} {
+		if provider == nil {
+			continue
+		}
+		if provider.IsHealthy(ctx) {
+			resp.Providers[key] = "healthy"
+			anyHealthy = true
 		} else {
-			providerStatus["tefas"] = "unhealthy"
-			allHealthy = false
+			resp.Providers[key] = "unhealthy"
 		}
 	}
+	if !anyHealthy {
+		resp.Status = "not_ready"
+	}
 
-	if h.cryptoProvider != nil {
-		if h.cryptoProvider.IsHealthy(ctx) {
-			providerStatus["crypto"] = "healthy"
-		} else {
-			providerStatus["crypto"] = "unhealthy"
-			allHealthy = false
+	status := http.StatusOK
+	if resp.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, resp)
+}
+
+// health checks every configured provider; Status is "degraded" if any is
+// unhealthy
+func (h *Handler) health(ctx context.Context) HealthResponse {
+	providerStatus := make(map[string]string)
+	details := make(map[string]ProviderHealth)
+	allHealthy := true
+
+	for key, provider := range map[string]providers.Provider{"tefas": h.tefasProvider, "crypto": h.cryptoProvider} {
+		if provider == nil {
+			continue
 		}
+		health := providerHealth(ctx, provider)
+		details[key] = health
+		providerStatus[key] = healthStatus(health.Healthy)
+		allHealthy = allHealthy && health.Healthy
 	}
 
-	if allHealthy {
-		c.JSON(http.StatusOK, HealthResponse{
-			Status:    "ok",
-			Timestamp: time.Now(),
-			Providers: providerStatus,
-		})
+	status := "ok"
+	if !allHealthy {
+		status = "degraded"
+	}
+	return HealthResponse{
+		Status:          status,
+		Timestamp:       time.Now(),
+		Providers:       providerStatus,
+		ProviderDetails: details,
+	}
+}
+
+// providerHealth checks a provider and adds whatever state it reports
+func providerHealth(ctx context.Context, p providers.Provider) ProviderHealth {
+	health := ProviderHealth{Provider: p.Name()}
+	if sh, ok := p.(providers.SubHealthReporter); ok {
+		// Checking each underlying provider once answers for the chain too
+		health.Underlying = make(map[string]string)
+		for name, healthy := range sh.SubHealth(ctx) {
+			health.Underlying[name] = healthStatus(healthy)
+			health.Healthy = health.Healthy || healthy
+		}
 	} else {
-		c.JSON(http.StatusPartialContent, HealthResponse{
-			Status:    "degraded",
-			Timestamp: time.Now(),
-			Providers: providerStatus,
-		})
+		health.Healthy = p.IsHealthy(ctx)
+	}
+	if cr, ok := p.(providers.CircuitReporter); ok {
+		if circuits := cr.Circuits(); len(circuits) > 0 {
+			health.Circuits = circuits
+		}
+	}
+
+	sr, ok := p.(providers.StatusReporter)
+	if !ok {
+		return health
+	}
+	status := sr.Status()
+	health.Serving = status.Serving
+	health.StaleAvailable = status.StaleAvailable
+	if !status.LastSuccess.IsZero() {
+		health.LastSuccess = &status.LastSuccess
+	}
+	if status.StaleAvailable {
+		age := status.CacheAge.Seconds()
+		health.CacheAgeSeconds = &age
+	}
+	return health
+}
+
+// healthStatus names a provider's health as reported by the health endpoints
+func healthStatus(healthy bool) string {
+	if healthy {
+		return "healthy"
 	}
+	return "unhealthy"
 }
 
 // VersionResponse represents the version info response
@@ -91,27 +274,94 @@ type VersionResponse struct {
 
 // Version handles GET /api/version
 func (h *Handler) Version(c *gin.Context) {
-	c.JSON(http.StatusOK, VersionResponse{
+	c.JSON(http.StatusOK, versionInfo())
+}
+
+// versionInfo returns the build's version information
+func versionInfo() VersionResponse {
+	return VersionResponse{
 		Version:   Version,
 		BuildTime: BuildTime,
-	})
+	}
 }
 
 // PortfolioSummary represents the unified portfolio summary
 type PortfolioSummary struct {
-	TotalValue      float64       `json:"total_value"`
-	TotalCostBasis  float64       `json:"total_cost_basis"`
-	TotalPnL        float64       `json:"total_pnl"`
-	TotalPnLPct     float64       `json:"total_pnl_pct"`
-	TEFASValue      float64       `json:"tefas_value"`
-	TEFASCostBasis  float64       `json:"tefas_cost_basis"`
-	TEFASPnL        float64       `json:"tefas_pnl"`
-	CryptoValue     float64       `json:"crypto_value"`
-	CryptoCostBasis float64       `json:"crypto_cost_basis"`
-	CryptoPnL       float64       `json:"crypto_pnl"`
-	LastUpdated     time.Time     `json:"last_updated"`
-	Funds           []FundPrice   `json:"funds"`
-	Cryptos         []CryptoPrice `json:"cryptos"`
+	TotalValue      float64 `json:"total_value"` // In DisplayCurrency when TotalsConverted
+	TotalCostBasis  float64 `json:"total_cost_basis"`
+	TotalPnL        float64 `json:"total_pnl"`
+	TotalPnLPct     float64 `json:"total_pnl_pct"`
+	CacheAge        float64 `json:"cache_age_seconds"` // How old this summary is; 0 when freshly computed
+	TEFASValue      float64 `json:"tefas_value"`       // In TEFASCurrency
+	TEFASCostBasis  float64 `json:"tefas_cost_basis"`
+	TEFASPnL        float64 `json:"tefas_pnl"`
+	CryptoValue     float64 `json:"crypto_value"` // In CryptoCurrency; only crypto priced in it when a rate is missing
+	CryptoCostBasis float64 `json:"crypto_cost_basis"`
+	CryptoPnL       float64 `json:"crypto_pnl"`
+
+	// DisplayCurrency is the configured display_currency and the TEFAS and
+	// crypto currencies those of the currencies setting. The totals are
+	// converted to DisplayCurrency at ConversionRates, the units of it one
+	// unit of each other currency held is worth. Without every rate needed,
+	// TotalsConverted is false and the totals add amounts as they are, the
+	// TEFAS subtotals stay in TRY and the crypto ones cover only crypto
+	// priced in CryptoCurrency.
+	DisplayCurrency string             `json:"display_currency"`
+	TEFASCurrency   string             `json:"tefas_currency"`
+	CryptoCurrency  string             `json:"crypto_currency"`
+	ConversionRates map[string]float64 `json:"conversion_rates,omitempty"`
+	TotalsConverted bool               `json:"totals_converted"`
+
+	// RealizedPnL sums the gains and losses of recorded sells and
+	// UnrealizedPnL is TotalValue less the remaining cost basis. No
+	// transaction history is kept yet, so RealizedPnL is 0 and UnrealizedPnL
+	// equals TotalPnL.
+	RealizedPnL   float64 `json:"realized_pnl"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+
+	// CryptoByCurrency totals crypto holdings by quote currency ("USD" for
+	// stablecoin pairs, "TRY" for BTCTRY), so TRY pairs need no conversion
+	CryptoByCurrency map[string]CurrencyTotals `json:"crypto_by_currency"`
+
+	// TotalValueTRY and TotalValueUSD value every holding in one currency,
+	// TEFAS funds being priced in TRY. They are omitted, with
+	// ConversionAvailable false, when an exchange rate is unavailable.
+	TotalValueTRY       *float64   `json:"total_value_try,omitempty"`
+	TotalValueUSD       *float64   `json:"total_value_usd,omitempty"`
+	USDTRYRate          *float64   `json:"usd_try_rate,omitempty"`
+	RateUpdated         *time.Time `json:"rate_updated,omitempty"`
+	ConversionAvailable bool       `json:"conversion_available"`
+
+	// DayChange and DayChangePct compare TotalValue with the latest snapshot
+	// before today, dated DayChangeFrom. They are null, with
+	// DayChangeAvailable false, when there is no snapshot from the past week.
+	DayChange          *float64 `json:"day_change"`
+	DayChangePct       *float64 `json:"day_change_pct"`
+	DayChangeFrom      *string  `json:"day_change_from"`
+	DayChangeAvailable bool     `json:"day_change_available"`
+
+	// AllTimeHigh is the highest total value across the snapshots and the
+	// current TotalValue, reached on AllTimeHighDate, and DrawdownFromATHPct
+	// how far below it TotalValue is now. They are null, with
+	// AllTimeHighAvailable false, until there are two snapshots.
+	AllTimeHigh          *float64 `json:"all_time_high"`
+	AllTimeHighDate      *string  `json:"all_time_high_date"`
+	DrawdownFromATHPct   *float64 `json:"drawdown_from_ath_pct"`
+	AllTimeHighAvailable bool     `json:"all_time_high_available"`
+
+	LastUpdated time.Time     `json:"last_updated"`
+	Funds       []FundPrice   `json:"funds"`
+	Cryptos     []CryptoPrice `json:"cryptos"`
+
+	tryRates   tryRates          // Kept so simulations convert at the same rates
+	currencies summaryCurrencies // and into the same currencies
+}
+
+// CurrencyTotals is the value of the holdings priced in one currency
+type CurrencyTotals struct {
+	Value     float64 `json:"value"`
+	CostBasis float64 `json:"cost_basis"`
+	PnL       float64 `json:"pnl"`
 }
 
 // FundPrice represents a TEFAS fund with holdings info
@@ -124,15 +374,24 @@ type FundPrice struct {
 	Quantity    float64   `json:"quantity"`
 	Value       float64   `json:"value"`      // Current value = price * quantity
 	CostBasis   float64   `json:"cost_basis"` // Total cost paid
+	AvgPrice    float64   `json:"avg_price"`  // Average buy price = cost_basis / quantity
 	PnL         float64   `json:"pnl"`        // Profit/Loss = value - cost_basis
 	PnLPct      float64   `json:"pnl_pct"`    // P&L percentage
 	LastUpdated time.Time `json:"last_updated"`
 	Stale       bool      `json:"stale"`
+	PriceDate   string    `json:"price_date,omitempty"` // Day the NAV was published (YYYY-MM-DD)
+	Category    string    `json:"category,omitempty"`   // TEFAS fund category (e.g. "Hisse Senedi Fonu"); empty when TEFAS doesn't report it
+	Watched     bool      `json:"watched,omitempty"`    // On the watchlist; quantity is 0 unless also held
+	Tags        []string  `json:"tags,omitempty"`       // The holding's tags
+
+	RealizedPnL   float64 `json:"realized_pnl"`   // Gains and losses of recorded sells; 0, as no transaction history is kept
+	UnrealizedPnL float64 `json:"unrealized_pnl"` // Value - remaining cost_basis; equals pnl without realized gains
 }
 
 // CryptoPrice represents a cryptocurrency with holdings info
 type CryptoPrice struct {
 	Symbol      string    `json:"symbol"`
+	Currency    string    `json:"currency"` // Quote currency of the price, value and cost basis
 	Name        string    `json:"name"`
 	Price       float64   `json:"price"`
 	DailyChange float64   `json:"daily_change"`
@@ -140,24 +399,130 @@ type CryptoPrice struct {
 	Quantity    float64   `json:"quantity"`
 	Value       float64   `json:"value"`      // Current value = price * quantity
 	CostBasis   float64   `json:"cost_basis"` // Total cost paid
+	AvgPrice    float64   `json:"avg_price"`  // Average buy price = cost_basis / quantity
 	PnL         float64   `json:"pnl"`        // Profit/Loss = value - cost_basis
 	PnLPct      float64   `json:"pnl_pct"`    // P&L percentage
 	LastUpdated time.Time `json:"last_updated"`
+	Watched     bool      `json:"watched,omitempty"`  // On the watchlist; quantity is 0 unless also held
+	Delisted    bool      `json:"delisted,omitempty"` // No longer listed upstream; the holding should be updated or removed
+	Tags        []string  `json:"tags,omitempty"`     // The holding's tags
+
+	RealizedPnL   float64 `json:"realized_pnl"`   // Gains and losses of recorded sells; 0, as no transaction history is kept
+	UnrealizedPnL float64 `json:"unrealized_pnl"` // Value - remaining cost_basis; equals pnl without realized gains
 }
 
 // GetPortfolioSummary handles GET /api/portfolio/summary
 func (h *Handler) GetPortfolioSummary(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
+
+	summary, err := h.cachedPortfolioSummary(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+
+	// The tag ignores when the summary was computed, so it only changes
+	// when prices, holdings or rates do
+	tagged := summary
+	tagged.CacheAge = 0
+	tagged.LastUpdated = time.Time{}
+	respondWithETag(c, summary, tagged)
+}
+
+// cachedPortfolioSummary returns a summary computed within server.summary_cache_ttl,
+// or computes a new one. Concurrent callers share a single computation. Failed
+// computations are not cached.
+func (h *Handler) cachedPortfolioSummary(ctx context.Context) (PortfolioSummary, error) {
+	ttl := h.cfg.Get().Server.SummaryCacheTTL
+	if ttl < 0 {
+		return h.buildPortfolioSummary(ctx)
+	}
+
+	summary, gen, ok := h.freshSummary(ttl)
+	if ok {
+		return summary, nil
+	}
+
+	result, err, _ := h.summaryGroup.Do(fmt.Sprintf("summary/%d", gen), func() (any, error) {
+		// Another caller may have refreshed it while we waited to get here
+		if summary, _, ok := h.freshSummary(ttl); ok {
+			return summary, nil
+		}
+
+		// Detach from the first caller so its disconnect doesn't fail everyone
+		buildCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.requestTimeout())
+		defer cancel()
+		summary, err := h.buildPortfolioSummary(buildCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		h.summaryMu.Lock()
+		if h.summaryGen == gen {
+			h.summary = &summary
+			h.summaryAt = time.Now()
+		}
+		h.summaryMu.Unlock()
+		return summary, nil
+	})
+	if err != nil {
+		return PortfolioSummary{}, err
+	}
+	return result.(PortfolioSummary), nil
+}
+
+// invalidateSummary drops the cached summary after holdings change, along
+// with any being built from the holdings as they were
+func (h *Handler) invalidateSummary() {
+	h.summaryMu.Lock()
+	defer h.summaryMu.Unlock()
+	h.summary = nil
+	h.summaryGen++
+}
+
+// freshSummary returns the cached summary if it is younger than ttl, with
+// its age filled in, and the current cache generation
+func (h *Handler) freshSummary(ttl time.Duration) (PortfolioSummary, uint64, bool) {
+	h.summaryMu.Lock()
+	defer h.summaryMu.Unlock()
+
+	if h.summary == nil {
+		return PortfolioSummary{}, h.summaryGen, false
+	}
+	age := time.Since(h.summaryAt)
+	if age >= ttl {
+		return PortfolioSummary{}, h.summaryGen, false
+	}
+
+	summary := *h.summary
+	summary.CacheAge = age.Seconds()
+	return summary, h.summaryGen, true
+}
+
+// buildPortfolioSummary fetches live prices for all holdings and aggregates
+// them. Shared by every endpoint that needs portfolio totals so they agree.
+// It fails only if the holdings can't be read; provider errors mark the
+// affected positions stale instead.
+func (h *Handler) buildPortfolioSummary(ctx context.Context) (PortfolioSummary, error) {
+	start := time.Now()
+	defer func() {
+		metrics.SummaryDuration.Observe(time.Since(start).Seconds())
+	}()
 
 	var funds []FundPrice
 	var cryptos []CryptoPrice
-	var tefasValue, tefasCostBasis, cryptoValue, cryptoCostBasis float64
 	now := time.Now()
+	currencies := h.summaryCurrencies()
 
 	// Get holdings from storage
-	fundHoldings, _ := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeFund)
-	cryptoHoldings, _ := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeCrypto)
+	fundHoldings, err := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeFund)
+	if err != nil {
+		return PortfolioSummary{}, fmt.Errorf("reading fund holdings: %w", err)
+	}
+	cryptoHoldings, err := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeCrypto)
+	if err != nil {
+		return PortfolioSummary{}, fmt.Errorf("reading crypto holdings: %w", err)
+	}
 
 	// Build lookup maps for quick access
 	fundHoldingMap := make(map[string]*storage.Holding)
@@ -175,45 +540,83 @@ func (h *Handler) GetPortfolioSummary(c *gin.Context) {
 		fundCodes = append(fundCodes, h.Symbol)
 	}
 
-	// Fetch TEFAS data
-	tefasFetchSuccess := false
-	if h.tefasProvider != nil && len(fundCodes) > 0 {
-		prices, err := h.tefasProvider.FetchPrices(ctx, fundCodes)
-		if err == nil {
-			tefasFetchSuccess = true
-			for _, p := range prices {
-				holding := fundHoldingMap[p.Symbol]
-				quantity := 0.0
-				costBasis := 0.0
-				if holding != nil {
-					quantity = holding.Quantity
-					costBasis = holding.CostBasis
-				}
+	// Get crypto symbols from storage
+	cryptoSymbols := make([]string, 0, len(cryptoHoldings))
+	for _, h := range cryptoHoldings {
+		cryptoSymbols = append(cryptoSymbols, h.Symbol)
+	}
 
-				value := p.Price * quantity
-				pnl := value - costBasis
-				pnlPct := 0.0
-				if costBasis > 0 {
-					pnlPct = (pnl / costBasis) * 100
-				}
+	// Fetch TEFAS and crypto data concurrently so the slow Playwright round-trip
+	// doesn't add to crypto latency. Each provider bounds its own fetch with a
+	// sub-context of ctx (see the provider timeout config).
+	var tefasResult, cryptoResult fetchResult
+	var wg sync.WaitGroup
+	if h.tefasProvider != nil && len(fundCodes) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tefasResult = fetchPrices(ctx, h.tefasProvider, fundCodes)
+		}()
+	}
+	if h.cryptoProvider != nil && len(cryptoSymbols) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cryptoResult = fetchPrices(ctx, h.cryptoProvider, cryptoSymbols)
+		}()
+	}
+	var rates tryRates
+	if h.fxProvider != nil || h.cryptoProvider != nil {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			rates = h.fetchTRYRates(ctx, cryptoSymbols, currencies)
+		}()
+		go func() {
+			defer wg.Done()
+			costs := h.newCostConverter()
+			costs.convertAll(ctx, fundHoldings)
+			costs.convertAll(ctx, cryptoHoldings)
+		}()
+	}
+	wg.Wait()
 
-				funds = append(funds, FundPrice{
-					Code:        p.Symbol,
-					Name:        p.Name,
-					Price:       p.Price,
-					DailyChange: p.DailyChange,
-					DailyPct:    p.DailyPct,
-					Quantity:    quantity,
-					Value:       value,
-					CostBasis:   costBasis,
-					PnL:         pnl,
-					PnLPct:      pnlPct,
-					LastUpdated: p.LastUpdated,
-					Stale:       p.Stale,
-				})
-				tefasValue += value
-				tefasCostBasis += costBasis
+	// Process TEFAS data
+	tefasFetchSuccess := false
+	if tefasResult.ok {
+		tefasFetchSuccess = true
+		for _, p := range tefasResult.prices {
+			holding := fundHoldingMap[p.Symbol]
+			quantity := 0.0
+			costBasis := 0.0
+			var tags []string
+			if holding != nil {
+				quantity = holding.Quantity
+				costBasis = holding.CostBasis
+				tags = holding.Tags
 			}
+
+			value, pnl, pnlPct := positionPnL(p.Price, quantity, costBasis)
+
+			funds = append(funds, FundPrice{
+				Code:          p.Symbol,
+				Name:          p.Name,
+				Price:         p.Price,
+				DailyChange:   p.DailyChange,
+				DailyPct:      p.DailyPct,
+				Quantity:      quantity,
+				Value:         value,
+				CostBasis:     costBasis,
+				AvgPrice:      storage.AvgPrice(costBasis, quantity),
+				PnL:           pnl,
+				UnrealizedPnL: pnl,
+				PnLPct:        pnlPct,
+				LastUpdated:   p.LastUpdated,
+				Stale:         p.Stale,
+				PriceDate:     p.PriceDate,
+				Category:      p.Category,
+				Tags:          tags,
+			})
 		}
 	}
 
@@ -222,66 +625,58 @@ func (h *Handler) GetPortfolioSummary(c *gin.Context) {
 		for _, holding := range fundHoldings {
 			funds = append(funds, FundPrice{
 				Code:        holding.Symbol,
-				Name:        getFundDisplayName(holding.Symbol),
+				Name:        h.fundName(holding.Symbol),
 				Price:       0,
 				DailyChange: 0,
 				DailyPct:    0,
 				Quantity:    holding.Quantity,
 				Value:       0,
 				CostBasis:   holding.CostBasis,
+				AvgPrice:    storage.AvgPrice(holding.CostBasis, holding.Quantity),
 				PnL:         0,
 				PnLPct:      0,
 				LastUpdated: now,
 				Stale:       true,
+				Tags:        holding.Tags,
 			})
-			tefasCostBasis += holding.CostBasis
 		}
 	}
 
-	// Get crypto symbols from storage
-	cryptoSymbols := make([]string, 0, len(cryptoHoldings))
-	for _, h := range cryptoHoldings {
-		cryptoSymbols = append(cryptoSymbols, h.Symbol)
-	}
-
-	// Fetch crypto data
+	// Process crypto data
 	cryptoFetchSuccess := false
-	if h.cryptoProvider != nil && len(cryptoSymbols) > 0 {
-		prices, err := h.cryptoProvider.FetchPrices(ctx, cryptoSymbols)
-		if err == nil {
-			cryptoFetchSuccess = true
-			for _, p := range prices {
-				holding := cryptoHoldingMap[p.Symbol]
-				quantity := 0.0
-				costBasis := 0.0
-				if holding != nil {
-					quantity = holding.Quantity
-					costBasis = holding.CostBasis
-				}
+	if cryptoResult.ok {
+		cryptoFetchSuccess = true
+		for _, p := range cryptoResult.prices {
+			holding := cryptoHoldingMap[p.Symbol]
+			quantity := 0.0
+			costBasis := 0.0
+			var tags []string
+			if holding != nil {
+				quantity = holding.Quantity
+				costBasis = holding.CostBasis
+				tags = holding.Tags
+			}
 
-				value := p.Price * quantity
-				pnl := value - costBasis
-				pnlPct := 0.0
-				if costBasis > 0 {
-					pnlPct = (pnl / costBasis) * 100
-				}
+			value, pnl, pnlPct := positionPnL(p.Price, quantity, costBasis)
 
-				cryptos = append(cryptos, CryptoPrice{
-					Symbol:      p.Symbol,
-					Name:        p.Name,
-					Price:       p.Price,
-					DailyChange: p.DailyChange,
-					DailyPct:    p.DailyPct,
-					Quantity:    quantity,
-					Value:       value,
-					CostBasis:   costBasis,
-					PnL:         pnl,
-					PnLPct:      pnlPct,
-					LastUpdated: p.LastUpdated,
-				})
-				cryptoValue += value
-				cryptoCostBasis += costBasis
-			}
+			cryptos = append(cryptos, CryptoPrice{
+				Symbol:        p.Symbol,
+				Currency:      providers.QuoteCurrency(p.Symbol),
+				Name:          p.Name,
+				Price:         p.Price,
+				DailyChange:   p.DailyChange,
+				DailyPct:      p.DailyPct,
+				Quantity:      quantity,
+				Value:         value,
+				CostBasis:     costBasis,
+				AvgPrice:      storage.AvgPrice(costBasis, quantity),
+				PnL:           pnl,
+				UnrealizedPnL: pnl,
+				PnLPct:        pnlPct,
+				LastUpdated:   p.LastUpdated,
+				Delisted:      p.Delisted,
+				Tags:          tags,
+			})
 		}
 	}
 
@@ -290,6 +685,7 @@ func (h *Handler) GetPortfolioSummary(c *gin.Context) {
 		for _, holding := range cryptoHoldings {
 			cryptos = append(cryptos, CryptoPrice{
 				Symbol:      holding.Symbol,
+				Currency:    providers.QuoteCurrency(holding.Symbol),
 				Name:        holding.Symbol,
 				Price:       0,
 				DailyChange: 0,
@@ -297,54 +693,440 @@ func (h *Handler) GetPortfolioSummary(c *gin.Context) {
 				Quantity:    holding.Quantity,
 				Value:       0,
 				CostBasis:   holding.CostBasis,
+				AvgPrice:    storage.AvgPrice(holding.CostBasis, holding.Quantity),
 				PnL:         0,
 				PnLPct:      0,
 				LastUpdated: now,
+				Tags:        holding.Tags,
 			})
-			cryptoCostBasis += holding.CostBasis
-		}
-	}
-
-	totalValue := tefasValue + cryptoValue
-	totalCostBasis := tefasCostBasis + cryptoCostBasis
-	totalPnL := totalValue - totalCostBasis
-	totalPnLPct := 0.0
-	if totalCostBasis > 0 {
-		totalPnLPct = (totalPnL / totalCostBasis) * 100
-	}
-
-	c.JSON(http.StatusOK, PortfolioSummary{
-		TotalValue:      totalValue,
-		TotalCostBasis:  totalCostBasis,
-		TotalPnL:        totalPnL,
-		TotalPnLPct:     totalPnLPct,
-		TEFASValue:      tefasValue,
-		TEFASCostBasis:  tefasCostBasis,
-		TEFASPnL:        tefasValue - tefasCostBasis,
-		CryptoValue:     cryptoValue,
-		CryptoCostBasis: cryptoCostBasis,
-		CryptoPnL:       cryptoValue - cryptoCostBasis,
-		LastUpdated:     time.Now(),
-		Funds:           funds,
-		Cryptos:         cryptos,
-	})
+		}
+	}
+
+	summary := summarize(funds, cryptos)
+	summary.convertTotals(rates, currencies)
+	h.setDayChange(ctx, &summary, now)
+	h.setAllTimeHigh(ctx, &summary, now)
+	metrics.PortfolioValue.Set(summary.TotalValue)
+	return summary, nil
+}
+
+// tryRates holds the price in TRY of one unit of each currency in the
+// portfolio, and when the USD/TRY rate was last updated
+type tryRates struct {
+	rates   map[string]float64
+	updated time.Time
+}
+
+// fetchTRYRates fetches the TRY rate of USD, of every other quote currency
+// among the crypto symbols and of the currencies totals are reported in. It
+// returns no rates if any fails, as the totals can't be converted without
+// all of them.
+func (h *Handler) fetchTRYRates(ctx context.Context, cryptoSymbols []string, totalsIn summaryCurrencies) tryRates {
+	result := tryRates{rates: map[string]float64{"TRY": 1}}
+	currencies := []string{"USD"}
+	for _, symbol := range cryptoSymbols {
+		if currency := providers.QuoteCurrency(symbol); !slices.Contains(currencies, currency) && currency != "TRY" {
+			currencies = append(currencies, currency)
+		}
+	}
+	for _, currency := range []string{totalsIn.fund, totalsIn.crypto, totalsIn.display} {
+		if !slices.Contains(currencies, currency) && currency != "TRY" {
+			currencies = append(currencies, currency)
+		}
+	}
+
+	for _, currency := range currencies {
+		rate, updated, err := h.exchangeRate(ctx, currency, "TRY")
+		if err != nil {
+			slog.Warn("exchange rate unavailable, summary shows native totals only", "from", currency, "error", err)
+			return tryRates{}
+		}
+		result.rates[currency] = rate
+		if currency == "USD" {
+			result.updated = updated
+		}
+	}
+	return result
+}
+
+// rate returns how many units of to one unit of from is worth, reporting
+// false when either has no rate
+func (r tryRates) rate(from, to string) (decimal.Decimal, bool) {
+	if from == to {
+		return decimal.NewFromInt(1), true
+	}
+	fromTRY, toTRY := r.rates[from], r.rates[to]
+	if fromTRY <= 0 || toTRY <= 0 {
+		return decimal.Decimal{}, false
+	}
+	return decimal.NewFromFloat(fromTRY).Div(decimal.NewFromFloat(toTRY)), true
+}
+
+// summaryCurrencies are the currencies a summary's subtotals and totals are
+// reported in
+type summaryCurrencies struct {
+	fund, crypto, display string
+}
+
+// summaryCurrencies returns the configured currencies, with the defaults
+// for unset ones
+func (h *Handler) summaryCurrencies() summaryCurrencies {
+	cfg := h.cfg.Get()
+	currencies := summaryCurrencies{fund: cfg.Currencies.Fund, crypto: cfg.Currencies.Crypto, display: cfg.DisplayCurrency}
+	if currencies.fund == "" {
+		currencies.fund = "TRY"
+	}
+	if currencies.crypto == "" {
+		currencies.crypto = "USD"
+	}
+	if currencies.display == "" {
+		currencies.display = "TRY"
+	}
+	return currencies
+}
+
+// pricedAmount is a row's value and cost basis in the currency it is priced in
+type pricedAmount struct {
+	currency         string
+	value, costBasis float64
+}
+
+// convertedTotal adds up amounts in currency to. The gross cost basis counts
+// shorts by their magnitude, as summarize does. It reports false when an
+// amount has no rate to convert it.
+func (r tryRates) convertedTotal(amounts []pricedAmount, to string) (value, costBasis, grossCostBasis decimal.Decimal, ok bool) {
+	for _, a := range amounts {
+		rate, ok := r.rate(a.currency, to)
+		if !ok {
+			return decimal.Decimal{}, decimal.Decimal{}, decimal.Decimal{}, false
+		}
+		cost := decimal.NewFromFloat(a.costBasis).Mul(rate)
+		value = value.Add(decimal.NewFromFloat(a.value).Mul(rate))
+		costBasis = costBasis.Add(cost)
+		grossCostBasis = grossCostBasis.Add(cost.Abs())
+	}
+	return value.Round(moneyPlaces), costBasis.Round(moneyPlaces), grossCostBasis.Round(moneyPlaces), true
+}
+
+// convertTotals restates the subtotals and totals summarize added up as they
+// are in the configured currencies, where the rates allow, and fills in the
+// TRY and USD totals, leaving those unset when a currency in the summary has
+// no rate
+func (s *PortfolioSummary) convertTotals(rates tryRates, currencies summaryCurrencies) {
+	s.tryRates, s.currencies = rates, currencies
+	s.DisplayCurrency, s.TEFASCurrency, s.CryptoCurrency = currencies.display, "TRY", currencies.crypto
+
+	fundAmounts := make([]pricedAmount, 0, len(s.Funds))
+	for _, f := range s.Funds {
+		fundAmounts = append(fundAmounts, pricedAmount{"TRY", f.Value, f.CostBasis})
+	}
+	cryptoAmounts := make([]pricedAmount, 0, len(s.Cryptos))
+	for _, cr := range s.Cryptos {
+		cryptoAmounts = append(cryptoAmounts, pricedAmount{cr.Currency, cr.Value, cr.CostBasis})
+	}
+
+	if value, cost, _, ok := rates.convertedTotal(fundAmounts, currencies.fund); ok {
+		s.TEFASValue, s.TEFASCostBasis, s.TEFASPnL = value.InexactFloat64(), cost.InexactFloat64(), value.Sub(cost).InexactFloat64()
+		s.TEFASCurrency = currencies.fund
+	}
+	if value, cost, _, ok := rates.convertedTotal(cryptoAmounts, currencies.crypto); ok {
+		s.CryptoValue, s.CryptoCostBasis, s.CryptoPnL = value.InexactFloat64(), cost.InexactFloat64(), value.Sub(cost).InexactFloat64()
+	} else {
+		native := s.CryptoByCurrency[currencies.crypto]
+		s.CryptoValue, s.CryptoCostBasis, s.CryptoPnL = native.Value, native.CostBasis, native.PnL
+	}
+	all := append(fundAmounts, cryptoAmounts...)
+	if value, cost, gross, ok := rates.convertedTotal(all, currencies.display); ok {
+		pnl := value.Sub(cost)
+		s.TotalValue, s.TotalCostBasis, s.TotalPnL = value.InexactFloat64(), cost.InexactFloat64(), pnl.InexactFloat64()
+		s.TotalPnLPct = percentOf(pnl, gross)
+		s.UnrealizedPnL = s.TotalPnL
+		s.TotalsConverted = true
+		for _, a := range all {
+			if a.currency == currencies.display {
+				continue
+			}
+			if s.ConversionRates == nil {
+				s.ConversionRates = make(map[string]float64)
+			}
+			rate, _ := rates.rate(a.currency, currencies.display)
+			s.ConversionRates[a.currency] = rate.InexactFloat64()
+		}
+	}
+
+	usdTRY := rates.rates["USD"]
+	if usdTRY <= 0 {
+		return
+	}
+
+	totalTRY := decimal.NewFromFloat(s.TEFASValue)
+	for currency, totals := range s.CryptoByCurrency {
+		rate, ok := rates.rates[currency]
+		if !ok || rate <= 0 {
+			return
+		}
+		totalTRY = totalTRY.Add(decimal.NewFromFloat(totals.Value).Mul(decimal.NewFromFloat(rate)))
+	}
+
+	valueTRY := totalTRY.InexactFloat64()
+	valueUSD := totalTRY.Div(decimal.NewFromFloat(usdTRY)).InexactFloat64()
+	updated := rates.updated
+	s.TotalValueTRY = &valueTRY
+	s.TotalValueUSD = &valueUSD
+	s.USDTRYRate = &usdTRY
+	s.RateUpdated = &updated
+	s.ConversionAvailable = true
+}
+
+// totalsCurrency returns the currency s's totals are in: the display
+// currency once they are converted, or "" for totals that add currencies as
+// they are and can't be compared with any other
+func (s *PortfolioSummary) totalsCurrency() string {
+	if !s.TotalsConverted {
+		return ""
+	}
+	return s.DisplayCurrency
+}
+
+// inTotalsCurrency restates an amount a row is priced in currency in the
+// units of s's totals, so it can be weighed against TotalValue. Amounts
+// stay as they are when the totals aren't converted.
+func (s *PortfolioSummary) inTotalsCurrency(amount float64, currency string) float64 {
+	if !s.TotalsConverted {
+		return amount
+	}
+	rate, ok := s.tryRates.rate(currency, s.DisplayCurrency)
+	if !ok {
+		return amount
+	}
+	return decimal.NewFromFloat(amount).Mul(rate).Round(moneyPlaces).InexactFloat64()
+}
+
+// dayChangeLookbackDays is how far back setDayChange looks for a snapshot,
+// so weekends and holidays without one compare against the last day with one
+const dayChangeLookbackDays = 7
+
+// setDayChange compares the summary's total value with the latest snapshot
+// dated before now with totals in the same currency. It leaves the day
+// change unset when there is none, or when the totals aren't converted.
+func (h *Handler) setDayChange(ctx context.Context, s *PortfolioSummary, now time.Time) {
+	currency := s.totalsCurrency()
+	if currency == "" {
+		return
+	}
+	today, _ := time.Parse(time.DateOnly, now.Format(time.DateOnly))
+	snapshots, err := h.storage.GetSnapshots(ctx, today.AddDate(0, 0, -dayChangeLookbackDays), today.AddDate(0, 0, -1))
+	if err != nil {
+		slog.Warn("failed to load previous snapshot", "error", err)
+		return
+	}
+	snapshots = slices.DeleteFunc(snapshots, func(snap storage.Snapshot) bool { return snap.Currency != currency })
+	if len(snapshots) == 0 {
+		return
+	}
+
+	prev := snapshots[len(snapshots)-1]
+	prevValue := decimal.NewFromFloat(prev.TotalValue)
+	change := decimal.NewFromFloat(s.TotalValue).Sub(prevValue)
+	changeValue := change.InexactFloat64()
+	s.DayChange = &changeValue
+	if !prevValue.IsZero() {
+		pct := percentOf(change, prevValue)
+		s.DayChangePct = &pct
+	}
+	from := prev.Date.Format(time.DateOnly)
+	s.DayChangeFrom = &from
+	s.DayChangeAvailable = true
+}
+
+// minATHSnapshots is how many snapshots setAllTimeHigh needs before an
+// all-time high means anything
+const minATHSnapshots = 2
+
+// setAllTimeHigh sets the all-time high from the peak snapshot with totals
+// in the same currency, or from the summary's total value when that is
+// higher, and the drawdown from it. It leaves them unset with fewer than
+// minATHSnapshots such snapshots, or when the totals aren't converted.
+func (h *Handler) setAllTimeHigh(ctx context.Context, s *PortfolioSummary, now time.Time) {
+	currency := s.totalsCurrency()
+	if currency == "" {
+		return
+	}
+	peak, count, err := h.storage.GetPeakSnapshot(ctx, currency)
+	if err != nil {
+		slog.Warn("failed to load peak snapshot", "error", err)
+		return
+	}
+	if count < minATHSnapshots {
+		return
+	}
+
+	high, date := peak.TotalValue, peak.Date.Format(time.DateOnly)
+	if s.TotalValue > high {
+		high, date = s.TotalValue, now.Format(time.DateOnly)
+	}
+	s.AllTimeHigh = &high
+	s.AllTimeHighDate = &date
+	if high > 0 {
+		highValue := decimal.NewFromFloat(high)
+		drawdown := percentOf(highValue.Sub(decimal.NewFromFloat(s.TotalValue)), highValue)
+		s.DrawdownFromATHPct = &drawdown
+	}
+	s.AllTimeHighAvailable = true
+}
+
+// summarize totals the per-holding rows into a summary. Totals stay in
+// decimal until serialized so they reconcile to the cent with the rows.
+// The overall totals add every currency as is; convertTotals restates them
+// in one currency when it has the rates.
+func summarize(funds []FundPrice, cryptos []CryptoPrice) PortfolioSummary {
+	// grossCostBasis counts shorts by their magnitude, so they don't offset
+	// long positions in the total P&L percentage
+	var tefasValue, tefasCostBasis, allValue, allCostBasis, grossCostBasis moneyTotal
+	for _, f := range funds {
+		tefasValue.Add(f.Value)
+		tefasCostBasis.Add(f.CostBasis)
+		grossCostBasis.Add(f.CostBasis)
+	}
+	cryptoValue := make(map[string]*moneyTotal)
+	cryptoCostBasis := make(map[string]*moneyTotal)
+	for _, cr := range cryptos {
+		if cryptoValue[cr.Currency] == nil {
+			cryptoValue[cr.Currency] = &moneyTotal{}
+			cryptoCostBasis[cr.Currency] = &moneyTotal{}
+		}
+		cryptoValue[cr.Currency].Add(cr.Value)
+		cryptoCostBasis[cr.Currency].Add(cr.CostBasis)
+		allValue.Add(cr.Value)
+		allCostBasis.Add(cr.CostBasis)
+		grossCostBasis.Add(math.Abs(cr.CostBasis))
+	}
+	byCurrency := make(map[string]CurrencyTotals, len(cryptoValue))
+	for currency, value := range cryptoValue {
+		byCurrency[currency] = CurrencyTotals{
+			Value:     value.Float64(),
+			CostBasis: cryptoCostBasis[currency].Float64(),
+			PnL:       value.sum.Sub(cryptoCostBasis[currency].sum).InexactFloat64(),
+		}
+	}
+	usd := byCurrency["USD"]
+
+	totalValue := tefasValue.sum.Add(allValue.sum)
+	totalCostBasis := tefasCostBasis.sum.Add(allCostBasis.sum)
+	totalPnL := totalValue.Sub(totalCostBasis)
+
+	return PortfolioSummary{
+		TotalValue:       totalValue.InexactFloat64(),
+		TotalCostBasis:   totalCostBasis.InexactFloat64(),
+		TotalPnL:         totalPnL.InexactFloat64(),
+		TotalPnLPct:      percentOf(totalPnL, grossCostBasis.sum),
+		TEFASValue:       tefasValue.Float64(),
+		TEFASCostBasis:   tefasCostBasis.Float64(),
+		TEFASPnL:         tefasValue.sum.Sub(tefasCostBasis.sum).InexactFloat64(),
+		CryptoValue:      usd.Value,
+		CryptoCostBasis:  usd.CostBasis,
+		CryptoPnL:        usd.PnL,
+		CryptoByCurrency: byCurrency,
+		UnrealizedPnL:    totalPnL.InexactFloat64(),
+		LastUpdated:      time.Now(),
+		Funds:            funds,
+		Cryptos:          cryptos,
+	}
+}
+
+// HistoryResponse is the portfolio value history. From and To are the dates
+// of the first and last points returned, omitted when there are none.
+type HistoryResponse struct {
+	History     []storage.Snapshot  `json:"history"`
+	From        string              `json:"from,omitempty"`
+	To          string              `json:"to,omitempty"`
+	Granularity storage.Granularity `json:"granularity"`
+}
+
+// GetPortfolioHistory handles GET /api/portfolio/history?from=&to=&granularity=&limit=
+//
+// All parameters are optional. Weekly and monthly granularity return the last
+// snapshot of each week (starting Monday) or month; limit keeps the most
+// recent points.
+func (h *Handler) GetPortfolioHistory(c *gin.Context) {
+	q, err := parseHistoryQuery(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+
+	snapshots, err := h.storage.GetSnapshotHistory(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	resp := HistoryResponse{History: snapshots, Granularity: q.Granularity}
+	if len(snapshots) > 0 {
+		resp.From = snapshots[0].Date.Format(time.DateOnly)
+		resp.To = snapshots[len(snapshots)-1].Date.Format(time.DateOnly)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
-// GetPortfolioHistory handles GET /api/portfolio/history
-func (h *Handler) GetPortfolioHistory(c *gin.Context) {
-	// TODO: Implement with storage layer
-	c.JSON(http.StatusOK, gin.H{
-		"history": []interface{}{},
-	})
+// parseHistoryQuery reads the history filters, defaulting to every daily
+// snapshot up to today
+func parseHistoryQuery(c *gin.Context) (storage.SnapshotQuery, error) {
+	q := storage.SnapshotQuery{
+		To:          time.Now(),
+		Granularity: storage.Granularity(c.DefaultQuery("granularity", string(storage.GranularityDaily))),
+	}
+	if !storage.ValidGranularity(q.Granularity) {
+		return q, fmt.Errorf("granularity must be daily, weekly or monthly, got %q", q.Granularity)
+	}
+
+	if s := c.Query("from"); s != "" {
+		from, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return q, fmt.Errorf("invalid from date: %q", s)
+		}
+		q.From = from
+	}
+	if s := c.Query("to"); s != "" {
+		to, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return q, fmt.Errorf("invalid to date: %q", s)
+		}
+		q.To = to
+	}
+	if q.To.Before(q.From) {
+		return q, errors.New("from must not be after to")
+	}
+
+	if s := c.Query("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return q, fmt.Errorf("limit must be a positive integer, got %q", s)
+		}
+		q.Limit = limit
+	}
+	return q, nil
 }
 
-// GetFunds handles GET /api/funds
+// GetFunds handles GET /api/funds?category=
+//
+// With category, only funds TEFAS reports in that category are returned
+// (case-insensitive); funds without a known category are left out.
 func (h *Handler) GetFunds(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	// Get fund holdings from storage
-	fundHoldings, _ := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeFund)
+	fundHoldings, err := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeFund)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+	fundHoldings, watched, err := h.withWatched(ctx, storage.HoldingTypeFund, fundHoldings)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch watchlist")
+		return
+	}
+	h.newCostConverter().convertAll(ctx, fundHoldings)
 	fundCodes := make([]string, 0, len(fundHoldings))
 	fundHoldingMap := make(map[string]*storage.Holding)
 	for i := range fundHoldings {
@@ -367,26 +1149,26 @@ func (h *Handler) GetFunds(c *gin.Context) {
 					costBasis = holding.CostBasis
 				}
 
-				value := p.Price * quantity
-				pnl := value - costBasis
-				pnlPct := 0.0
-				if costBasis > 0 {
-					pnlPct = (pnl / costBasis) * 100
-				}
+				value, pnl, pnlPct := positionPnL(p.Price, quantity, costBasis)
 
 				funds = append(funds, FundPrice{
-					Code:        p.Symbol,
-					Name:        p.Name,
-					Price:       p.Price,
-					DailyChange: p.DailyChange,
-					DailyPct:    p.DailyPct,
-					Quantity:    quantity,
-					Value:       value,
-					CostBasis:   costBasis,
-					PnL:         pnl,
-					PnLPct:      pnlPct,
-					LastUpdated: p.LastUpdated,
-					Stale:       p.Stale,
+					Code:          p.Symbol,
+					Name:          p.Name,
+					Price:         p.Price,
+					DailyChange:   p.DailyChange,
+					DailyPct:      p.DailyPct,
+					Quantity:      quantity,
+					Value:         value,
+					CostBasis:     costBasis,
+					AvgPrice:      storage.AvgPrice(costBasis, quantity),
+					PnL:           pnl,
+					UnrealizedPnL: pnl,
+					PnLPct:        pnlPct,
+					LastUpdated:   p.LastUpdated,
+					Stale:         p.Stale,
+					PriceDate:     p.PriceDate,
+					Category:      p.Category,
+					Watched:       watched[p.Symbol],
 				})
 			}
 		} else {
@@ -395,17 +1177,19 @@ func (h *Handler) GetFunds(c *gin.Context) {
 			for _, holding := range fundHoldings {
 				funds = append(funds, FundPrice{
 					Code:        holding.Symbol,
-					Name:        getFundDisplayName(holding.Symbol),
+					Name:        h.fundName(holding.Symbol),
 					Price:       0,
 					DailyChange: 0,
 					DailyPct:    0,
 					Quantity:    holding.Quantity,
 					Value:       0,
 					CostBasis:   holding.CostBasis,
+					AvgPrice:    storage.AvgPrice(holding.CostBasis, holding.Quantity),
 					PnL:         0,
 					PnLPct:      0,
 					LastUpdated: now,
 					Stale:       true,
+					Watched:     watched[holding.Symbol],
 				})
 			}
 		}
@@ -414,21 +1198,29 @@ func (h *Handler) GetFunds(c *gin.Context) {
 		for _, holding := range fundHoldings {
 			funds = append(funds, FundPrice{
 				Code:        holding.Symbol,
-				Name:        getFundDisplayName(holding.Symbol),
+				Name:        h.fundName(holding.Symbol),
 				Price:       0,
 				DailyChange: 0,
 				DailyPct:    0,
 				Quantity:    holding.Quantity,
 				Value:       0,
 				CostBasis:   holding.CostBasis,
+				AvgPrice:    storage.AvgPrice(holding.CostBasis, holding.Quantity),
 				PnL:         0,
 				PnLPct:      0,
 				LastUpdated: now,
 				Stale:       true,
+				Watched:     watched[holding.Symbol],
 			})
 		}
 	}
 
+	if category := strings.TrimSpace(c.Query("category")); category != "" {
+		funds = slices.DeleteFunc(funds, func(f FundPrice) bool {
+			return !strings.EqualFold(f.Category, category)
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"funds": funds,
 	})
@@ -436,8 +1228,7 @@ func (h *Handler) GetFunds(c *gin.Context) {
 
 // GetFund handles GET /api/funds/:code
 func (h *Handler) GetFund(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	code := c.Param("code")
 
@@ -445,52 +1236,63 @@ func (h *Handler) GetFund(c *gin.Context) {
 		prices, err := h.tefasProvider.FetchPrices(ctx, []string{code})
 		if err == nil && len(prices) > 0 {
 			p := prices[0]
-			holding, _ := h.storage.GetHoldingBySymbol(ctx, storage.HoldingTypeFund, code)
+			holding, err := h.storage.GetHoldingBySymbol(ctx, storage.HoldingTypeFund, code)
+			if err != nil && !errors.Is(err, storage.ErrHoldingNotFound) {
+				respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holding")
+				return
+			}
 			quantity := 0.0
 			costBasis := 0.0
 			if holding != nil {
+				h.newCostConverter().convert(ctx, holding)
 				quantity = holding.Quantity
 				costBasis = holding.CostBasis
 			}
 
-			value := p.Price * quantity
-			pnl := value - costBasis
-			pnlPct := 0.0
-			if costBasis > 0 {
-				pnlPct = (pnl / costBasis) * 100
-			}
+			value, pnl, pnlPct := positionPnL(p.Price, quantity, costBasis)
 
 			c.JSON(http.StatusOK, FundPrice{
-				Code:        p.Symbol,
-				Name:        p.Name,
-				Price:       p.Price,
-				DailyChange: p.DailyChange,
-				DailyPct:    p.DailyPct,
-				Quantity:    quantity,
-				Value:       value,
-				CostBasis:   costBasis,
-				PnL:         pnl,
-				PnLPct:      pnlPct,
-				LastUpdated: p.LastUpdated,
-				Stale:       p.Stale,
+				Code:          p.Symbol,
+				Name:          p.Name,
+				Price:         p.Price,
+				DailyChange:   p.DailyChange,
+				DailyPct:      p.DailyPct,
+				Quantity:      quantity,
+				Value:         value,
+				CostBasis:     costBasis,
+				AvgPrice:      storage.AvgPrice(costBasis, quantity),
+				PnL:           pnl,
+				UnrealizedPnL: pnl,
+				PnLPct:        pnlPct,
+				LastUpdated:   p.LastUpdated,
+				Stale:         p.Stale,
+				PriceDate:     p.PriceDate,
+				Category:      p.Category,
 			})
 			return
 		}
 	}
 
 	// Fund not found or provider unavailable
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": "Fund not found",
-	})
+	respondError(c, http.StatusNotFound, CodeSymbolNotFound, "Fund not found")
 }
 
 // GetCryptos handles GET /api/crypto
 func (h *Handler) GetCryptos(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	// Get crypto holdings from storage
-	cryptoHoldings, _ := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeCrypto)
+	cryptoHoldings, err := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeCrypto)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+	cryptoHoldings, watched, err := h.withWatched(ctx, storage.HoldingTypeCrypto, cryptoHoldings)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch watchlist")
+		return
+	}
+	h.newCostConverter().convertAll(ctx, cryptoHoldings)
 	cryptoSymbols := make([]string, 0, len(cryptoHoldings))
 	cryptoHoldingMap := make(map[string]*storage.Holding)
 	for i := range cryptoHoldings {
@@ -512,31 +1314,29 @@ func (h *Handler) GetCryptos(c *gin.Context) {
 					costBasis = holding.CostBasis
 				}
 
-				value := p.Price * quantity
-				pnl := value - costBasis
-				pnlPct := 0.0
-				if costBasis > 0 {
-					pnlPct = (pnl / costBasis) * 100
-				}
+				value, pnl, pnlPct := positionPnL(p.Price, quantity, costBasis)
 
 				cryptos = append(cryptos, CryptoPrice{
-					Symbol:      p.Symbol,
-					Name:        p.Name,
-					Price:       p.Price,
-					DailyChange: p.DailyChange,
-					DailyPct:    p.DailyPct,
-					Quantity:    quantity,
-					Value:       value,
-					CostBasis:   costBasis,
-					PnL:         pnl,
-					PnLPct:      pnlPct,
-					LastUpdated: p.LastUpdated,
+					Symbol:        p.Symbol,
+					Currency:      providers.QuoteCurrency(p.Symbol),
+					Name:          p.Name,
+					Price:         p.Price,
+					DailyChange:   p.DailyChange,
+					DailyPct:      p.DailyPct,
+					Quantity:      quantity,
+					Value:         value,
+					CostBasis:     costBasis,
+					AvgPrice:      storage.AvgPrice(costBasis, quantity),
+					PnL:           pnl,
+					UnrealizedPnL: pnl,
+					PnLPct:        pnlPct,
+					LastUpdated:   p.LastUpdated,
+					Watched:       watched[p.Symbol],
+					Delisted:      p.Delisted,
 				})
 			}
 		} else {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error": "Failed to fetch crypto data",
-			})
+			respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Failed to fetch crypto data")
 			return
 		}
 	}
@@ -548,8 +1348,7 @@ func (h *Handler) GetCryptos(c *gin.Context) {
 
 // GetCrypto handles GET /api/crypto/:symbol
 func (h *Handler) GetCrypto(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	symbol := c.Param("symbol")
 
@@ -557,41 +1356,43 @@ func (h *Handler) GetCrypto(c *gin.Context) {
 		prices, err := h.cryptoProvider.FetchPrices(ctx, []string{symbol})
 		if err == nil && len(prices) > 0 {
 			p := prices[0]
-			holding, _ := h.storage.GetHoldingBySymbol(ctx, storage.HoldingTypeCrypto, symbol)
+			holding, err := h.storage.GetHoldingBySymbol(ctx, storage.HoldingTypeCrypto, symbol)
+			if err != nil && !errors.Is(err, storage.ErrHoldingNotFound) {
+				respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holding")
+				return
+			}
 			quantity := 0.0
 			costBasis := 0.0
 			if holding != nil {
+				h.newCostConverter().convert(ctx, holding)
 				quantity = holding.Quantity
 				costBasis = holding.CostBasis
 			}
 
-			value := p.Price * quantity
-			pnl := value - costBasis
-			pnlPct := 0.0
-			if costBasis > 0 {
-				pnlPct = (pnl / costBasis) * 100
-			}
+			value, pnl, pnlPct := positionPnL(p.Price, quantity, costBasis)
 
 			c.JSON(http.StatusOK, CryptoPrice{
-				Symbol:      p.Symbol,
-				Name:        p.Name,
-				Price:       p.Price,
-				DailyChange: p.DailyChange,
-				DailyPct:    p.DailyPct,
-				Quantity:    quantity,
-				Value:       value,
-				CostBasis:   costBasis,
-				PnL:         pnl,
-				PnLPct:      pnlPct,
-				LastUpdated: p.LastUpdated,
+				Symbol:        p.Symbol,
+				Currency:      providers.QuoteCurrency(p.Symbol),
+				Name:          p.Name,
+				Price:         p.Price,
+				DailyChange:   p.DailyChange,
+				DailyPct:      p.DailyPct,
+				Quantity:      quantity,
+				Value:         value,
+				CostBasis:     costBasis,
+				AvgPrice:      storage.AvgPrice(costBasis, quantity),
+				PnL:           pnl,
+				UnrealizedPnL: pnl,
+				PnLPct:        pnlPct,
+				LastUpdated:   p.LastUpdated,
+				Delisted:      p.Delisted,
 			})
 			return
 		}
 	}
 
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": "Crypto not found",
-	})
+	respondError(c, http.StatusNotFound, CodeSymbolNotFound, "Crypto not found")
 }
 
 // ==================== Holdings CRUD Handlers ====================
@@ -613,15 +1414,17 @@ func (h *Handler) GetHoldings(c *gin.Context) {
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch holdings",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"holdings": holdings,
-	})
+	// Optional tag filter
+	if tag := storage.NormalizeTag(c.Query("tag")); tag != "" {
+		holdings = slices.DeleteFunc(holdings, func(h storage.Holding) bool { return !slices.Contains(h.Tags, tag) })
+	}
+
+	body := gin.H{"holdings": holdings}
+	respondWithETag(c, body, body)
 }
 
 // GetHolding handles GET /api/holdings/:id
@@ -630,157 +1433,557 @@ func (h *Handler) GetHolding(c *gin.Context) {
 
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid holding ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid holding ID")
 		return
 	}
 
 	holding, err := h.storage.GetHoldingByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, storage.ErrHoldingNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Holding not found",
-			})
+			respondError(c, http.StatusNotFound, CodeHoldingNotFound, "Holding not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch holding",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holding")
 		return
 	}
 
 	c.JSON(http.StatusOK, holding)
 }
 
+// HoldingDetail is a stored holding valued at its live price. When the price
+// can't be fetched, Stale is set and the price-derived fields are zero.
+type HoldingDetail struct {
+	storage.Holding
+	Name        string    `json:"name"`
+	Price       float64   `json:"price"`
+	DailyChange float64   `json:"daily_change"`
+	DailyPct    float64   `json:"daily_pct"`
+	Value       float64   `json:"value"`
+	PnL         float64   `json:"pnl"`
+	PnLPct      float64   `json:"pnl_pct"`
+	LastUpdated time.Time `json:"last_updated"`
+	Stale       bool      `json:"stale"`
+	Delisted    bool      `json:"delisted,omitempty"`
+	PriceDate   string    `json:"price_date,omitempty"`
+
+	RealizedPnL   float64 `json:"realized_pnl"`   // Gains and losses of recorded sells; 0, as no transaction history is kept
+	UnrealizedPnL float64 `json:"unrealized_pnl"` // Value - remaining cost basis
+}
+
+// GetHoldingDetail handles GET /api/holdings/:id/detail
+func (h *Handler) GetHoldingDetail(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid holding ID")
+		return
+	}
+
+	holding, err := h.storage.GetHoldingByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrHoldingNotFound) {
+			respondError(c, http.StatusNotFound, CodeHoldingNotFound, "Holding not found")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holding")
+		return
+	}
+
+	h.newCostConverter().convert(ctx, holding)
+	detail := HoldingDetail{
+		Holding:     *holding,
+		Name:        holding.Symbol,
+		LastUpdated: time.Now(),
+		Stale:       true,
+	}
+	provider := h.cryptoProvider
+	if holding.Type == storage.HoldingTypeFund {
+		provider = h.tefasProvider
+		detail.Name = h.fundName(holding.Symbol)
+	}
+
+	if provider != nil {
+		result := fetchPrices(ctx, provider, []string{holding.Symbol})
+		for _, p := range result.prices {
+			if p.Symbol != holding.Symbol {
+				continue
+			}
+			if p.Name != "" {
+				detail.Name = p.Name
+			}
+			detail.Price = p.Price
+			detail.DailyChange = p.DailyChange
+			detail.DailyPct = p.DailyPct
+			detail.Value, detail.PnL, detail.PnLPct = positionPnL(p.Price, holding.Quantity, holding.CostBasis)
+			detail.UnrealizedPnL = detail.PnL
+			detail.LastUpdated = p.LastUpdated
+			detail.Stale = p.Stale
+			detail.PriceDate = p.PriceDate
+			detail.Delisted = p.Delisted
+		}
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
 // CreateHolding handles POST /api/holdings
+//
+// With an Idempotency-Key header, a retry of a request that created a
+// holding replays the original response instead of creating another. Only
+// the request that created it gets the response unmarked; reusing the key
+// for a different request is refused with 422.
 func (h *Handler) CreateHolding(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	var req storage.CreateHoldingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body: " + err.Error(),
-		})
+		respondInvalidBody(c, err)
+		return
+	}
+
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		status, body := h.createHolding(ctx, req)
+		c.JSON(status, body)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+		return
+	}
+
+	// Concurrent retries share one attempt; only the caller that ran it
+	// gets its response unmarked, the others replay it
+	hash := h.requestHash(req)
+	ran := false
+	v, _, _ := h.idempotencyGroup.Do(key, func() (any, error) {
+		ran = true
+		saved, err := h.storage.GetIdempotentResponse(ctx, key)
+		if err == nil {
+			return idempotentResult{IdempotentResponse: *saved, replayed: true}, nil
+		}
+		if !errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+			slog.Error("failed to read idempotency key", "error", err)
+			resp := jsonResponse(http.StatusInternalServerError, newError(CodeInternal, "Failed to create holding"))
+			resp.RequestHash = hash
+			return idempotentResult{IdempotentResponse: resp}, nil
+		}
+
+		resp := jsonResponse(h.createHolding(ctx, req))
+		resp.RequestHash = hash
+		if resp.Status == http.StatusCreated {
+			if err := h.storage.SaveIdempotentResponse(ctx, key, resp, h.idempotencyTTL()); err != nil {
+				slog.Warn("failed to save idempotency key", "error", err)
+			}
+		}
+		return idempotentResult{IdempotentResponse: resp}, nil
+	})
+	result := v.(idempotentResult)
+	// Keys recorded before request hashes were kept match any request
+	if result.RequestHash != "" && result.RequestHash != hash {
+		respondError(c, http.StatusUnprocessableEntity, CodeIdempotencyKeyUsed,
+			"Idempotency-Key was already used for a different request")
 		return
 	}
+	if result.replayed || !ran {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.Data(result.Status, "application/json; charset=utf-8", result.Body)
+}
+
+// requestHash identifies req for its Idempotency-Key. The type is inferred
+// and the symbol normalized as createHolding does, so a retry spelling the
+// same holding differently still matches.
+func (h *Handler) requestHash(req storage.CreateHoldingRequest) string {
+	if req.Type == "" {
+		req.Type = inferHoldingType(req.Symbol)
+	}
+	if req.Type == storage.HoldingTypeFund {
+		req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	}
+	if symbol, err := h.normalizeSymbol(req.Type, req.Symbol); err == nil {
+		req.Symbol = symbol
+	}
+	encoded, _ := json.Marshal(req) // Plain fields only
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header stored per request
+const maxIdempotencyKeyLength = 255
+
+// defaultIdempotencyTTL applies when the config doesn't set server.idempotency_ttl
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyTTL returns server.idempotency_ttl, or the default when unset
+func (h *Handler) idempotencyTTL() time.Duration {
+	ttl := h.cfg.Get().Server.IdempotencyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return ttl
+}
+
+// idempotentResult is a CreateHolding response and whether it was replayed
+type idempotentResult struct {
+	storage.IdempotentResponse
+	replayed bool
+}
+
+// jsonResponse encodes body as the JSON response to record for a key
+func jsonResponse(status int, body any) storage.IdempotentResponse {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return storage.IdempotentResponse{
+			Status: http.StatusInternalServerError,
+			Body:   []byte(`{"error":{"code":"INTERNAL_ERROR","message":"Failed to encode response"}}`),
+		}
+	}
+	return storage.IdempotentResponse{Status: status, Body: encoded}
+}
+
+// createHolding validates and stores req, returning the status and body to
+// respond with
+func (h *Handler) createHolding(ctx context.Context, req storage.CreateHoldingRequest) (int, any) {
+	inferred := req.Type == ""
+	if inferred {
+		req.Type = inferHoldingType(req.Symbol)
+		if req.Type == storage.HoldingTypeFund {
+			req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+		}
+	}
 
 	// Validate type
 	if req.Type != storage.HoldingTypeFund && req.Type != storage.HoldingTypeCrypto {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Type must be 'fund' or 'crypto'",
-		})
-		return
+		return http.StatusBadRequest, newError(CodeValidationFailed, "Type must be 'fund' or 'crypto'")
+	}
+	if req.AvgPrice != nil && req.CostBasis != 0 {
+		return http.StatusBadRequest, newError(CodeValidationFailed, "Provide either cost_basis or avg_price, not both")
+	}
+	if req.InferCostBasis != nil && *req.InferCostBasis && (req.AvgPrice != nil || req.CostBasis != 0) {
+		return http.StatusBadRequest, newError(CodeValidationFailed, "infer_cost_basis can't be combined with cost_basis or avg_price")
+	}
+	if req.FundType != "" && req.Type != storage.HoldingTypeFund {
+		return http.StatusBadRequest, newError(CodeValidationFailed, "fund_type only applies to fund holdings")
+	}
+	symbol, err := h.normalizeSymbol(req.Type, req.Symbol)
+	if err != nil {
+		return http.StatusBadRequest, newError(CodeValidationFailed, err.Error())
+	}
+	req.Symbol = symbol
+	req.CostCurrency = strings.ToUpper(req.CostCurrency)
+
+	if inferred {
+		if status, body, ok := h.checkInferredType(ctx, req.Type, req.Symbol); !ok {
+			return status, body
+		}
+	}
+
+	if h.wantsInferredCostBasis(req) {
+		if err := h.inferCostBasis(ctx, &req); err != nil {
+			return http.StatusServiceUnavailable, newError(CodeProviderUnavailable,
+				"No live price to infer cost_basis from; provide cost_basis or avg_price, or set infer_cost_basis to false")
+		}
 	}
 
 	holding, err := h.storage.CreateHolding(ctx, req)
 	if err != nil {
+		if errors.Is(err, storage.ErrHoldingInTrash) {
+			return http.StatusConflict, newError(CodeHoldingInTrash, "Holding for this symbol is in the trash; restore it or delete it permanently")
+		}
 		if errors.Is(err, storage.ErrHoldingExists) {
-			c.JSON(http.StatusConflict, gin.H{
-				"error": "Holding already exists for this symbol",
-			})
-			return
+			return http.StatusConflict, newError(CodeHoldingExists, "Holding already exists for this symbol")
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create holding",
-		})
-		return
+		if errors.Is(err, storage.ErrInvalidPosition) {
+			return http.StatusBadRequest, newError(CodeValidationFailed, err.Error())
+		}
+		return http.StatusInternalServerError, newError(CodeInternal, "Failed to create holding")
+	}
+
+	h.invalidateSummary()
+	return http.StatusCreated, CreatedHolding{Holding: holding, TypeInferred: inferred}
+}
+
+// CreatedHolding is the response to POST /api/holdings
+type CreatedHolding struct {
+	*storage.Holding
+	TypeInferred bool `json:"type_inferred,omitempty"` // Type was omitted and inferred from the symbol
+}
+
+// inferHoldingType guesses a holding's type from its symbol: a Binance pair
+// ("BTCUSDT", "eth-try") is crypto, anything else, such as a 2-4 character
+// TEFAS code, is a fund
+func inferHoldingType(symbol string) storage.HoldingType {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if strings.ContainsAny(symbol, "-/_: ") {
+		return storage.HoldingTypeCrypto
+	}
+	if len(symbol) > 4 {
+		if _, quote := providers.SplitPair(symbol); slices.Contains(providers.QuoteAssets, quote) {
+			return storage.HoldingTypeCrypto
+		}
+	}
+	return storage.HoldingTypeFund
+}
+
+// checkInferredType confirms that the provider for an inferred type knows
+// symbol, returning the error response to send when it doesn't
+func (h *Handler) checkInferredType(ctx context.Context, holdingType storage.HoldingType, symbol string) (int, any, bool) {
+	provider, kind := h.tefasProvider, "TEFAS fund"
+	if holdingType == storage.HoldingTypeCrypto {
+		provider, kind = h.cryptoProvider, "crypto pair"
+	}
+	if provider == nil {
+		return http.StatusBadRequest, newError(CodeValidationFailed,
+			fmt.Sprintf("Type inferred as %s, but that provider is disabled; set type explicitly", holdingType)), false
 	}
 
-	c.JSON(http.StatusCreated, holding)
+	prices, err := provider.FetchPrices(ctx, []string{symbol})
+	if err != nil {
+		slog.Warn("failed to check inferred holding type", "symbol", symbol, "type", holdingType, "error", err)
+		return http.StatusServiceUnavailable, newError(CodeProviderUnavailable,
+			fmt.Sprintf("Couldn't confirm %s is a %s; try again or set type explicitly", symbol, kind)), false
+	}
+	for _, p := range prices {
+		if p.Symbol == symbol && p.Price > 0 {
+			return 0, nil, true
+		}
+	}
+	return http.StatusBadRequest, newError(CodeValidationFailed,
+		fmt.Sprintf("%s is not a known %s; set type explicitly", symbol, kind)), false
 }
 
-// UpdateHolding handles PUT /api/holdings/:id
+// UpdateHolding handles PUT and PATCH /api/holdings/:id
 func (h *Handler) UpdateHolding(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid holding ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid holding ID")
 		return
 	}
 
 	var req storage.UpdateHoldingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body: " + err.Error(),
-		})
+		respondInvalidBody(c, err)
 		return
 	}
 
 	// Validate that at least one field is provided
-	if req.Quantity == nil && req.CostBasis == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "At least one field (quantity or cost_basis) must be provided",
-		})
+	if req.Type == nil && req.Symbol == nil && req.Quantity == nil && req.CostBasis == nil && req.TargetPct == nil && req.FundType == nil && req.CostCurrency == nil &&
+		req.Tags == nil && req.Notes == nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "At least one field (type, symbol, quantity, cost_basis, target_pct, fund_type, cost_currency, tags or notes) must be provided")
+		return
+	}
+	if req.CostCurrency != nil {
+		currency := strings.ToUpper(*req.CostCurrency)
+		req.CostCurrency = &currency
+	}
+
+	// Validate type and symbol when renaming
+	if req.Type != nil && *req.Type != storage.HoldingTypeFund && *req.Type != storage.HoldingTypeCrypto {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Type must be 'fund' or 'crypto'")
+		return
+	}
+	if req.Symbol != nil && *req.Symbol == "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Symbol must not be empty")
 		return
 	}
+	if req.Symbol != nil {
+		holdingType := req.Type
+		if holdingType == nil {
+			// A lookup failure is reported by UpdateHolding below
+			if existing, err := h.storage.GetHoldingByID(ctx, id); err == nil {
+				holdingType = &existing.Type
+			}
+		}
+		if holdingType != nil {
+			symbol, err := h.normalizeSymbol(*holdingType, *req.Symbol)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+				return
+			}
+			req.Symbol = &symbol
+		}
+	}
 
 	holding, err := h.storage.UpdateHolding(ctx, id, req)
 	if err != nil {
 		if errors.Is(err, storage.ErrHoldingNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Holding not found",
-			})
+			respondError(c, http.StatusNotFound, CodeHoldingNotFound, "Holding not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to update holding",
-		})
+		if errors.Is(err, storage.ErrHoldingInTrash) {
+			respondError(c, http.StatusConflict, CodeHoldingInTrash, "Holding for this symbol is in the trash; restore it or delete it permanently")
+			return
+		}
+		if errors.Is(err, storage.ErrHoldingExists) {
+			respondError(c, http.StatusConflict, CodeHoldingExists, "Holding already exists for this symbol")
+			return
+		}
+		if errors.Is(err, storage.ErrTypeChangeRequiresCostBasis) {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "Changing type requires cost_basis in the target currency")
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidPosition) {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to update holding")
 		return
 	}
 
+	h.invalidateSummary()
 	c.JSON(http.StatusOK, holding)
 }
 
+// maxBulkUpdates caps the items in one bulk update request
+const maxBulkUpdates = 500
+
+// BulkUpdateResponse reports the outcome of each item of a bulk update, in
+// request order
+type BulkUpdateResponse struct {
+	Updated  int                        `json:"updated"`
+	NotFound int                        `json:"not_found"`
+	Results  []storage.BulkUpdateResult `json:"results"`
+}
+
+// BulkUpdateHoldings handles PUT /api/holdings/bulk
+//
+// The body is a list of {id, quantity?, cost_basis?}, applied in one
+// transaction. Holdings that don't exist or are in the trash are reported as
+// not_found and the rest still apply; an invalid item rejects the whole
+// request and a storage failure rolls the whole batch back.
+func (h *Handler) BulkUpdateHoldings(c *gin.Context) {
+	var updates []storage.BulkUpdate
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		respondInvalidBody(c, err)
+		return
+	}
+	if len(updates) == 0 || len(updates) > maxBulkUpdates {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("Provide between 1 and %d updates", maxBulkUpdates))
+		return
+	}
+	for i, u := range updates {
+		if u.Quantity == nil && u.CostBasis == nil {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("Update %d (id %d) must set quantity or cost_basis", i, u.ID))
+			return
+		}
+	}
+
+	results, err := h.storage.BulkUpdateHoldings(c.Request.Context(), updates)
+	if errors.Is(err, storage.ErrInvalidPosition) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error()+"; no changes were applied")
+		return
+	}
+	if err != nil {
+		slog.Error("bulk holding update failed", "count", len(updates), "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to update holdings; no changes were applied")
+		return
+	}
+
+	resp := BulkUpdateResponse{Results: results}
+	for _, r := range results {
+		if r.Status == storage.BulkUpdated {
+			resp.Updated++
+		} else {
+			resp.NotFound++
+		}
+	}
+	if resp.Updated > 0 {
+		h.invalidateSummary()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// normalizeSymbol stores crypto symbols in canonical Binance form, so "btc"
+// and "BTC-USDT" both become "BTCUSDT" (with the default crypto.binance.quote)
+func (h *Handler) normalizeSymbol(holdingType storage.HoldingType, symbol string) (string, error) {
+	if holdingType != storage.HoldingTypeCrypto {
+		return symbol, nil
+	}
+	return binance.NormalizeSymbol(symbol, h.cfg.Get().Crypto.Binance.Quote)
+}
+
 // DeleteHolding handles DELETE /api/holdings/:id
+// Holdings are moved to the trash unless ?hard=true is given.
 func (h *Handler) DeleteHolding(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid holding ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid holding ID")
 		return
 	}
 
-	err = h.storage.DeleteHolding(ctx, id)
+	hard := c.Query("hard") == "true"
+	if hard {
+		err = h.storage.HardDeleteHolding(ctx, id)
+	} else {
+		err = h.storage.DeleteHolding(ctx, id)
+	}
 	if err != nil {
 		if errors.Is(err, storage.ErrHoldingNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Holding not found",
-			})
+			respondError(c, http.StatusNotFound, CodeHoldingNotFound, "Holding not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete holding",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to delete holding")
+		return
+	}
+
+	h.invalidateSummary()
+	message := "Holding moved to trash"
+	if hard {
+		message = "Holding deleted permanently"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+	})
+}
+
+// GetTrash handles GET /api/holdings/trash
+func (h *Handler) GetTrash(c *gin.Context) {
+	holdings, err := h.storage.GetDeletedHoldings(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch trash")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Holding deleted successfully",
+		"holdings": holdings,
 	})
 }
 
-// getFundDisplayName returns a human-readable name for a fund code
-func getFundDisplayName(code string) string {
-	names := map[string]string{
-		"KUT": "Kuveyt Türk Portföy Kısa Vadeli Kira Sertifikaları Katılım Fonu",
-		"TI2": "TEB Portföy İkinci Değişken Fon",
-		"AFT": "Ak Portföy Amerikan Doları Fon Sepeti Fonu",
-		"YZG": "Yapı Kredi Portföy Gümüş Fonu",
-		"KTV": "Kuveyt Türk Portföy Altın Katılım Fonu",
-		"HKH": "Halk Portföy Kısa Vadeli Borçlanma Araçları Fonu",
-		"IOG": "İş Portföy Orta Vadeli Borçlanma Araçları Fonu",
-		"KGM": "Kuveyt Türk Portföy Gümüş Katılım Fonu",
+// RestoreHolding handles POST /api/holdings/:id/restore
+func (h *Handler) RestoreHolding(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid holding ID")
+		return
+	}
+
+	holding, err := h.storage.RestoreHolding(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrHoldingNotFound) {
+			respondError(c, http.StatusNotFound, CodeHoldingNotFound, "Holding not found in trash")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to restore holding")
+		return
 	}
 
-	if name, ok := names[code]; ok {
-		return name
+	h.invalidateSummary()
+	c.JSON(http.StatusOK, holding)
+}
+
+// fundName returns the display name for a fund code, from the TEFAS
+// provider when it can name funds
+func (h *Handler) fundName(code string) string {
+	if namer, ok := h.tefasProvider.(providers.FundNamer); ok {
+		return namer.FundName(code)
 	}
 	return code + " Fund"
 }
@@ -795,44 +1998,93 @@ type ExchangeRateResponse struct {
 	LastUpdated time.Time `json:"last_updated"`
 }
 
-// GetExchangeRate handles GET /api/exchange-rate
+// GetExchangeRate handles GET /api/exchange-rate?from=&to=
+// Defaults to USD/TRY when the parameters are omitted.
 func (h *Handler) GetExchangeRate(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
-	// Check if crypto provider supports exchange rates
-	if h.cryptoProvider == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Exchange rate provider not available",
-		})
+	from := strings.ToUpper(c.DefaultQuery("from", "USD"))
+	to := strings.ToUpper(c.DefaultQuery("to", "TRY"))
+	if !isCurrencyCode(from) || !isCurrencyCode(to) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "from and to must be 3-letter currency codes")
 		return
 	}
 
-	// Try to get exchange rate from the provider
-	// The provider might be a FallbackProvider, so we need to check underlying providers
-	rate, lastUpdated, err := getExchangeRateFromProvider(ctx, h.cryptoProvider)
+	if h.fxProvider == nil && h.cryptoProvider == nil {
+		respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Exchange rate provider not available")
+		return
+	}
+
+	rate, lastUpdated, err := h.exchangeRate(ctx, from, to)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Failed to fetch exchange rate: " + err.Error(),
-		})
+		status, code := http.StatusServiceUnavailable, CodeProviderUnavailable
+		if errors.Is(err, providers.ErrUnsupportedCurrency) {
+			status, code = http.StatusBadRequest, CodeUnsupportedCurrency
+		}
+		respondError(c, status, code, "Failed to fetch exchange rate: "+err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, ExchangeRateResponse{
-		From:        "USD",
-		To:          "TRY",
+		From:        from,
+		To:          to,
 		Rate:        rate,
 		LastUpdated: lastUpdated,
 	})
 }
 
+// isCurrencyCode reports whether s looks like an ISO 4217 code
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// exchangeRate returns the from/to rate from the fiat FX provider if one is
+// configured, falling back to the crypto provider's stablecoin-based rate
+func (h *Handler) exchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
+	if h.fxProvider != nil {
+		rate, updated, err := h.fxProvider.FetchExchangeRate(ctx, from, to)
+		if err == nil || h.cryptoProvider == nil {
+			return rate, updated, err
+		}
+		slog.Warn("fiat exchange rate unavailable, falling back to crypto provider", "from", from, "to", to, "error", err)
+	}
+
+	// The provider might be a FallbackProvider, so we need to check underlying providers
+	return getExchangeRateFromProvider(ctx, h.cryptoProvider, from, to)
+}
+
 // getExchangeRateFromProvider attempts to get exchange rate from a provider
-func getExchangeRateFromProvider(ctx context.Context, p providers.Provider) (float64, time.Time, error) {
+func getExchangeRateFromProvider(ctx context.Context, p providers.Provider, from, to string) (float64, time.Time, error) {
 	// Check if provider implements ExchangeRateProvider
 	// This works for both direct providers (CoinGecko) and FallbackProvider
 	if erp, ok := p.(providers.ExchangeRateProvider); ok {
-		return erp.FetchExchangeRate(ctx)
+		return erp.FetchExchangeRate(ctx, from, to)
 	}
 
 	return 0, time.Time{}, errors.New("provider does not support exchange rates")
 }
+
+// fetchResult holds the outcome of one provider fetch in the summary
+type fetchResult struct {
+	prices []providers.Price
+	ok     bool
+}
+
+// fetchPrices fetches prices from provider, logging failures so callers can
+// fall back to stale placeholders
+func fetchPrices(ctx context.Context, provider providers.Provider, symbols []string) fetchResult {
+	prices, err := provider.FetchPrices(ctx, symbols)
+	if err != nil {
+		slog.Warn("failed to fetch prices", "provider", provider.Name(), "error", err)
+		return fetchResult{}
+	}
+	return fetchResult{prices: prices, ok: true}
+}
@@ -0,0 +1,885 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// rendezvousProvider blocks in FetchPrices until every provider sharing its
+// WaitGroup has been entered, so it only succeeds when fetches overlap
+type rendezvousProvider struct {
+	name    string
+	entered *sync.WaitGroup
+	price   float64
+}
+
+func (p *rendezvousProvider) Name() string { return p.name }
+
+func (p *rendezvousProvider) FetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
+	p.entered.Done()
+
+	all := make(chan struct{})
+	go func() {
+		p.entered.Wait()
+		close(all)
+	}()
+
+	select {
+	case <-all:
+	case <-time.After(2 * time.Second):
+		return nil, errors.New(p.name + ": other provider was never called concurrently")
+	}
+
+	prices := make([]providers.Price, 0, len(symbols))
+	for _, s := range symbols {
+		prices = append(prices, providers.Price{Symbol: s, Name: s, Price: p.price, LastUpdated: time.Now()})
+	}
+	return prices, nil
+}
+
+func (p *rendezvousProvider) IsHealthy(ctx context.Context) bool { return true }
+
+func (p *rendezvousProvider) Close() error { return nil }
+
+func TestHoldingsReadErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		store      *fakeStore
+		path       string
+		wantStatus int
+	}{
+		{"summary with failing storage", &fakeStore{err: errors.New("database is closed")}, "/api/portfolio/summary", http.StatusInternalServerError},
+		{"funds with failing storage", &fakeStore{err: errors.New("database is closed")}, "/api/funds", http.StatusInternalServerError},
+		{"cryptos with failing storage", &fakeStore{err: errors.New("database is closed")}, "/api/crypto", http.StatusInternalServerError},
+		{"summary with no holdings", newFakeStore(), "/api/portfolio/summary", http.StatusOK},
+		{"funds with no holdings", newFakeStore(), "/api/funds", http.StatusOK},
+		{"cryptos with no holdings", newFakeStore(), "/api/crypto", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(config.NewHolder(&config.Config{}), &weekdayHistory{}, &weekdayHistory{}, nil, tt.store)
+			r := gin.New()
+			r.GET("/api/portfolio/summary", h.GetPortfolioSummary)
+			r.GET("/api/funds", h.GetFunds)
+			r.GET("/api/crypto", h.GetCryptos)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestBuildPortfolioSummaryFetchesConcurrently(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2},
+	)
+	ctx := context.Background()
+
+	var entered sync.WaitGroup
+	entered.Add(2)
+	tefas := &rendezvousProvider{name: "tefas", entered: &entered, price: 3}
+	crypto := &rendezvousProvider{name: "crypto", entered: &entered, price: 5}
+
+	h := NewHandler(config.NewHolder(&config.Config{}), tefas, crypto, nil, store)
+	summary, err := h.buildPortfolioSummary(ctx)
+	if err != nil {
+		t.Fatalf("buildPortfolioSummary() error = %v", err)
+	}
+
+	if len(summary.Funds) != 1 || summary.Funds[0].Stale {
+		t.Fatalf("funds = %+v, want one fresh fund", summary.Funds)
+	}
+	if len(summary.Cryptos) != 1 || summary.Cryptos[0].Value != 10 {
+		t.Fatalf("cryptos = %+v, want one crypto worth 10", summary.Cryptos)
+	}
+	if got := summary.Funds[0].Value; got != 30 {
+		t.Errorf("fund value = %v, want 30", got)
+	}
+}
+
+// gatedProvider reports each FetchPrices call on started and holds it until
+// a value arrives on release
+type gatedProvider struct {
+	staticProvider
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *gatedProvider) FetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return p.staticProvider.FetchPrices(ctx, symbols)
+}
+
+func TestCachedPortfolioSummaryInvalidation(t *testing.T) {
+	store := newFakeStore(storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10})
+	cfg := &config.Config{}
+	cfg.Server.SummaryCacheTTL = time.Minute
+	cfg.Server.RequestTimeout = time.Minute
+	tefas := &gatedProvider{
+		staticProvider: staticProvider{prices: map[string]float64{"KUT": 3}},
+		started:        make(chan struct{}, 2),
+		release:        make(chan struct{}),
+	}
+	h := NewHandler(config.NewHolder(cfg), tefas, &staticProvider{}, nil, store)
+	ctx := context.Background()
+
+	summarize := func() <-chan PortfolioSummary {
+		done := make(chan PortfolioSummary, 1)
+		go func() {
+			summary, err := h.cachedPortfolioSummary(ctx)
+			if err != nil {
+				t.Errorf("cachedPortfolioSummary() error = %v", err)
+			}
+			done <- summary
+		}()
+		return done
+	}
+
+	// Holdings change while a summary is being built from the old ones
+	stale := summarize()
+	<-tefas.started
+	h.invalidateSummary()
+
+	// A request after the change doesn't wait on the stale build
+	fresh := summarize()
+	select {
+	case <-tefas.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("request after invalidation joined the build from before it")
+	}
+	tefas.release <- struct{}{}
+	tefas.release <- struct{}{}
+	<-stale
+	<-fresh
+
+	if _, _, ok := h.freshSummary(time.Minute); !ok {
+		t.Fatal("summary built after the invalidation wasn't cached")
+	}
+
+	// A build that finishes after an invalidation isn't cached
+	h.invalidateSummary()
+	stale = summarize()
+	<-tefas.started
+	h.invalidateSummary()
+	tefas.release <- struct{}{}
+	<-stale
+	if _, _, ok := h.freshSummary(time.Minute); ok {
+		t.Error("summary built before the invalidation was cached")
+	}
+}
+
+func TestHoldingTrash(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 1, CostBasis: 50000},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}), &staticProvider{}, &staticProvider{}, nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/holdings/trash", h.GetTrash)
+	r.POST("/api/holdings", h.CreateHolding)
+	r.DELETE("/api/holdings/:id", h.DeleteHolding)
+	r.POST("/api/holdings/:id/restore", h.RestoreHolding)
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   ErrorCode
+		wantTrash  []string
+	}{
+		{name: "move to trash", method: http.MethodDelete, path: "/api/holdings/1", wantStatus: http.StatusOK, wantTrash: []string{"KUT"}},
+		{name: "already in trash", method: http.MethodDelete, path: "/api/holdings/1", wantStatus: http.StatusNotFound, wantCode: CodeHoldingNotFound, wantTrash: []string{"KUT"}},
+		{name: "recreate trashed symbol", method: http.MethodPost, path: "/api/holdings", body: `{"type":"fund","symbol":"KUT","quantity":5,"cost_basis":10}`,
+			wantStatus: http.StatusConflict, wantCode: CodeHoldingInTrash, wantTrash: []string{"KUT"}},
+		{name: "restore", method: http.MethodPost, path: "/api/holdings/1/restore", wantStatus: http.StatusOK},
+		{name: "restore live holding", method: http.MethodPost, path: "/api/holdings/1/restore", wantStatus: http.StatusNotFound, wantCode: CodeHoldingNotFound},
+		{name: "hard delete from trash", method: http.MethodDelete, path: "/api/holdings/1", wantStatus: http.StatusOK, wantTrash: []string{"KUT"}},
+		{name: "hard delete", method: http.MethodDelete, path: "/api/holdings/1?hard=true", wantStatus: http.StatusOK},
+		{name: "hard delete live holding", method: http.MethodDelete, path: "/api/holdings/2?hard=true", wantStatus: http.StatusOK},
+		{name: "restore hard-deleted holding", method: http.MethodPost, path: "/api/holdings/1/restore", wantStatus: http.StatusNotFound, wantCode: CodeHoldingNotFound},
+		{name: "recreate hard-deleted symbol", method: http.MethodPost, path: "/api/holdings", body: `{"type":"fund","symbol":"KUT","quantity":5,"cost_basis":10}`,
+			wantStatus: http.StatusCreated},
+		{name: "invalid id", method: http.MethodDelete, path: "/api/holdings/abc", wantStatus: http.StatusBadRequest, wantCode: CodeValidationFailed},
+	}
+
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, w.Code, step.wantStatus, w.Body)
+		}
+		if step.wantCode != "" {
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error.Code != step.wantCode {
+				t.Errorf("%s: body = %s, want a %s error", step.name, w.Body, step.wantCode)
+			}
+		}
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/holdings/trash", nil))
+		var trash struct {
+			Holdings []storage.Holding `json:"holdings"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &trash); err != nil {
+			t.Fatalf("%s: decoding trash: %v", step.name, err)
+		}
+		var symbols []string
+		for _, holding := range trash.Holdings {
+			symbols = append(symbols, holding.Symbol)
+		}
+		if !slices.Equal(symbols, step.wantTrash) {
+			t.Errorf("%s: trash = %v, want %v", step.name, symbols, step.wantTrash)
+		}
+	}
+}
+
+func TestGetHoldingDetail(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5},
+	)
+	live := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 3}},
+		&staticProvider{prices: map[string]float64{"BTCUSDT": 5}},
+		nil, store)
+	down := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{err: errors.New("upstream down")},
+		&staticProvider{err: errors.New("upstream down")},
+		nil, store)
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		handler    *Handler
+		path       string
+		wantStatus int
+		wantValue  float64
+		wantPnL    float64
+		wantStale  bool
+	}{
+		{"fund at live price", live, "/api/holdings/1/detail", http.StatusOK, 30, 10, false},
+		{"crypto at live price", live, "/api/holdings/2/detail", http.StatusOK, 10, 5, false},
+		{"provider down", down, "/api/holdings/1/detail", http.StatusOK, 0, 0, true},
+		{"unknown holding", live, "/api/holdings/9/detail", http.StatusNotFound, 0, 0, false},
+		{"invalid id", live, "/api/holdings/abc/detail", http.StatusBadRequest, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/api/holdings/:id/detail", tt.handler.GetHoldingDetail)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var detail HoldingDetail
+			if err := json.Unmarshal(w.Body.Bytes(), &detail); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if detail.Value != tt.wantValue || detail.PnL != tt.wantPnL || detail.Stale != tt.wantStale {
+				t.Errorf("value/pnl/stale = %v/%v/%v, want %v/%v/%v",
+					detail.Value, detail.PnL, detail.Stale, tt.wantValue, tt.wantPnL, tt.wantStale)
+			}
+			if detail.Quantity == 0 {
+				t.Errorf("stored quantity missing from %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestGetPriceHolding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		path         string
+		storeErr     error
+		wantStatus   int
+		wantQuantity float64
+	}{
+		{"held fund", "/api/funds/KUT", nil, http.StatusOK, 10},
+		{"fund not held", "/api/funds/TI2", nil, http.StatusOK, 0},
+		{"fund with failing storage", "/api/funds/KUT", errors.New("database is locked"), http.StatusInternalServerError, 0},
+		{"held crypto", "/api/crypto/BTCUSDT", nil, http.StatusOK, 2},
+		{"crypto not held", "/api/crypto/ETHUSDT", nil, http.StatusOK, 0},
+		{"crypto with failing storage", "/api/crypto/BTCUSDT", errors.New("database is locked"), http.StatusInternalServerError, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5},
+			)
+			store.err = tt.storeErr
+			h := NewHandler(config.NewHolder(&config.Config{}),
+				&staticProvider{prices: map[string]float64{"KUT": 3, "TI2": 4}},
+				&staticProvider{prices: map[string]float64{"BTCUSDT": 5, "ETHUSDT": 6}},
+				nil, store)
+			r := gin.New()
+			r.GET("/api/funds/:code", h.GetFund)
+			r.GET("/api/crypto/:symbol", h.GetCrypto)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Quantity float64 `json:"quantity"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.Quantity != tt.wantQuantity {
+				t.Errorf("quantity = %v, want %v", resp.Quantity, tt.wantQuantity)
+			}
+		})
+	}
+}
+
+// staticFX serves fixed rates to TRY, failing for any other currency
+type staticFX map[string]float64
+
+func (fx staticFX) FetchExchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
+	rate, ok := fx[from]
+	if !ok || to != "TRY" {
+		return 0, time.Time{}, providers.ErrUnsupportedCurrency
+	}
+	return rate, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+func TestPortfolioSummaryCostCurrency(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCTRY", Quantity: 2, CostBasis: 0.125, CostCurrency: "USD"},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 400, CostCurrency: "TRY"},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 3}},
+		&staticProvider{prices: map[string]float64{"BTCTRY": 5, "BTCUSDT": 5}},
+		staticFX{"USD": 40}, store)
+
+	summary, err := h.buildPortfolioSummary(context.Background())
+	if err != nil {
+		t.Fatalf("buildPortfolioSummary() error = %v", err)
+	}
+
+	// KUT is already in TRY, BTCTRY's USD cost converts at 40, and BTCUSDT
+	// keeps its cost as entered since staticFX has no TRY/USD rate
+	wantCost := map[string]float64{"KUT": 20, "BTCTRY": 5, "BTCUSDT": 400}
+	wantPnL := map[string]float64{"KUT": 10, "BTCTRY": 5, "BTCUSDT": -390}
+	rows := map[string][2]float64{}
+	for _, f := range summary.Funds {
+		rows[f.Code] = [2]float64{f.CostBasis, f.PnL}
+	}
+	for _, cr := range summary.Cryptos {
+		rows[cr.Symbol] = [2]float64{cr.CostBasis, cr.PnL}
+	}
+	for symbol, cost := range wantCost {
+		if got := rows[symbol]; got[0] != cost || got[1] != wantPnL[symbol] {
+			t.Errorf("%s cost basis/P&L = %v/%v, want %v/%v", symbol, got[0], got[1], cost, wantPnL[symbol])
+		}
+	}
+
+	// The stored holding keeps what was entered
+	stored, _ := store.GetHoldingByID(context.Background(), 2)
+	if stored.CostBasis != 0.125 || stored.CostCurrency != "USD" {
+		t.Errorf("stored holding = %v %s, want 0.125 USD", stored.CostBasis, stored.CostCurrency)
+	}
+}
+
+func TestPortfolioSummaryPnLSplit(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 15},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 3}},
+		&staticProvider{prices: map[string]float64{"BTCUSDT": 5}},
+		nil, store)
+
+	summary, err := h.buildPortfolioSummary(context.Background())
+	if err != nil {
+		t.Fatalf("buildPortfolioSummary() error = %v", err)
+	}
+
+	// Without recorded sells nothing is realized
+	if summary.RealizedPnL != 0 || summary.UnrealizedPnL != 5 || summary.TotalPnL != 5 {
+		t.Errorf("realized/unrealized/total P&L = %v/%v/%v, want 0/5/5",
+			summary.RealizedPnL, summary.UnrealizedPnL, summary.TotalPnL)
+	}
+	for _, f := range summary.Funds {
+		if f.RealizedPnL != 0 || f.UnrealizedPnL != f.PnL {
+			t.Errorf("%s realized/unrealized P&L = %v/%v, want 0/%v", f.Code, f.RealizedPnL, f.UnrealizedPnL, f.PnL)
+		}
+	}
+	for _, cr := range summary.Cryptos {
+		if cr.RealizedPnL != 0 || cr.UnrealizedPnL != cr.PnL {
+			t.Errorf("%s realized/unrealized P&L = %v/%v, want 0/%v", cr.Symbol, cr.RealizedPnL, cr.UnrealizedPnL, cr.PnL)
+		}
+	}
+}
+
+func TestPortfolioSummaryShortPosition(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: -2, CostBasis: -15},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 3}},
+		&staticProvider{prices: map[string]float64{"BTCUSDT": 5}},
+		nil, store)
+
+	summary, err := h.buildPortfolioSummary(context.Background())
+	if err != nil {
+		t.Fatalf("buildPortfolioSummary() error = %v", err)
+	}
+
+	// Sold 2 at 7.5 and now worth 5 each: the short is 5 up
+	cr := summary.Cryptos[0]
+	if cr.Value != -10 || cr.PnL != 5 || cr.AvgPrice != 7.5 || math.Abs(cr.PnLPct-100.0/3) > 1e-9 {
+		t.Errorf("short value/pnl/avg/pct = %v/%v/%v/%v, want -10/5/7.5/33.33", cr.Value, cr.PnL, cr.AvgPrice, cr.PnLPct)
+	}
+	// The short doesn't offset the long's cost in the percentage: 15 of 35
+	if summary.TotalValue != 20 || summary.TotalPnL != 15 || math.Abs(summary.TotalPnLPct-300.0/7) > 1e-9 {
+		t.Errorf("total value/pnl/pct = %v/%v/%v, want 20/15/42.86", summary.TotalValue, summary.TotalPnL, summary.TotalPnLPct)
+	}
+}
+
+func TestPortfolioSummaryConversion(t *testing.T) {
+	tests := []struct {
+		name      string
+		fx        staticFX
+		holdings  []storage.CreateHoldingRequest
+		wantTRY   float64
+		wantUSD   float64
+		available bool
+	}{
+		{
+			name: "funds in TRY, crypto in USD",
+			fx:   staticFX{"USD": 40},
+			holdings: []storage.CreateHoldingRequest{
+				{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10},
+				{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2},
+			},
+			wantTRY:   430,
+			wantUSD:   10.75,
+			available: true,
+		},
+		{
+			name: "TRY pair needs no conversion",
+			fx:   staticFX{"USD": 40},
+			holdings: []storage.CreateHoldingRequest{
+				{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10},
+				{Type: storage.HoldingTypeCrypto, Symbol: "BTCTRY", Quantity: 2},
+			},
+			wantTRY:   40,
+			wantUSD:   1,
+			available: true,
+		},
+		{
+			name: "rate unavailable",
+			fx:   staticFX{},
+			holdings: []storage.CreateHoldingRequest{
+				{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10},
+			},
+		},
+		{
+			name: "quote currency without a rate",
+			fx:   staticFX{"USD": 40},
+			holdings: []storage.CreateHoldingRequest{
+				{Type: storage.HoldingTypeCrypto, Symbol: "ETHBTC", Quantity: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(config.NewHolder(&config.Config{}),
+				&staticProvider{prices: map[string]float64{"KUT": 3}},
+				&staticProvider{prices: map[string]float64{"BTCUSDT": 5, "BTCTRY": 5, "ETHBTC": 0.05}},
+				tt.fx, newFakeStore(tt.holdings...))
+			summary, err := h.buildPortfolioSummary(context.Background())
+			if err != nil {
+				t.Fatalf("buildPortfolioSummary() error = %v", err)
+			}
+
+			if summary.ConversionAvailable != tt.available {
+				t.Fatalf("conversion_available = %v, want %v", summary.ConversionAvailable, tt.available)
+			}
+			if !tt.available {
+				if summary.TotalValueTRY != nil || summary.TotalValueUSD != nil || summary.USDTRYRate != nil {
+					t.Errorf("converted totals set without a rate: %+v", summary)
+				}
+				return
+			}
+			if *summary.TotalValueTRY != tt.wantTRY || *summary.TotalValueUSD != tt.wantUSD {
+				t.Errorf("TRY/USD = %v/%v, want %v/%v", *summary.TotalValueTRY, *summary.TotalValueUSD, tt.wantTRY, tt.wantUSD)
+			}
+			if *summary.USDTRYRate != 40 || summary.RateUpdated.IsZero() {
+				t.Errorf("rate = %v at %v, want 40 with a timestamp", *summary.USDTRYRate, summary.RateUpdated)
+			}
+		})
+	}
+}
+
+func TestPortfolioSummaryDisplayCurrency(t *testing.T) {
+	// KUT is worth 30 TRY (cost 20), BTCUSDT 10 USD (cost 5) and BTCTRY 10
+	// TRY (cost 8), at 40 TRY to the dollar
+	holdings := []storage.CreateHoldingRequest{
+		{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5},
+		{Type: storage.HoldingTypeCrypto, Symbol: "BTCTRY", Quantity: 2, CostBasis: 8},
+	}
+
+	tests := []struct {
+		name                  string
+		fx                    staticFX
+		currencies            config.CurrencyConfig
+		display               string
+		wantTotal, wantCost   float64
+		wantTEFAS, wantCrypto float64
+		wantTEFASCurrency     string
+		wantRates             map[string]float64
+		wantConverted         bool
+	}{
+		{
+			name:              "defaults",
+			fx:                staticFX{"USD": 40},
+			wantTotal:         440,
+			wantCost:          228,
+			wantTEFAS:         30,
+			wantCrypto:        10.25,
+			wantTEFASCurrency: "TRY",
+			wantRates:         map[string]float64{"USD": 40},
+			wantConverted:     true,
+		},
+		{
+			name:              "everything in dollars",
+			fx:                staticFX{"USD": 40},
+			currencies:        config.CurrencyConfig{Fund: "USD", Crypto: "USD"},
+			display:           "USD",
+			wantTotal:         11,
+			wantCost:          5.7,
+			wantTEFAS:         0.75,
+			wantCrypto:        10.25,
+			wantTEFASCurrency: "USD",
+			wantRates:         map[string]float64{"TRY": 0.025},
+			wantConverted:     true,
+		},
+		{
+			name:              "rate unavailable",
+			fx:                staticFX{},
+			wantTotal:         50,
+			wantCost:          33,
+			wantTEFAS:         30,
+			wantCrypto:        10,
+			wantTEFASCurrency: "TRY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Currencies: tt.currencies, DisplayCurrency: tt.display}
+			h := NewHandler(config.NewHolder(cfg),
+				&staticProvider{prices: map[string]float64{"KUT": 3}},
+				&staticProvider{prices: map[string]float64{"BTCUSDT": 5, "BTCTRY": 5}},
+				tt.fx, newFakeStore(holdings...))
+			summary, err := h.buildPortfolioSummary(context.Background())
+			if err != nil {
+				t.Fatalf("buildPortfolioSummary() error = %v", err)
+			}
+
+			if summary.TotalValue != tt.wantTotal || summary.TotalCostBasis != tt.wantCost || summary.TotalsConverted != tt.wantConverted {
+				t.Errorf("total value/cost basis/converted = %v/%v/%v, want %v/%v/%v", summary.TotalValue, summary.TotalCostBasis,
+					summary.TotalsConverted, tt.wantTotal, tt.wantCost, tt.wantConverted)
+			}
+			if summary.TEFASValue != tt.wantTEFAS || summary.TEFASCurrency != tt.wantTEFASCurrency {
+				t.Errorf("TEFAS value = %v %s, want %v %s", summary.TEFASValue, summary.TEFASCurrency, tt.wantTEFAS, tt.wantTEFASCurrency)
+			}
+			if summary.CryptoValue != tt.wantCrypto {
+				t.Errorf("crypto value = %v, want %v", summary.CryptoValue, tt.wantCrypto)
+			}
+			if !reflect.DeepEqual(summary.ConversionRates, tt.wantRates) {
+				t.Errorf("conversion rates = %v, want %v", summary.ConversionRates, tt.wantRates)
+			}
+		})
+	}
+}
+
+func TestPortfolioSummaryDayChange(t *testing.T) {
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	daysAgo := func(n int) time.Time { return today.AddDate(0, 0, -n) }
+
+	tests := []struct {
+		name      string
+		snapshots []storage.Snapshot
+		wantFrom  string
+		wantDelta float64
+		wantPct   float64
+		noPct     bool
+	}{
+		{name: "no snapshots"},
+		{
+			name:      "yesterday",
+			snapshots: []storage.Snapshot{{Date: daysAgo(1), TotalValue: 24, Currency: "TRY"}},
+			wantFrom:  daysAgo(1).Format(time.DateOnly),
+			wantDelta: 6,
+			wantPct:   25,
+		},
+		{
+			name:      "latest before a weekend",
+			snapshots: []storage.Snapshot{{Date: daysAgo(5), TotalValue: 10, Currency: "TRY"}, {Date: daysAgo(3), TotalValue: 40, Currency: "TRY"}},
+			wantFrom:  daysAgo(3).Format(time.DateOnly),
+			wantDelta: -10,
+			wantPct:   -25,
+		},
+		{
+			name:      "skips snapshots in other units",
+			snapshots: []storage.Snapshot{{Date: daysAgo(3), TotalValue: 40, Currency: "TRY"}, {Date: daysAgo(2), TotalValue: 5, Currency: "USD"}, {Date: daysAgo(1), TotalValue: 1000}},
+			wantFrom:  daysAgo(3).Format(time.DateOnly),
+			wantDelta: -10,
+			wantPct:   -25,
+		},
+		{
+			name:      "today's snapshot is not a previous one",
+			snapshots: []storage.Snapshot{{Date: today, TotalValue: 30, Currency: "TRY"}},
+		},
+		{
+			name:      "older than a week",
+			snapshots: []storage.Snapshot{{Date: daysAgo(dayChangeLookbackDays + 1), TotalValue: 10, Currency: "TRY"}},
+		},
+		{
+			name:      "previous value zero has no percentage",
+			snapshots: []storage.Snapshot{{Date: daysAgo(1), Currency: "TRY"}},
+			wantFrom:  daysAgo(1).Format(time.DateOnly),
+			wantDelta: 30,
+			noPct:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10})
+			for _, snap := range tt.snapshots {
+				store.UpsertSnapshot(context.Background(), snap)
+			}
+			h := NewHandler(config.NewHolder(&config.Config{}),
+				&staticProvider{prices: map[string]float64{"KUT": 3}}, nil, nil, store)
+			summary, err := h.buildPortfolioSummary(context.Background())
+			if err != nil {
+				t.Fatalf("buildPortfolioSummary() error = %v", err)
+			}
+
+			if tt.wantFrom == "" {
+				if summary.DayChangeAvailable || summary.DayChange != nil || summary.DayChangePct != nil || summary.DayChangeFrom != nil {
+					t.Errorf("day change set without a previous snapshot: %+v", summary)
+				}
+				return
+			}
+			if !summary.DayChangeAvailable || *summary.DayChangeFrom != tt.wantFrom || *summary.DayChange != tt.wantDelta {
+				t.Fatalf("day change = %v from %v (available %v), want %v from %v",
+					summary.DayChange, summary.DayChangeFrom, summary.DayChangeAvailable, tt.wantDelta, tt.wantFrom)
+			}
+			switch {
+			case tt.noPct && summary.DayChangePct != nil:
+				t.Errorf("day change pct = %v, want null", *summary.DayChangePct)
+			case !tt.noPct && (summary.DayChangePct == nil || *summary.DayChangePct != tt.wantPct):
+				t.Errorf("day change pct = %v, want %v", summary.DayChangePct, tt.wantPct)
+			}
+		})
+	}
+}
+
+func TestPortfolioSummaryAllTimeHigh(t *testing.T) {
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	daysAgo := func(n int) time.Time { return today.AddDate(0, 0, -n) }
+
+	// The portfolio below is worth 30 now
+	tests := []struct {
+		name         string
+		snapshots    []storage.Snapshot
+		wantHigh     float64
+		wantDate     string
+		wantDrawdown float64
+	}{
+		{name: "no snapshots"},
+		{name: "one snapshot", snapshots: []storage.Snapshot{{Date: daysAgo(1), TotalValue: 40, Currency: "TRY"}}},
+		{
+			name:         "below the peak",
+			snapshots:    []storage.Snapshot{{Date: daysAgo(9), TotalValue: 20, Currency: "TRY"}, {Date: daysAgo(5), TotalValue: 40, Currency: "TRY"}, {Date: daysAgo(1), TotalValue: 35, Currency: "TRY"}},
+			wantHigh:     40,
+			wantDate:     daysAgo(5).Format(time.DateOnly),
+			wantDrawdown: 25,
+		},
+		{
+			name:         "ignores snapshots in other units",
+			snapshots:    []storage.Snapshot{{Date: daysAgo(9), TotalValue: 1000}, {Date: daysAgo(5), TotalValue: 40, Currency: "TRY"}, {Date: daysAgo(1), TotalValue: 35, Currency: "TRY"}},
+			wantHigh:     40,
+			wantDate:     daysAgo(5).Format(time.DateOnly),
+			wantDrawdown: 25,
+		},
+		{
+			name:      "too few in the same units",
+			snapshots: []storage.Snapshot{{Date: daysAgo(2), TotalValue: 20}, {Date: daysAgo(1), TotalValue: 25, Currency: "TRY"}},
+		},
+		{
+			name:      "above every snapshot",
+			snapshots: []storage.Snapshot{{Date: daysAgo(2), TotalValue: 20, Currency: "TRY"}, {Date: daysAgo(1), TotalValue: 25, Currency: "TRY"}},
+			wantHigh:  30,
+			wantDate:  today.Format(time.DateOnly),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10})
+			for _, snap := range tt.snapshots {
+				store.UpsertSnapshot(context.Background(), snap)
+			}
+			h := NewHandler(config.NewHolder(&config.Config{}),
+				&staticProvider{prices: map[string]float64{"KUT": 3}}, nil, nil, store)
+			summary, err := h.buildPortfolioSummary(context.Background())
+			if err != nil {
+				t.Fatalf("buildPortfolioSummary() error = %v", err)
+			}
+
+			if tt.wantDate == "" {
+				if summary.AllTimeHighAvailable || summary.AllTimeHigh != nil || summary.AllTimeHighDate != nil || summary.DrawdownFromATHPct != nil {
+					t.Errorf("all-time high set with too few snapshots: %+v", summary)
+				}
+				return
+			}
+			if !summary.AllTimeHighAvailable || *summary.AllTimeHigh != tt.wantHigh || *summary.AllTimeHighDate != tt.wantDate {
+				t.Fatalf("all-time high = %v on %v (available %v), want %v on %v",
+					summary.AllTimeHigh, summary.AllTimeHighDate, summary.AllTimeHighAvailable, tt.wantHigh, tt.wantDate)
+			}
+			if summary.DrawdownFromATHPct == nil || *summary.DrawdownFromATHPct != tt.wantDrawdown {
+				t.Errorf("drawdown = %v, want %v", summary.DrawdownFromATHPct, tt.wantDrawdown)
+			}
+		})
+	}
+}
+
+// flushingProvider counts FlushCache calls
+type flushingProvider struct {
+	staticProvider
+	flushes int
+}
+
+func (p *flushingProvider) FlushCache() { p.flushes++ }
+
+func TestFlushCaches(t *testing.T) {
+	tefas := &flushingProvider{}
+	crypto := &staticProvider{}
+	h := NewHandler(config.NewHolder(&config.Config{}), tefas, crypto, nil, newFakeStore())
+	h.summary = &PortfolioSummary{}
+	h.summaryAt = time.Now()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/admin/cache/flush", h.FlushCaches)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/cache/flush", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var resp CacheFlushResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(resp.Flushed) != 1 || resp.Flushed[0] != "static" {
+		t.Errorf("flushed = %v, want [static]", resp.Flushed)
+	}
+	if tefas.flushes != 1 {
+		t.Errorf("FlushCache called %d times, want 1", tefas.flushes)
+	}
+	if h.summary != nil {
+		t.Error("cached summary survived the flush")
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		storeErr   error
+		tefas      providers.Provider
+		wantStatus int
+		wantState  string
+	}{
+		{name: "ready", tefas: &staticProvider{}, wantStatus: http.StatusOK, wantState: "ready"},
+		{name: "storage unreachable", storeErr: errors.New("disk I/O error"), tefas: &staticProvider{}, wantStatus: http.StatusServiceUnavailable, wantState: "not_ready"},
+		{name: "no healthy provider", wantStatus: http.StatusServiceUnavailable, wantState: "not_ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			store.err = tt.storeErr
+			h := NewHandler(config.NewHolder(&config.Config{}), tt.tefas, nil, nil, store)
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.GET("/health/live", h.Liveness)
+			r.GET("/health/ready", h.Readiness)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("live status = %d, want 200", w.Code)
+			}
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ready status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			var resp ReadinessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.Status != tt.wantState {
+				t.Errorf("status = %q, want %q", resp.Status, tt.wantState)
+			}
+		})
+	}
+}
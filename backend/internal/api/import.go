@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Outcomes of importing one config holding
+const (
+	importCreated   = "created"
+	importUpdated   = "updated"
+	importUnchanged = "unchanged"
+	importInTrash   = "in_trash" // Left alone; restore or permanently delete it first
+	importInvalid   = "invalid"  // Skipped; see Problems
+)
+
+// ConfigImportResult is the outcome of importing one config holding
+type ConfigImportResult struct {
+	Type     storage.HoldingType `json:"type"`
+	Symbol   string              `json:"symbol"`
+	Status   string              `json:"status"`
+	ID       int64               `json:"id,omitempty"`       // Zero for holdings in the trash, invalid ones and dry-run creations
+	Problems []string            `json:"problems,omitempty"` // Why an invalid holding was skipped
+}
+
+// ConfigImportResponse summarizes an import, listing each config holding in
+// config order. A dry run reports what the import would do.
+type ConfigImportResponse struct {
+	DryRun    bool                 `json:"dry_run,omitempty"`
+	Created   int                  `json:"created"`
+	Updated   int                  `json:"updated"`
+	Unchanged int                  `json:"unchanged"`
+	InTrash   int                  `json:"in_trash"`
+	Invalid   int                  `json:"invalid"`
+	Results   []ConfigImportResult `json:"results"`
+}
+
+// ImportConfigHoldings handles POST /api/holdings/import/config?update=true&dry_run=true
+//
+// Creates the holdings listed in the current config that aren't in the
+// database yet. Existing ones are left unchanged unless update=true, which
+// sets their quantity, and their cost basis where the config gives one, to
+// the config values. Invalid holdings, including repeats of an earlier
+// one, are skipped. Items are applied one by one, so a storage failure
+// leaves the earlier ones imported. With dry_run=true nothing is written.
+func (h *Handler) ImportConfigHoldings(c *gin.Context) {
+	ctx := c.Request.Context()
+	update := c.Query("update") == "true"
+	dryRun := c.Query("dry_run") == "true"
+
+	reqs, err := h.configHoldings(h.cfg.Get())
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+
+	validations, err := h.storage.ValidateHoldings(ctx, reqs)
+	if err != nil {
+		slog.Error("failed to validate config holdings", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to validate holdings")
+		return
+	}
+
+	resp := ConfigImportResponse{DryRun: dryRun, Results: make([]ConfigImportResult, 0, len(reqs))}
+	for i, req := range reqs {
+		result, err := h.importHolding(ctx, req, validations[i], update, dryRun)
+		if err != nil {
+			slog.Error("config holding import failed", "type", req.Type, "symbol", req.Symbol, "error", err)
+			msg := fmt.Sprintf("Failed to import %s; %d earlier holdings were imported", req.Symbol, len(resp.Results))
+			if dryRun {
+				msg = fmt.Sprintf("Failed to check %s", req.Symbol)
+			}
+			respondError(c, http.StatusInternalServerError, CodeInternal, msg)
+			if len(resp.Results) > 0 && !dryRun {
+				h.invalidateSummary()
+			}
+			return
+		}
+
+		switch result.Status {
+		case importCreated:
+			resp.Created++
+		case importUpdated:
+			resp.Updated++
+		case importUnchanged:
+			resp.Unchanged++
+		case importInTrash:
+			resp.InTrash++
+		case importInvalid:
+			resp.Invalid++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	if resp.Created > 0 || resp.Updated > 0 {
+		h.invalidateSummary()
+	}
+	slog.Info("imported holdings from config", "created", resp.Created, "updated", resp.Updated, "unchanged", resp.Unchanged, "in_trash", resp.InTrash, "invalid", resp.Invalid)
+	c.JSON(http.StatusOK, resp)
+}
+
+// configHoldings returns the fund and crypto holdings listed in cfg, with
+// crypto symbols in canonical form like those created via the API
+func (h *Handler) configHoldings(cfg *config.Config) ([]storage.CreateHoldingRequest, error) {
+	var reqs []storage.CreateHoldingRequest
+	for _, fh := range cfg.TEFAS.Holdings {
+		reqs = append(reqs, storage.CreateHoldingRequest{
+			Type:      storage.HoldingTypeFund,
+			Symbol:    fh.Code,
+			Quantity:  fh.Quantity,
+			CostBasis: fh.CostBasis,
+			FundType:  fh.FundType,
+		})
+	}
+	for _, ch := range cfg.Crypto.Binance.Holdings {
+		symbol, err := h.normalizeSymbol(storage.HoldingTypeCrypto, ch.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("crypto.binance.holdings: %w", err)
+		}
+		reqs = append(reqs, storage.CreateHoldingRequest{
+			Type:      storage.HoldingTypeCrypto,
+			Symbol:    symbol,
+			Quantity:  ch.Quantity,
+			CostBasis: ch.CostBasis,
+		})
+	}
+	return reqs, nil
+}
+
+// importHolding creates req's holding, or brings an existing one in line
+// with it when update is set; v is req's validation. A dry run reports the
+// outcome without writing it.
+func (h *Handler) importHolding(ctx context.Context, req storage.CreateHoldingRequest, v storage.HoldingValidation, update, dryRun bool) (ConfigImportResult, error) {
+	result := ConfigImportResult{Type: req.Type, Symbol: req.Symbol}
+	switch v.Status {
+	case storage.ValidationInvalid:
+		result.Status, result.Problems = importInvalid, v.Problems
+		return result, nil
+	case storage.ValidationInTrash:
+		result.Status = importInTrash
+		return result, nil
+	case storage.ValidationNew:
+		if dryRun {
+			result.Status = importCreated
+			return result, nil
+		}
+		return h.createImported(ctx, req, update)
+	}
+	return h.syncImported(ctx, req, update, dryRun)
+}
+
+// createImported creates req's holding, falling back to syncing it when
+// it was created since validation
+func (h *Handler) createImported(ctx context.Context, req storage.CreateHoldingRequest, update bool) (ConfigImportResult, error) {
+	result := ConfigImportResult{Type: req.Type, Symbol: req.Symbol}
+
+	// Only a new holding gets an inferred cost; the update below compares
+	// against the config's own cost basis
+	toCreate := req
+	if h.wantsInferredCostBasis(toCreate) {
+		if err := h.inferCostBasis(ctx, &toCreate); err != nil {
+			slog.Warn("importing holding without a cost basis", "symbol", req.Symbol, "error", err)
+		}
+	}
+
+	created, err := h.storage.CreateHolding(ctx, toCreate)
+	switch {
+	case err == nil:
+		result.ID, result.Status = created.ID, importCreated
+		return result, nil
+	case errors.Is(err, storage.ErrHoldingInTrash):
+		result.Status = importInTrash
+		return result, nil
+	case !errors.Is(err, storage.ErrHoldingExists):
+		return result, err
+	}
+	return h.syncImported(ctx, req, update, false)
+}
+
+// syncImported compares req with the stored holding and, when update is
+// set, applies the differences
+func (h *Handler) syncImported(ctx context.Context, req storage.CreateHoldingRequest, update, dryRun bool) (ConfigImportResult, error) {
+	result := ConfigImportResult{Type: req.Type, Symbol: req.Symbol}
+	existing, err := h.storage.GetHoldingBySymbol(ctx, req.Type, req.Symbol)
+	if err != nil {
+		return result, err
+	}
+	result.ID, result.Status = existing.ID, importUnchanged
+
+	// A config without cost_basis keeps whatever cost basis was entered since
+	changes := storage.UpdateHoldingRequest{}
+	if existing.Quantity != req.Quantity {
+		changes.Quantity = &req.Quantity
+	}
+	if req.CostBasis != 0 && existing.CostBasis != req.CostBasis {
+		changes.CostBasis = &req.CostBasis
+	}
+	if !update || (changes.Quantity == nil && changes.CostBasis == nil) {
+		return result, nil
+	}
+	if dryRun {
+		result.Status = importUpdated
+		return result, nil
+	}
+
+	if _, err := h.storage.UpdateHolding(ctx, existing.ID, changes); err != nil {
+		return result, err
+	}
+	result.Status = importUpdated
+	return result, nil
+}
+
+// InferMissingCostBases gives holdings that have a quantity but no cost
+// basis one at the current price, so they start at break-even. It runs once
+// after config holdings are imported into an empty database with
+// infer_cost_basis set; holdings without a live price keep a zero cost.
+func (h *Handler) InferMissingCostBases(ctx context.Context) {
+	holdings, err := h.storage.GetAllHoldings(ctx)
+	if err != nil {
+		slog.Error("failed to load holdings to infer cost bases", "error", err)
+		return
+	}
+
+	var updates []storage.BulkUpdate
+	for _, holding := range holdings {
+		if holding.CostBasis != 0 || holding.Quantity == 0 {
+			continue
+		}
+		req := storage.CreateHoldingRequest{Type: holding.Type, Symbol: holding.Symbol, Quantity: holding.Quantity}
+		if err := h.inferCostBasis(ctx, &req); err != nil {
+			slog.Warn("cost basis not inferred", "symbol", holding.Symbol, "error", err)
+			continue
+		}
+		updates = append(updates, storage.BulkUpdate{ID: holding.ID, CostBasis: &req.CostBasis})
+	}
+	if len(updates) == 0 {
+		return
+	}
+
+	if _, err := h.storage.BulkUpdateHoldings(ctx, updates); err != nil {
+		slog.Error("failed to store inferred cost bases", "error", err)
+		return
+	}
+	h.invalidateSummary()
+	slog.Info("inferred cost bases at current prices", "count", len(updates))
+}
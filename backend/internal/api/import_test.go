@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestImportConfigHoldings(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.TEFAS.Holdings = []config.FundHolding{
+		{Code: "KUT", Quantity: 100, CostBasis: 1200}, // Quantity changed since first run
+		{Code: "TI2", Quantity: 50, CostBasis: 500},   // Same as stored
+		{Code: "AFT", Quantity: 10},                   // In the trash
+		{Code: "IPB", Quantity: 5, CostBasis: 60},     // Added to config later
+	}
+	cfg.Crypto.Binance.Holdings = []config.CryptoHolding{{Symbol: "btc", Quantity: 0.5}}
+
+	tests := []struct {
+		name         string
+		query        string
+		wantStatuses []string
+		wantKUT      float64
+	}{
+		{
+			name:         "create only",
+			wantStatuses: []string{"unchanged", "unchanged", "in_trash", "created", "created"},
+			wantKUT:      80,
+		},
+		{
+			name:         "update existing",
+			query:        "?update=true",
+			wantStatuses: []string{"updated", "unchanged", "in_trash", "created", "created"},
+			wantKUT:      100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 80, CostBasis: 1200},
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "TI2", Quantity: 50, CostBasis: 500},
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "AFT", Quantity: 10},
+			)
+			store.DeleteHolding(context.Background(), 3)
+			h := NewHandler(config.NewHolder(cfg), nil, nil, nil, store)
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/holdings/import/config", h.ImportConfigHoldings)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/holdings/import/config"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+
+			var resp ConfigImportResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if len(resp.Results) != len(tt.wantStatuses) {
+				t.Fatalf("results = %+v, want statuses %v", resp.Results, tt.wantStatuses)
+			}
+			for i, want := range tt.wantStatuses {
+				if resp.Results[i].Status != want {
+					t.Errorf("results[%d] (%s) status = %q, want %q", i, resp.Results[i].Symbol, resp.Results[i].Status, want)
+				}
+			}
+			if resp.Created != 2 || resp.InTrash != 1 || resp.Updated+resp.Unchanged != 2 {
+				t.Errorf("counts = %+v, want 2 created, 1 in trash, 2 updated or unchanged", resp)
+			}
+			if got := resp.Results[4].Symbol; got != "BTCUSDT" {
+				t.Errorf("crypto symbol = %q, want canonical BTCUSDT", got)
+			}
+
+			kut, _ := store.GetHoldingByID(context.Background(), 1)
+			if kut.Quantity != tt.wantKUT {
+				t.Errorf("KUT quantity = %v, want %v", kut.Quantity, tt.wantKUT)
+			}
+		})
+	}
+}
+
+func TestImportConfigHoldingsDryRun(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.TEFAS.Holdings = []config.FundHolding{
+		{Code: "KUT", Quantity: 100, CostBasis: 1200},
+		{Code: "IPB", Quantity: 5},
+		{Code: "KUT", Quantity: 1}, // Listed twice
+	}
+	store := newFakeStore(storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 80, CostBasis: 1200})
+	h := NewHandler(config.NewHolder(cfg), nil, nil, nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/holdings/import/config", h.ImportConfigHoldings)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/holdings/import/config?update=true&dry_run=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	var resp ConfigImportResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	wantStatuses := []string{"updated", "created", "invalid"}
+	if !resp.DryRun || len(resp.Results) != len(wantStatuses) {
+		t.Fatalf("response = %+v, want a dry run with statuses %v", resp, wantStatuses)
+	}
+	for i, want := range wantStatuses {
+		if resp.Results[i].Status != want {
+			t.Errorf("results[%d] (%s) status = %q, want %q", i, resp.Results[i].Symbol, resp.Results[i].Status, want)
+		}
+	}
+	if len(resp.Results[2].Problems) == 0 {
+		t.Error("duplicate holding reported without problems")
+	}
+
+	holdings, _ := store.GetAllHoldings(context.Background())
+	if len(holdings) != 1 || holdings[0].Quantity != 80 {
+		t.Errorf("holdings after a dry run = %+v, want KUT untouched at 80", holdings)
+	}
+}
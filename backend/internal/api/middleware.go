@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyAuth requires a matching "Authorization: Bearer <key>" header on every
+// request except health checks. An empty key disables authentication.
+func APIKeyAuth(apiKey string) gin.HandlerFunc {
+	if apiKey == "" {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		if isHealthCheck(c) {
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Invalid or missing API key")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAPIKey refuses every request with 403 unless an API key is
+// configured, for routes too destructive to leave open when APIKeyAuth lets
+// everything through. With a key, APIKeyAuth has already checked it.
+func RequireAPIKey(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			respondError(c, http.StatusForbidden, CodeForbidden, "Admin endpoints are disabled until server.api_key is set")
+			return
+		}
+		c.Next()
+	}
+}
+
+// isHealthCheck reports whether the request targets a health endpoint
+func isHealthCheck(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, "/api/health")
+}
+
+// BodyLimit caps request bodies at limit bytes, or at the limit routeLimits
+// gives the matched route (e.g. "/api/holdings/bulk"). A body declared
+// larger is refused with 413 up front; one that only turns out larger fails
+// to read, which handlers report as 413 through respondInvalidBody.
+func BodyLimit(limit int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bodyLimit := limit
+		if routeLimit, ok := routeLimits[c.FullPath()]; ok {
+			bodyLimit = routeLimit
+		}
+		if c.Request.ContentLength > bodyLimit {
+			respondBodyTooLarge(c, bodyLimit)
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, bodyLimit)
+		}
+		c.Next()
+	}
+}
+
+// RequestTimeout bounds each request's context by timeout(), read per
+// request so server.request_timeout hot-reloads. Handlers pass that context
+// down and carve any sub-timeouts out of it. A request that runs out of time
+// gets 503 REQUEST_TIMEOUT: respondError rewrites 5xx errors raised past the
+// deadline, and a handler that wrote nothing is answered here.
+func RequestTimeout(timeout func() time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout())
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			respondTimeout(c)
+		}
+	}
+}
+
+// RequestLogger logs each request through logger, replacing gin's text
+// access log when log lines must share one structured format
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		logger.Info("request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency", time.Since(start),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAuthTestRouter(apiKey string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(APIKeyAuth(apiKey))
+	api.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.GET("/holdings", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKey     string
+		path       string
+		authHeader string
+		wantStatus int
+	}{
+		{"unconfigured allows without header", "", "/api/holdings", "", http.StatusOK},
+		{"unconfigured ignores header", "", "/api/holdings", "Bearer whatever", http.StatusOK},
+		{"configured rejects missing header", "secret", "/api/holdings", "", http.StatusUnauthorized},
+		{"configured rejects wrong key", "secret", "/api/holdings", "Bearer nope", http.StatusUnauthorized},
+		{"configured rejects non-bearer scheme", "secret", "/api/holdings", "Basic secret", http.StatusUnauthorized},
+		{"configured accepts correct key", "secret", "/api/holdings", "Bearer secret", http.StatusOK},
+		{"configured exempts health", "secret", "/api/health", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newAuthTestRouter(tt.apiKey)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	r := gin.New()
+	r.Use(RequestLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	r.GET("/api/holdings", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/holdings", nil))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log line %q is not JSON: %v", buf.String(), err)
+	}
+	if entry["method"] != "GET" || entry["path"] != "/api/holdings" || entry["status"] != float64(http.StatusTeapot) {
+		t.Errorf("log entry = %v, want GET /api/holdings with status %d", entry, http.StatusTeapot)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(BodyLimit(32, map[string]int64{"/api/holdings/bulk": 64}))
+	bind := func(c *gin.Context) {
+		var body map[string]any
+		if err := c.ShouldBindJSON(&body); err != nil {
+			respondInvalidBody(c, err)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	api.POST("/holdings", bind)
+	api.PUT("/holdings/bulk", bind)
+
+	body := func(n int) string { return `{"symbol":"` + strings.Repeat("x", n) + `"}` }
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{"within limit", http.MethodPost, "/api/holdings", body(10), false, http.StatusOK},
+		{"declared too large", http.MethodPost, "/api/holdings", body(40), false, http.StatusRequestEntityTooLarge},
+		{"read too large", http.MethodPost, "/api/holdings", body(40), true, http.StatusRequestEntityTooLarge},
+		{"route limit", http.MethodPut, "/api/holdings/bulk", body(40), false, http.StatusOK},
+		{"over route limit", http.MethodPut, "/api/holdings/bulk", body(80), true, http.StatusRequestEntityTooLarge},
+		{"malformed", http.MethodPost, "/api/holdings", `{"symbol":`, false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1 // Length unknown until the body is read
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), string(CodeBodyTooLarge)) {
+				t.Errorf("body = %s, want code %s", w.Body, CodeBodyTooLarge)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestTimeout(func() time.Duration { return 20 * time.Millisecond }))
+	wait := func(c *gin.Context) { <-c.Request.Context().Done() }
+	r.GET("/fast", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/silent", wait)
+	r.GET("/failed", func(c *gin.Context) {
+		wait(c)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+	})
+	r.GET("/not-found", func(c *gin.Context) {
+		wait(c)
+		respondError(c, http.StatusNotFound, CodeHoldingNotFound, "Holding not found")
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantCode   ErrorCode
+	}{
+		{"/fast", http.StatusNoContent, ""},
+		{"/silent", http.StatusServiceUnavailable, CodeTimeout},
+		{"/failed", http.StatusServiceUnavailable, CodeTimeout},
+		{"/not-found", http.StatusNotFound, CodeHoldingNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCode != "" && !strings.Contains(w.Body.String(), string(tt.wantCode)) {
+				t.Errorf("body = %s, want code %s", w.Body, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/shopspring/decimal"
+)
+
+// moneyPlaces is the precision position values are rounded to
+const moneyPlaces = 2
+
+// positionPnL values a position and returns its value, P&L and P&L
+// percentage. The math is done in decimal and the value is rounded to the
+// cent, so per-holding values add up exactly to the summary totals.
+//
+// A short position has a negative quantity and cost basis, so its value is
+// what buying it back would cost, negated, and its P&L is positive when the
+// price has fallen. The percentage is taken of the cost basis' magnitude so
+// it has the sign of the P&L either way.
+func positionPnL(price, quantity, costBasis float64) (value, pnl, pnlPct float64) {
+	v := decimal.NewFromFloat(price).Mul(decimal.NewFromFloat(quantity)).Round(moneyPlaces)
+	cost := decimal.NewFromFloat(costBasis)
+	p := v.Sub(cost)
+	return v.InexactFloat64(), p.InexactFloat64(), percentOf(p, cost.Abs())
+}
+
+// moneyTotal sums money amounts without float64 rounding drift
+type moneyTotal struct {
+	sum decimal.Decimal
+}
+
+// Add adds amount to the total
+func (t *moneyTotal) Add(amount float64) {
+	t.sum = t.sum.Add(decimal.NewFromFloat(amount))
+}
+
+// Float64 returns the total for JSON responses
+func (t moneyTotal) Float64() float64 {
+	return t.sum.InexactFloat64()
+}
+
+// percentOf returns part as a percentage of whole, or 0 if whole isn't positive
+func percentOf(part, whole decimal.Decimal) float64 {
+	if !whole.IsPositive() {
+		return 0
+	}
+	return part.Div(whole).Mul(decimal.NewFromInt(100)).InexactFloat64()
+}
+
+// priceCurrency returns the currency a holding is priced in: TRY for TEFAS
+// funds, the pair's quote currency for crypto
+func priceCurrency(holding storage.Holding) string {
+	if holding.Type == storage.HoldingTypeFund {
+		return "TRY"
+	}
+	return providers.QuoteCurrency(holding.Symbol)
+}
+
+// errNoPriceToInfer is returned by inferCostBasis when there is no live
+// price to value a holding at
+var errNoPriceToInfer = errors.New("no live price to infer the cost basis from")
+
+// wantsInferredCostBasis reports whether req gives no cost and should start
+// at break-even: it asks to, or leaves it to infer_cost_basis
+func (h *Handler) wantsInferredCostBasis(req storage.CreateHoldingRequest) bool {
+	if req.CostBasis != 0 || req.AvgPrice != nil || req.Quantity == 0 {
+		return false
+	}
+	if req.InferCostBasis != nil {
+		return *req.InferCostBasis
+	}
+	return h.cfg.Get().InferCostBasis
+}
+
+// inferCostBasis sets req's cost basis to its quantity at the current price,
+// in the currency the holding is priced in, so it starts at break-even
+// instead of showing its whole value as profit
+func (h *Handler) inferCostBasis(ctx context.Context, req *storage.CreateHoldingRequest) error {
+	provider := h.cryptoProvider
+	if req.Type == storage.HoldingTypeFund {
+		provider = h.tefasProvider
+	}
+	if provider == nil {
+		return fmt.Errorf("%w for %s", errNoPriceToInfer, req.Symbol)
+	}
+
+	for _, p := range fetchPrices(ctx, provider, []string{req.Symbol}).prices {
+		if p.Symbol == req.Symbol && p.Price > 0 && !p.Delisted {
+			req.CostBasis = decimal.NewFromFloat(p.Price).Mul(decimal.NewFromFloat(req.Quantity)).Round(moneyPlaces).InexactFloat64()
+			req.CostCurrency = ""
+			return nil
+		}
+	}
+	return fmt.Errorf("%w for %s", errNoPriceToInfer, req.Symbol)
+}
+
+// costConverter restates cost bases paid in another currency in the
+// currency each holding is priced in, so P&L compares like with like.
+// Each rate is fetched once per converter.
+type costConverter struct {
+	h     *Handler
+	rates map[string]float64 // Keyed by "FROM/TO"; 0 if unavailable
+}
+
+// newCostConverter returns a converter using the handler's exchange rates
+func (h *Handler) newCostConverter() *costConverter {
+	return &costConverter{h: h, rates: make(map[string]float64)}
+}
+
+// convertAll converts the cost basis of every holding in place
+func (cc *costConverter) convertAll(ctx context.Context, holdings []storage.Holding) {
+	for i := range holdings {
+		cc.convert(ctx, &holdings[i])
+	}
+}
+
+// convert restates holding's cost basis in its price currency and clears
+// its cost currency. Without a rate the cost basis is left as entered.
+func (cc *costConverter) convert(ctx context.Context, holding *storage.Holding) {
+	from, to := holding.CostCurrency, priceCurrency(*holding)
+	if from == "" || from == to {
+		holding.CostCurrency = ""
+		return
+	}
+
+	pair := from + "/" + to
+	rate, ok := cc.rates[pair]
+	if !ok {
+		var err error
+		if rate, _, err = cc.h.exchangeRate(ctx, from, to); err != nil {
+			slog.Warn("exchange rate unavailable, cost basis left unconverted", "pair", pair, "error", err)
+			rate = 0
+		}
+		cc.rates[pair] = rate
+	}
+	if rate <= 0 {
+		return
+	}
+
+	holding.CostBasis = convertCost(holding.CostBasis, rate)
+	holding.AvgPrice = storage.AvgPrice(holding.CostBasis, holding.Quantity)
+	holding.CostCurrency = ""
+}
+
+// convertCost restates a cost basis at an exchange rate
+func convertCost(costBasis, rate float64) float64 {
+	return decimal.NewFromFloat(costBasis).Mul(decimal.NewFromFloat(rate)).InexactFloat64()
+}
@@ -0,0 +1,45 @@
+package api
+
+import "testing"
+
+func TestPositionPnL(t *testing.T) {
+	tests := []struct {
+		name                  string
+		price, qty, costBasis float64
+		wantValue, wantPnL    float64
+		wantPct               float64
+	}{
+		{"rounds value to the cent", 1.23456, 3, 3, 3.70, 0.70, 23.33333333333333},
+		{"float64 drift is avoided", 0.1, 3, 0.2, 0.3, 0.1, 50},
+		{"zero cost basis", 10, 2, 0, 20, 20, 0},
+		{"short gains as the price falls", 8, -2, -20, -16, 4, 20},
+		{"short loses as the price rises", 12, -2, -20, -24, -4, -20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, pnl, pct := positionPnL(tt.price, tt.qty, tt.costBasis)
+			if value != tt.wantValue {
+				t.Errorf("value = %v, want %v", value, tt.wantValue)
+			}
+			if pnl != tt.wantPnL {
+				t.Errorf("pnl = %v, want %v", pnl, tt.wantPnL)
+			}
+			if pct != tt.wantPct {
+				t.Errorf("pnl pct = %v, want %v", pct, tt.wantPct)
+			}
+		})
+	}
+}
+
+func TestMoneyTotalReconciles(t *testing.T) {
+	// Summing 0.1 a thousand times in float64 gives 99.9999999999986
+	var total moneyTotal
+	for i := 0; i < 1000; i++ {
+		total.Add(0.1)
+	}
+
+	if got := total.Float64(); got != 100 {
+		t.Errorf("total = %v, want 100", got)
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/notify"
+)
+
+// RunDailySummary sends a portfolio summary through n every day at the given
+// "HH:MM" local time until ctx is cancelled. Delivery failures are logged and
+// never stop the loop.
+func (h *Handler) RunDailySummary(ctx context.Context, n notify.Notifier, at string) {
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		slog.Error("invalid daily summary time, not scheduling", "time", at, "error", err)
+		return
+	}
+
+	slog.Info("scheduled daily portfolio summary", "time", at)
+
+	for {
+		wait := time.Until(nextDailyRun(time.Now(), clock.Hour(), clock.Minute()))
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		summary, err := h.buildPortfolioSummary(sendCtx)
+		if err != nil {
+			slog.Warn("skipping daily summary", "error", err)
+		} else if err := n.Send(sendCtx, formatDailySummary(summary)); err != nil {
+			slog.Warn("failed to send daily summary", "error", err)
+		} else {
+			slog.Info("sent daily portfolio summary")
+		}
+		cancel()
+	}
+}
+
+// nextDailyRun returns the next time at hour:minute strictly after now
+func nextDailyRun(now time.Time, hour, minute int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// formatDailySummary renders a portfolio summary as a short text message
+func formatDailySummary(s PortfolioSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Prism daily summary (%s)\n", s.LastUpdated.Format("2006-01-02"))
+	total := fmt.Sprintf("%.2f", s.TotalValue)
+	if s.TotalsConverted {
+		total += " " + s.DisplayCurrency
+	}
+	fmt.Fprintf(&b, "Total: %s (P&L %+.2f / %+.2f%%)\n", total, s.TotalPnL, s.TotalPnLPct)
+	fmt.Fprintf(&b, "TEFAS: %.2f %s (P&L %+.2f)\n", s.TEFASValue, s.TEFASCurrency, s.TEFASPnL)
+	fmt.Fprintf(&b, "Crypto: %.2f %s (P&L %+.2f)", s.CryptoValue, s.CryptoCurrency, s.CryptoPnL)
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextDailyRun(t *testing.T) {
+	istanbul := time.FixedZone("TRT", 3*60*60)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"later today", time.Date(2024, 3, 13, 8, 0, 0, 0, istanbul), time.Date(2024, 3, 13, 9, 30, 0, 0, istanbul)},
+		{"already passed today", time.Date(2024, 3, 13, 10, 0, 0, 0, istanbul), time.Date(2024, 3, 14, 9, 30, 0, 0, istanbul)},
+		{"exactly now", time.Date(2024, 3, 13, 9, 30, 0, 0, istanbul), time.Date(2024, 3, 14, 9, 30, 0, 0, istanbul)},
+		{"end of month", time.Date(2024, 2, 29, 23, 0, 0, 0, istanbul), time.Date(2024, 3, 1, 9, 30, 0, 0, istanbul)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDailyRun(tt.now, 9, 30); !got.Equal(tt.want) {
+				t.Errorf("nextDailyRun(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDailySummary(t *testing.T) {
+	summary := PortfolioSummary{
+		TotalValue: 430, TotalPnL: 210, TotalPnLPct: 95.45,
+		TEFASValue: 30, TEFASPnL: 10, TEFASCurrency: "TRY",
+		CryptoValue: 10, CryptoPnL: 5, CryptoCurrency: "USD",
+		DisplayCurrency: "TRY", TotalsConverted: true,
+		LastUpdated: time.Date(2024, 3, 13, 9, 30, 0, 0, time.UTC),
+	}
+
+	want := "Prism daily summary (2024-03-13)\n" +
+		"Total: 430.00 TRY (P&L +210.00 / +95.45%)\n" +
+		"TEFAS: 30.00 TRY (P&L +10.00)\n" +
+		"Crypto: 10.00 USD (P&L +5.00)"
+	if got := formatDailySummary(summary); got != want {
+		t.Errorf("formatDailySummary() = %q, want %q", got, want)
+	}
+
+	// Unconverted totals add currencies, so they are shown without one
+	summary.TotalsConverted = false
+	summary.TotalValue, summary.TotalPnL, summary.TotalPnLPct = 40, -5, -11.11
+	if got := formatDailySummary(summary); !strings.Contains(got, "Total: 40.00 (P&L -5.00 / -11.11%)\n") {
+		t.Errorf("formatDailySummary() = %q, want the unconverted total without a currency", got)
+	}
+}
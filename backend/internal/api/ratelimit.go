@@ -0,0 +1,115 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// rateLimitCleanupInterval is how often idle buckets are swept
+	rateLimitCleanupInterval = time.Minute
+	// rateLimitIdleTTL is how long a bucket may sit unused before removal
+	rateLimitIdleTTL = 10 * time.Minute
+)
+
+// tokenBucket tracks the remaining request allowance for one client
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter is an in-memory token-bucket limiter keyed by client IP
+type rateLimiter struct {
+	mu          sync.Mutex
+	buckets     map[string]*tokenBucket
+	rate        float64 // tokens refilled per second
+	burst       float64 // bucket capacity
+	lastCleanup time.Time
+}
+
+// newRateLimiter creates a limiter allowing requestsPerMinute per client
+func newRateLimiter(requestsPerMinute int) *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		rate:    float64(requestsPerMinute) / 60,
+		burst:   float64(requestsPerMinute),
+	}
+}
+
+// allow consumes a token for key, returning how long to wait when none is
+// left. Idle buckets are swept on the way every rateLimitCleanupInterval, so
+// memory doesn't grow unbounded without a goroutine to stop.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastCleanup) >= rateLimitCleanupInterval {
+		l.cleanupLocked(now)
+		l.lastCleanup = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	// Refill based on elapsed time
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanupLocked removes buckets that have been idle longer than
+// rateLimitIdleTTL. l.mu must be held.
+func (l *rateLimiter) cleanupLocked(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > rateLimitIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit limits each client IP to requestsPerMinute requests.
+// Health checks are exempt. A non-positive limit disables rate limiting.
+// The client IP comes from X-Forwarded-For only behind the engine's
+// trusted proxies.
+func RateLimit(requestsPerMinute int) gin.HandlerFunc {
+	if requestsPerMinute <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	limiter := newRateLimiter(requestsPerMinute)
+
+	return func(c *gin.Context) {
+		if isHealthCheck(c) {
+			c.Next()
+			return
+		}
+
+		allowed, wait := limiter.allow(c.ClientIP(), time.Now())
+		if !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			respondError(c, http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded")
+			return
+		}
+
+		c.Next()
+	}
+}
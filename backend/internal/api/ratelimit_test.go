@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := newRateLimiter(60) // One token a second, bursts of 60
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := range 60 {
+		if ok, _ := l.allow("1.2.3.4", start); !ok {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+	ok, wait := l.allow("1.2.3.4", start)
+	if ok || wait != time.Second {
+		t.Fatalf("request past the burst = %v, wait %v; want refused, wait 1s", ok, wait)
+	}
+	if ok, _ := l.allow("5.6.7.8", start); !ok {
+		t.Error("another client shares the exhausted bucket")
+	}
+
+	if ok, _ := l.allow("1.2.3.4", start.Add(500*time.Millisecond)); ok {
+		t.Error("allowed after half a token refilled")
+	}
+	if ok, _ := l.allow("1.2.3.4", start.Add(time.Second)); !ok {
+		t.Error("refused after a token refilled")
+	}
+	// Refills stop at the burst size
+	for i := range 60 {
+		if ok, _ := l.allow("1.2.3.4", start.Add(time.Hour)); !ok {
+			t.Fatalf("request %d after an hour idle refused", i+1)
+		}
+	}
+	if ok, _ := l.allow("1.2.3.4", start.Add(time.Hour)); ok {
+		t.Error("idle time refilled past the burst size")
+	}
+}
+
+func TestRateLimiterSweepsIdleBuckets(t *testing.T) {
+	l := newRateLimiter(60)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	l.allow("1.2.3.4", start)
+	l.allow("5.6.7.8", start.Add(rateLimitIdleTTL))
+
+	l.allow("5.6.7.8", start.Add(rateLimitIdleTTL+rateLimitCleanupInterval))
+	if _, ok := l.buckets["1.2.3.4"]; ok {
+		t.Error("idle bucket kept past rateLimitIdleTTL")
+	}
+	if _, ok := l.buckets["5.6.7.8"]; !ok {
+		t.Error("active bucket swept")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.SetTrustedProxies(nil)
+	api := r.Group("/api")
+	api.Use(RateLimit(2))
+	api.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.GET("/holdings", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(path, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := range 2 {
+		if w := get("/api/holdings", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i+1, w.Code)
+		}
+	}
+
+	w := get("/api/holdings", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request past the limit: status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error.Code != CodeRateLimited {
+		t.Errorf("body = %s, want a %s error", w.Body, CodeRateLimited)
+	}
+
+	// An untrusted peer can't pose as another client
+	if w := get("/api/holdings", "203.0.113.7"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("request with a spoofed X-Forwarded-For: status %d, want 429", w.Code)
+	}
+	if w := get("/api/health", ""); w.Code != http.StatusOK {
+		t.Errorf("health check: status %d, want 200", w.Code)
+	}
+}
+
+func TestRouterClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		wantStatus     int
+	}{
+		{"untrusted peer can't rotate X-Forwarded-For", nil, http.StatusTooManyRequests},
+		{"trusted proxy forwards the client IP", []string{"10.0.0.0/8"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Server.RateLimit = 1
+			cfg.Server.TrustedProxies = tt.trustedProxies
+			cfg.Server.MaxBodyBytes = 1 << 20
+			cfg.Server.RequestTimeout = time.Minute
+			r := NewRouter(&RouterConfig{Config: config.NewHolder(cfg), Storage: newFakeStore()})
+
+			var w *httptest.ResponseRecorder
+			for _, client := range []string{"203.0.113.7", "203.0.113.8"} {
+				req := httptest.NewRequest(http.MethodGet, "/api/holdings", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				req.Header.Set("X-Forwarded-For", client)
+				w = httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("second client's status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Return calculation methods reported in ReturnsResponse
+const (
+	returnMethodSimple = "simple"
+)
+
+// ReturnsResponse represents portfolio-level returns
+type ReturnsResponse struct {
+	TotalValue     float64   `json:"total_value"`
+	TotalCostBasis float64   `json:"total_cost_basis"`
+	TotalReturn    float64   `json:"total_return"`     // total_value - total_cost_basis
+	TotalReturnPct float64   `json:"total_return_pct"` // Simple return, not annualized
+	XIRR           *float64  `json:"xirr"`             // Annualized money-weighted return; null when it can't be computed
+	Method         string    `json:"method"`
+	Note           string    `json:"note,omitempty"`
+	LastUpdated    time.Time `json:"last_updated"`
+}
+
+// GetReturns handles GET /api/portfolio/returns
+func (h *Handler) GetReturns(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	summary, err := h.cachedPortfolioSummary(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+
+	c.JSON(http.StatusOK, buildReturns(summary))
+}
+
+// buildReturns computes returns from the portfolio summary. Holdings only
+// record a lump-sum cost basis with no purchase dates, so there are no dated
+// cash flows to annualize and XIRR falls back to the simple return.
+func buildReturns(summary PortfolioSummary) ReturnsResponse {
+	return ReturnsResponse{
+		TotalValue:     summary.TotalValue,
+		TotalCostBasis: summary.TotalCostBasis,
+		TotalReturn:    summary.TotalPnL,
+		TotalReturnPct: summary.TotalPnLPct,
+		Method:         returnMethodSimple,
+		Note:           "No transaction history is recorded, so the money-weighted return (XIRR) can't be computed; showing simple total return",
+		LastUpdated:    summary.LastUpdated,
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetReturns(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 3}},
+		&staticProvider{prices: map[string]float64{"BTCUSDT": 5}},
+		staticFX{"USD": 40}, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/portfolio/summary", h.GetPortfolioSummary)
+	r.GET("/api/portfolio/returns", h.GetReturns)
+
+	get := func(path string, v any) []byte {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want 200: %s", path, w.Code, w.Body)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("decoding %s: %v", path, err)
+		}
+		return w.Body.Bytes()
+	}
+
+	var summary PortfolioSummary
+	get("/api/portfolio/summary", &summary)
+	var returns ReturnsResponse
+	body := get("/api/portfolio/returns", &returns)
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatalf("decoding returns: %v", err)
+	}
+	if xirr, ok := raw["xirr"]; !ok || string(xirr) != "null" {
+		t.Errorf("xirr = %s, want null", xirr)
+	}
+	if returns.Method != returnMethodSimple {
+		t.Errorf("method = %q, want %q", returns.Method, returnMethodSimple)
+	}
+	if !strings.Contains(returns.Note, "XIRR") {
+		t.Errorf("note = %q, want it to explain the XIRR fallback", returns.Note)
+	}
+
+	// 30 TRY of KUT and 10 USD of BTC at 40 TRY, with 20 TRY and 5 USD paid
+	if summary.TotalValue != 430 || summary.TotalCostBasis != 220 {
+		t.Fatalf("summary totals = %v/%v, want 430/220", summary.TotalValue, summary.TotalCostBasis)
+	}
+	if returns.TotalValue != summary.TotalValue || returns.TotalCostBasis != summary.TotalCostBasis ||
+		returns.TotalReturn != summary.TotalPnL || returns.TotalReturnPct != summary.TotalPnLPct {
+		t.Errorf("returns totals = %v/%v/%v/%v, want the summary's %v/%v/%v/%v",
+			returns.TotalValue, returns.TotalCostBasis, returns.TotalReturn, returns.TotalReturnPct,
+			summary.TotalValue, summary.TotalCostBasis, summary.TotalPnL, summary.TotalPnLPct)
+	}
+}
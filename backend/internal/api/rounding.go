@@ -0,0 +1,335 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// pctPlaces is the precision percentages are rounded to in responses
+const pctPlaces = 2
+
+// Response fields by how RoundNumbers rounds them. Fields in exactFields,
+// such as quantities and exchange rates, keep full precision; every other
+// number field of a response belongs in one of these sets.
+var (
+	priceFields = fieldSet("price", "avg_price", "target_avg_price", "daily_change", "open", "high", "low", "close")
+	moneyFields = fieldSet("value", "cost_basis", "entered_cost_basis", "pnl", "total_value", "total_cost_basis",
+		"total_pnl", "tefas_value", "tefas_cost_basis", "tefas_pnl", "crypto_value", "crypto_cost_basis",
+		"crypto_pnl", "total_value_try", "total_value_usd", "total_return", "total_net_deposits", "net_deposits",
+		"rebalance_amount", "portfolio_size", "day_change", "buy_cost", "all_time_high", "realized_pnl",
+		"unrealized_pnl")
+	pctFields = fieldSet("pct", "return_7d", "return_30d", "avg_daily_return_7d", "avg_daily_return_30d",
+		"volatility", "max_drawdown", "xirr")
+	exactFields = fieldSet("quantity", "buy_quantity", "delta_quantity", "rate", "conversion_rate",
+		"conversion_rates", "usd_try_rate", "volume", "shares_outstanding", "cache_age_seconds")
+)
+
+// assetListFields maps the fields holding lists of one asset type to it, for
+// rows that don't carry a type of their own
+var assetListFields = map[string]string{"funds": "fund", "cryptos": "crypto", "candles": "crypto"}
+
+// fieldSet builds a set of JSON field names
+func fieldSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// RoundNumbers rounds the numbers in JSON responses for display when
+// server.rounding is enabled: prices to the configured decimals for their
+// asset type, money amounts to the cent and percentages to pctPlaces.
+// Stored data keeps full precision, and ?raw=true bypasses rounding.
+func RoundNumbers(cfg *config.Holder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rounding := cfg.Get().Server.Rounding
+		if raw, _ := strconv.ParseBool(c.Query("raw")); raw || !rounding.Enabled {
+			c.Next()
+			return
+		}
+
+		w := &roundingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+			w.finish(newRounder(rounding, routeAssetType(c.FullPath())))
+		}()
+		c.Next()
+	}
+}
+
+// routeAssetType returns the asset type every price on a route is of, or ""
+// when the route mixes them
+func routeAssetType(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/funds"):
+		return "fund"
+	case strings.HasPrefix(path, "/api/crypto"):
+		return "crypto"
+	}
+	return ""
+}
+
+// roundingWriter holds back JSON bodies so they can be rounded once the
+// handler is done. Other responses, and any that get flushed, pass through.
+type roundingWriter struct {
+	gin.ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	passthrough bool
+}
+
+func (w *roundingWriter) WriteHeader(status int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *roundingWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *roundingWriter) Write(p []byte) (int, error) {
+	if !w.passthrough && !isJSON(w.Header().Get("Content-Type")) {
+		w.pass()
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *roundingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *roundingWriter) Status() int {
+	if w.status != 0 && !w.passthrough {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *roundingWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	return w.buf.Len()
+}
+
+func (w *roundingWriter) Written() bool {
+	return w.status != 0 || w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *roundingWriter) Flush() {
+	w.pass()
+	w.ResponseWriter.Flush()
+}
+
+// pass sends what is held back and lets later writes straight through
+func (w *roundingWriter) pass() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// finish writes the held-back body, rounded when it parses as JSON
+func (w *roundingWriter) finish(r rounder) {
+	if w.passthrough {
+		return
+	}
+	if w.buf.Len() > 0 {
+		if rounded, err := r.round(w.buf.Bytes()); err == nil {
+			w.buf.Reset()
+			w.buf.Write(rounded)
+			// The ETag was computed from the unrounded data, so it only
+			// still holds as a weak validator
+			if etag := w.Header().Get("ETag"); strings.HasPrefix(etag, `"`) {
+				w.Header().Set("ETag", "W/"+etag)
+			}
+		}
+		w.Header().Del("Content-Length")
+	}
+	w.pass()
+}
+
+// isJSON reports whether a Content-Type is JSON
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// rounder rewrites the numbers of a JSON document by field name
+type rounder struct {
+	fundPlaces   int32 // Negative leaves fund prices as they are
+	cryptoPlaces int32 // Negative leaves crypto prices as they are
+	assetType    string
+}
+
+// newRounder builds the rounder for a route whose prices are all of
+// assetType, or of mixed types when it is ""
+func newRounder(cfg config.RoundingConfig, assetType string) rounder {
+	return rounder{
+		fundPlaces:   int32(cfg.FundPriceDecimals),
+		cryptoPlaces: int32(cfg.CryptoPriceDecimals),
+		assetType:    assetType,
+	}
+}
+
+// jsonFrame is an object or array being rewritten
+type jsonFrame struct {
+	object    bool
+	wantKey   bool   // Objects: the next token is a key
+	key       string // Objects: the key of the value being read
+	entries   int
+	assetType string // "fund" or "crypto" when known
+}
+
+// round rewrites doc with its numbers rounded, keeping field order. The
+// asset type of a price comes from the "type" field of its object when that
+// precedes it, else from the list holding the object, else from the route.
+func (r rounder) round(doc []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var out bytes.Buffer
+	var stack []*jsonFrame
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			out.WriteByte(byte(delim))
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				stack[len(stack)-1].valueDone()
+			}
+			continue
+		}
+
+		if top != nil && top.object && top.wantKey {
+			if top.entries > 0 {
+				out.WriteByte(',')
+			}
+			writeJSON(&out, tok)
+			out.WriteByte(':')
+			top.key, top.wantKey = tok.(string), false
+			continue
+		}
+		if top != nil && !top.object && top.entries > 0 {
+			out.WriteByte(',')
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteByte(byte(v))
+			frame := &jsonFrame{object: v == '{', wantKey: v == '{', assetType: r.assetType}
+			if top != nil {
+				frame.assetType = top.assetType
+				if t, ok := assetListFields[top.key]; ok && top.object {
+					frame.assetType = t
+				}
+			}
+			stack = append(stack, frame)
+			continue
+		case json.Number:
+			out.WriteString(r.roundNumber(v, top))
+		case string:
+			if top != nil && top.object && top.key == "type" && (v == "fund" || v == "crypto") {
+				top.assetType = v
+			}
+			writeJSON(&out, v)
+		default:
+			writeJSON(&out, v)
+		}
+		if top != nil {
+			top.valueDone()
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// valueDone records that a value of the frame has been written
+func (f *jsonFrame) valueDone() {
+	f.entries++
+	f.wantKey = f.object
+}
+
+// roundNumber returns n rounded for the field it is the value of
+func (r rounder) roundNumber(n json.Number, frame *jsonFrame) string {
+	if frame == nil || !frame.object {
+		return n.String()
+	}
+
+	places := int32(-1)
+	switch key := frame.key; {
+	case priceFields[key]:
+		places = r.pricePlaces(frame.assetType)
+	case moneyFields[key]:
+		places = moneyPlaces
+	case pctFields[key] || strings.HasSuffix(key, "_pct"):
+		places = pctPlaces
+	}
+	if places < 0 {
+		return n.String()
+	}
+
+	d, err := decimal.NewFromString(n.String())
+	if err != nil {
+		return n.String()
+	}
+	return d.Round(places).String()
+}
+
+// pricePlaces returns the decimals prices of assetType are rounded to; with
+// the type unknown, the more precise setting applies
+func (r rounder) pricePlaces(assetType string) int32 {
+	switch assetType {
+	case "fund":
+		return r.fundPlaces
+	case "crypto":
+		return r.cryptoPlaces
+	}
+	if r.fundPlaces < 0 || r.cryptoPlaces < 0 {
+		return -1
+	}
+	return max(r.fundPlaces, r.cryptoPlaces)
+}
+
+// writeJSON writes v as JSON, the way gin renders it
+func writeJSON(out *bytes.Buffer, v any) {
+	encoded, _ := json.Marshal(v) // Tokens are strings, bools or nil
+	out.Write(encoded)
+}
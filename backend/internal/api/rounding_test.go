@@ -0,0 +1,186 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoundNumbers(t *testing.T) {
+	rounding := config.RoundingConfig{Enabled: true, FundPriceDecimals: 4, CryptoPriceDecimals: 6}
+
+	tests := []struct {
+		name     string
+		rounding config.RoundingConfig
+		path     string
+		body     string
+		want     string
+	}{
+		{
+			name:     "money, percentages and quantities",
+			rounding: rounding,
+			path:     "/api/portfolio/summary",
+			body:     `{"total_value":1234.5678,"total_pnl_pct":12.3456,"xirr":8.7654,"quantity":0.123456789,"usd_try_rate":32.123456}`,
+			want:     `{"total_value":1234.57,"total_pnl_pct":12.35,"xirr":8.77,"quantity":0.123456789,"usd_try_rate":32.123456}`,
+		},
+		{
+			name:     "prices by list",
+			rounding: rounding,
+			path:     "/api/portfolio/summary",
+			body:     `{"funds":[{"price":1.123456789}],"cryptos":[{"price":1.123456789,"tags":["a"]}]}`,
+			want:     `{"funds":[{"price":1.1235}],"cryptos":[{"price":1.123457,"tags":["a"]}]}`,
+		},
+		{
+			name:     "prices by type field",
+			rounding: rounding,
+			path:     "/api/holdings",
+			body:     `[{"type":"fund","avg_price":2.123456789},{"type":"crypto","avg_price":2.123456789},{"avg_price":2.123456789}]`,
+			want:     `[{"type":"fund","avg_price":2.1235},{"type":"crypto","avg_price":2.123457},{"avg_price":2.123457}]`,
+		},
+		{
+			name:     "prices by route",
+			rounding: rounding,
+			path:     "/api/funds/KUT",
+			body:     `{"code":"KUT","price":3.123456789,"note":null,"stale":false}`,
+			want:     `{"code":"KUT","price":3.1235,"note":null,"stale":false}`,
+		},
+		{
+			name:     "negative decimals leave prices alone",
+			rounding: config.RoundingConfig{Enabled: true, FundPriceDecimals: -1, CryptoPriceDecimals: 6},
+			path:     "/api/funds/KUT",
+			body:     `{"price":3.123456789,"value":10.005}`,
+			want:     `{"price":3.123456789,"value":10.01}`,
+		},
+		{
+			name:     "raw",
+			rounding: rounding,
+			path:     "/api/portfolio/summary?raw=true",
+			body:     `{"total_value":1234.5678}`,
+			want:     `{"total_value":1234.5678}`,
+		},
+		{
+			name:     "disabled",
+			rounding: config.RoundingConfig{FundPriceDecimals: 4, CryptoPriceDecimals: 6},
+			path:     "/api/portfolio/summary",
+			body:     `{"total_value":1234.5678}`,
+			want:     `{"total_value":1234.5678}`,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewHolder(&config.Config{Server: config.ServerConfig{Rounding: tt.rounding}})
+			r := gin.New()
+			r.Use(RoundNumbers(cfg))
+			handler := func(c *gin.Context) { c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(tt.body)) }
+			r.GET("/api/portfolio/summary", handler)
+			r.GET("/api/holdings", handler)
+			r.GET("/api/funds/:code", handler)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200", w.Code)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundNumbersETag(t *testing.T) {
+	cfg := config.NewHolder(&config.Config{Server: config.ServerConfig{Rounding: config.RoundingConfig{Enabled: true}}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RoundNumbers(cfg))
+	body := gin.H{"total_value": 1.234}
+	r.GET("/api/portfolio/summary", func(c *gin.Context) { respondWithETag(c, body, body) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/portfolio/summary", nil))
+	etag := w.Header().Get("ETag")
+	if w.Body.String() != `{"total_value":1.23}` || len(etag) < 2 || etag[:2] != "W/" {
+		t.Fatalf("got %s with ETag %s, want the rounded body with a weak ETag", w.Body.String(), etag)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/portfolio/summary", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("revalidation status = %d, want 304", w.Code)
+	}
+}
+
+// TestRoundingCoversResponseFields fails when a number field of a response
+// is in none of the field sets, so new fields can't go out unrounded by
+// accident
+func TestRoundingCoversResponseFields(t *testing.T) {
+	responses := []any{
+		PortfolioSummary{}, HoldingDetail{}, FundPrice{}, CryptoPrice{}, CurrencyTotals{},
+		ProviderHealth{}, ExchangeRateResponse{}, FundDetailsResponse{}, FundNAVResponse{},
+		AllocationResponse{}, BreakdownResponse{}, CashflowResponse{}, PnLExplanation{},
+		ReturnsResponse{}, Adjustment{}, HoldingStats{}, SuggestionsResponse{},
+		storage.Holding{}, storage.Snapshot{}, providers.Price{}, providers.Candle{},
+	}
+
+	seen := make(map[reflect.Type]bool)
+	for _, resp := range responses {
+		numberFields(reflect.TypeOf(resp), seen, func(owner reflect.Type, name string) {
+			classified := priceFields[name] || moneyFields[name] || pctFields[name] ||
+				strings.HasSuffix(name, "_pct") || exactFields[name]
+			if !classified {
+				t.Errorf("%s field %q is in none of the rounding field sets", owner, name)
+			}
+		})
+	}
+}
+
+// numberFields calls fn with the JSON name of each float field reachable
+// from typ, including lists and maps of floats
+func numberFields(typ reflect.Type, seen map[reflect.Type]bool, fn func(owner reflect.Type, name string)) {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || seen[typ] {
+		return
+	}
+	seen[typ] = true
+
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			continue // Its fields are promoted and visited on their own
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		elem := field.Type
+		for elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array || elem.Kind() == reflect.Map {
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Float64 || elem.Kind() == reflect.Float32 {
+			fn(typ, name)
+			continue
+		}
+		numberFields(elem, seen, fn)
+	}
+}
@@ -1,19 +1,23 @@
 package api
 
 import (
+	"log/slog"
+	"slices"
+
 	"github.com/ferhatkunduraci/prism/internal/config"
 	"github.com/ferhatkunduraci/prism/internal/providers"
-	"github.com/ferhatkunduraci/prism/internal/storage"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // RouterConfig holds all dependencies needed to create the router
 type RouterConfig struct {
-	Config         *config.Config
+	Config         *config.Holder
 	TEFASProvider  providers.Provider
 	CryptoProvider providers.Provider
-	Storage        *storage.Storage
+	FXProvider     providers.ExchangeRateProvider // Optional, preferred for exchange rates
+	Storage        HoldingsStore
 }
 
 // NewRouter creates and configures the Gin router
@@ -21,44 +25,90 @@ func NewRouter(rc *RouterConfig) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
+	cfg := rc.Config.Get()
+
+	// Only configured proxies may set the client IP through X-Forwarded-For;
+	// otherwise any client could dodge the rate limit by rotating it
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		slog.Error("invalid trusted proxies, trusting none", "error", err)
+	}
 
 	// Middleware
 	r.Use(gin.Recovery())
-	r.Use(gin.Logger())
+	if cfg.Logging.Format == "json" {
+		r.Use(RequestLogger(slog.Default()))
+	} else {
+		r.Use(gin.Logger())
+	}
+	if cfg.Server.Compression {
+		r.Use(Compression())
+	}
 
 	// CORS configuration
+	// Origins are checked against the live config so they can be hot-reloaded;
+	// credentials and max age are fixed at startup
 	corsConfig := cors.DefaultConfig()
-	if len(rc.Config.Server.CORSOrigins) > 0 {
-		corsConfig.AllowOrigins = rc.Config.Server.CORSOrigins
-	} else {
-		corsConfig.AllowAllOrigins = true
+	corsConfig.AllowCredentials = cfg.Server.CORSAllowCredentials
+	corsConfig.MaxAge = cfg.Server.CORSMaxAge
+	corsConfig.AllowOriginFunc = func(origin string) bool {
+		origins := rc.Config.Get().Server.CORSOrigins
+		// Never open up to every origin while credentials are allowed, even if
+		// a reload clears the list
+		return (len(origins) == 0 && !corsConfig.AllowCredentials) || slices.Contains(origins, origin)
 	}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Idempotency-Key", "If-None-Match"}
+	corsConfig.ExposeHeaders = []string{"ETag", "Idempotent-Replayed"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	r.Use(cors.New(corsConfig))
 
 	// Initialize handlers
-	h := NewHandler(rc.Config, rc.TEFASProvider, rc.CryptoProvider, rc.Storage)
+	h := NewHandler(rc.Config, rc.TEFASProvider, rc.CryptoProvider, rc.FXProvider, rc.Storage)
+
+	// Prometheus metrics
+	if cfg.Metrics.Enabled {
+		r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	}
 
 	// API routes
 	api := r.Group("/api")
+	api.Use(RateLimit(cfg.Server.RateLimit))
+	api.Use(APIKeyAuth(cfg.Server.APIKey))
+	api.Use(RequestTimeout(h.requestTimeout))
+	api.Use(BodyLimit(cfg.Server.MaxBodyBytes, map[string]int64{
+		"/api/holdings/bulk": cfg.Server.MaxBulkBodyBytes,
+	}))
+	api.Use(RoundNumbers(rc.Config))
 	{
 		// Health & Meta
 		api.GET("/health", h.Health)
+		api.GET("/health/live", h.Liveness)
+		api.GET("/health/ready", h.Readiness)
 		api.GET("/version", h.Version)
+		api.GET("/dashboard", h.GetDashboard)
 
 		// Portfolio
 		portfolio := api.Group("/portfolio")
 		{
 			portfolio.GET("/summary", h.GetPortfolioSummary)
 			portfolio.GET("/history", h.GetPortfolioHistory)
+			portfolio.GET("/allocation", h.GetAllocation)
+			portfolio.GET("/breakdown", h.GetBreakdown)
+			portfolio.GET("/returns", h.GetReturns)
+			portfolio.GET("/cashflow", h.GetCashflow)
+			portfolio.GET("/suggestions", h.GetSuggestions)
+			portfolio.POST("/simulate", h.SimulatePortfolio)
+			portfolio.POST("/snapshot", h.TakeSnapshot)
+			portfolio.POST("/snapshots/backfill", h.BackfillSnapshots)
 		}
 
 		// TEFAS Funds
 		funds := api.Group("/funds")
 		{
 			funds.GET("", h.GetFunds)
+			funds.GET("/search", h.SearchFunds)
 			funds.GET("/:code", h.GetFund)
+			funds.GET("/:code/details", h.GetFundDetails)
+			funds.GET("/:code/price", h.GetFundPrice)
 		}
 
 		// Crypto
@@ -66,20 +116,45 @@ func NewRouter(rc *RouterConfig) *gin.Engine {
 		{
 			crypto.GET("", h.GetCryptos)
 			crypto.GET("/:symbol", h.GetCrypto)
+			crypto.GET("/:symbol/history", h.GetCryptoHistory)
 		}
 
 		// Holdings CRUD
 		holdings := api.Group("/holdings")
 		{
 			holdings.GET("", h.GetHoldings)
+			holdings.GET("/trash", h.GetTrash)
 			holdings.GET("/:id", h.GetHolding)
+			holdings.GET("/:id/detail", h.GetHoldingDetail)
+			holdings.GET("/:id/pnl/explain", h.ExplainPnL)
+			holdings.GET("/:id/stats", h.GetHoldingStats)
 			holdings.POST("", h.CreateHolding)
+			holdings.POST("/import/config", h.ImportConfigHoldings)
+			holdings.PUT("/bulk", h.BulkUpdateHoldings)
 			holdings.PUT("/:id", h.UpdateHolding)
+			holdings.PATCH("/:id", h.UpdateHolding)
 			holdings.DELETE("/:id", h.DeleteHolding)
+			holdings.POST("/:id/restore", h.RestoreHolding)
+		}
+
+		// Watchlist
+		watchlist := api.Group("/watchlist")
+		{
+			watchlist.GET("", h.GetWatchlist)
+			watchlist.POST("", h.AddWatchItem)
+			watchlist.DELETE("/:id", h.RemoveWatchItem)
 		}
 
 		// Exchange Rate
 		api.GET("/exchange-rate", h.GetExchangeRate)
+
+		// Admin, only reachable with an API key
+		admin := api.Group("/admin")
+		admin.Use(RequireAPIKey(cfg.Server.APIKey))
+		{
+			admin.POST("/cache/flush", h.FlushCaches)
+			admin.POST("/reset", h.Reset)
+		}
 	}
 
 	return r
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSimulatedAdjustments caps the adjustments in one simulation request
+const maxSimulatedAdjustments = 100
+
+// SimulateRequest lists hypothetical trades to apply to the current portfolio
+type SimulateRequest struct {
+	Adjustments []Adjustment `json:"adjustments" binding:"required,min=1,dive"`
+}
+
+// Adjustment is one hypothetical trade. Type is only needed for symbols not
+// already held, or held as both a fund and a crypto. Price defaults to the
+// live price of an existing holding.
+type Adjustment struct {
+	Symbol        string              `json:"symbol" binding:"required"`
+	Type          storage.HoldingType `json:"type,omitempty" binding:"omitempty,oneof=fund crypto"`
+	DeltaQuantity float64             `json:"delta_quantity"` // Positive = buy, negative = sell
+	Price         *float64            `json:"price,omitempty" binding:"omitempty,gt=0"`
+}
+
+// SimulationResponse is the portfolio as it would look after the adjustments
+type SimulationResponse struct {
+	Summary    PortfolioSummary   `json:"summary"`
+	Allocation AllocationResponse `json:"allocation"`
+}
+
+// SimulatePortfolio handles POST /api/portfolio/simulate
+//
+// Nothing is persisted. Unchanged holdings keep their live prices; adjusted
+// ones are revalued at the trade price. Buys add quantity * price to the cost
+// basis and sells remove cost at the average buy price; crypto can be sold
+// past zero into a short position.
+func (h *Handler) SimulatePortfolio(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req SimulateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidBody(c, err)
+		return
+	}
+	if len(req.Adjustments) > maxSimulatedAdjustments {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("At most %d adjustments per simulation", maxSimulatedAdjustments))
+		return
+	}
+
+	summary, err := h.cachedPortfolioSummary(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+	holdings, err := h.storage.GetAllHoldings(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+
+	// The cached summary is shared, so adjust copies of its rows
+	funds := append([]FundPrice{}, summary.Funds...)
+	cryptos := append([]CryptoPrice{}, summary.Cryptos...)
+	for _, adj := range req.Adjustments {
+		if funds, cryptos, err = h.applyAdjustment(funds, cryptos, adj); err != nil {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+			return
+		}
+	}
+
+	projected := summarize(funds, cryptos)
+	projected.convertTotals(summary.tryRates, summary.currencies)
+	projected.LastUpdated = summary.LastUpdated
+	c.JSON(http.StatusOK, SimulationResponse{
+		Summary:    projected,
+		Allocation: buildAllocation(projected, holdings),
+	})
+}
+
+// applyAdjustment applies one trade to the matching row, adding a row for a
+// symbol not yet held
+func (h *Handler) applyAdjustment(funds []FundPrice, cryptos []CryptoPrice, adj Adjustment) ([]FundPrice, []CryptoPrice, error) {
+	fundIdx, cryptoIdx := -1, -1
+	if adj.Type != storage.HoldingTypeCrypto {
+		for i := range funds {
+			if funds[i].Code == adj.Symbol {
+				fundIdx = i
+			}
+		}
+	}
+	if adj.Type != storage.HoldingTypeFund {
+		for i := range cryptos {
+			if cryptos[i].Symbol == adj.Symbol {
+				cryptoIdx = i
+			}
+		}
+	}
+
+	switch {
+	case fundIdx >= 0 && cryptoIdx >= 0:
+		return nil, nil, fmt.Errorf("%s is held as both a fund and a crypto; set type", adj.Symbol)
+	case fundIdx >= 0:
+		f := &funds[fundIdx]
+		price, quantity, costBasis, err := trade(adj, f.Price, f.Quantity, f.CostBasis, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		f.Price, f.Quantity, f.CostBasis = price, quantity, costBasis
+		f.Value, f.PnL, f.PnLPct = positionPnL(price, quantity, costBasis)
+		f.UnrealizedPnL = f.PnL
+		f.AvgPrice = storage.AvgPrice(costBasis, quantity)
+		f.Stale = false
+	case cryptoIdx >= 0:
+		cr := &cryptos[cryptoIdx]
+		price, quantity, costBasis, err := trade(adj, cr.Price, cr.Quantity, cr.CostBasis, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		cr.Price, cr.Quantity, cr.CostBasis = price, quantity, costBasis
+		cr.Value, cr.PnL, cr.PnLPct = positionPnL(price, quantity, costBasis)
+		cr.UnrealizedPnL = cr.PnL
+		cr.AvgPrice = storage.AvgPrice(costBasis, quantity)
+	case adj.Type == "":
+		return nil, nil, fmt.Errorf("%s is not held; set type to fund or crypto", adj.Symbol)
+	default:
+		price, quantity, costBasis, err := trade(adj, 0, 0, 0, adj.Type == storage.HoldingTypeCrypto)
+		if err != nil {
+			return nil, nil, err
+		}
+		value, pnl, pnlPct := positionPnL(price, quantity, costBasis)
+		if adj.Type == storage.HoldingTypeFund {
+			funds = append(funds, FundPrice{
+				Code:          adj.Symbol,
+				Name:          h.fundName(adj.Symbol),
+				Price:         price,
+				Quantity:      quantity,
+				Value:         value,
+				CostBasis:     costBasis,
+				AvgPrice:      storage.AvgPrice(costBasis, quantity),
+				PnL:           pnl,
+				UnrealizedPnL: pnl,
+				PnLPct:        pnlPct,
+			})
+		} else {
+			cryptos = append(cryptos, CryptoPrice{
+				Symbol:        adj.Symbol,
+				Currency:      providers.QuoteCurrency(adj.Symbol),
+				Name:          adj.Symbol,
+				Price:         price,
+				Quantity:      quantity,
+				Value:         value,
+				CostBasis:     costBasis,
+				AvgPrice:      storage.AvgPrice(costBasis, quantity),
+				PnL:           pnl,
+				UnrealizedPnL: pnl,
+				PnLPct:        pnlPct,
+			})
+		}
+	}
+	return funds, cryptos, nil
+}
+
+// trade returns the price, quantity and cost basis of a position after adj.
+// A trade that grows the position, long or short, adds its amount to the
+// cost basis; one that shrinks it releases cost at the average price; one
+// that takes it through zero starts the new position at the trade price. The
+// position may only end up short when allowShort is set.
+func trade(adj Adjustment, livePrice, quantity, costBasis float64, allowShort bool) (float64, float64, float64, error) {
+	price := livePrice
+	if adj.Price != nil {
+		price = *adj.Price
+	}
+	if price <= 0 {
+		return 0, 0, 0, fmt.Errorf("no live price for %s; set price", adj.Symbol)
+	}
+
+	newQuantity := quantity + adj.DeltaQuantity
+	switch {
+	case newQuantity < 0 && !allowShort:
+		return 0, 0, 0, fmt.Errorf("cannot sell %g %s; only %g held", -adj.DeltaQuantity, adj.Symbol, quantity)
+	case newQuantity == 0:
+		costBasis = 0
+	case quantity == 0 || (quantity > 0) == (adj.DeltaQuantity > 0):
+		costBasis += adj.DeltaQuantity * price
+	case (newQuantity > 0) == (quantity > 0):
+		costBasis *= newQuantity / quantity
+	default:
+		costBasis = newQuantity * price
+	}
+	return price, newQuantity, costBasis, nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// staticProvider serves fixed prices for the symbols it knows, or fails
+// with err when set. Symbols in categories are reported in that category.
+type staticProvider struct {
+	prices     map[string]float64
+	categories map[string]string
+	err        error
+}
+
+func (p *staticProvider) Name() string { return "static" }
+
+func (p *staticProvider) FetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	prices := make([]providers.Price, 0, len(symbols))
+	for _, s := range symbols {
+		if price, ok := p.prices[s]; ok {
+			prices = append(prices, providers.Price{Symbol: s, Name: s, Price: price, LastUpdated: time.Now(), Category: p.categories[s]})
+		}
+	}
+	return prices, nil
+}
+
+func (p *staticProvider) IsHealthy(ctx context.Context) bool { return true }
+
+func (p *staticProvider) Close() error { return nil }
+
+func TestSimulatePortfolio(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5},
+	)
+	cfg := &config.Config{}
+	cfg.Server.SummaryCacheTTL = time.Minute
+	h := NewHandler(config.NewHolder(cfg),
+		&staticProvider{prices: map[string]float64{"KUT": 3}},
+		&staticProvider{prices: map[string]float64{"BTCUSDT": 5}},
+		nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/simulate", h.SimulatePortfolio)
+
+	// Live portfolio: KUT worth 30 (cost 20), BTCUSDT worth 10 (cost 5)
+	tests := []struct {
+		name          string
+		body          string
+		wantStatus    int
+		wantValue     float64
+		wantCostBasis float64
+	}{
+		{"buy at supplied price", `{"adjustments":[{"symbol":"KUT","delta_quantity":5,"price":4}]}`, http.StatusOK, 70, 45},
+		{"sell half at live price", `{"adjustments":[{"symbol":"BTCUSDT","delta_quantity":-1}]}`, http.StatusOK, 35, 22.5},
+		{"sell everything", `{"adjustments":[{"symbol":"BTCUSDT","delta_quantity":-2}]}`, http.StatusOK, 30, 20},
+		{"new holding", `{"adjustments":[{"symbol":"ETHUSDT","type":"crypto","delta_quantity":1,"price":100}]}`, http.StatusOK, 140, 125},
+		{"several trades", `{"adjustments":[{"symbol":"KUT","delta_quantity":-10},{"symbol":"BTCUSDT","delta_quantity":6}]}`, http.StatusOK, 40, 35},
+		{"new holding without type", `{"adjustments":[{"symbol":"ETHUSDT","delta_quantity":1,"price":100}]}`, http.StatusBadRequest, 0, 0},
+		{"new holding without price", `{"adjustments":[{"symbol":"TI2","type":"fund","delta_quantity":1}]}`, http.StatusBadRequest, 0, 0},
+		{"sell crypto into a short", `{"adjustments":[{"symbol":"BTCUSDT","delta_quantity":-3}]}`, http.StatusOK, 25, 15},
+		{"add to a new short", `{"adjustments":[{"symbol":"ETHUSDT","type":"crypto","delta_quantity":-1,"price":100}]}`, http.StatusOK, -60, -75},
+		{"oversell", `{"adjustments":[{"symbol":"KUT","delta_quantity":-11}]}`, http.StatusBadRequest, 0, 0},
+		{"no adjustments", `{"adjustments":[]}`, http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/simulate", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp SimulationResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.Summary.TotalValue != tt.wantValue || resp.Summary.TotalCostBasis != tt.wantCostBasis {
+				t.Errorf("value/cost basis = %v/%v, want %v/%v",
+					resp.Summary.TotalValue, resp.Summary.TotalCostBasis, tt.wantValue, tt.wantCostBasis)
+			}
+			if resp.Allocation.TotalValue != tt.wantValue {
+				t.Errorf("allocation total = %v, want %v", resp.Allocation.TotalValue, tt.wantValue)
+			}
+		})
+	}
+
+	// Simulations must not leak into the cached live summary
+	summary, err := h.cachedPortfolioSummary(context.Background())
+	if err != nil {
+		t.Fatalf("cachedPortfolioSummary() error = %v", err)
+	}
+	if summary.TotalValue != 40 || summary.Funds[0].Quantity != 10 {
+		t.Errorf("live summary = %v with KUT quantity %v, want 40 with 10", summary.TotalValue, summary.Funds[0].Quantity)
+	}
+	holdings, _ := store.GetAllHoldings(context.Background())
+	if len(holdings) != 2 {
+		t.Errorf("store has %d holdings, want 2", len(holdings))
+	}
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBackfillDays caps the range of one backfill request
+const maxBackfillDays = 366
+
+// BackfillResponse reports the outcome of a snapshot backfill
+type BackfillResponse struct {
+	From        string       `json:"from"`
+	To          string       `json:"to"`
+	DaysWritten int          `json:"days_written"`
+	DaysSkipped int          `json:"days_skipped"`
+	Skipped     []SkippedDay `json:"skipped"`
+}
+
+// SkippedDay is a day the backfill could not write a snapshot for
+type SkippedDay struct {
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+}
+
+// BackfillSnapshots handles POST /api/portfolio/snapshots/backfill?from=&to=
+//
+// Each day in range is valued with that day's prices against the current
+// holdings and upserted, so re-running a range is safe. Days without prices
+// for every holding (weekends, holidays, unlisted symbols), and days that
+// already have a snapshot with converted totals, are skipped. If the request
+// deadline is reached, the remaining days are reported as skipped. The
+// totals are converted to the configured currencies at today's rates, as
+// are cost bases paid in another currency; without the rates every day is
+// skipped.
+func (h *Handler) BackfillSnapshots(c *gin.Context) {
+	from, to, err := parseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	fundHoldings, err := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeFund)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	cryptoHoldings, err := h.storage.GetHoldingsByType(ctx, storage.HoldingTypeCrypto)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	if len(fundHoldings) == 0 && len(cryptoHoldings) == 0 {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "no holdings to value")
+		return
+	}
+	costs := h.newCostConverter()
+	costs.convertAll(ctx, fundHoldings)
+	costs.convertAll(ctx, cryptoHoldings)
+	cryptoSymbols := make([]string, 0, len(cryptoHoldings))
+	for _, holding := range cryptoHoldings {
+		cryptoSymbols = append(cryptoSymbols, holding.Symbol)
+	}
+	currencies := h.summaryCurrencies()
+	rates := h.fetchTRYRates(ctx, cryptoSymbols, currencies)
+
+	fundHistory := historyProvider(h.tefasProvider)
+	cryptoHistory := historyProvider(h.cryptoProvider)
+	if (len(fundHoldings) > 0 && fundHistory == nil) || (len(cryptoHoldings) > 0 && cryptoHistory == nil) {
+		respondError(c, http.StatusNotImplemented, CodeNotSupported, "configured providers don't support historical prices")
+		return
+	}
+
+	existing, err := h.storage.GetSnapshots(ctx, from, to)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to read snapshots")
+		return
+	}
+	converted := make(map[string]bool, len(existing))
+	for _, snap := range existing {
+		converted[snap.Date.Format(time.DateOnly)] = snap.Currency != ""
+	}
+
+	resp := BackfillResponse{
+		From:    from.Format(time.DateOnly),
+		To:      to.Format(time.DateOnly),
+		Skipped: []SkippedDay{},
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		if ctx.Err() != nil {
+			resp.Skipped = append(resp.Skipped, SkippedDay{Date: date, Reason: "request timed out"})
+			continue
+		}
+		if converted[date] {
+			resp.Skipped = append(resp.Skipped, SkippedDay{Date: date, Reason: "snapshot already recorded"})
+			continue
+		}
+
+		snap, reason := historicalSnapshot(ctx, day, fundHoldings, cryptoHoldings, fundHistory, cryptoHistory, rates, currencies)
+		if reason != "" {
+			resp.Skipped = append(resp.Skipped, SkippedDay{Date: date, Reason: reason})
+			continue
+		}
+		if err := h.storage.UpsertSnapshot(ctx, snap); err != nil {
+			resp.Skipped = append(resp.Skipped, SkippedDay{Date: date, Reason: err.Error()})
+			continue
+		}
+		resp.DaysWritten++
+	}
+	resp.DaysSkipped = len(resp.Skipped)
+
+	slog.Info("backfilled portfolio snapshots", "from", resp.From, "to", resp.To,
+		"written", resp.DaysWritten, "skipped", resp.DaysSkipped)
+	c.JSON(http.StatusOK, resp)
+}
+
+// SnapshotResponse is a stored snapshot and whether it replaced an earlier
+// one for the same day
+type SnapshotResponse struct {
+	storage.Snapshot
+	Replaced bool `json:"replaced"`
+}
+
+// TakeSnapshot handles POST /api/portfolio/snapshot
+//
+// Values the portfolio at current prices and upserts it as today's
+// snapshot, e.g. to record the state right before a trade. Responds 201 for
+// a new snapshot and 200 when it overwrote today's. A holding without a
+// live price fails the request rather than storing an understated total.
+func (h *Handler) TakeSnapshot(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	holdings, err := h.storage.GetAllHoldings(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+	summary, err := h.buildPortfolioSummary(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+	if symbol := unpricedHolding(summary, holdings); symbol != "" {
+		respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable,
+			fmt.Sprintf("No current price for %s; snapshot not taken", symbol))
+		return
+	}
+
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	existing, err := h.storage.GetSnapshots(ctx, today, today)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to read snapshots")
+		return
+	}
+
+	snap := storage.Snapshot{
+		Date:           today,
+		TotalValue:     summary.TotalValue,
+		TotalCostBasis: summary.TotalCostBasis,
+		TEFASValue:     summary.TEFASValue,
+		CryptoValue:    summary.CryptoValue,
+		Currency:       summary.totalsCurrency(),
+	}
+	if err := h.storage.UpsertSnapshot(ctx, snap); err != nil {
+		if errors.Is(err, storage.ErrSnapshotConverted) {
+			respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable,
+				"Exchange rates unavailable; today's snapshot with converted totals was kept")
+			return
+		}
+		slog.Error("failed to save snapshot", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to save snapshot")
+		return
+	}
+
+	resp := SnapshotResponse{Snapshot: snap, Replaced: len(existing) > 0}
+	slog.Info("took portfolio snapshot", "date", today.Format(time.DateOnly), "total_value", snap.TotalValue, "replaced", resp.Replaced)
+	if resp.Replaced {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// unpricedHolding returns the symbol of a holding with a quantity that the
+// summary has no price for, or "" if every one is priced
+func unpricedHolding(summary PortfolioSummary, holdings []storage.Holding) string {
+	priced := make(map[string]bool, len(summary.Funds)+len(summary.Cryptos))
+	for _, f := range summary.Funds {
+		priced[string(storage.HoldingTypeFund)+":"+f.Code] = f.Price > 0
+	}
+	for _, cp := range summary.Cryptos {
+		priced[string(storage.HoldingTypeCrypto)+":"+cp.Symbol] = cp.Price > 0
+	}
+	for _, holding := range holdings {
+		if holding.Quantity != 0 && !priced[string(holding.Type)+":"+holding.Symbol] {
+			return holding.Symbol
+		}
+	}
+	return ""
+}
+
+// snapshotPruneInterval is how often RunSnapshotPruning applies the retention policy
+const snapshotPruneInterval = 24 * time.Hour
+
+// RunSnapshotPruning applies the snapshot retention policy at startup and
+// then daily until ctx is cancelled. The policy is read on every run, so a
+// config reload takes effect on the next one.
+func (h *Handler) RunSnapshotPruning(ctx context.Context) {
+	ticker := time.NewTicker(snapshotPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		h.pruneSnapshots(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneSnapshots deletes snapshots older than snapshot.retention_days as of
+// now. A retention of 0 keeps everything.
+func (h *Handler) pruneSnapshots(ctx context.Context, now time.Time) {
+	policy := h.cfg.Get().Snapshot
+	if policy.RetentionDays <= 0 {
+		return
+	}
+
+	today, _ := time.Parse(time.DateOnly, now.Format(time.DateOnly))
+	before := today.AddDate(0, 0, -policy.RetentionDays)
+	deleted, err := h.storage.PruneSnapshots(ctx, before, policy.KeepMonthEnds)
+	if err != nil {
+		slog.Warn("failed to prune snapshots", "error", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Info("pruned portfolio snapshots", "before", before.Format(time.DateOnly), "deleted", deleted, "keep_month_ends", policy.KeepMonthEnds)
+	}
+}
+
+// historicalSnapshot values the holdings at day's prices and converts the
+// totals as convertTotals does. It returns a non-empty reason instead of a
+// snapshot when any holding lacks a price or an amount has no rate.
+func historicalSnapshot(ctx context.Context, day time.Time, funds, cryptos []storage.Holding, fundHistory, cryptoHistory providers.HistoricalPriceProvider, rates tryRates, currencies summaryCurrencies) (storage.Snapshot, string) {
+	var fundAmounts, cryptoAmounts []pricedAmount
+
+	if len(funds) > 0 {
+		if reason := valueAt(ctx, fundHistory, day, funds, &fundAmounts); reason != "" {
+			return storage.Snapshot{}, "fund prices: " + reason
+		}
+	}
+	if len(cryptos) > 0 {
+		if reason := valueAt(ctx, cryptoHistory, day, cryptos, &cryptoAmounts); reason != "" {
+			return storage.Snapshot{}, "crypto prices: " + reason
+		}
+	}
+
+	tefasValue, _, _, fundsOK := rates.convertedTotal(fundAmounts, currencies.fund)
+	cryptoValue, _, _, cryptoOK := rates.convertedTotal(cryptoAmounts, currencies.crypto)
+	total, costBasis, _, totalOK := rates.convertedTotal(append(fundAmounts, cryptoAmounts...), currencies.display)
+	if !fundsOK || !cryptoOK || !totalOK {
+		return storage.Snapshot{}, "exchange rates unavailable"
+	}
+	return storage.Snapshot{
+		Date:           day,
+		TotalValue:     total.InexactFloat64(),
+		TotalCostBasis: costBasis.InexactFloat64(),
+		TEFASValue:     tefasValue.InexactFloat64(),
+		CryptoValue:    cryptoValue.InexactFloat64(),
+		Currency:       currencies.display,
+	}, ""
+}
+
+// valueAt appends the value and cost basis of holdings at day's prices, in
+// the currency each is priced in, to amounts, or returns why it couldn't
+func valueAt(ctx context.Context, hp providers.HistoricalPriceProvider, day time.Time, holdings []storage.Holding, amounts *[]pricedAmount) string {
+	symbols := make([]string, 0, len(holdings))
+	for _, holding := range holdings {
+		symbols = append(symbols, holding.Symbol)
+	}
+
+	prices, err := hp.FetchHistoricalPrices(ctx, day, symbols)
+	if err != nil {
+		return err.Error()
+	}
+	priceMap := make(map[string]float64, len(prices))
+	for _, p := range prices {
+		priceMap[p.Symbol] = p.Price
+	}
+
+	for _, holding := range holdings {
+		price, ok := priceMap[holding.Symbol]
+		if !ok {
+			return fmt.Sprintf("no data for %s", holding.Symbol)
+		}
+		v, _, _ := positionPnL(price, holding.Quantity, holding.CostBasis)
+		*amounts = append(*amounts, pricedAmount{priceCurrency(holding), v, holding.CostBasis})
+	}
+	return ""
+}
+
+// historyProvider returns p's historical price support, or nil if it has none
+func historyProvider(p providers.Provider) providers.HistoricalPriceProvider {
+	if p == nil {
+		return nil
+	}
+	hp, _ := p.(providers.HistoricalPriceProvider)
+	return hp
+}
+
+// parseDateRange parses an inclusive YYYY-MM-DD range ending no later than today
+func parseDateRange(fromStr, toStr string) (time.Time, time.Time, error) {
+	if fromStr == "" || toStr == "" {
+		return time.Time{}, time.Time{}, errors.New("from and to are required (YYYY-MM-DD)")
+	}
+	from, err := time.Parse(time.DateOnly, fromStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from date: %q", fromStr)
+	}
+	to, err := time.Parse(time.DateOnly, toStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to date: %q", toStr)
+	}
+
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	switch {
+	case to.Before(from):
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	case to.After(today):
+		return time.Time{}, time.Time{}, errors.New("to must not be in the future")
+	case to.Sub(from) >= maxBackfillDays*24*time.Hour:
+		return time.Time{}, time.Time{}, fmt.Errorf("range must not exceed %d days", maxBackfillDays)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// weekdayHistory serves a fixed historical price for every symbol, but only
+// on weekdays, like TEFAS
+type weekdayHistory struct {
+	price float64
+}
+
+func (p *weekdayHistory) Name() string { return "history" }
+
+func (p *weekdayHistory) FetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
+	return nil, nil
+}
+
+func (p *weekdayHistory) FetchHistoricalPrices(ctx context.Context, date time.Time, symbols []string) ([]providers.Price, error) {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return nil, nil
+	}
+	prices := make([]providers.Price, 0, len(symbols))
+	for _, s := range symbols {
+		prices = append(prices, providers.Price{Symbol: s, Price: p.price, LastUpdated: date})
+	}
+	return prices, nil
+}
+
+func (p *weekdayHistory) IsHealthy(ctx context.Context) bool { return true }
+
+func (p *weekdayHistory) Close() error { return nil }
+
+func TestBackfillSnapshots(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5},
+	)
+	ctx := context.Background()
+
+	h := NewHandler(config.NewHolder(&config.Config{}), &weekdayHistory{price: 3}, &weekdayHistory{price: 5}, staticFX{"USD": 40}, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/backfill", h.BackfillSnapshots)
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantWritten int
+		wantSkipped int
+	}{
+		// 2024-03-15 is a Friday; the weekend is skipped
+		{"range with weekend", "?from=2024-03-15&to=2024-03-18", http.StatusOK, 2, 2},
+		{"rerun keeps the written days", "?from=2024-03-15&to=2024-03-18", http.StatusOK, 0, 4},
+		{"missing to", "?from=2024-03-15", http.StatusBadRequest, 0, 0},
+		{"reversed range", "?from=2024-03-18&to=2024-03-15", http.StatusBadRequest, 0, 0},
+		{"future date", "?from=2024-03-15&to=2999-01-01", http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/backfill"+tt.query, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp BackfillResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.DaysWritten != tt.wantWritten || resp.DaysSkipped != tt.wantSkipped {
+				t.Errorf("written/skipped = %d/%d, want %d/%d", resp.DaysWritten, resp.DaysSkipped, tt.wantWritten, tt.wantSkipped)
+			}
+		})
+	}
+
+	snapshots, err := store.GetSnapshots(ctx, time.Time{}, time.Now())
+	if err != nil {
+		t.Fatalf("GetSnapshots() error = %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snapshots))
+	}
+	// 30 TRY of KUT and 10 USD of BTC at 40 TRY, with 20 TRY and 5 USD paid
+	want := storage.Snapshot{Date: snapshots[0].Date, TotalValue: 430, TotalCostBasis: 220, TEFASValue: 30, CryptoValue: 10, Currency: "TRY"}
+	if snap := snapshots[0]; snap.Date.Format(time.DateOnly) != "2024-03-15" || snap != want {
+		t.Errorf("first snapshot = %+v, want 2024-03-15 %+v", snap, want)
+	}
+}
+
+func TestBackfillSnapshotsKeepsConvertedSnapshots(t *testing.T) {
+	friday := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	monday := friday.AddDate(0, 0, 3)
+	taken := storage.Snapshot{Date: monday, TotalValue: 999, TotalCostBasis: 500, TEFASValue: 599, CryptoValue: 10, Currency: "TRY"}
+
+	tests := []struct {
+		name        string
+		fx          providers.ExchangeRateProvider
+		wantWritten int
+		wantReasons map[string]string
+	}{
+		{
+			name:        "unconverted day replaced",
+			fx:          staticFX{"USD": 40},
+			wantWritten: 1,
+			wantReasons: map[string]string{"2024-03-18": "snapshot already recorded"},
+		},
+		{
+			name: "no rates",
+			wantReasons: map[string]string{
+				"2024-03-15": "exchange rates unavailable",
+				"2024-03-18": "snapshot already recorded",
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5},
+			)
+			ctx := context.Background()
+			store.UpsertSnapshot(ctx, storage.Snapshot{Date: friday, TotalValue: 40, TotalCostBasis: 25})
+			store.UpsertSnapshot(ctx, taken)
+
+			h := NewHandler(config.NewHolder(&config.Config{}), &weekdayHistory{price: 3}, &weekdayHistory{price: 5}, tt.fx, store)
+			r := gin.New()
+			r.POST("/backfill", h.BackfillSnapshots)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/backfill?from=2024-03-15&to=2024-03-18", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+			var resp BackfillResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.DaysWritten != tt.wantWritten {
+				t.Errorf("days written = %d, want %d", resp.DaysWritten, tt.wantWritten)
+			}
+			for date, want := range tt.wantReasons {
+				if !slices.Contains(resp.Skipped, SkippedDay{Date: date, Reason: want}) {
+					t.Errorf("skipped = %+v, want %s skipped for %q", resp.Skipped, date, want)
+				}
+			}
+
+			snapshots, err := store.GetSnapshots(ctx, friday, monday)
+			if err != nil {
+				t.Fatalf("GetSnapshots() error = %v", err)
+			}
+			if len(snapshots) != 2 || snapshots[1] != taken {
+				t.Fatalf("snapshots = %+v, want the taken one on 2024-03-18 kept as is", snapshots)
+			}
+			if tt.wantWritten > 0 && snapshots[0].Currency != "TRY" {
+				t.Errorf("2024-03-15 snapshot = %+v, want it replaced with converted totals", snapshots[0])
+			}
+		})
+	}
+}
+
+func TestGetPortfolioHistory(t *testing.T) {
+	store := newFakeStore()
+	start := time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 9; i++ {
+		store.UpsertSnapshot(context.Background(), storage.Snapshot{Date: start.AddDate(0, 0, i)})
+	}
+
+	h := NewHandler(config.NewHolder(&config.Config{}), nil, nil, nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/history", h.GetPortfolioHistory)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFrom   string
+		wantTo     string
+		wantPoints int
+	}{
+		{"defaults", "", http.StatusOK, "2024-01-29", "2024-02-06", 9},
+		{"date range", "?from=2024-02-01&to=2024-02-03", http.StatusOK, "2024-02-01", "2024-02-03", 3},
+		{"weekly with limit", "?granularity=weekly&limit=1", http.StatusOK, "2024-02-06", "2024-02-06", 1},
+		{"monthly", "?granularity=monthly", http.StatusOK, "2024-01-31", "2024-02-06", 2},
+		{"nothing in range", "?to=2023-12-31", http.StatusOK, "", "", 0},
+		{"unknown granularity", "?granularity=hourly", http.StatusBadRequest, "", "", 0},
+		{"bad limit", "?limit=0", http.StatusBadRequest, "", "", 0},
+		{"reversed range", "?from=2024-02-03&to=2024-02-01", http.StatusBadRequest, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history"+tt.query, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp HistoryResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.From != tt.wantFrom || resp.To != tt.wantTo || len(resp.History) != tt.wantPoints {
+				t.Errorf("got %s..%s with %d points, want %s..%s with %d", resp.From, resp.To, len(resp.History), tt.wantFrom, tt.wantTo, tt.wantPoints)
+			}
+		})
+	}
+}
+
+func TestPruneSnapshotsRetention(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		policy    config.SnapshotConfig
+		wantCount int
+	}{
+		{"default keeps everything", config.SnapshotConfig{}, 40},
+		{"retention window", config.SnapshotConfig{RetentionDays: 7}, 8},
+		{"retention keeping month ends", config.SnapshotConfig{RetentionDays: 7, KeepMonthEnds: true}, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			for d := now.AddDate(0, 0, -39); !d.After(now); d = d.AddDate(0, 0, 1) {
+				store.UpsertSnapshot(context.Background(), storage.Snapshot{Date: d})
+			}
+			cfg := &config.Config{Snapshot: tt.policy}
+			h := NewHandler(config.NewHolder(cfg), nil, nil, nil, store)
+
+			h.pruneSnapshots(context.Background(), now)
+			if len(store.snapshots) != tt.wantCount {
+				t.Errorf("%d snapshots left, want %d", len(store.snapshots), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 2, CostBasis: 5},
+	)
+	tefas := &staticProvider{prices: map[string]float64{"KUT": 3}}
+	crypto := &staticProvider{prices: map[string]float64{"BTCUSDT": 5}}
+	h := NewHandler(config.NewHolder(&config.Config{}), tefas, crypto, nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/snapshot", h.TakeSnapshot)
+
+	take := func() (int, SnapshotResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/snapshot", nil))
+		var resp SnapshotResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	status, resp := take()
+	if status != http.StatusCreated || resp.Replaced {
+		t.Fatalf("first snapshot: status %d, replaced %v; want 201, not replaced", status, resp.Replaced)
+	}
+	if resp.TEFASValue != 30 || resp.CryptoValue != 10 || resp.TotalCostBasis != 25 {
+		t.Errorf("snapshot = %+v, want TEFAS 30, crypto 10, cost basis 25", resp.Snapshot)
+	}
+	// Without a USD/TRY rate the totals add TRY and USD as they are
+	if resp.Currency != "" {
+		t.Errorf("snapshot currency = %q, want none for unconverted totals", resp.Currency)
+	}
+
+	tefas.prices["KUT"] = 4
+	status, resp = take()
+	if status != http.StatusOK || !resp.Replaced || resp.TEFASValue != 40 {
+		t.Errorf("second snapshot: status %d, %+v; want 200 replacing with TEFAS 40", status, resp)
+	}
+	if len(store.snapshots) != 1 {
+		t.Errorf("stored %d snapshots, want today's only", len(store.snapshots))
+	}
+
+	delete(tefas.prices, "KUT")
+	if status, _ := take(); status != http.StatusServiceUnavailable {
+		t.Errorf("snapshot without a KUT price: status %d, want 503", status)
+	}
+
+	// With the rate, the totals are in the display currency and say so
+	tefas.prices["KUT"] = 3
+	h.fxProvider = staticFX{"USD": 40}
+	if _, resp := take(); resp.Currency != "TRY" || resp.TotalValue != 430 {
+		t.Errorf("converted snapshot = %+v, want 430 TRY", resp.Snapshot)
+	}
+}
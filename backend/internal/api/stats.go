@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// statsWindowDays is the trailing window holding stats cover
+	statsWindowDays = 30
+
+	// statsLookbackDays pads the window so a price from on or before its
+	// first day exists even when that day is a weekend or holiday
+	statsLookbackDays = statsWindowDays + 7
+)
+
+// HoldingStats summarizes a holding's price moves over the trailing 30 days.
+// Returns, volatility and drawdown are percentages, and each is null when
+// the price history is too short to compute it.
+type HoldingStats struct {
+	HoldingID         int64     `json:"holding_id"`
+	Symbol            string    `json:"symbol"`
+	Return7d          *float64  `json:"return_7d"`
+	Return30d         *float64  `json:"return_30d"`
+	AvgDailyReturn7d  *float64  `json:"avg_daily_return_7d"`
+	AvgDailyReturn30d *float64  `json:"avg_daily_return_30d"`
+	Volatility        *float64  `json:"volatility"`   // Standard deviation of daily returns
+	MaxDrawdown       *float64  `json:"max_drawdown"` // Largest fall from a peak, as a positive percentage
+	DataPoints        int       `json:"data_points"`  // Daily prices the stats are based on
+	From              string    `json:"from,omitempty"`
+	To                string    `json:"to,omitempty"`
+	ComputedAt        time.Time `json:"computed_at"`
+}
+
+// cachedStats is a stats result and the day it was computed on; the
+// inputs are daily prices, so it is reused until the day changes
+type cachedStats struct {
+	day   string
+	stats HoldingStats
+}
+
+// pricePoint is one daily price
+type pricePoint struct {
+	date  time.Time
+	price float64
+}
+
+// errNoPriceHistory is returned by priceHistory when the provider can't
+// serve past prices
+var errNoPriceHistory = errors.New("price history not supported")
+
+// GetHoldingStats handles GET /api/holdings/:id/stats
+//
+// Computed from daily prices (TEFAS history, Binance daily candles) and
+// cached per symbol until the next day.
+func (h *Handler) GetHoldingStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid holding ID")
+		return
+	}
+
+	holding, err := h.storage.GetHoldingByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrHoldingNotFound) {
+			respondError(c, http.StatusNotFound, CodeHoldingNotFound, "Holding not found")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holding")
+		return
+	}
+
+	stats, err := h.holdingStats(ctx, *holding, time.Now())
+	if err != nil {
+		if errors.Is(err, errNoPriceHistory) {
+			respondError(c, http.StatusNotImplemented, CodeNotSupported, "Configured providers don't support price history")
+			return
+		}
+		slog.Error("failed to fetch price history", "symbol", holding.Symbol, "error", err)
+		respondError(c, http.StatusServiceUnavailable, CodeProviderUnavailable, "Failed to fetch price history")
+		return
+	}
+	stats.HoldingID = holding.ID
+	c.JSON(http.StatusOK, stats)
+}
+
+// holdingStats returns the stats of holding's symbol as of now, from the
+// cache when they were computed today
+func (h *Handler) holdingStats(ctx context.Context, holding storage.Holding, now time.Time) (HoldingStats, error) {
+	key := string(holding.Type) + ":" + holding.Symbol
+	day := now.Format(time.DateOnly)
+
+	h.statsMu.Lock()
+	cached, ok := h.stats[key]
+	h.statsMu.Unlock()
+	if ok && cached.day == day {
+		return cached.stats, nil
+	}
+
+	points, err := h.priceHistory(ctx, holding, now)
+	if err != nil {
+		return HoldingStats{}, err
+	}
+	stats := computeStats(points)
+	stats.Symbol = holding.Symbol
+	stats.ComputedAt = now
+
+	h.statsMu.Lock()
+	h.stats[key] = cachedStats{day: day, stats: stats}
+	h.statsMu.Unlock()
+	return stats, nil
+}
+
+// priceHistory returns holding's daily prices over the stats lookback,
+// oldest first, from whichever history the provider supports
+func (h *Handler) priceHistory(ctx context.Context, holding storage.Holding, now time.Time) ([]pricePoint, error) {
+	provider := h.cryptoProvider
+	if holding.Type == storage.HoldingTypeFund {
+		provider = h.tefasProvider
+	}
+	if provider == nil {
+		return nil, errNoPriceHistory
+	}
+	today, _ := time.Parse(time.DateOnly, now.Format(time.DateOnly))
+	from := today.AddDate(0, 0, -statsLookbackDays)
+
+	if php, ok := provider.(providers.PriceHistoryProvider); ok {
+		prices, err := php.FetchPriceHistory(ctx, holding.Symbol, from, today)
+		if err != nil {
+			return nil, err
+		}
+		points := make([]pricePoint, 0, len(prices))
+		for _, p := range prices {
+			date, err := time.Parse(time.DateOnly, p.PriceDate)
+			if err != nil {
+				continue
+			}
+			points = append(points, pricePoint{date: date, price: p.Price})
+		}
+		return points, nil
+	}
+
+	if kp, ok := provider.(providers.KlineProvider); ok {
+		candles, err := kp.FetchKlines(ctx, holding.Symbol, "1d", statsLookbackDays+1)
+		if err == nil {
+			points := make([]pricePoint, 0, len(candles))
+			for _, c := range candles {
+				date, _ := time.Parse(time.DateOnly, c.OpenTime.UTC().Format(time.DateOnly))
+				points = append(points, pricePoint{date: date, price: c.Close})
+			}
+			return points, nil
+		}
+		if !errors.Is(err, providers.ErrNotSupported) {
+			return nil, err
+		}
+	}
+
+	hp := historyProvider(provider)
+	if hp == nil {
+		return nil, errNoPriceHistory
+	}
+	var points []pricePoint
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		prices, err := hp.FetchHistoricalPrices(ctx, day, []string{holding.Symbol})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", day.Format(time.DateOnly), err)
+		}
+		for _, p := range prices {
+			if p.Symbol == holding.Symbol && p.Price > 0 {
+				points = append(points, pricePoint{date: day, price: p.Price})
+			}
+		}
+	}
+	return points, nil
+}
+
+// computeStats computes stats from daily prices, oldest first. Returns over
+// n days compare the last price with the latest one on or before n days
+// earlier; volatility and drawdown cover the 30-day window.
+func computeStats(points []pricePoint) HoldingStats {
+	stats := HoldingStats{}
+	if len(points) == 0 {
+		return stats
+	}
+	last := points[len(points)-1]
+
+	// baseIndex returns the index of the latest point on or before days
+	// before the last one, or -1 if the history doesn't reach back that far
+	baseIndex := func(days int) int {
+		cutoff := last.date.AddDate(0, 0, -days)
+		for i := len(points) - 1; i >= 0; i-- {
+			if !points[i].date.After(cutoff) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if i := baseIndex(7); i >= 0 {
+		stats.Return7d = pctChange(points[i].price, last.price)
+		stats.AvgDailyReturn7d = mean(dailyReturns(points[i:]))
+	}
+	window := points
+	if i := baseIndex(statsWindowDays); i >= 0 {
+		stats.Return30d = pctChange(points[i].price, last.price)
+		stats.AvgDailyReturn30d = mean(dailyReturns(points[i:]))
+		window = points[i:]
+	}
+
+	stats.Volatility = stdev(dailyReturns(window))
+	if len(window) >= 2 {
+		drawdown := maxDrawdown(window)
+		stats.MaxDrawdown = &drawdown
+	}
+	stats.DataPoints = len(window)
+	stats.From = window[0].date.Format(time.DateOnly)
+	stats.To = last.date.Format(time.DateOnly)
+	return stats
+}
+
+// dailyReturns returns the percentage change between consecutive points
+func dailyReturns(points []pricePoint) []float64 {
+	returns := make([]float64, 0, len(points))
+	for i := 1; i < len(points); i++ {
+		if r := pctChange(points[i-1].price, points[i].price); r != nil {
+			returns = append(returns, *r)
+		}
+	}
+	return returns
+}
+
+// pctChange returns the change from before to after as a percentage, or nil
+// if before isn't positive
+func pctChange(before, after float64) *float64 {
+	if before <= 0 {
+		return nil
+	}
+	change := (after/before - 1) * 100
+	return &change
+}
+
+// mean returns the average of values, or nil if there are none
+func mean(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	avg := sum / float64(len(values))
+	return &avg
+}
+
+// stdev returns the sample standard deviation of values, or nil for fewer
+// than two
+func stdev(values []float64) *float64 {
+	if len(values) < 2 {
+		return nil
+	}
+	avg := *mean(values)
+	var sq float64
+	for _, v := range values {
+		sq += (v - avg) * (v - avg)
+	}
+	sd := math.Sqrt(sq / float64(len(values)-1))
+	return &sd
+}
+
+// maxDrawdown returns the largest fall from a running peak, as a positive
+// percentage of the peak
+func maxDrawdown(points []pricePoint) float64 {
+	var peak, worst float64
+	for _, p := range points {
+		peak = max(peak, p.price)
+		if peak > 0 {
+			worst = max(worst, (peak-p.price)/peak*100)
+		}
+	}
+	return worst
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestComputeStats(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n) }
+	series := func(prices ...float64) []pricePoint {
+		points := make([]pricePoint, len(prices))
+		for i, p := range prices {
+			points[i] = pricePoint{date: day(i), price: p}
+		}
+		return points
+	}
+
+	tests := []struct {
+		name         string
+		points       []pricePoint
+		wantReturn7d *float64
+		want30d      *float64
+		wantDrawdown *float64
+		wantVolNil   bool
+	}{
+		{
+			name:       "empty",
+			wantVolNil: true,
+		},
+		{
+			name:         "too short for 7d",
+			points:       series(100, 110, 99),
+			wantDrawdown: ptrFloat(10),
+		},
+		{
+			name:         "8 days",
+			points:       series(100, 110, 120, 90, 100, 105, 110, 120),
+			wantReturn7d: ptrFloat(20),
+			wantDrawdown: ptrFloat(25),
+		},
+		{
+			name: "gap before the 7d cutoff uses the earlier price",
+			points: []pricePoint{
+				{date: day(0), price: 50},
+				{date: day(2), price: 80}, // Cutoff is day 3
+				{date: day(5), price: 90},
+				{date: day(10), price: 100},
+			},
+			wantReturn7d: ptrFloat(25),
+			wantDrawdown: ptrFloat(0),
+		},
+		{
+			name:         "31 days flat then up",
+			points:       append(series(make30(100)...), pricePoint{date: day(30), price: 110}),
+			wantReturn7d: ptrFloat(10),
+			want30d:      ptrFloat(10),
+			wantDrawdown: ptrFloat(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeStats(tt.points)
+			checkPct(t, "return_7d", got.Return7d, tt.wantReturn7d)
+			checkPct(t, "return_30d", got.Return30d, tt.want30d)
+			checkPct(t, "max_drawdown", got.MaxDrawdown, tt.wantDrawdown)
+			if tt.wantVolNil != (got.Volatility == nil) {
+				t.Errorf("volatility = %v, want nil: %v", got.Volatility, tt.wantVolNil)
+			}
+		})
+	}
+}
+
+func TestComputeStatsVolatility(t *testing.T) {
+	// Daily returns of +10% and -10%: mean 0, sample stdev sqrt(200/1)
+	points := []pricePoint{
+		{date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), price: 100},
+		{date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), price: 110},
+		{date: time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), price: 99},
+	}
+	got := computeStats(points)
+	checkPct(t, "volatility", got.Volatility, ptrFloat(math.Sqrt(200)))
+	checkPct(t, "avg_daily_return_30d", got.AvgDailyReturn30d, nil)
+}
+
+func TestGetHoldingStats(t *testing.T) {
+	store := newFakeStore(storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20})
+	history := &weekdayHistory{price: 2.5}
+	h := NewHandler(config.NewHolder(&config.Config{}), history, nil, nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/holdings/:id/stats", h.GetHoldingStats)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/holdings/1/stats", http.StatusOK},
+		{"/holdings/9/stats", http.StatusNotFound},
+		{"/holdings/abc/stats", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d: %s", tt.path, w.Code, tt.want, w.Body)
+		}
+	}
+
+	holding, _ := store.GetHoldingByID(context.Background(), 1)
+	stats, err := h.holdingStats(context.Background(), *holding, time.Now())
+	if err != nil {
+		t.Fatalf("holdingStats: %v", err)
+	}
+	if stats.Volatility == nil || *stats.Volatility != 0 || stats.Return30d == nil || *stats.Return30d != 0 {
+		t.Errorf("flat prices: volatility %v, 30d return %v; want 0 and 0", stats.Volatility, stats.Return30d)
+	}
+
+	// A cached result is served until the day changes
+	history.price = 5
+	cached, _ := h.holdingStats(context.Background(), *holding, time.Now())
+	if !cached.ComputedAt.Equal(stats.ComputedAt) {
+		t.Error("stats recomputed within the same day, want cached")
+	}
+}
+
+func make30(price float64) []float64 {
+	prices := make([]float64, 30)
+	for i := range prices {
+		prices[i] = price
+	}
+	return prices
+}
+
+func ptrFloat(v float64) *float64 { return &v }
+
+func checkPct(t *testing.T, name string, got, want *float64) {
+	t.Helper()
+	switch {
+	case want == nil && got != nil:
+		t.Errorf("%s = %v, want nil", name, *got)
+	case want != nil && got == nil:
+		t.Errorf("%s = nil, want %v", name, *want)
+	case want != nil && math.Abs(*got-*want) > 1e-9:
+		t.Errorf("%s = %v, want %v", name, *got, *want)
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/storage"
+)
+
+// HoldingsStore is the persistence the handlers depend on. *storage.Storage
+// implements it in production; tests can substitute an in-memory fake.
+type HoldingsStore interface {
+	// GetAllHoldings returns every holding not in the trash
+	GetAllHoldings(ctx context.Context) ([]storage.Holding, error)
+
+	// GetHoldingsByType returns the holdings of one type not in the trash
+	GetHoldingsByType(ctx context.Context, holdingType storage.HoldingType) ([]storage.Holding, error)
+
+	// GetDeletedHoldings returns the holdings in the trash
+	GetDeletedHoldings(ctx context.Context) ([]storage.Holding, error)
+
+	// GetHoldingByID returns a holding, or storage.ErrHoldingNotFound
+	GetHoldingByID(ctx context.Context, id int64) (*storage.Holding, error)
+
+	// GetHoldingBySymbol returns a holding, or storage.ErrHoldingNotFound
+	GetHoldingBySymbol(ctx context.Context, holdingType storage.HoldingType, symbol string) (*storage.Holding, error)
+
+	// CreateHolding creates a holding, or returns storage.ErrHoldingExists
+	// or storage.ErrHoldingInTrash for a duplicate
+	CreateHolding(ctx context.Context, req storage.CreateHoldingRequest) (*storage.Holding, error)
+
+	// ValidateHoldings reports whether each holding could be created,
+	// without writing anything
+	ValidateHoldings(ctx context.Context, holdings []storage.CreateHoldingRequest) ([]storage.HoldingValidation, error)
+
+	// UpdateHolding applies the non-nil fields of req to a holding
+	UpdateHolding(ctx context.Context, id int64, req storage.UpdateHoldingRequest) (*storage.Holding, error)
+
+	// BulkUpdateHoldings applies updates in one transaction, reporting
+	// missing holdings per item rather than failing the batch
+	BulkUpdateHoldings(ctx context.Context, updates []storage.BulkUpdate) ([]storage.BulkUpdateResult, error)
+
+	// DeleteHolding moves a holding to the trash
+	DeleteHolding(ctx context.Context, id int64) error
+
+	// HardDeleteHolding permanently removes a holding
+	HardDeleteHolding(ctx context.Context, id int64) error
+
+	// RestoreHolding moves a holding out of the trash
+	RestoreHolding(ctx context.Context, id int64) (*storage.Holding, error)
+
+	// UpsertSnapshot stores the portfolio snapshot for its date
+	UpsertSnapshot(ctx context.Context, snap storage.Snapshot) error
+
+	// GetSnapshots returns the snapshots dated within [from, to], oldest first
+	GetSnapshots(ctx context.Context, from, to time.Time) ([]storage.Snapshot, error)
+
+	// GetPeakSnapshot returns the snapshot with totals in currency with the
+	// highest total value and how many snapshots have totals in currency
+	GetPeakSnapshot(ctx context.Context, currency string) (storage.Snapshot, int, error)
+
+	// PruneSnapshots deletes snapshots dated before before, keeping each
+	// month's last one if keepMonthEnds, and returns how many it deleted
+	PruneSnapshots(ctx context.Context, before time.Time, keepMonthEnds bool) (int64, error)
+
+	// Reset deletes every holding and snapshot, then creates the seed
+	// holdings, all at once
+	Reset(ctx context.Context, seed []storage.CreateHoldingRequest) (storage.ResetResult, error)
+
+	// GetWatchlist returns every watched symbol, oldest first
+	GetWatchlist(ctx context.Context) ([]storage.WatchItem, error)
+
+	// GetWatchlistByType returns the watched symbols of one type
+	GetWatchlistByType(ctx context.Context, holdingType storage.HoldingType) ([]storage.WatchItem, error)
+
+	// AddWatchItem starts watching a symbol, or returns
+	// storage.ErrWatchItemExists
+	AddWatchItem(ctx context.Context, req storage.WatchRequest) (*storage.WatchItem, error)
+
+	// RemoveWatchItem stops watching a symbol, or returns
+	// storage.ErrWatchItemNotFound
+	RemoveWatchItem(ctx context.Context, id int64) error
+
+	// GetIdempotentResponse returns the unexpired response recorded under
+	// key, or storage.ErrIdempotencyKeyNotFound
+	GetIdempotentResponse(ctx context.Context, key string) (*storage.IdempotentResponse, error)
+
+	// SaveIdempotentResponse records resp under key until ttl elapses
+	SaveIdempotentResponse(ctx context.Context, key string, resp storage.IdempotentResponse, ttl time.Duration) error
+
+	// GetSnapshotHistory returns the last snapshot of each bucket in range,
+	// oldest first
+	GetSnapshotHistory(ctx context.Context, q storage.SnapshotQuery) ([]storage.Snapshot, error)
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+var _ HoldingsStore = (*storage.Storage)(nil)
@@ -0,0 +1,896 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeStore is an in-memory HoldingsStore. When err is set, every call
+// fails with it, as if the database were unreachable.
+type fakeStore struct {
+	err         error
+	nextID      int64
+	holdings    map[int64]*storage.Holding
+	snapshots   map[string]storage.Snapshot
+	idempotency map[string]storage.IdempotentResponse
+	watchlist   []storage.WatchItem
+}
+
+func newFakeStore(holdings ...storage.CreateHoldingRequest) *fakeStore {
+	s := &fakeStore{
+		holdings:    make(map[int64]*storage.Holding),
+		snapshots:   make(map[string]storage.Snapshot),
+		idempotency: make(map[string]storage.IdempotentResponse),
+	}
+	for _, req := range holdings {
+		s.CreateHolding(context.Background(), req)
+	}
+	return s
+}
+
+// list returns copies of the holdings matching keep, ordered by ID
+func (s *fakeStore) list(keep func(*storage.Holding) bool) ([]storage.Holding, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	holdings := []storage.Holding{}
+	for _, h := range s.holdings {
+		if keep(h) {
+			holdings = append(holdings, *h)
+		}
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].ID < holdings[j].ID })
+	return holdings, nil
+}
+
+func (s *fakeStore) GetAllHoldings(ctx context.Context) ([]storage.Holding, error) {
+	return s.list(func(h *storage.Holding) bool { return h.DeletedAt == nil })
+}
+
+func (s *fakeStore) GetHoldingsByType(ctx context.Context, holdingType storage.HoldingType) ([]storage.Holding, error) {
+	return s.list(func(h *storage.Holding) bool { return h.DeletedAt == nil && h.Type == holdingType })
+}
+
+func (s *fakeStore) GetDeletedHoldings(ctx context.Context) ([]storage.Holding, error) {
+	return s.list(func(h *storage.Holding) bool { return h.DeletedAt != nil })
+}
+
+func (s *fakeStore) GetHoldingByID(ctx context.Context, id int64) (*storage.Holding, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	h, ok := s.holdings[id]
+	if !ok || h.DeletedAt != nil {
+		return nil, storage.ErrHoldingNotFound
+	}
+	holding := *h
+	return &holding, nil
+}
+
+func (s *fakeStore) GetHoldingBySymbol(ctx context.Context, holdingType storage.HoldingType, symbol string) (*storage.Holding, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, h := range s.holdings {
+		if h.DeletedAt == nil && h.Type == holdingType && h.Symbol == symbol {
+			holding := *h
+			return &holding, nil
+		}
+	}
+	return nil, storage.ErrHoldingNotFound
+}
+
+func (s *fakeStore) CreateHolding(ctx context.Context, req storage.CreateHoldingRequest) (*storage.Holding, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, h := range s.holdings {
+		if h.Type == req.Type && h.Symbol == req.Symbol {
+			if h.DeletedAt != nil {
+				return nil, storage.ErrHoldingInTrash
+			}
+			return nil, storage.ErrHoldingExists
+		}
+	}
+	if req.AvgPrice != nil {
+		if req.CostBasis != 0 || req.Quantity == 0 {
+			return nil, fmt.Errorf("%w: avg_price can't give the cost basis", storage.ErrInvalidPosition)
+		}
+		req.CostBasis = req.Quantity * *req.AvgPrice
+	}
+	if problem := storage.PositionProblem(req.Type, req.Quantity, req.CostBasis); problem != "" {
+		return nil, fmt.Errorf("%w: %s", storage.ErrInvalidPosition, problem)
+	}
+
+	s.nextID++
+	now := time.Now()
+	h := &storage.Holding{
+		ID:           s.nextID,
+		Type:         req.Type,
+		Symbol:       req.Symbol,
+		Quantity:     req.Quantity,
+		CostBasis:    req.CostBasis,
+		AvgPrice:     storage.AvgPrice(req.CostBasis, req.Quantity),
+		TargetPct:    req.TargetPct,
+		FundType:     req.FundType,
+		CostCurrency: req.CostCurrency,
+		Tags:         storage.NormalizeTags(req.Tags),
+		Notes:        req.Notes,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	s.holdings[h.ID] = h
+	holding := *h
+	return &holding, nil
+}
+
+func (s *fakeStore) ValidateHoldings(ctx context.Context, holdings []storage.CreateHoldingRequest) ([]storage.HoldingValidation, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	results := storage.CheckHoldings(holdings)
+	for i, r := range results {
+		if r.Status != storage.ValidationNew {
+			continue
+		}
+		for _, h := range s.holdings {
+			if h.Type == r.Type && h.Symbol == r.Symbol {
+				results[i].Status = storage.ValidationExists
+				if h.DeletedAt != nil {
+					results[i].Status = storage.ValidationInTrash
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+func (s *fakeStore) UpdateHolding(ctx context.Context, id int64, req storage.UpdateHoldingRequest) (*storage.Holding, error) {
+	if _, err := s.GetHoldingByID(ctx, id); err != nil {
+		return nil, err
+	}
+	updated := *s.holdings[id]
+	h := &updated
+	if req.Type != nil && *req.Type != h.Type && h.CostBasis != 0 && req.CostBasis == nil {
+		return nil, storage.ErrTypeChangeRequiresCostBasis
+	}
+	if req.Type != nil {
+		h.Type = *req.Type
+	}
+	if req.Symbol != nil {
+		h.Symbol = *req.Symbol
+	}
+	if req.Quantity != nil {
+		h.Quantity = *req.Quantity
+	}
+	if req.CostBasis != nil {
+		h.CostBasis = *req.CostBasis
+	}
+	if req.TargetPct != nil {
+		h.TargetPct = req.TargetPct
+	}
+	if req.FundType != nil {
+		h.FundType = *req.FundType
+	}
+	if req.CostCurrency != nil {
+		h.CostCurrency = *req.CostCurrency
+	}
+	if req.Tags != nil {
+		h.Tags = storage.NormalizeTags(*req.Tags)
+	}
+	if req.Notes != nil {
+		h.Notes = *req.Notes
+	}
+	if problem := storage.PositionProblem(h.Type, h.Quantity, h.CostBasis); problem != "" {
+		return nil, fmt.Errorf("%w: %s", storage.ErrInvalidPosition, problem)
+	}
+	h.AvgPrice = storage.AvgPrice(h.CostBasis, h.Quantity)
+	h.UpdatedAt = time.Now()
+	s.holdings[id] = h
+	holding := *h
+	return &holding, nil
+}
+
+func (s *fakeStore) BulkUpdateHoldings(ctx context.Context, updates []storage.BulkUpdate) ([]storage.BulkUpdateResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	results := make([]storage.BulkUpdateResult, 0, len(updates))
+	for _, u := range updates {
+		holding, err := s.UpdateHolding(ctx, u.ID, storage.UpdateHoldingRequest{Quantity: u.Quantity, CostBasis: u.CostBasis})
+		if errors.Is(err, storage.ErrInvalidPosition) {
+			return nil, err
+		}
+		if err != nil {
+			results = append(results, storage.BulkUpdateResult{ID: u.ID, Status: storage.BulkNotFound})
+			continue
+		}
+		results = append(results, storage.BulkUpdateResult{ID: u.ID, Status: storage.BulkUpdated, Holding: holding})
+	}
+	return results, nil
+}
+
+func (s *fakeStore) DeleteHolding(ctx context.Context, id int64) error {
+	if _, err := s.GetHoldingByID(ctx, id); err != nil {
+		return err
+	}
+	now := time.Now()
+	s.holdings[id].DeletedAt = &now
+	return nil
+}
+
+func (s *fakeStore) HardDeleteHolding(ctx context.Context, id int64) error {
+	if s.err != nil {
+		return s.err
+	}
+	if _, ok := s.holdings[id]; !ok {
+		return storage.ErrHoldingNotFound
+	}
+	delete(s.holdings, id)
+	return nil
+}
+
+func (s *fakeStore) RestoreHolding(ctx context.Context, id int64) (*storage.Holding, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	h, ok := s.holdings[id]
+	if !ok || h.DeletedAt == nil {
+		return nil, storage.ErrHoldingNotFound
+	}
+	h.DeletedAt = nil
+	return s.GetHoldingByID(ctx, id)
+}
+
+func (s *fakeStore) UpsertSnapshot(ctx context.Context, snap storage.Snapshot) error {
+	if s.err != nil {
+		return s.err
+	}
+	date := snap.Date.Format(time.DateOnly)
+	if existing, ok := s.snapshots[date]; ok && existing.Currency != "" && snap.Currency == "" {
+		return fmt.Errorf("saving snapshot %s: %w", date, storage.ErrSnapshotConverted)
+	}
+	s.snapshots[date] = snap
+	return nil
+}
+
+func (s *fakeStore) GetSnapshots(ctx context.Context, from, to time.Time) ([]storage.Snapshot, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	snapshots := []storage.Snapshot{}
+	for _, snap := range s.snapshots {
+		if !snap.Date.Before(from) && !snap.Date.After(to) {
+			snapshots = append(snapshots, snap)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Date.Before(snapshots[j].Date) })
+	return snapshots, nil
+}
+
+func (s *fakeStore) GetPeakSnapshot(ctx context.Context, currency string) (storage.Snapshot, int, error) {
+	all, err := s.GetSnapshots(ctx, time.Time{}, time.Now().AddDate(100, 0, 0))
+	if err != nil {
+		return storage.Snapshot{}, 0, err
+	}
+	var snapshots []storage.Snapshot
+	for _, snap := range all {
+		if snap.Currency == currency {
+			snapshots = append(snapshots, snap)
+		}
+	}
+	if len(snapshots) == 0 {
+		return storage.Snapshot{}, 0, nil
+	}
+	peak := snapshots[0]
+	for _, snap := range snapshots[1:] {
+		if snap.TotalValue > peak.TotalValue {
+			peak = snap
+		}
+	}
+	return peak, len(snapshots), nil
+}
+
+func (s *fakeStore) GetWatchlist(ctx context.Context) ([]storage.WatchItem, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return append([]storage.WatchItem{}, s.watchlist...), nil
+}
+
+func (s *fakeStore) GetWatchlistByType(ctx context.Context, holdingType storage.HoldingType) ([]storage.WatchItem, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	items := []storage.WatchItem{}
+	for _, item := range s.watchlist {
+		if item.Type == holdingType {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (s *fakeStore) AddWatchItem(ctx context.Context, req storage.WatchRequest) (*storage.WatchItem, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, item := range s.watchlist {
+		if item.Type == req.Type && item.Symbol == req.Symbol {
+			return nil, storage.ErrWatchItemExists
+		}
+	}
+	s.nextID++
+	item := storage.WatchItem{ID: s.nextID, Type: req.Type, Symbol: req.Symbol, CreatedAt: time.Now()}
+	s.watchlist = append(s.watchlist, item)
+	return &item, nil
+}
+
+func (s *fakeStore) RemoveWatchItem(ctx context.Context, id int64) error {
+	if s.err != nil {
+		return s.err
+	}
+	for i, item := range s.watchlist {
+		if item.ID == id {
+			s.watchlist = append(s.watchlist[:i], s.watchlist[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrWatchItemNotFound
+}
+
+func (s *fakeStore) GetIdempotentResponse(ctx context.Context, key string) (*storage.IdempotentResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	resp, ok := s.idempotency[key]
+	if !ok {
+		return nil, storage.ErrIdempotencyKeyNotFound
+	}
+	return &resp, nil
+}
+
+func (s *fakeStore) SaveIdempotentResponse(ctx context.Context, key string, resp storage.IdempotentResponse, ttl time.Duration) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.idempotency[key] = resp
+	return nil
+}
+
+func (s *fakeStore) PruneSnapshots(ctx context.Context, before time.Time, keepMonthEnds bool) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	monthEnds := make(map[string]string)
+	for date := range s.snapshots {
+		if month := date[:7]; date > monthEnds[month] {
+			monthEnds[month] = date
+		}
+	}
+	var deleted int64
+	for date := range s.snapshots {
+		if date >= before.Format(time.DateOnly) || (keepMonthEnds && monthEnds[date[:7]] == date) {
+			continue
+		}
+		delete(s.snapshots, date)
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (s *fakeStore) Reset(ctx context.Context, seed []storage.CreateHoldingRequest) (storage.ResetResult, error) {
+	if s.err != nil {
+		return storage.ResetResult{}, s.err
+	}
+	result := storage.ResetResult{HoldingsRemoved: int64(len(s.holdings)), SnapshotsRemoved: int64(len(s.snapshots))}
+	clear(s.holdings)
+	clear(s.snapshots)
+	clear(s.idempotency)
+	for _, req := range seed {
+		if _, err := s.CreateHolding(ctx, req); err == nil {
+			result.HoldingsSeeded++
+		}
+	}
+	return result, nil
+}
+
+func (s *fakeStore) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func (s *fakeStore) GetSnapshotHistory(ctx context.Context, q storage.SnapshotQuery) ([]storage.Snapshot, error) {
+	snapshots, err := s.GetSnapshots(ctx, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := func(d time.Time) string {
+		switch q.Granularity {
+		case storage.GranularityWeekly:
+			return d.AddDate(0, 0, -(int(d.Weekday())+6)%7).Format(time.DateOnly)
+		case storage.GranularityMonthly:
+			return d.Format("2006-01")
+		}
+		return d.Format(time.DateOnly)
+	}
+
+	// Oldest first, so a later snapshot in the same bucket replaces the earlier
+	var last []storage.Snapshot
+	for _, snap := range snapshots {
+		if n := len(last); n > 0 && bucket(last[n-1].Date) == bucket(snap.Date) {
+			last[n-1] = snap
+			continue
+		}
+		last = append(last, snap)
+	}
+	if q.Limit > 0 && len(last) > q.Limit {
+		last = last[len(last)-q.Limit:]
+	}
+	return append([]storage.Snapshot{}, last...), nil
+}
+
+func TestCreateHoldingConflicts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"new holding", `{"type":"fund","symbol":"TI2","quantity":5}`, http.StatusCreated},
+		{"live duplicate", `{"type":"fund","symbol":"KUT","quantity":5}`, http.StatusConflict},
+		{"duplicate in trash", `{"type":"crypto","symbol":"ETHUSDT","quantity":1}`, http.StatusConflict},
+		{"same symbol, other type", `{"type":"crypto","symbol":"KUT","quantity":1}`, http.StatusCreated},
+		{"cost basis and avg price", `{"type":"fund","symbol":"AH5","quantity":1,"cost_basis":2,"avg_price":2}`, http.StatusBadRequest},
+		{"bare coin is normalized", `{"type":"crypto","symbol":"btc","quantity":1}`, http.StatusCreated},
+		{"normalized duplicate", `{"type":"crypto","symbol":"eth-usdt","quantity":1}`, http.StatusConflict},
+		{"quote asset alone", `{"type":"crypto","symbol":"USDT","quantity":1}`, http.StatusBadRequest},
+		{"short crypto", `{"type":"crypto","symbol":"BTCUSDT","quantity":-1,"cost_basis":-50}`, http.StatusCreated},
+		{"short at avg price", `{"type":"crypto","symbol":"BTCUSDT","quantity":-1,"avg_price":50}`, http.StatusCreated},
+		{"short with positive cost", `{"type":"crypto","symbol":"BTCUSDT","quantity":-1,"cost_basis":50}`, http.StatusBadRequest},
+		{"short fund", `{"type":"fund","symbol":"TI2","quantity":-5}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10},
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "ETHUSDT", Quantity: 1},
+			)
+			store.DeleteHolding(context.Background(), 2)
+
+			h := NewHandler(config.NewHolder(&config.Config{}), nil, nil, nil, store)
+			r := gin.New()
+			r.POST("/api/holdings", h.CreateHolding)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/holdings", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestCreateHoldingInferCostBasis(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		configDefault bool
+		body          string
+		wantStatus    int
+		wantCostBasis float64
+	}{
+		{"asked for", false, `{"type":"fund","symbol":"KUT","quantity":4,"infer_cost_basis":true}`, http.StatusCreated, 49.38},
+		{"config default", true, `{"type":"crypto","symbol":"BTCUSDT","quantity":0.5}`, http.StatusCreated, 30000},
+		{"opted out", true, `{"type":"fund","symbol":"KUT","quantity":4,"infer_cost_basis":false}`, http.StatusCreated, 0},
+		{"cost basis given", true, `{"type":"fund","symbol":"KUT","quantity":4,"cost_basis":40}`, http.StatusCreated, 40},
+		{"not configured", false, `{"type":"fund","symbol":"KUT","quantity":4}`, http.StatusCreated, 0},
+		{"no live price", false, `{"type":"fund","symbol":"TI2","quantity":4,"infer_cost_basis":true}`, http.StatusServiceUnavailable, 0},
+		{"conflicting cost", false, `{"type":"fund","symbol":"KUT","quantity":4,"cost_basis":40,"infer_cost_basis":true}`, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{InferCostBasis: tt.configDefault}
+			h := NewHandler(config.NewHolder(cfg),
+				&staticProvider{prices: map[string]float64{"KUT": 12.345}},
+				&staticProvider{prices: map[string]float64{"BTCUSDT": 60000}},
+				nil, newFakeStore())
+			r := gin.New()
+			r.POST("/api/holdings", h.CreateHolding)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/holdings", strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			var holding storage.Holding
+			if err := json.Unmarshal(w.Body.Bytes(), &holding); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if holding.CostBasis != tt.wantCostBasis {
+				t.Errorf("cost_basis = %v, want %v", holding.CostBasis, tt.wantCostBasis)
+			}
+		})
+	}
+}
+
+func TestCreateHoldingAvgPrice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		body          string
+		wantStatus    int
+		wantCostBasis float64
+	}{
+		{"derives cost basis", `{"type":"fund","symbol":"KUT","quantity":10,"avg_price":2.5}`, http.StatusCreated, 25},
+		{"short position", `{"type":"crypto","symbol":"BTCUSDT","quantity":-2,"avg_price":100}`, http.StatusCreated, -200},
+		{"both fields", `{"type":"fund","symbol":"KUT","quantity":10,"cost_basis":25,"avg_price":2.5}`, http.StatusBadRequest, 0},
+		{"zero quantity", `{"type":"fund","symbol":"KUT","quantity":0,"avg_price":2.5}`, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(config.NewHolder(&config.Config{}), nil, nil, nil, newFakeStore())
+			r := gin.New()
+			r.POST("/api/holdings", h.CreateHolding)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/holdings", strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusCreated {
+				var resp ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error.Code != CodeValidationFailed {
+					t.Errorf("body = %s, want a %s error", w.Body, CodeValidationFailed)
+				}
+				return
+			}
+			var holding storage.Holding
+			if err := json.Unmarshal(w.Body.Bytes(), &holding); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if holding.CostBasis != tt.wantCostBasis {
+				t.Errorf("cost_basis = %v, want %v", holding.CostBasis, tt.wantCostBasis)
+			}
+		})
+	}
+}
+
+func TestCreateHoldingInfersType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantType     storage.HoldingType
+		wantSymbol   string
+		wantInferred bool
+	}{
+		{"fund code", `{"symbol":"KUT","quantity":1}`, http.StatusCreated, storage.HoldingTypeFund, "KUT", true},
+		{"lowercase fund code", `{"symbol":"kut","quantity":1}`, http.StatusCreated, storage.HoldingTypeFund, "KUT", true},
+		{"crypto pair", `{"symbol":"BTCUSDT","quantity":1}`, http.StatusCreated, storage.HoldingTypeCrypto, "BTCUSDT", true},
+		{"separated pair", `{"symbol":"eth/try","quantity":1}`, http.StatusCreated, storage.HoldingTypeCrypto, "ETHTRY", true},
+		{"unknown fund", `{"symbol":"XYZ","quantity":1}`, http.StatusBadRequest, "", "", false},
+		{"unknown pair", `{"symbol":"DOGEUSDT","quantity":1}`, http.StatusBadRequest, "", "", false},
+		{"explicit type wins", `{"type":"crypto","symbol":"KUT","quantity":1}`, http.StatusCreated, storage.HoldingTypeCrypto, "KUTUSDT", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(config.NewHolder(&config.Config{}),
+				&staticProvider{prices: map[string]float64{"KUT": 12}},
+				&staticProvider{prices: map[string]float64{"BTCUSDT": 60000, "ETHTRY": 90000}},
+				nil, newFakeStore())
+			r := gin.New()
+			r.POST("/api/holdings", h.CreateHolding)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/holdings", strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			var created CreatedHolding
+			if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if created.Type != tt.wantType || created.Symbol != tt.wantSymbol || created.TypeInferred != tt.wantInferred {
+				t.Errorf("created %s %s (inferred %v), want %s %s (inferred %v)",
+					created.Type, created.Symbol, created.TypeInferred, tt.wantType, tt.wantSymbol, tt.wantInferred)
+			}
+		})
+	}
+}
+
+func TestCreateHoldingIdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newFakeStore()
+	h := NewHandler(config.NewHolder(&config.Config{}), nil, nil, nil, store)
+	r := gin.New()
+	r.POST("/api/holdings", h.CreateHolding)
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/holdings", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("k1", `{"type":"crypto","symbol":"btc","quantity":1}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201: %s", first.Code, first.Body)
+	}
+
+	// An equivalent retry replays the original response
+	retry := post("k1", `{"type":"crypto","symbol":"BTC-USDT","quantity":1}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want 201 %s", retry.Code, retry.Body, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry not marked as replayed")
+	}
+
+	// Without the key the duplicate is rejected as before
+	if w := post("", `{"type":"crypto","symbol":"btc","quantity":1}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate without key status = %d, want 409", w.Code)
+	}
+
+	// Failed attempts aren't recorded, so a corrected retry can succeed
+	if w := post("k2", `{"type":"crypto","symbol":"USDT","quantity":1}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid status = %d, want 400", w.Code)
+	}
+	if w := post("k2", `{"type":"crypto","symbol":"eth","quantity":1}`); w.Code != http.StatusCreated {
+		t.Errorf("corrected retry status = %d, want 201: %s", w.Code, w.Body)
+	}
+
+	holdings, _ := store.GetAllHoldings(context.Background())
+	if len(holdings) != 2 {
+		t.Errorf("store has %d holdings, want 2", len(holdings))
+	}
+	if w := post(strings.Repeat("k", 256), `{"type":"fund","symbol":"KUT","quantity":1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized key status = %d, want 400", w.Code)
+	}
+
+	// The key can't be reused for another request
+	w := post("k1", `{"type":"crypto","symbol":"btc","quantity":2}`)
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); w.Code != http.StatusUnprocessableEntity || err != nil || resp.Error.Code != CodeIdempotencyKeyUsed {
+		t.Errorf("reused key = %d %s, want 422 %s", w.Code, w.Body, CodeIdempotencyKeyUsed)
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("original response marked as replayed")
+	}
+}
+
+func TestBulkUpdateHoldings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		body         string
+		storeErr     error
+		wantStatus   int
+		wantStatuses []string
+	}{
+		{"updates and missing", `[{"id":1,"quantity":12},{"id":99,"quantity":1},{"id":2,"cost_basis":50},{"id":3,"quantity":4}]`, nil, http.StatusOK,
+			[]string{storage.BulkUpdated, storage.BulkNotFound, storage.BulkUpdated, storage.BulkNotFound}},
+		{"empty batch", `[]`, nil, http.StatusBadRequest, nil},
+		{"nothing to change", `[{"id":1}]`, nil, http.StatusBadRequest, nil},
+		{"negative fund quantity", `[{"id":1,"quantity":-1}]`, nil, http.StatusBadRequest, nil},
+		{"short keeping a positive cost", `[{"id":2,"quantity":-1}]`, nil, http.StatusBadRequest, nil},
+		{"not a list", `{"id":1,"quantity":2}`, nil, http.StatusBadRequest, nil},
+		{"storage failure", `[{"id":1,"quantity":12}]`, errors.New("database is locked"), http.StatusInternalServerError, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 1, CostBasis: 40},
+				storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "ETHUSDT", Quantity: 1},
+			)
+			store.DeleteHolding(context.Background(), 3)
+			store.err = tt.storeErr
+
+			h := NewHandler(config.NewHolder(&config.Config{}), nil, nil, nil, store)
+			r := gin.New()
+			r.PUT("/api/holdings/bulk", h.BulkUpdateHoldings)
+			r.PUT("/api/holdings/:id", h.UpdateHolding)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/holdings/bulk", strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp BulkUpdateResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if len(resp.Results) != len(tt.wantStatuses) {
+				t.Fatalf("results = %+v, want statuses %v", resp.Results, tt.wantStatuses)
+			}
+			for i, want := range tt.wantStatuses {
+				if resp.Results[i].Status != want {
+					t.Errorf("results[%d].status = %q, want %q", i, resp.Results[i].Status, want)
+				}
+			}
+			if resp.Updated != 2 || resp.NotFound != 2 {
+				t.Errorf("updated/not_found = %d/%d, want 2/2", resp.Updated, resp.NotFound)
+			}
+
+			kut, _ := store.GetHoldingByID(context.Background(), 1)
+			btc, _ := store.GetHoldingByID(context.Background(), 2)
+			if kut.Quantity != 12 || kut.CostBasis != 20 || btc.Quantity != 1 || btc.CostBasis != 50 {
+				t.Errorf("KUT %v/%v, BTCUSDT %v/%v; want 12/20 and 1/50", kut.Quantity, kut.CostBasis, btc.Quantity, btc.CostBasis)
+			}
+		})
+	}
+}
+
+func TestHoldingTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, Tags: []string{"retirement", "gold"}},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "TTE", Quantity: 5, Tags: []string{"retirement"}},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "AAK", Quantity: 2},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 3, "TTE": 2, "AAK": 10}}, nil, nil, store)
+	r := gin.New()
+	r.GET("/api/holdings", h.GetHoldings)
+	r.PATCH("/api/holdings/:id", h.UpdateHolding)
+	r.GET("/api/portfolio/breakdown", h.GetBreakdown)
+
+	filter := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"KUT", "TTE", "AAK"}},
+		{"?tag=retirement", []string{"KUT", "TTE"}},
+		{"?tag=%20Gold", []string{"KUT"}},
+		{"?tag=speculative", []string{}},
+	}
+	for _, tt := range filter {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/holdings"+tt.query, nil))
+		var resp struct{ Holdings []storage.Holding }
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("GET /api/holdings%s: decoding response: %v", tt.query, err)
+		}
+		got := []string{}
+		for _, holding := range resp.Holdings {
+			got = append(got, holding.Symbol)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("GET /api/holdings%s = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/holdings/3", strings.NewReader(`{"tags":["Speculative"],"notes":"Small bet"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH tags status = %d: %s", w.Code, w.Body)
+	}
+	if aak, _ := store.GetHoldingByID(context.Background(), 3); strings.Join(aak.Tags, ",") != "speculative" || aak.Notes != "Small bet" {
+		t.Errorf("AAK tags/notes = %v/%q, want [speculative]/\"Small bet\"", aak.Tags, aak.Notes)
+	}
+
+	// KUT (30) counts toward both of its tags
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/portfolio/breakdown?by=tag", nil))
+	var breakdown BreakdownResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &breakdown); err != nil {
+		t.Fatalf("decoding breakdown: %v", err)
+	}
+	var groups []string
+	for _, g := range breakdown.Groups {
+		groups = append(groups, fmt.Sprintf("%s=%g", g.Group, g.Value))
+	}
+	if want := "retirement=40,gold=30,speculative=20"; strings.Join(groups, ",") != want {
+		t.Errorf("groups by tag = %v, want %s", groups, want)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/portfolio/breakdown?by=currency", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("by=currency status = %d, want 400", w.Code)
+	}
+}
+
+// blockingCreateStore holds CreateHolding until release is closed
+type blockingCreateStore struct {
+	*fakeStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingCreateStore) CreateHolding(ctx context.Context, req storage.CreateHoldingRequest) (*storage.Holding, error) {
+	close(s.started)
+	<-s.release
+	return s.fakeStore.CreateHolding(ctx, req)
+}
+
+func TestCreateHoldingIdempotencyKeyConcurrent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &blockingCreateStore{fakeStore: newFakeStore(), started: make(chan struct{}), release: make(chan struct{})}
+	h := NewHandler(config.NewHolder(&config.Config{}), nil, nil, nil, store)
+	r := gin.New()
+	r.POST("/api/holdings", h.CreateHolding)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/holdings", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "k1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	const body = `{"type":"crypto","symbol":"btc","quantity":1}`
+	results := make([]*httptest.ResponseRecorder, 3)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0] = post(body)
+	}()
+	<-store.started
+	// Join the attempt in flight, or replay it once recorded
+	for i, b := range []string{body, `{"type":"crypto","symbol":"eth","quantity":1}`} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i+1] = post(b)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(store.release)
+	wg.Wait()
+
+	creator, retry, other := results[0], results[1], results[2]
+	if creator.Code != http.StatusCreated || creator.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("creator = %d (replayed %q), want an unmarked 201", creator.Code, creator.Header().Get("Idempotent-Replayed"))
+	}
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != creator.Body.String() {
+		t.Errorf("concurrent retry = %d %s (replayed %q), want the creator's response marked as replayed",
+			retry.Code, retry.Body, retry.Header().Get("Idempotent-Replayed"))
+	}
+	if other.Code != http.StatusUnprocessableEntity {
+		t.Errorf("concurrent request with another body = %d %s, want 422", other.Code, other.Body)
+	}
+	if holdings, _ := store.GetAllHoldings(context.Background()); len(holdings) != 1 {
+		t.Errorf("store has %d holdings, want 1", len(holdings))
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAverageDownPct is how far below the current average price the
+// suggestions aim when the request doesn't say
+const defaultAverageDownPct = 10
+
+// AverageDownSuggestion is how much more of a holding trading below its
+// average price would bring the average down to TargetAvgPrice. Prices and
+// costs are in Currency.
+type AverageDownSuggestion struct {
+	Type           storage.HoldingType `json:"type"`
+	Symbol         string              `json:"symbol"`
+	Currency       string              `json:"currency"`
+	Price          float64             `json:"price"` // Current price, at which the extra quantity is bought
+	Quantity       float64             `json:"quantity"`
+	AvgPrice       float64             `json:"avg_price"`
+	PnLPct         float64             `json:"pnl_pct"`
+	TargetAvgPrice float64             `json:"target_avg_price"`
+	BuyQuantity    *float64            `json:"buy_quantity"` // Null when the target is at or below the current price, so no amount reaches it
+	BuyCost        *float64            `json:"buy_cost"`     // BuyQuantity * Price
+}
+
+// SuggestionsResponse lists average-down suggestions, deepest loss first
+type SuggestionsResponse struct {
+	TargetPct   float64                 `json:"target_pct"`
+	Suggestions []AverageDownSuggestion `json:"suggestions"`
+}
+
+// GetSuggestions handles GET /api/portfolio/suggestions
+//
+// For each holding priced below its average buy price, works out the
+// quantity to buy at the current price that would bring the average
+// target_pct percent (default 10) below where it is now. Informational only.
+func (h *Handler) GetSuggestions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	targetPct := float64(defaultAverageDownPct)
+	if s := c.Query("target_pct"); s != "" {
+		pct, err := strconv.ParseFloat(s, 64)
+		if err != nil || pct <= 0 || pct >= 100 {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("target_pct must be a number between 0 and 100, got %q", s))
+			return
+		}
+		targetPct = pct
+	}
+
+	summary, err := h.cachedPortfolioSummary(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch holdings")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuggestionsResponse{
+		TargetPct:   targetPct,
+		Suggestions: averageDownSuggestions(summary, targetPct),
+	})
+}
+
+// averageDownSuggestions returns a suggestion for every priced holding of
+// summary trading below its average price
+func averageDownSuggestions(summary PortfolioSummary, targetPct float64) []AverageDownSuggestion {
+	suggestions := make([]AverageDownSuggestion, 0)
+	for _, f := range summary.Funds {
+		if s, ok := averageDown(f.Price, f.Quantity, f.CostBasis, targetPct); ok {
+			s.Type, s.Symbol, s.Currency, s.PnLPct = storage.HoldingTypeFund, f.Code, "TRY", f.PnLPct
+			suggestions = append(suggestions, s)
+		}
+	}
+	for _, cr := range summary.Cryptos {
+		if cr.Delisted {
+			continue
+		}
+		if s, ok := averageDown(cr.Price, cr.Quantity, cr.CostBasis, targetPct); ok {
+			s.Type, s.Symbol, s.Currency, s.PnLPct = storage.HoldingTypeCrypto, cr.Symbol, cr.Currency, cr.PnLPct
+			suggestions = append(suggestions, s)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].PnLPct < suggestions[j].PnLPct })
+	return suggestions
+}
+
+// averageDown works out the buy at price that brings a position of quantity
+// bought for costBasis to an average price targetPct percent lower. It
+// reports false when the position isn't below its average price.
+//
+// Buying x at price p moves the average to (costBasis + x*p) / (quantity + x);
+// setting that to the target t gives x = (costBasis - t*quantity) / (t - p).
+func averageDown(price, quantity, costBasis, targetPct float64) (AverageDownSuggestion, bool) {
+	if price <= 0 || quantity <= 0 || costBasis <= 0 {
+		return AverageDownSuggestion{}, false
+	}
+	avg := costBasis / quantity
+	if price >= avg {
+		return AverageDownSuggestion{}, false
+	}
+
+	target := avg * (1 - targetPct/100)
+	s := AverageDownSuggestion{
+		Price:          price,
+		Quantity:       quantity,
+		AvgPrice:       avg,
+		TargetAvgPrice: target,
+	}
+	if target > price {
+		buy := (costBasis - target*quantity) / (target - price)
+		cost := buy * price
+		s.BuyQuantity, s.BuyCost = &buy, &cost
+	}
+	return s, true
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAverageDown(t *testing.T) {
+	tests := []struct {
+		name      string
+		price     float64
+		quantity  float64
+		costBasis float64
+		targetPct float64
+		wantOK    bool
+		wantBuy   *float64
+	}{
+		// Average 10, target 9: (100 + 5x) / (10 + x) = 9 gives x = 2.5
+		{"reachable", 5, 10, 100, 10, true, ptrFloat(2.5)},
+		{"target at the price", 9, 10, 100, 10, true, nil},
+		{"target below the price", 9.5, 10, 100, 10, true, nil},
+		{"in profit", 12, 10, 100, 10, false, nil},
+		{"at average", 10, 10, 100, 10, false, nil},
+		{"no cost basis", 5, 10, 0, 10, false, nil},
+		{"no price", 0, 10, 100, 10, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := averageDown(tt.price, tt.quantity, tt.costBasis, tt.targetPct)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			switch {
+			case tt.wantBuy == nil && s.BuyQuantity != nil:
+				t.Errorf("buy_quantity = %v, want null", *s.BuyQuantity)
+			case tt.wantBuy != nil && (s.BuyQuantity == nil || math.Abs(*s.BuyQuantity-*tt.wantBuy) > 1e-9):
+				t.Errorf("buy_quantity = %v, want %v", s.BuyQuantity, *tt.wantBuy)
+			case tt.wantBuy != nil:
+				newAvg := (tt.costBasis + *s.BuyCost) / (tt.quantity + *s.BuyQuantity)
+				if math.Abs(newAvg-s.TargetAvgPrice) > 1e-9 {
+					t.Errorf("average after buying = %v, want target %v", newAvg, s.TargetAvgPrice)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSuggestions(t *testing.T) {
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 100},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "TI2", Quantity: 10, CostBasis: 100},
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 1, CostBasis: 60000},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 8, "TI2": 12}},
+		&staticProvider{prices: map[string]float64{"BTCUSDT": 30000}},
+		nil, store)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/suggestions", h.GetSuggestions)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/suggestions?target_pct=20", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var resp SuggestionsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	// TI2 is in profit; BTC is the deeper loss, and its target of 48000
+	// takes (60000 - 48000) / (48000 - 30000) more coins
+	if len(resp.Suggestions) != 2 || resp.Suggestions[0].Symbol != "BTCUSDT" || resp.Suggestions[1].Symbol != "KUT" {
+		t.Fatalf("suggestions = %+v, want BTCUSDT then KUT", resp.Suggestions)
+	}
+	btc := resp.Suggestions[0]
+	if btc.Currency != "USD" || btc.BuyQuantity == nil || math.Abs(*btc.BuyQuantity-2.0/3) > 1e-9 {
+		t.Errorf("BTCUSDT suggestion = %+v, want 0.667 more in USD", btc)
+	}
+
+	for _, query := range []string{"?target_pct=0", "?target_pct=100", "?target_pct=abc"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/suggestions"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// WatchlistResponse lists the watched symbols
+type WatchlistResponse struct {
+	Watchlist []storage.WatchItem `json:"watchlist"`
+}
+
+// GetWatchlist handles GET /api/watchlist
+func (h *Handler) GetWatchlist(c *gin.Context) {
+	items, err := h.storage.GetWatchlist(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to fetch watchlist")
+		return
+	}
+	c.JSON(http.StatusOK, WatchlistResponse{Watchlist: items})
+}
+
+// AddWatchItem handles POST /api/watchlist
+func (h *Handler) AddWatchItem(c *gin.Context) {
+	var req storage.WatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidBody(c, err)
+		return
+	}
+	symbol, err := h.normalizeSymbol(req.Type, req.Symbol)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, err.Error())
+		return
+	}
+	req.Symbol = symbol
+
+	item, err := h.storage.AddWatchItem(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, storage.ErrWatchItemExists) {
+			respondError(c, http.StatusConflict, CodeWatchItemExists, "Symbol is already watched")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to add to watchlist")
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// RemoveWatchItem handles DELETE /api/watchlist/:id
+func (h *Handler) RemoveWatchItem(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "Invalid watchlist item ID")
+		return
+	}
+
+	if err := h.storage.RemoveWatchItem(c.Request.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrWatchItemNotFound) {
+			respondError(c, http.StatusNotFound, CodeWatchItemNotFound, "Watchlist item not found")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to remove from watchlist")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// withWatched appends zero-quantity placeholders to holdings for watched
+// symbols of holdingType not already held, so they are priced alongside
+// them. It also returns the set of watched symbols.
+func (h *Handler) withWatched(ctx context.Context, holdingType storage.HoldingType, holdings []storage.Holding) ([]storage.Holding, map[string]bool, error) {
+	items, err := h.storage.GetWatchlistByType(ctx, holdingType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	held := make(map[string]bool, len(holdings))
+	for _, holding := range holdings {
+		held[holding.Symbol] = true
+	}
+	watched := make(map[string]bool, len(items))
+	for _, item := range items {
+		watched[item.Symbol] = true
+		if !held[item.Symbol] {
+			holdings = append(holdings, storage.Holding{Type: holdingType, Symbol: item.Symbol})
+		}
+	}
+	return holdings, watched, nil
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ferhatkunduraci/prism/internal/config"
+	"github.com/ferhatkunduraci/prism/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestWatchlist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newFakeStore(
+		storage.CreateHoldingRequest{Type: storage.HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20},
+	)
+	h := NewHandler(config.NewHolder(&config.Config{}),
+		&staticProvider{prices: map[string]float64{"KUT": 3, "TI2": 7}},
+		&staticProvider{prices: map[string]float64{"ETHUSDT": 2000}},
+		nil, store)
+	r := gin.New()
+	r.GET("/api/watchlist", h.GetWatchlist)
+	r.POST("/api/watchlist", h.AddWatchItem)
+	r.DELETE("/api/watchlist/:id", h.RemoveWatchItem)
+	r.GET("/api/funds", h.GetFunds)
+	r.GET("/api/crypto", h.GetCryptos)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	for _, body := range []string{`{"type":"fund","symbol":"TI2"}`, `{"type":"fund","symbol":"KUT"}`, `{"type":"crypto","symbol":"eth"}`} {
+		if w := do(http.MethodPost, "/api/watchlist", body); w.Code != http.StatusCreated {
+			t.Fatalf("watching %s: status = %d, want 201: %s", body, w.Code, w.Body)
+		}
+	}
+	if w := do(http.MethodPost, "/api/watchlist", `{"type":"crypto","symbol":"ETH-USDT"}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate status = %d, want 409", w.Code)
+	}
+
+	var funds struct {
+		Funds []FundPrice `json:"funds"`
+	}
+	w := do(http.MethodGet, "/api/funds", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &funds); err != nil {
+		t.Fatalf("decoding funds: %v", err)
+	}
+	want := map[string]FundPrice{
+		"KUT": {Price: 3, Quantity: 10, Watched: true},
+		"TI2": {Price: 7, Quantity: 0, Watched: true},
+	}
+	if len(funds.Funds) != len(want) {
+		t.Fatalf("funds = %+v, want KUT and TI2", funds.Funds)
+	}
+	for _, f := range funds.Funds {
+		if w := want[f.Code]; f.Price != w.Price || f.Quantity != w.Quantity || f.Watched != w.Watched {
+			t.Errorf("%s price/quantity/watched = %v/%v/%v, want %v/%v/%v",
+				f.Code, f.Price, f.Quantity, f.Watched, w.Price, w.Quantity, w.Watched)
+		}
+	}
+
+	var cryptos struct {
+		Cryptos []CryptoPrice `json:"cryptos"`
+	}
+	w = do(http.MethodGet, "/api/crypto", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &cryptos); err != nil {
+		t.Fatalf("decoding cryptos: %v", err)
+	}
+	if len(cryptos.Cryptos) != 1 || cryptos.Cryptos[0].Symbol != "ETHUSDT" || !cryptos.Cryptos[0].Watched {
+		t.Errorf("cryptos = %+v, want watched ETHUSDT", cryptos.Cryptos)
+	}
+
+	var list WatchlistResponse
+	w = do(http.MethodGet, "/api/watchlist", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decoding watchlist: %v", err)
+	}
+	if len(list.Watchlist) != 3 {
+		t.Fatalf("watchlist = %+v, want 3 items", list.Watchlist)
+	}
+	path := "/api/watchlist/" + strconv.FormatInt(list.Watchlist[0].ID, 10)
+	if w := do(http.MethodDelete, path, ""); w.Code != http.StatusNoContent {
+		t.Errorf("remove status = %d, want 204", w.Code)
+	}
+	if w := do(http.MethodDelete, path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second remove status = %d, want 404", w.Code)
+	}
+}
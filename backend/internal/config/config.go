@@ -1,10 +1,20 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ferhatkunduraci/prism/internal/providers"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,19 +23,109 @@ type Config struct {
 	Server   ServerConfig   `yaml:"server"`
 	TEFAS    TEFASConfig    `yaml:"tefas"`
 	Crypto   CryptoConfig   `yaml:"crypto"`
+	FX       FXConfig       `yaml:"fx"`
 	Database DatabaseConfig `yaml:"database"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Notify   NotifyConfig   `yaml:"notify"`
+	Mock     MockConfig     `yaml:"mock"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	Snapshot SnapshotConfig `yaml:"snapshot"`
+
+	// InferCostBasis values holdings entered without a cost basis, in config
+	// or via the API, at the current price so they start at break-even
+	InferCostBasis bool `yaml:"infer_cost_basis"`
+
+	// Currencies names the currency each holding type's subtotals are
+	// reported in, and DisplayCurrency the one the portfolio totals are
+	// converted to before they are added up
+	Currencies      CurrencyConfig `yaml:"currencies"`
+	DisplayCurrency string         `yaml:"display_currency"` // Optional: ISO 4217 code (default "TRY")
+}
+
+// CurrencyConfig holds the currency each holding type is totalled in
+type CurrencyConfig struct {
+	Fund   string `yaml:"fund"`   // Optional: currency of the TEFAS subtotals (default "TRY", which fund prices are in)
+	Crypto string `yaml:"crypto"` // Optional: currency of the crypto subtotals (default "USD")
 }
 
+// isCurrencyCode reports whether code has the form of an ISO 4217 code:
+// three uppercase letters
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxRequestTimeout caps server.request_timeout. The HTTP server's write
+// timeout is set past it at startup, so a reload raising request_timeout
+// still has its timeouts reported rather than the connection dropped.
+const MaxRequestTimeout = 5 * time.Minute
+
 // ServerConfig holds HTTP server settings
 type ServerConfig struct {
 	Port        string   `yaml:"port"`
 	CORSOrigins []string `yaml:"cors_origins"`
+	APIKey      string   `yaml:"api_key"`    // Optional: require "Authorization: Bearer <key>" on /api routes
+	RateLimit   int      `yaml:"rate_limit"` // Optional: max requests per minute per client IP (0 = unlimited)
+
+	TrustedProxies []string `yaml:"trusted_proxies"` // Optional: proxy IPs or CIDRs whose X-Forwarded-For names the client IP (default: none, the peer address is used)
+
+	CORSAllowCredentials bool          `yaml:"cors_allow_credentials"` // Optional: allow cookies on cross-origin requests; requires cors_origins
+	CORSMaxAge           time.Duration `yaml:"cors_max_age"`           // Optional: how long browsers cache preflight results (default 12h, negative omits the header)
+
+	RequestTimeout  time.Duration `yaml:"request_timeout"`   // Optional: overall deadline for API handlers (default 30s)
+	SummaryCacheTTL time.Duration `yaml:"summary_cache_ttl"` // Optional: how long a computed portfolio summary is reused (default 10s, negative disables)
+	IdempotencyTTL  time.Duration `yaml:"idempotency_ttl"`   // Optional: how long a response is replayed for a repeated Idempotency-Key (default 24h)
+
+	Compression bool           `yaml:"compression"` // Optional: gzip responses for clients that accept it
+	Rounding    RoundingConfig `yaml:"rounding"`    // Optional: round numbers in API responses for display
+
+	MaxBodyBytes     int64 `yaml:"max_body_bytes"`      // Optional: largest request body accepted (default 1MB)
+	MaxBulkBodyBytes int64 `yaml:"max_bulk_body_bytes"` // Optional: largest body accepted by PUT /api/holdings/bulk (default 10MB)
+}
+
+// RoundingConfig holds the precision numbers in API responses are rounded to.
+// Money amounts are always rounded to the cent and percentages to 2 decimals.
+type RoundingConfig struct {
+	Enabled             bool `yaml:"enabled"`
+	FundPriceDecimals   int  `yaml:"fund_price_decimals"`   // Optional: decimals of fund prices (default 6, negative leaves them unrounded)
+	CryptoPriceDecimals int  `yaml:"crypto_price_decimals"` // Optional: decimals of crypto prices (default 8, negative leaves them unrounded)
 }
 
 // TEFASConfig holds TEFAS provider settings
 type TEFASConfig struct {
 	Headless bool          `yaml:"headless"`
+	CacheTTL time.Duration `yaml:"cache_ttl"` // Optional: how long prices are cached (e.g. "5m")
+	Timeout  time.Duration `yaml:"timeout"`   // Optional: deadline for a single price fetch (default 20s)
 	Holdings []FundHolding `yaml:"holdings"`
+
+	RestartAfterFailures int `yaml:"restart_after_failures"` // Optional: restart the browser after N consecutive failed fetches (default 3, -1 disables)
+	MaxLookbackDays      int `yaml:"max_lookback_days"`      // Optional: earlier business days tried when the last one has no prices yet (default 5, -1 disables)
+	FetchConcurrency     int `yaml:"fetch_concurrency"`      // Optional: API calls run at once, e.g. one per fund type (default 1)
+
+	StaleAfter time.Duration `yaml:"stale_after"` // Optional: age at which prices are flagged stale for display (default: cache_ttl)
+
+	KeepaliveInterval time.Duration `yaml:"keepalive_interval"` // Optional: reload the TEFAS page this often while idle to keep firewall cookies fresh (0 disables)
+
+	Proxy      ProxyConfig `yaml:"proxy"`       // Optional: route the browser through a proxy
+	LaunchArgs []string    `yaml:"launch_args"` // Optional: Chromium flags replacing the built-in ones
+	UserAgents []string    `yaml:"user_agents"` // Optional: browser user agents, one per browser start in turn (default: a recent desktop Chrome)
+
+	Holidays []string `yaml:"holidays"` // Optional: extra market holidays ("2006-01-02") on top of the built-in Turkish ones
+	Timezone string   `yaml:"timezone"` // Optional: IANA zone business days are computed in (default "Europe/Istanbul")
+}
+
+// ProxyConfig holds proxy server settings
+type ProxyConfig struct {
+	Server   string `yaml:"server"`   // e.g. "http://proxy.example:3128" or "socks5://proxy.example:1080"
+	Username string `yaml:"username"` // Optional
+	Password string `yaml:"password"` // Optional
 }
 
 // FundHolding represents a TEFAS fund holding with quantity
@@ -33,36 +133,203 @@ type FundHolding struct {
 	Code      string  `yaml:"code"`                 // Fund code (e.g., "KUT")
 	Quantity  float64 `yaml:"quantity"`             // Number of shares owned
 	CostBasis float64 `yaml:"cost_basis,omitempty"` // Optional: total cost paid (for P&L calculation)
+	FundType  string  `yaml:"fund_type,omitempty"`  // Optional: "YAT" (investment) or "EMK" (pension); detected when empty
 }
 
 // CryptoConfig holds cryptocurrency provider settings
 type CryptoConfig struct {
 	Binance   BinanceConfig   `yaml:"binance"`
 	CoinGecko CoinGeckoConfig `yaml:"coingecko"`
+
+	// HTTPProxy routes Binance and CoinGecko requests through a proxy, e.g.
+	// where Binance is geo-blocked. Unset uses HTTPS_PROXY/HTTP_PROXY.
+	HTTPProxy string `yaml:"http_proxy"`
+
+	// CircuitBreaker skips a provider that keeps failing while another one
+	// backs it up
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+}
+
+// CircuitBreakerConfig holds crypto provider circuit breaker settings
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // Optional: consecutive failed fetches that open the circuit (default 3, -1 disables)
+	Cooldown         time.Duration `yaml:"cooldown"`          // Optional: how long an open circuit skips the provider before probing it (default 30s)
+}
+
+// GetHTTPProxy returns the configured crypto proxy, or nil when unset or
+// rejected by Validate
+func (c *CryptoConfig) GetHTTPProxy() *url.URL {
+	if c.HTTPProxy == "" {
+		return nil
+	}
+	proxy, err := parseProxyURL(c.HTTPProxy)
+	if err != nil {
+		return nil
+	}
+	return proxy
+}
+
+// parseProxyURL parses an HTTP, HTTPS or SOCKS5 proxy URL
+func parseProxyURL(s string) (*url.URL, error) {
+	proxy, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("%q must be a URL like http://proxy.example:3128 (http, https or socks5)", s)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("%q has no host", s)
+	}
+	return proxy, nil
 }
 
 // BinanceConfig holds Binance API settings
 type BinanceConfig struct {
 	Enabled  bool            `yaml:"enabled"`
+	CacheTTL time.Duration   `yaml:"cache_ttl"` // Optional: how long prices are cached (e.g. "30s")
+	Timeout  time.Duration   `yaml:"timeout"`   // Optional: deadline for a single price fetch (default 10s)
 	Holdings []CryptoHolding `yaml:"holdings"`
+	Quote    string          `yaml:"quote"` // Optional: quote asset for bare coins entered without a pair, e.g. "TRY" (default "USDT")
+
+	StaleAfter time.Duration `yaml:"stale_after"` // Optional: age at which prices are flagged stale for display (default: cache_ttl)
+
+	// BackgroundRefresh refetches prices shortly before the cache expires, so
+	// requests are served from cache instead of waiting on Binance
+	BackgroundRefresh bool `yaml:"background_refresh"`
 }
 
 // CryptoHolding represents a cryptocurrency holding with quantity
 type CryptoHolding struct {
 	Symbol    string  `yaml:"symbol"`               // Trading pair (e.g., "BTCUSDT")
-	Quantity  float64 `yaml:"quantity"`             // Amount owned
-	CostBasis float64 `yaml:"cost_basis,omitempty"` // Optional: total cost paid (for P&L calculation)
+	Quantity  float64 `yaml:"quantity"`             // Amount owned; negative for a short position
+	CostBasis float64 `yaml:"cost_basis,omitempty"` // Optional: total cost paid (for P&L calculation), or minus the proceeds of a short sale
 }
 
 // CoinGeckoConfig holds CoinGecko API settings
 type CoinGeckoConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	APIKey  string `yaml:"api_key"`
+	Enabled  bool          `yaml:"enabled"`
+	APIKey   string        `yaml:"api_key"`
+	CacheTTL time.Duration `yaml:"cache_ttl"` // Optional: how long prices are cached (e.g. "60s")
+	Timeout  time.Duration `yaml:"timeout"`   // Optional: deadline for a single price fetch (default 10s)
+
+	StaleAfter time.Duration `yaml:"stale_after"` // Optional: age at which prices are flagged stale for display (default: cache_ttl)
+}
+
+// FXConfig holds fiat exchange-rate provider settings
+type FXConfig struct {
+	Frankfurter FrankfurterConfig `yaml:"frankfurter"`
+}
+
+// FrankfurterConfig holds Frankfurter (ECB reference rates) settings. When
+// enabled, it is preferred over the crypto providers' USDT-based rate.
+type FrankfurterConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	BaseURL  string        `yaml:"base_url"`  // Optional: for self-hosted instances (default https://api.frankfurter.app)
+	CacheTTL time.Duration `yaml:"cache_ttl"` // Optional: how long rates are cached (default 1h)
+	Timeout  time.Duration `yaml:"timeout"`   // Optional: deadline for a single rate fetch (default 10s)
 }
 
 // DatabaseConfig holds database settings
 type DatabaseConfig struct {
-	Path string `yaml:"path"`
+	Path         string        `yaml:"path"`
+	MaxOpenConns int           `yaml:"max_open_conns"` // Optional: open connections, readers and writers together (default 4)
+	MaxIdleConns int           `yaml:"max_idle_conns"` // Optional: connections kept open when idle (default max_open_conns)
+	BusyTimeout  time.Duration `yaml:"busy_timeout"`   // Optional: how long a write waits for the lock (default 5s)
+	Backup       BackupConfig  `yaml:"backup"`
+}
+
+// BackupConfig holds database backup settings
+type BackupConfig struct {
+	Dir           string        `yaml:"dir"`            // Optional: where backups are written (default: "backups" beside the database)
+	Interval      time.Duration `yaml:"interval"`       // Optional: how often to back up, e.g. "24h" (0 disables scheduled backups)
+	Keep          int           `yaml:"keep"`           // Optional: newest backups kept; older ones are deleted (default 7)
+	BeforeMigrate bool          `yaml:"before_migrate"` // Optional: back up an existing database before schema migrations
+}
+
+// MetricsConfig holds Prometheus metrics settings
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"` // Expose /metrics for Prometheus scraping
+}
+
+// LogFormats are the accepted logging.format values
+var LogFormats = []string{"text", "json"}
+
+// LogLevels are the accepted logging.level values
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// LoggingConfig holds log output settings
+type LoggingConfig struct {
+	Format string `yaml:"format"` // Optional: "text" or "json" (default "text")
+	Level  string `yaml:"level"`  // Optional: "debug", "info", "warn" or "error" (default "info")
+}
+
+// SnapshotConfig holds the portfolio snapshot retention policy
+type SnapshotConfig struct {
+	RetentionDays int  `yaml:"retention_days"`  // Optional: delete snapshots older than this many days (default 0 keeps everything)
+	KeepMonthEnds bool `yaml:"keep_month_ends"` // Keep each month's last snapshot past the retention window
+}
+
+// MockConfig holds settings for the synthetic price providers used in
+// development and offline demos
+type MockConfig struct {
+	Enabled    bool    `yaml:"enabled"`    // Replace TEFAS and crypto providers with synthetic prices
+	Seed       int64   `yaml:"seed"`       // Random seed; the same seed yields the same price sequence
+	Volatility float64 `yaml:"volatility"` // Optional: std. deviation of each price step in percent (default 1)
+}
+
+// NotifyConfig holds notification channel settings
+type NotifyConfig struct {
+	Telegram         TelegramConfig `yaml:"telegram"`
+	DailySummaryTime string         `yaml:"daily_summary_time"` // Optional: "HH:MM" local time to send a portfolio summary
+
+	Alerts        []AlertConfig `yaml:"alerts"`         // Optional: conditions to notify about
+	AlertInterval time.Duration `yaml:"alert_interval"` // Optional: how often alerts are checked (default 5m)
+}
+
+// AlertScopePortfolio evaluates an alert against the whole portfolio
+const AlertScopePortfolio = "portfolio"
+
+// AlertConfig is a condition that sends a notification when it is met. Each
+// alert sets exactly one of Above, Below and DayDropPct.
+type AlertConfig struct {
+	Scope      string  `yaml:"scope"`        // "portfolio", the only scope so far (default)
+	Above      float64 `yaml:"above"`        // Total value rises to or above this, in display_currency
+	Below      float64 `yaml:"below"`        // Total value falls to or below this, in display_currency
+	DayDropPct float64 `yaml:"day_drop_pct"` // Total value is down more than this percentage since the previous day's snapshot
+}
+
+// TelegramConfig holds Telegram bot settings
+type TelegramConfig struct {
+	Token  string `yaml:"token"`
+	ChatID string `yaml:"chat_id"`
+}
+
+// Enabled reports whether Telegram notifications are configured
+func (c *TelegramConfig) Enabled() bool {
+	return c.Token != "" && c.ChatID != ""
+}
+
+// EnabledProviders returns the price and rate providers the config turns
+// on, in the order they are tried. Price providers without holdings to
+// price are left out.
+func (c *Config) EnabledProviders() []providers.ProviderType {
+	var enabled []providers.ProviderType
+	if len(c.TEFAS.Holdings) > 0 {
+		enabled = append(enabled, providers.ProviderTypeTEFAS)
+	}
+	if c.Crypto.Binance.Enabled && len(c.Crypto.Binance.Holdings) > 0 {
+		enabled = append(enabled, providers.ProviderTypeBinance)
+	}
+	if c.Crypto.CoinGecko.Enabled {
+		enabled = append(enabled, providers.ProviderTypeCoinGecko)
+	}
+	if c.FX.Frankfurter.Enabled {
+		enabled = append(enabled, providers.ProviderTypeFrankfurter)
+	}
+	return enabled
 }
 
 // GetFundCodes returns a list of all fund codes from holdings
@@ -74,6 +341,39 @@ func (c *TEFASConfig) GetFundCodes() []string {
 	return codes
 }
 
+// GetFundTypes returns the configured fund type for each holding that sets one
+func (c *TEFASConfig) GetFundTypes() map[string]string {
+	types := make(map[string]string)
+	for _, h := range c.Holdings {
+		if h.FundType != "" {
+			types[h.Code] = h.FundType
+		}
+	}
+	return types
+}
+
+// GetHolidays returns the configured extra market holidays, skipping
+// dates Validate rejects
+func (c *TEFASConfig) GetHolidays() []time.Time {
+	days := make([]time.Time, 0, len(c.Holidays))
+	for _, s := range c.Holidays {
+		if day, err := time.Parse(time.DateOnly, s); err == nil {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// GetLocation returns the configured time zone, or nil when Validate
+// rejects it
+func (c *TEFASConfig) GetLocation() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
 // GetHoldingByCode returns the holding for a specific fund code
 func (c *TEFASConfig) GetHoldingByCode(code string) *FundHolding {
 	for i := range c.Holdings {
@@ -103,8 +403,17 @@ func (c *BinanceConfig) GetHoldingBySymbol(symbol string) *CryptoHolding {
 	return nil
 }
 
-// Load reads and parses the configuration file
-func Load(path string) (*Config, error) {
+// LoadOptions adjusts how Load parses the configuration file
+type LoadOptions struct {
+	// AllowUnknownKeys ignores keys Prism doesn't know, as older versions
+	// did, instead of refusing the file
+	AllowUnknownKeys bool
+}
+
+// Load reads and parses the configuration file. Unknown keys, such as typos
+// or settings indented under the wrong section, are errors naming each key
+// unless opts allows them.
+func Load(path string, opts LoadOptions) (*Config, error) {
 	// Check for environment variable override
 	if envPath := os.Getenv("PRISM_CONFIG"); envPath != "" {
 		path = envPath
@@ -123,7 +432,13 @@ func Load(path string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(!opts.AllowUnknownKeys)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		// The decoder names unknown keys without their section
+		if keyErrs := unknownKeys(data); len(keyErrs) > 0 && !opts.AllowUnknownKeys {
+			return nil, fmt.Errorf("parsing config file: %w", errors.Join(keyErrs...))
+		}
 		return nil, fmt.Errorf("parsing config file: %w", err)
 	}
 
@@ -134,6 +449,90 @@ func Load(path string) (*Config, error) {
 	if cfg.Database.Path == "" {
 		cfg.Database.Path = "./data/prism.db"
 	}
+	if cfg.Database.MaxOpenConns == 0 {
+		cfg.Database.MaxOpenConns = 4
+	}
+	if cfg.Database.BusyTimeout == 0 {
+		cfg.Database.BusyTimeout = 5 * time.Second
+	}
+	if cfg.Database.Backup.Keep == 0 {
+		cfg.Database.Backup.Keep = 7
+	}
+	if cfg.Server.RequestTimeout == 0 {
+		cfg.Server.RequestTimeout = 30 * time.Second
+	}
+	if cfg.Server.SummaryCacheTTL == 0 {
+		cfg.Server.SummaryCacheTTL = 10 * time.Second
+	}
+	if cfg.Server.IdempotencyTTL == 0 {
+		cfg.Server.IdempotencyTTL = 24 * time.Hour
+	}
+	if cfg.Server.MaxBodyBytes == 0 {
+		cfg.Server.MaxBodyBytes = 1 << 20
+	}
+	if cfg.Server.MaxBulkBodyBytes == 0 {
+		cfg.Server.MaxBulkBodyBytes = 10 << 20
+	}
+	if cfg.Server.Rounding.FundPriceDecimals == 0 {
+		cfg.Server.Rounding.FundPriceDecimals = 6
+	}
+	if cfg.Server.Rounding.CryptoPriceDecimals == 0 {
+		cfg.Server.Rounding.CryptoPriceDecimals = 8
+	}
+	if cfg.Notify.AlertInterval == 0 {
+		cfg.Notify.AlertInterval = 5 * time.Minute
+	}
+	for i := range cfg.Notify.Alerts {
+		if cfg.Notify.Alerts[i].Scope == "" {
+			cfg.Notify.Alerts[i].Scope = AlertScopePortfolio
+		}
+	}
+	if cfg.Server.CORSMaxAge == 0 {
+		cfg.Server.CORSMaxAge = 12 * time.Hour
+	}
+	if cfg.TEFAS.Timeout == 0 {
+		cfg.TEFAS.Timeout = 20 * time.Second // Playwright round-trips are slow
+	}
+	if cfg.TEFAS.Timezone == "" {
+		cfg.TEFAS.Timezone = "Europe/Istanbul"
+	}
+	if cfg.Crypto.Binance.Timeout == 0 {
+		cfg.Crypto.Binance.Timeout = 10 * time.Second
+	}
+	if cfg.Crypto.CoinGecko.Timeout == 0 {
+		cfg.Crypto.CoinGecko.Timeout = 10 * time.Second
+	}
+	if cfg.Crypto.CircuitBreaker.FailureThreshold == 0 {
+		cfg.Crypto.CircuitBreaker.FailureThreshold = 3
+	}
+	if cfg.Crypto.CircuitBreaker.Cooldown == 0 {
+		cfg.Crypto.CircuitBreaker.Cooldown = 30 * time.Second
+	}
+	cfg.Crypto.Binance.Quote = strings.ToUpper(cfg.Crypto.Binance.Quote)
+	if cfg.Crypto.Binance.Quote == "" {
+		cfg.Crypto.Binance.Quote = "USDT"
+	}
+	if cfg.FX.Frankfurter.Timeout == 0 {
+		cfg.FX.Frankfurter.Timeout = 10 * time.Second
+	}
+	cfg.Currencies.Fund = strings.ToUpper(cfg.Currencies.Fund)
+	if cfg.Currencies.Fund == "" {
+		cfg.Currencies.Fund = "TRY"
+	}
+	cfg.Currencies.Crypto = strings.ToUpper(cfg.Currencies.Crypto)
+	if cfg.Currencies.Crypto == "" {
+		cfg.Currencies.Crypto = "USD"
+	}
+	cfg.DisplayCurrency = strings.ToUpper(cfg.DisplayCurrency)
+	if cfg.DisplayCurrency == "" {
+		cfg.DisplayCurrency = "TRY"
+	}
+	if cfg.Logging.Format == "" {
+		cfg.Logging.Format = "text"
+	}
+	if cfg.Logging.Level == "" {
+		cfg.Logging.Level = "info"
+	}
 
 	// Environment variable overrides
 	if port := os.Getenv("PRISM_PORT"); port != "" {
@@ -145,12 +544,265 @@ func Load(path string) (*Config, error) {
 	if apiKey := os.Getenv("COINGECKO_API_KEY"); apiKey != "" {
 		cfg.Crypto.CoinGecko.APIKey = apiKey
 	}
+	if apiKey := os.Getenv("PRISM_API_KEY"); apiKey != "" {
+		cfg.Server.APIKey = apiKey
+	}
+	if format := os.Getenv("PRISM_LOG_FORMAT"); format != "" {
+		cfg.Logging.Format = format
+	}
+	if level := os.Getenv("PRISM_LOG_LEVEL"); level != "" {
+		cfg.Logging.Level = level
+	}
+	cfg.Logging.Format = strings.ToLower(cfg.Logging.Format)
+	cfg.Logging.Level = strings.ToLower(cfg.Logging.Level)
 
 	// Resolve database path relative to config file directory (not working directory)
 	// This ensures the database is always found regardless of where the binary is run from
 	if !filepath.IsAbs(cfg.Database.Path) {
 		cfg.Database.Path = filepath.Join(configDir, cfg.Database.Path)
 	}
+	if cfg.Database.Backup.Dir == "" {
+		cfg.Database.Backup.Dir = filepath.Join(filepath.Dir(cfg.Database.Path), "backups")
+	} else if !filepath.IsAbs(cfg.Database.Backup.Dir) {
+		cfg.Database.Backup.Dir = filepath.Join(configDir, cfg.Database.Backup.Dir)
+	}
 
 	return &cfg, nil
 }
+
+// Validate checks the configuration for values that would produce a broken
+// portfolio. All problems are reported together.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port: %q is not a valid port", c.Server.Port))
+	}
+
+	// Browsers refuse credentials on a wildcard origin, and reflecting any
+	// origin with credentials would let every site act as the user
+	if c.Server.CORSAllowCredentials {
+		if len(c.Server.CORSOrigins) == 0 {
+			errs = append(errs, errors.New("server.cors_origins: must list explicit origins when server.cors_allow_credentials is enabled"))
+		} else if slices.Contains(c.Server.CORSOrigins, "*") {
+			errs = append(errs, errors.New(`server.cors_origins: "*" cannot be combined with server.cors_allow_credentials`))
+		}
+	}
+
+	for i, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("server.trusted_proxies[%d]: %q is not an IP address or CIDR", i, proxy))
+			}
+		}
+	}
+
+	for i, h := range c.TEFAS.Holdings {
+		if h.Code == "" {
+			errs = append(errs, fmt.Errorf("tefas.holdings[%d].code: must not be empty", i))
+		}
+		if h.Quantity < 0 {
+			errs = append(errs, fmt.Errorf("tefas.holdings[%d].quantity: must not be negative (got %v)", i, h.Quantity))
+		}
+		if h.CostBasis < 0 {
+			errs = append(errs, fmt.Errorf("tefas.holdings[%d].cost_basis: must not be negative (got %v)", i, h.CostBasis))
+		}
+		if h.FundType != "" && h.FundType != "YAT" && h.FundType != "EMK" {
+			errs = append(errs, fmt.Errorf("tefas.holdings[%d].fund_type: %q must be YAT or EMK", i, h.FundType))
+		}
+	}
+
+	if c.Crypto.HTTPProxy != "" {
+		if _, err := parseProxyURL(c.Crypto.HTTPProxy); err != nil {
+			errs = append(errs, fmt.Errorf("crypto.http_proxy: %w", err))
+		}
+	} else {
+		// The fallback; Go reads a bare host:port as an http:// proxy
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+			value := os.Getenv(name)
+			if value == "" {
+				continue
+			}
+			if !strings.Contains(value, "://") {
+				value = "http://" + value
+			}
+			if _, err := parseProxyURL(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s environment variable: %w", name, err))
+			}
+		}
+	}
+
+	if c.TEFAS.Proxy.Server == "" && (c.TEFAS.Proxy.Username != "" || c.TEFAS.Proxy.Password != "") {
+		errs = append(errs, errors.New("tefas.proxy.server: must be set when a proxy username or password is"))
+	}
+	for i, arg := range c.TEFAS.LaunchArgs {
+		if !strings.HasPrefix(arg, "--") {
+			errs = append(errs, fmt.Errorf("tefas.launch_args[%d]: %q must be a Chromium flag starting with --", i, arg))
+		}
+	}
+	for i, ua := range c.TEFAS.UserAgents {
+		if strings.TrimSpace(ua) == "" {
+			errs = append(errs, fmt.Errorf("tefas.user_agents[%d]: must not be empty", i))
+		}
+	}
+	if c.TEFAS.FetchConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("tefas.fetch_concurrency: must not be negative (got %d)", c.TEFAS.FetchConcurrency))
+	}
+	if c.TEFAS.KeepaliveInterval < 0 {
+		errs = append(errs, fmt.Errorf("tefas.keepalive_interval: must not be negative (got %v)", c.TEFAS.KeepaliveInterval))
+	}
+	if _, err := time.LoadLocation(c.TEFAS.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("tefas.timezone: %w", err))
+	}
+	for i, day := range c.TEFAS.Holidays {
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			errs = append(errs, fmt.Errorf("tefas.holidays[%d]: %q must be a date like 2006-01-02", i, day))
+		}
+	}
+
+	for i, h := range c.Crypto.Binance.Holdings {
+		if h.Symbol == "" {
+			errs = append(errs, fmt.Errorf("crypto.binance.holdings[%d].symbol: must not be empty", i))
+		}
+		// A negative quantity is a short position, whose cost basis is minus
+		// the proceeds of the sale
+		if h.Quantity < 0 && h.CostBasis > 0 {
+			errs = append(errs, fmt.Errorf("crypto.binance.holdings[%d].cost_basis: must not be positive for a short position (got %v)", i, h.CostBasis))
+		}
+		if h.Quantity >= 0 && h.CostBasis < 0 {
+			errs = append(errs, fmt.Errorf("crypto.binance.holdings[%d].cost_basis: must not be negative (got %v)", i, h.CostBasis))
+		}
+	}
+
+	for _, currency := range []struct{ key, code string }{
+		{"currencies.fund", c.Currencies.Fund},
+		{"currencies.crypto", c.Currencies.Crypto},
+		{"display_currency", c.DisplayCurrency},
+	} {
+		if currency.code != "" && !isCurrencyCode(currency.code) {
+			errs = append(errs, fmt.Errorf("%s: %q is not a 3-letter currency code", currency.key, currency.code))
+		}
+	}
+	if c.Crypto.Binance.Quote != "" && !slices.Contains(providers.QuoteAssets, c.Crypto.Binance.Quote) {
+		errs = append(errs, fmt.Errorf("crypto.binance.quote: %q must be one of %s", c.Crypto.Binance.Quote, strings.Join(providers.QuoteAssets, ", ")))
+	}
+
+	if c.Database.MaxOpenConns < 0 {
+		errs = append(errs, fmt.Errorf("database.max_open_conns: must not be negative (got %d)", c.Database.MaxOpenConns))
+	}
+	if c.Database.MaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("database.max_idle_conns: must not be negative (got %d)", c.Database.MaxIdleConns))
+	}
+	if c.Database.BusyTimeout < 0 {
+		errs = append(errs, fmt.Errorf("database.busy_timeout: must not be negative (got %v)", c.Database.BusyTimeout))
+	}
+	if c.Database.Backup.Interval < 0 {
+		errs = append(errs, fmt.Errorf("database.backup.interval: must not be negative (got %v)", c.Database.Backup.Interval))
+	}
+	if c.Database.Backup.Keep < 0 {
+		errs = append(errs, fmt.Errorf("database.backup.keep: must not be negative (got %d)", c.Database.Backup.Keep))
+	}
+
+	// Each provider must give up before the handler does, so one slow
+	// provider can't consume the whole request budget
+	if c.Server.RequestTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("server.request_timeout: must be positive (got %v)", c.Server.RequestTimeout))
+	} else if c.Server.RequestTimeout > MaxRequestTimeout {
+		errs = append(errs, fmt.Errorf("server.request_timeout: must be at most %v (got %v)", MaxRequestTimeout, c.Server.RequestTimeout))
+	}
+	if c.Server.IdempotencyTTL < 0 {
+		errs = append(errs, fmt.Errorf("server.idempotency_ttl: must not be negative (got %v)", c.Server.IdempotencyTTL))
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_body_bytes: must be positive (got %d)", c.Server.MaxBodyBytes))
+	}
+	if c.Server.MaxBulkBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_bulk_body_bytes: must be positive (got %d)", c.Server.MaxBulkBodyBytes))
+	}
+	providerTimeouts := []struct {
+		key     string
+		timeout time.Duration
+	}{
+		{"tefas.timeout", c.TEFAS.Timeout},
+		{"crypto.binance.timeout", c.Crypto.Binance.Timeout},
+		{"crypto.coingecko.timeout", c.Crypto.CoinGecko.Timeout},
+		{"fx.frankfurter.timeout", c.FX.Frankfurter.Timeout},
+	}
+	for _, pt := range providerTimeouts {
+		if pt.timeout <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive (got %v)", pt.key, pt.timeout))
+		} else if pt.timeout >= c.Server.RequestTimeout {
+			errs = append(errs, fmt.Errorf("%s: must be shorter than server.request_timeout (%v >= %v)", pt.key, pt.timeout, c.Server.RequestTimeout))
+		}
+	}
+	// CoinGecko is asked for what Binance couldn't price only once Binance
+	// gives up, so the two run back to back within one request
+	enabled := c.EnabledProviders()
+	if slices.Contains(enabled, providers.ProviderTypeBinance) && slices.Contains(enabled, providers.ProviderTypeCoinGecko) {
+		if chain := c.Crypto.Binance.Timeout + c.Crypto.CoinGecko.Timeout; chain >= c.Server.RequestTimeout {
+			errs = append(errs, fmt.Errorf("crypto.binance.timeout + crypto.coingecko.timeout: must be shorter than server.request_timeout, as CoinGecko backs Binance up (%v >= %v)", chain, c.Server.RequestTimeout))
+		}
+	}
+
+	if c.Crypto.CircuitBreaker.Cooldown < 0 {
+		errs = append(errs, fmt.Errorf("crypto.circuit_breaker.cooldown: must not be negative (got %v)", c.Crypto.CircuitBreaker.Cooldown))
+	}
+
+	staleThresholds := []struct {
+		key        string
+		staleAfter time.Duration
+	}{
+		{"tefas.stale_after", c.TEFAS.StaleAfter},
+		{"crypto.binance.stale_after", c.Crypto.Binance.StaleAfter},
+		{"crypto.coingecko.stale_after", c.Crypto.CoinGecko.StaleAfter},
+	}
+	for _, st := range staleThresholds {
+		if st.staleAfter < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative (got %v)", st.key, st.staleAfter))
+		}
+	}
+
+	if !slices.Contains(LogFormats, c.Logging.Format) {
+		errs = append(errs, fmt.Errorf("logging.format: %q must be one of %s", c.Logging.Format, strings.Join(LogFormats, ", ")))
+	}
+	if !slices.Contains(LogLevels, c.Logging.Level) {
+		errs = append(errs, fmt.Errorf("logging.level: %q must be one of %s", c.Logging.Level, strings.Join(LogLevels, ", ")))
+	}
+
+	if c.Snapshot.RetentionDays < 0 {
+		errs = append(errs, fmt.Errorf("snapshot.retention_days: must not be negative (got %d)", c.Snapshot.RetentionDays))
+	}
+
+	if c.Notify.DailySummaryTime != "" {
+		if _, err := time.Parse("15:04", c.Notify.DailySummaryTime); err != nil {
+			errs = append(errs, fmt.Errorf("notify.daily_summary_time: %q must be in HH:MM format", c.Notify.DailySummaryTime))
+		}
+	}
+	if c.Notify.AlertInterval < 0 {
+		errs = append(errs, fmt.Errorf("notify.alert_interval: must not be negative (got %v)", c.Notify.AlertInterval))
+	}
+	for i, a := range c.Notify.Alerts {
+		if a.Scope != AlertScopePortfolio {
+			errs = append(errs, fmt.Errorf("notify.alerts[%d].scope: %q must be %q", i, a.Scope, AlertScopePortfolio))
+		}
+		set := 0
+		for _, v := range []float64{a.Above, a.Below, a.DayDropPct} {
+			if v < 0 {
+				errs = append(errs, fmt.Errorf("notify.alerts[%d]: thresholds must not be negative", i))
+			}
+			if v > 0 {
+				set++
+			}
+		}
+		if set != 1 {
+			errs = append(errs, fmt.Errorf("notify.alerts[%d]: set exactly one of above, below and day_drop_pct", i))
+		}
+	}
+
+	// TEFAS is enabled implicitly by listing fund holdings
+	if len(c.TEFAS.Holdings) == 0 && !c.Crypto.Binance.Enabled && !c.Crypto.CoinGecko.Enabled && !c.Mock.Enabled {
+		errs = append(errs, errors.New("no provider enabled: add tefas.holdings or enable crypto.binance / crypto.coingecko / mock"))
+	}
+
+	return errors.Join(errs...)
+}
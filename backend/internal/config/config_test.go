@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadYAML loads data as a config file with opts
+func loadYAML(t *testing.T, data string, opts LoadOptions) (*Config, error) {
+	t.Helper()
+	t.Setenv("PRISM_CONFIG", "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	return Load(path, opts)
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			yaml: "mock:\n  enabled: true\n",
+		},
+		{
+			name:    "request timeout past the cap",
+			yaml:    "mock:\n  enabled: true\nserver:\n  request_timeout: 6m\n",
+			wantErr: "server.request_timeout: must be at most 5m0s",
+		},
+		{
+			name:    "provider timeout past the request timeout",
+			yaml:    "mock:\n  enabled: true\nserver:\n  request_timeout: 5s\n",
+			wantErr: "tefas.timeout: must be shorter than server.request_timeout",
+		},
+		{
+			name: "fallback chain within the request timeout",
+			yaml: `server:
+  request_timeout: 30s
+crypto:
+  binance:
+    enabled: true
+    holdings: [{symbol: BTCUSDT, quantity: 1}]
+  coingecko:
+    enabled: true
+`,
+		},
+		{
+			name: "fallback chain past the request timeout",
+			yaml: `server:
+  request_timeout: 30s
+crypto:
+  binance:
+    enabled: true
+    timeout: 20s
+    holdings: [{symbol: BTCUSDT, quantity: 1}]
+  coingecko:
+    enabled: true
+`,
+			wantErr: "crypto.binance.timeout + crypto.coingecko.timeout: must be shorter than server.request_timeout",
+		},
+		{
+			name: "CoinGecko alone",
+			yaml: `server:
+  request_timeout: 30s
+crypto:
+  coingecko:
+    enabled: true
+    timeout: 25s
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadYAML(t, tt.yaml, LoadOptions{})
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			err = cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+import "sync"
+
+// Holder provides concurrency-safe access to the current configuration so it
+// can be swapped on reload. Callers should call Get once per request and use
+// that snapshot throughout, so in-flight requests see a consistent config.
+//
+// Hot-reloadable fields: holdings (provider symbol lists), cache TTLs,
+// server.cors_origins, server.request_timeout, and server.summary_cache_ttl.
+// Fields read only at startup (server.port, database settings, provider
+// enablement and timeouts) require a restart.
+type Holder struct {
+	mu  sync.RWMutex
+	cfg *Config
+}
+
+// NewHolder creates a Holder with the initial configuration
+func NewHolder(cfg *Config) *Holder {
+	return &Holder{cfg: cfg}
+}
+
+// Get returns the current configuration
+func (h *Holder) Get() *Config {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.cfg
+}
+
+// Set replaces the current configuration
+func (h *Holder) Set(cfg *Config) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cfg = cfg
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHolder(t *testing.T) {
+	old := &Config{}
+	old.Server.RequestTimeout = 30 * time.Second
+	h := NewHolder(old)
+	if h.Get() != old {
+		t.Fatal("Get() doesn't return the initial config")
+	}
+
+	// Readers racing a reload see one whole config or the other
+	reloaded := &Config{}
+	reloaded.Server.RequestTimeout = time.Minute
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if cfg := h.Get(); cfg != old && cfg != reloaded {
+					t.Error("Get() returned neither the old nor the reloaded config")
+					return
+				}
+			}
+		}()
+	}
+	h.Set(reloaded)
+	wg.Wait()
+
+	if got := h.Get().Server.RequestTimeout; got != time.Minute {
+		t.Errorf("request timeout after Set() = %v, want 1m", got)
+	}
+	if old.Server.RequestTimeout != 30*time.Second {
+		t.Error("Set() modified the replaced config")
+	}
+}
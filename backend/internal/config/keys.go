@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unknownKeys returns an error for every key in the YAML document data that
+// no Config field is tagged with, naming the key by its full path and
+// suggesting where it may have been meant to go
+func unknownKeys(data []byte) []error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	configType := reflect.TypeOf(Config{})
+	known := make(map[string][]string)
+	knownPaths(configType, "", known)
+	for _, paths := range known {
+		slices.Sort(paths)
+	}
+
+	var errs []error
+	checkKeys(doc.Content[0], configType, "", known, &errs)
+	return errs
+}
+
+// checkKeys appends an error to errs for each key of node unknown to t
+func checkKeys(node *yaml.Node, t reflect.Type, path string, known map[string][]string, errs *[]error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := joinPath(path, key.Value)
+			field, ok := fields[key.Value]
+			if !ok {
+				*errs = append(*errs, fmt.Errorf("line %d: %s: unknown key%s", key.Line, keyPath, keyHint(key.Value, keyPath, fields, known)))
+				continue
+			}
+			checkKeys(value, field.Type, keyPath, known, errs)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), known, errs)
+		}
+	}
+}
+
+// keyHint suggests what an unknown key was meant to be: a known sibling it
+// is a typo of, or the paths a key of that name is valid at
+func keyHint(key, keyPath string, siblings map[string]reflect.StructField, known map[string][]string) string {
+	closest, best := "", max(2, len(key)/3)+1
+	for name := range siblings {
+		if d := editDistance(key, name); d < best || (d == best && name < closest) {
+			closest, best = name, d
+		}
+	}
+	if closest != "" {
+		return fmt.Sprintf(" (did you mean %s?)", closest)
+	}
+	if elsewhere := known[key]; len(elsewhere) > 0 && !slices.Contains(elsewhere, keyPath) {
+		return fmt.Sprintf(" (valid as %s)", strings.Join(elsewhere, " or "))
+	}
+	return ""
+}
+
+// knownPaths records, for every key Config accepts, the paths it is valid at
+func knownPaths(t reflect.Type, path string, known map[string][]string) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for name, field := range yamlFields(t) {
+		keyPath := joinPath(path, name)
+		known[name] = append(known[name], keyPath)
+		knownPaths(field.Type, keyPath, known)
+	}
+}
+
+// yamlFields maps the YAML keys of a struct's fields to the fields
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(field.Name) // yaml.v3's default
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// joinPath appends key to a dotted key path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(b)]
+}
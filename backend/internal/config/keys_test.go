@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		opts    LoadOptions
+		wantErr []string
+	}{
+		{
+			name: "known keys",
+			yaml: "server:\n  request_timeout: 30s\ncrypto:\n  binance:\n    holdings: [{symbol: BTCUSDT, quantity: 1}]\n",
+		},
+		{
+			name: "misplaced key",
+			yaml: "crypto:\n  coingecko:\n    holdings: [{symbol: BTCUSDT, quantity: 1}]\n",
+			wantErr: []string{
+				"line 3: crypto.coingecko.holdings: unknown key (valid as crypto.binance.holdings or tefas.holdings)",
+			},
+		},
+		{
+			name:    "typo",
+			yaml:    "server:\n  requst_timeout: 30s\n",
+			wantErr: []string{"line 2: server.requst_timeout: unknown key (did you mean request_timeout?)"},
+		},
+		{
+			name:    "unknown key in a list item",
+			yaml:    "tefas:\n  holdings:\n    - code: KUT\n      quantity: 1\n      cost: 5\n",
+			wantErr: []string{"line 5: tefas.holdings[0].cost: unknown key"},
+		},
+		{
+			name: "every unknown key named",
+			yaml: "sever:\n  port: \"8080\"\nserver:\n  prot: \"8080\"\n",
+			wantErr: []string{
+				"line 1: sever: unknown key (did you mean server?)",
+				"line 4: server.prot: unknown key (did you mean port?)",
+			},
+		},
+		{
+			name: "unknown keys allowed",
+			yaml: "server:\n  requst_timeout: 30s\ncrypto:\n  coingecko:\n    holdings: []\n",
+			opts: LoadOptions{AllowUnknownKeys: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadYAML(t, tt.yaml, tt.opts)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Load() succeeded, want errors %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExampleConfigLoads(t *testing.T) {
+	t.Setenv("PRISM_CONFIG", "")
+	cfg, err := Load("../../config.example.yaml", LoadOptions{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"port", "port", 0},
+		{"", "port", 4},
+		{"prot", "port", 2},
+		{"requst_timeout", "request_timeout", 1},
+		{"cache_tll", "cache_ttl", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ProviderFetchDuration tracks how long live provider fetches take
+	ProviderFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "prism",
+		Name:      "provider_fetch_duration_seconds",
+		Help:      "Latency of live price fetches per provider.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"provider"})
+
+	// ProviderFetchErrors counts failed live provider fetches
+	ProviderFetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "prism",
+		Name:      "provider_fetch_errors_total",
+		Help:      "Number of failed price fetches per provider.",
+	}, []string{"provider"})
+
+	// CacheHits counts price requests served entirely from a provider cache
+	CacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "prism",
+		Name:      "provider_cache_hits_total",
+		Help:      "Number of price requests served from provider cache.",
+	}, []string{"provider"})
+
+	// CacheMisses counts price requests that required a live fetch
+	CacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "prism",
+		Name:      "provider_cache_misses_total",
+		Help:      "Number of price requests that missed the provider cache.",
+	}, []string{"provider"})
+
+	// SummaryDuration tracks how long building the portfolio summary takes
+	SummaryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "prism",
+		Name:      "portfolio_summary_duration_seconds",
+		Help:      "Latency of portfolio summary computation.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	})
+
+	// PortfolioValue reports the total portfolio value from the last summary
+	PortfolioValue = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "prism",
+		Name:      "portfolio_total_value",
+		Help:      "Total portfolio value as of the last computed summary.",
+	})
+)
+
+// ObserveFetch records the latency and outcome of a live provider fetch
+func ObserveFetch(provider string, start time.Time, err error) {
+	ProviderFetchDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ProviderFetchErrors.WithLabelValues(provider).Inc()
+	}
+}
+
+// ObserveCache records a provider cache hit or miss
+func ObserveCache(provider string, hit bool) {
+	if hit {
+		CacheHits.WithLabelValues(provider).Inc()
+		return
+	}
+	CacheMisses.WithLabelValues(provider).Inc()
+}
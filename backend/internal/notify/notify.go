@@ -0,0 +1,23 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// Notifier delivers a plain-text message to a notification channel
+type Notifier interface {
+	// Send delivers the message, returning an error if delivery failed
+	Send(ctx context.Context, message string) error
+}
+
+// unwrapURLError drops the request URL from transport errors so secrets
+// embedded in it (such as bot tokens) don't end up in logs
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultTelegramBaseURL is the Telegram Bot API root
+	defaultTelegramBaseURL = "https://api.telegram.org"
+)
+
+// Telegram sends notifications through a Telegram bot
+type Telegram struct {
+	client  *http.Client
+	baseURL string
+	token   string
+	chatID  string
+}
+
+// NewTelegram creates a Telegram notifier for the given bot token and chat
+func NewTelegram(token, chatID string) *Telegram {
+	return &Telegram{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: defaultTelegramBaseURL,
+		token:   token,
+		chatID:  chatID,
+	}
+}
+
+// sendMessageRequest represents the Telegram sendMessage payload
+type sendMessageRequest struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// Send posts the message to the configured chat
+func (t *Telegram) Send(ctx context.Context, message string) error {
+	body, err := json.Marshal(sendMessageRequest{
+		ChatID: t.chatID,
+		Text:   message,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", t.baseURL, t.token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// Strip the URL, which contains the bot token
+		return fmt.Errorf("sending telegram message: %w", unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTelegramSend(t *testing.T) {
+	const token = "123456:secret-token"
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{"delivered", http.StatusOK, ""},
+		{"rejected", http.StatusUnauthorized, "unexpected status: 401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotContentType string
+			var got sendMessageRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath, gotContentType = r.URL.Path, r.Header.Get("Content-Type")
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding payload: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			tg := NewTelegram(token, "42")
+			tg.baseURL = srv.URL
+			err := tg.Send(context.Background(), "portfolio is up")
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("Send() error = %v, want %q", err, tt.wantErr)
+			}
+			if gotPath != "/bot"+token+"/sendMessage" || gotContentType != "application/json" {
+				t.Errorf("request = %s (%s), want /bot<token>/sendMessage as JSON", gotPath, gotContentType)
+			}
+			if got != (sendMessageRequest{ChatID: "42", Text: "portfolio is up"}) {
+				t.Errorf("payload = %+v, want chat 42 with the message", got)
+			}
+		})
+	}
+}
+
+func TestTelegramSendHidesToken(t *testing.T) {
+	const token = "123456:secret-token"
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close() // Refuse connections
+
+	tg := NewTelegram(token, "42")
+	tg.baseURL = srv.URL
+	err := tg.Send(context.Background(), "hello")
+	if err == nil {
+		t.Fatal("Send() to a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), token) {
+		t.Errorf("Send() error %q leaks the bot token", err)
+	}
+}
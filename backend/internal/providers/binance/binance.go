@@ -3,33 +3,98 @@ package binance
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
+	"github.com/ferhatkunduraci/prism/internal/metrics"
 	"github.com/ferhatkunduraci/prism/internal/providers"
 )
 
 const (
-	baseURL = "https://api.binance.com"
+	// defaultBaseURL is the Binance API root used when Config.BaseURL is empty
+	defaultBaseURL = "https://api.binance.com"
+
+	// defaultCacheTTL is used when no TTL is configured
+	defaultCacheTTL = 30 * time.Second // Crypto prices change frequently
+
+	// defaultTimeout is used when no fetch timeout is configured
+	defaultTimeout = 10 * time.Second
+
+	// delistedAfter is how many fetches in a row must reject a symbol as
+	// invalid before it is reported as delisted rather than failing
+	delistedAfter = 3
+
+	// codeInvalidSymbol is the Binance error code for an unknown symbol
+	codeInvalidSymbol = -1121
 )
 
+// errUnlisted is returned by fetch24hrTicker when Binance rejects the symbol
+var errUnlisted = fmt.Errorf("%w on Binance", providers.ErrSymbolNotFound)
+
+// KlineIntervals are the candle intervals Binance serves
+var KlineIntervals = []string{"1s", "1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M"}
+
+// MaxKlineLimit is the most candles Binance returns for one request
+const MaxKlineLimit = 1000
+
+// ErrInvalidInterval is returned by FetchKlines for an interval not in KlineIntervals
+var ErrInvalidInterval = errors.New("invalid kline interval")
+
 // Provider implements the Binance data provider
 type Provider struct {
 	client   *http.Client
+	baseURL  string
 	symbols  []string
 	cache    map[string]providers.Price
 	cacheMu  sync.RWMutex
 	cacheExp time.Time
 	cacheTTL time.Duration
+	store    providers.PriceStore
+	timeout  time.Duration
+
+	staleAfter  time.Duration  // Age at which prices are flagged stale; 0 follows cacheTTL (guarded by cacheMu)
+	lastSuccess time.Time      // Last fetch that returned fresh prices (guarded by cacheMu)
+	unlisted    map[string]int // Consecutive fetches rejecting each symbol (guarded by cacheMu)
+
+	// Candles by symbol, interval and limit, cached for cacheTTL (guarded by cacheMu)
+	klines map[string]klineEntry
+
+	// Symbols of the last fetch a request triggered, kept warm by the
+	// background refresh alongside the configured ones (guarded by cacheMu)
+	lastRequested []string
+
+	// Holds a token while prices are fetched, so a request that misses the
+	// cache waits for a fetch in flight instead of repeating it
+	fetching chan struct{}
+}
+
+// klineEntry is a cached FetchKlines result
+type klineEntry struct {
+	candles []providers.Candle
+	expires time.Time
 }
 
 // Config holds Binance provider configuration
 type Config struct {
-	Symbols []string
+	Symbols  []string
+	CacheTTL time.Duration        // Optional, defaults to 30s
+	Store    providers.PriceStore // Optional, persists last-known prices
+	Timeout  time.Duration        // Optional, deadline for one fetch, defaults to 10s
+
+	StaleAfter time.Duration // Optional, age at which prices are flagged stale, defaults to CacheTTL
+
+	BaseURL    string       // Optional, defaults to the public Binance API
+	HTTPClient *http.Client // Optional, replaces the default client (e.g. in tests)
+	Proxy      *url.URL     // Optional, routes requests through a proxy; nil uses HTTPS_PROXY/HTTP_PROXY
 }
 
 // tickerResponse represents Binance 24hr ticker response
@@ -40,61 +105,197 @@ type tickerResponse struct {
 	LastPrice          string `json:"lastPrice"`
 }
 
+// errorResponse represents the body of a Binance API error
+type errorResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
 // NewProvider creates a new Binance provider
 func NewProvider(cfg Config) *Provider {
+	timeout := providers.OrDefault(cfg.Timeout, defaultTimeout)
+	client := cfg.HTTPClient
+	if client == nil {
+		client = providers.NewHTTPClient(timeout, cfg.Proxy)
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
 	return &Provider{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client:   client,
+		baseURL:  baseURL,
 		symbols:  cfg.Symbols,
 		cache:    make(map[string]providers.Price),
-		cacheTTL: 30 * time.Second, // Crypto prices change frequently
+		unlisted: make(map[string]int),
+		klines:   make(map[string]klineEntry),
+		fetching: make(chan struct{}, 1),
+		cacheTTL: providers.OrDefault(cfg.CacheTTL, defaultCacheTTL),
+		store:    cfg.Store,
+		timeout:  timeout,
+
+		staleAfter: cfg.StaleAfter,
 	}
 }
 
+// LoadCache fills the cache with prices persisted by a previous run
+func (p *Provider) LoadCache(ctx context.Context) error {
+	if p.store == nil {
+		return nil
+	}
+
+	saved, err := p.store.LoadPrices(ctx, p.Name())
+	if err != nil {
+		return err
+	}
+
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	restored, exp := providers.RestorePrices(saved, p.cacheTTL, p.staleThreshold(), time.Now())
+	for _, price := range restored {
+		p.cache[price.Symbol] = price
+	}
+	p.cacheExp = exp
+	return nil
+}
+
+// SetSymbols replaces the configured symbol list (safe for concurrent use)
+func (p *Provider) SetSymbols(symbols []string) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.symbols = symbols
+}
+
+// SetCacheTTL changes how long fetched prices are cached (safe for concurrent use)
+func (p *Provider) SetCacheTTL(ttl time.Duration) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.cacheTTL = providers.OrDefault(ttl, defaultCacheTTL)
+}
+
+// SetStaleAfter changes the age at which prices are flagged stale; zero
+// follows the cache TTL (safe for concurrent use)
+func (p *Provider) SetStaleAfter(staleAfter time.Duration) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.staleAfter = staleAfter
+}
+
+// staleThreshold returns the age at which prices are flagged stale (callers
+// hold cacheMu)
+func (p *Provider) staleThreshold() time.Duration {
+	if p.staleAfter <= 0 {
+		return p.cacheTTL
+	}
+	return p.staleAfter
+}
+
+// FlushCache expires cached prices so the next fetch goes to Binance. They
+// are kept as a stale fallback in case it fails.
+func (p *Provider) FlushCache() {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.cacheExp = time.Time{}
+	clear(p.klines)
+}
+
 // Name returns the provider name
 func (p *Provider) Name() string {
 	return "binance"
 }
 
-// FetchPrices retrieves prices for the given symbols
+// FetchPrices retrieves prices for the given symbols. A symbol Binance
+// rejects as invalid on delistedAfter fetches in a row is returned with
+// Delisted set, at its last known price if one is cached.
 func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
 	// Check cache first
+	if prices, ok := p.cachedPrices(symbols); ok {
+		metrics.ObserveCache(p.Name(), true)
+		return prices, nil
+	}
+	metrics.ObserveCache(p.Name(), false)
+
+	// Wait for a fetch in flight, such as the background refresh, which may
+	// bring the prices wanted here
+	select {
+	case p.fetching <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-p.fetching }()
+	if prices, ok := p.cachedPrices(symbols); ok {
+		return prices, nil
+	}
+
+	p.cacheMu.Lock()
+	p.lastRequested = slices.Clone(symbols)
+	p.cacheMu.Unlock()
+
+	slog.Info("fetching Binance data", "symbols", symbols)
+	return p.fetchPrices(ctx, symbols)
+}
+
+// cachedPrices returns the cached prices of symbols, if the cache hasn't
+// expired and holds them all
+func (p *Provider) cachedPrices(symbols []string) ([]providers.Price, bool) {
 	p.cacheMu.RLock()
-	if time.Now().Before(p.cacheExp) && len(p.cache) > 0 {
-		prices := make([]providers.Price, 0, len(symbols))
-		allCached := true
-		for _, s := range symbols {
-			if price, ok := p.cache[s]; ok {
-				prices = append(prices, price)
-			} else {
-				allCached = false
-				break
-			}
-		}
-		if allCached {
-			p.cacheMu.RUnlock()
-			return prices, nil
+	defer p.cacheMu.RUnlock()
+	if !time.Now().Before(p.cacheExp) || len(p.cache) == 0 {
+		return nil, false
+	}
+	prices := make([]providers.Price, 0, len(symbols))
+	for _, s := range symbols {
+		price, ok := p.cache[s]
+		if !ok {
+			return nil, false
 		}
+		prices = append(prices, price)
 	}
-	p.cacheMu.RUnlock()
+	return providers.MarkStale(prices, p.staleThreshold(), time.Now()), true
+}
 
-	slog.Info("fetching Binance data", "symbols", symbols)
+// fetchPrices fetches symbols from Binance and caches the result. Callers
+// hold the fetching token.
+func (p *Provider) fetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
+	// Bound the whole batch, not just each request, since symbols are fetched sequentially
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
 
 	prices := make([]providers.Price, 0, len(symbols))
+	fresh := make([]providers.Price, 0, len(symbols))
 	now := time.Now()
 
 	for _, symbol := range symbols {
-		ticker, err := p.fetch24hrTicker(ctx, symbol)
+		start := time.Now()
+		ticker, err := p.fetch24hrTicker(fetchCtx, symbol)
+		metrics.ObserveFetch(p.Name(), start, err)
 		if err != nil {
 			slog.Warn("failed to fetch ticker", "symbol", symbol, "error", err)
-			// Return cached value if available
-			p.cacheMu.RLock()
-			if cached, ok := p.cache[symbol]; ok {
+			p.cacheMu.Lock()
+			delisted := false
+			if errors.Is(err, errUnlisted) {
+				p.unlisted[symbol]++
+				delisted = p.unlisted[symbol] >= delistedAfter
+			}
+			cached, ok := p.cache[symbol]
+			p.cacheMu.Unlock()
+
+			switch {
+			case delisted:
+				// Keep the last known price, if any, so the holding isn't
+				// silently valued at zero
+				if !ok {
+					cached = providers.Price{Symbol: symbol, Name: getSymbolName(symbol), LastUpdated: now}
+				}
 				cached.Stale = true
+				cached.Delisted = true
+				prices = append(prices, cached)
+			case ok:
+				// Return cached value if available; it is flagged stale
+				// below once it's older than the stale threshold
 				prices = append(prices, cached)
 			}
-			p.cacheMu.RUnlock()
 			continue
 		}
 
@@ -112,6 +313,7 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 			Stale:       false,
 		}
 		prices = append(prices, price)
+		fresh = append(fresh, price)
 	}
 
 	// Update cache
@@ -120,14 +322,23 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 		p.cache[price.Symbol] = price
 	}
 	p.cacheExp = time.Now().Add(p.cacheTTL)
+	if len(fresh) > 0 {
+		p.lastSuccess = now
+	}
+	for _, price := range fresh {
+		delete(p.unlisted, price.Symbol)
+	}
+	staleAfter := p.staleThreshold()
 	p.cacheMu.Unlock()
 
-	return prices, nil
+	providers.PersistPrices(ctx, p.store, p.Name(), fresh)
+
+	return providers.MarkStale(prices, staleAfter, now), nil
 }
 
 // fetch24hrTicker fetches 24hr ticker data for a symbol
 func (p *Provider) fetch24hrTicker(ctx context.Context, symbol string) (*tickerResponse, error) {
-	url := fmt.Sprintf("%s/api/v3/ticker/24hr?symbol=%s", baseURL, symbol)
+	url := fmt.Sprintf("%s/api/v3/ticker/24hr?symbol=%s", p.baseURL, symbol)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -140,8 +351,8 @@ func (p *Provider) fetch24hrTicker(ctx context.Context, symbol string) (*tickerR
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	if err := statusError(resp, symbol); err != nil {
+		return nil, err
 	}
 
 	var ticker tickerResponse
@@ -152,9 +363,222 @@ func (p *Provider) fetch24hrTicker(ctx context.Context, symbol string) (*tickerR
 	return &ticker, nil
 }
 
+// statusError returns the error a non-200 response for symbol represents,
+// wrapping errUnlisted when Binance rejects the symbol as invalid
+func statusError(resp *http.Response, symbol string) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		var apiErr errorResponse
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Code == codeInvalidSymbol {
+			return fmt.Errorf("%w: %s", errUnlisted, symbol)
+		}
+	}
+	return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+}
+
+// FetchHistoricalPrices returns the daily close of each symbol on date (UTC
+// candles). Symbols that fail or have no candle for that day are omitted.
+func (p *Provider) FetchHistoricalPrices(ctx context.Context, date time.Time, symbols []string) ([]providers.Price, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	prices := make([]providers.Price, 0, len(symbols))
+	var lastErr error
+	for _, symbol := range symbols {
+		start := time.Now()
+		closePrice, err := p.fetchDailyClose(fetchCtx, symbol, day)
+		metrics.ObserveFetch(p.Name(), start, err)
+		if err != nil {
+			slog.Warn("failed to fetch historical price", "symbol", symbol, "date", day.Format(time.DateOnly), "error", err)
+			lastErr = err
+			continue
+		}
+		prices = append(prices, providers.Price{
+			Symbol:      symbol,
+			Name:        getSymbolName(symbol),
+			Price:       closePrice,
+			LastUpdated: day,
+		})
+	}
+
+	if len(prices) == 0 && lastErr != nil {
+		return nil, fmt.Errorf("fetching historical prices: %w", lastErr)
+	}
+	return prices, nil
+}
+
+// FetchKlines returns the last limit candles of interval for symbol, oldest
+// first. Limit is capped at MaxKlineLimit. Results are cached like prices.
+func (p *Provider) FetchKlines(ctx context.Context, symbol, interval string, limit int) ([]providers.Candle, error) {
+	if !slices.Contains(KlineIntervals, interval) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidInterval, interval)
+	}
+	limit = min(max(limit, 1), MaxKlineLimit)
+	key := fmt.Sprintf("%s/%s/%d", symbol, interval, limit)
+
+	p.cacheMu.RLock()
+	entry, ok := p.klines[key]
+	p.cacheMu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		metrics.ObserveCache(p.Name(), true)
+		return entry.candles, nil
+	}
+	metrics.ObserveCache(p.Name(), false)
+
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	candles, err := p.fetchKlines(fetchCtx, symbol, interval, limit)
+	metrics.ObserveFetch(p.Name(), start, err)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s klines for %s: %w", interval, symbol, err)
+	}
+
+	now := time.Now()
+	p.cacheMu.Lock()
+	for k, e := range p.klines {
+		if now.After(e.expires) {
+			delete(p.klines, k)
+		}
+	}
+	p.klines[key] = klineEntry{candles: candles, expires: now.Add(p.cacheTTL)}
+	p.cacheMu.Unlock()
+
+	return candles, nil
+}
+
+// fetchKlines fetches candles from the klines endpoint
+func (p *Provider) fetchKlines(ctx context.Context, symbol, interval string, limit int) ([]providers.Candle, error) {
+	url := fmt.Sprintf("%s/api/v3/klines?symbol=%s&interval=%s&limit=%d", p.baseURL, symbol, interval, limit)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := statusError(resp, symbol); err != nil {
+		return nil, err
+	}
+
+	// Each kline is [openTime, open, high, low, close, volume, ...]
+	var klines [][]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&klines); err != nil {
+		return nil, err
+	}
+
+	candles := make([]providers.Candle, 0, len(klines))
+	for _, k := range klines {
+		candle, err := parseKline(k)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// parseKline converts one Binance kline into a candle
+func parseKline(k []json.RawMessage) (providers.Candle, error) {
+	if len(k) < 6 {
+		return providers.Candle{}, fmt.Errorf("kline has %d fields, want at least 6", len(k))
+	}
+	var openTime int64
+	if err := json.Unmarshal(k[0], &openTime); err != nil {
+		return providers.Candle{}, fmt.Errorf("parsing kline open time: %w", err)
+	}
+
+	var values [5]float64 // Open, high, low, close, volume
+	for i := range values {
+		var str string
+		if err := json.Unmarshal(k[i+1], &str); err != nil {
+			return providers.Candle{}, fmt.Errorf("parsing kline field %d: %w", i+1, err)
+		}
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return providers.Candle{}, fmt.Errorf("parsing kline field %d: %w", i+1, err)
+		}
+		values[i] = v
+	}
+
+	return providers.Candle{
+		OpenTime: time.UnixMilli(openTime).UTC(),
+		Open:     values[0],
+		High:     values[1],
+		Low:      values[2],
+		Close:    values[3],
+		Volume:   values[4],
+	}, nil
+}
+
+// fetchDailyClose fetches the close price of the daily candle opening at day
+func (p *Provider) fetchDailyClose(ctx context.Context, symbol string, day time.Time) (float64, error) {
+	url := fmt.Sprintf("%s/api/v3/klines?symbol=%s&interval=1d&startTime=%d&limit=1", p.baseURL, symbol, day.UnixMilli())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := statusError(resp, symbol); err != nil {
+		return 0, err
+	}
+
+	// Each kline is [openTime, open, high, low, close, ...]
+	var klines [][]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&klines); err != nil {
+		return 0, err
+	}
+
+	// Binance returns the next available candle if the symbol wasn't listed yet
+	if len(klines) == 0 || len(klines[0]) < 5 {
+		return 0, providers.ErrSymbolNotFound
+	}
+	var openTime int64
+	var closeStr string
+	if err := json.Unmarshal(klines[0][0], &openTime); err != nil {
+		return 0, fmt.Errorf("parsing kline open time: %w", err)
+	}
+	if openTime != day.UnixMilli() {
+		return 0, providers.ErrSymbolNotFound
+	}
+	if err := json.Unmarshal(klines[0][4], &closeStr); err != nil {
+		return 0, fmt.Errorf("parsing kline close: %w", err)
+	}
+
+	return strconv.ParseFloat(closeStr, 64)
+}
+
+// Status reports the last successful fetch and the state of the price cache
+func (p *Provider) Status() providers.ProviderStatus {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+	age, cached := providers.CacheAge(p.cache, time.Now())
+	return providers.ProviderStatus{
+		LastSuccess:    p.lastSuccess,
+		CacheAge:       age,
+		StaleAvailable: cached,
+	}
+}
+
 // IsHealthy checks if the provider is operational
 func (p *Provider) IsHealthy(ctx context.Context) bool {
-	url := fmt.Sprintf("%s/api/v3/ping", baseURL)
+	url := fmt.Sprintf("%s/api/v3/ping", p.baseURL)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return false
@@ -174,22 +598,73 @@ func (p *Provider) Close() error {
 	return nil
 }
 
-// getSymbolName returns a human-readable name for a symbol
+// ErrInvalidSymbol is returned by NormalizeSymbol for input that can't be
+// read as a coin or trading pair
+var ErrInvalidSymbol = errors.New(`invalid crypto symbol; use a coin ("BTC"), a pair ("BTCUSDT"), or base and quote separated by "-" or "/" ("ETH/BTC")`)
+
+// DefaultQuote is appended to a bare coin when no quote is configured
+const DefaultQuote = "USDT"
+
+// NormalizeSymbol converts user input such as "btc", "ETH-USDT" or "sol/try"
+// to a Binance pair ("BTCUSDT", "ETHUSDT", "SOLTRY"). Bare coins get quote,
+// or DefaultQuote when it's empty; valid pairs are returned unchanged.
+//
+// Only fiat and stablecoin quotes (providers.QuoteAssets) are recognized
+// without a separator. Crypto quotes (BTC, ETH, BNB) also end coin names such
+// as WBTC, so pairs quoted in them need one ("ETH/BTC").
+func NormalizeSymbol(input, quote string) (string, error) {
+	if quote == "" {
+		quote = DefaultQuote
+	}
+
+	parts := strings.FieldsFunc(strings.ToUpper(input), func(r rune) bool {
+		return r == '-' || r == '/' || r == '_' || r == ':' || unicode.IsSpace(r)
+	})
+	for _, part := range parts {
+		for _, r := range part {
+			if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+				return "", fmt.Errorf("%w: %q", ErrInvalidSymbol, input)
+			}
+		}
+	}
+
+	switch len(parts) {
+	case 1:
+		symbol := parts[0]
+		for _, q := range providers.QuoteAssets {
+			if symbol == q {
+				return "", fmt.Errorf("%w: %q is a quote asset, not a coin", ErrInvalidSymbol, input)
+			}
+			if strings.HasSuffix(symbol, q) {
+				return symbol, nil
+			}
+		}
+		return symbol + quote, nil
+	case 2:
+		return parts[0] + parts[1], nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidSymbol, input)
+	}
+}
+
+// coinNames are human-readable names keyed by base asset
+var coinNames = map[string]string{
+	"BTC":   "Bitcoin",
+	"ETH":   "Ethereum",
+	"SOL":   "Solana",
+	"BNB":   "BNB",
+	"XRP":   "XRP",
+	"ADA":   "Cardano",
+	"DOGE":  "Dogecoin",
+	"DOT":   "Polkadot",
+	"MATIC": "Polygon",
+	"AVAX":  "Avalanche",
+}
+
+// getSymbolName returns a human-readable name for a symbol, whatever its quote
 func getSymbolName(symbol string) string {
-	names := map[string]string{
-		"BTCUSDT":   "Bitcoin",
-		"ETHUSDT":   "Ethereum",
-		"SOLUSDT":   "Solana",
-		"BNBUSDT":   "BNB",
-		"XRPUSDT":   "XRP",
-		"ADAUSDT":   "Cardano",
-		"DOGEUSDT":  "Dogecoin",
-		"DOTUSDT":   "Polkadot",
-		"MATICUSDT": "Polygon",
-		"AVAXUSDT":  "Avalanche",
-	}
-
-	if name, ok := names[symbol]; ok {
+	base, _ := providers.SplitPair(symbol)
+	if name, ok := coinNames[base]; ok {
 		return name
 	}
 	return symbol
@@ -0,0 +1,358 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+)
+
+// fakeBinance serves 24hr tickers from a fixed table and counts requests
+type fakeBinance struct {
+	tickers  map[string]tickerResponse
+	failing  atomic.Bool
+	requests atomic.Int32
+}
+
+func (f *fakeBinance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	if f.failing.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	ticker, ok := f.tickers[r.URL.Query().Get("symbol")]
+	if !ok {
+		http.Error(w, `{"code":-1121,"msg":"Invalid symbol."}`, http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path == "/api/v3/klines" {
+		// One candle opening at startTime, closing at the ticker's last price
+		openTime, _ := strconv.ParseInt(r.URL.Query().Get("startTime"), 10, 64)
+		json.NewEncoder(w).Encode([][]any{{openTime, "1", "2", "0.5", ticker.LastPrice, "10", openTime + 1}})
+		return
+	}
+	json.NewEncoder(w).Encode(ticker)
+}
+
+func newFakeBinance(t *testing.T) (*fakeBinance, *httptest.Server) {
+	t.Helper()
+	fake := &fakeBinance{tickers: map[string]tickerResponse{
+		"BTCUSDT": {Symbol: "BTCUSDT", LastPrice: "50000.50", PriceChange: "-250.25", PriceChangePercent: "-0.498"},
+		"ETHUSDT": {Symbol: "ETHUSDT", LastPrice: "3000", PriceChange: "30", PriceChangePercent: "1.01"},
+	}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func TestFetchPricesParsesTickers(t *testing.T) {
+	tests := []struct {
+		name       string
+		symbols    []string
+		wantPrices map[string]float64
+		wantPct    map[string]float64
+	}{
+		{
+			name:       "single symbol",
+			symbols:    []string{"BTCUSDT"},
+			wantPrices: map[string]float64{"BTCUSDT": 50000.50},
+			wantPct:    map[string]float64{"BTCUSDT": -0.498},
+		},
+		{
+			name:       "multiple symbols",
+			symbols:    []string{"BTCUSDT", "ETHUSDT"},
+			wantPrices: map[string]float64{"BTCUSDT": 50000.50, "ETHUSDT": 3000},
+			wantPct:    map[string]float64{"BTCUSDT": -0.498, "ETHUSDT": 1.01},
+		},
+		{
+			name:       "unknown symbol is skipped",
+			symbols:    []string{"BTCUSDT", "NOPEUSDT"},
+			wantPrices: map[string]float64{"BTCUSDT": 50000.50},
+			wantPct:    map[string]float64{"BTCUSDT": -0.498},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, srv := newFakeBinance(t)
+			p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client()})
+
+			prices, err := p.FetchPrices(context.Background(), tt.symbols)
+			if err != nil {
+				t.Fatalf("FetchPrices() error = %v", err)
+			}
+			if len(prices) != len(tt.wantPrices) {
+				t.Fatalf("got %d prices, want %d", len(prices), len(tt.wantPrices))
+			}
+			for _, price := range prices {
+				if price.Price != tt.wantPrices[price.Symbol] {
+					t.Errorf("%s price = %v, want %v", price.Symbol, price.Price, tt.wantPrices[price.Symbol])
+				}
+				if price.DailyPct != tt.wantPct[price.Symbol] {
+					t.Errorf("%s daily pct = %v, want %v", price.Symbol, price.DailyPct, tt.wantPct[price.Symbol])
+				}
+				if price.Stale {
+					t.Errorf("%s is stale, want fresh", price.Symbol)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchPricesUsesCache(t *testing.T) {
+	fake, srv := newFakeBinance(t)
+	p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client(), CacheTTL: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.FetchPrices(ctx, []string{"BTCUSDT"}); err != nil {
+			t.Fatalf("FetchPrices() error = %v", err)
+		}
+	}
+
+	if got := fake.requests.Load(); got != 1 {
+		t.Errorf("server requests = %d, want 1", got)
+	}
+}
+
+func TestFlushCache(t *testing.T) {
+	fake, srv := newFakeBinance(t)
+	p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client(), CacheTTL: time.Hour})
+	ctx := context.Background()
+
+	if _, err := p.FetchPrices(ctx, []string{"BTCUSDT"}); err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+	p.FlushCache()
+	if _, err := p.FetchPrices(ctx, []string{"BTCUSDT"}); err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+	if got := fake.requests.Load(); got != 2 {
+		t.Errorf("server requests = %d, want 2 after flush", got)
+	}
+
+	// Flushed prices still back up a failing upstream
+	p.FlushCache()
+	fake.failing.Store(true)
+	prices, err := p.FetchPrices(ctx, []string{"BTCUSDT"})
+	if err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+	if len(prices) != 1 || prices[0].Stale {
+		t.Errorf("prices = %+v, want one price, not yet stale", prices)
+	}
+
+	// They are flagged stale once older than the stale threshold
+	p.SetStaleAfter(time.Nanosecond)
+	prices, err = p.FetchPrices(ctx, []string{"BTCUSDT"})
+	if err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+	if len(prices) != 1 || !prices[0].Stale {
+		t.Errorf("prices = %+v, want one stale price", prices)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	fake, srv := newFakeBinance(t)
+	p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client(), CacheTTL: time.Hour, Symbols: []string{"BTCUSDT"}})
+	ctx := context.Background()
+
+	steps := []struct {
+		name         string
+		do           func()
+		wantRequests int32
+	}{
+		{name: "refresh warms configured symbols", do: func() { p.refresh(ctx) }, wantRequests: 1},
+		{name: "request hits the warm cache", do: func() { p.FetchPrices(ctx, []string{"BTCUSDT"}) }, wantRequests: 1},
+		{name: "refresh before it's due is skipped", do: func() { p.refresh(ctx) }, wantRequests: 1},
+		{name: "request for another symbol fetches it", do: func() { p.FetchPrices(ctx, []string{"ETHUSDT"}) }, wantRequests: 2},
+		{name: "due refresh includes requested symbols", do: func() { p.FlushCache(); p.refresh(ctx) }, wantRequests: 4},
+		{
+			name: "request waits for the fetch in flight",
+			do: func() {
+				p.FlushCache()
+				p.fetching <- struct{}{}
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					p.FetchPrices(ctx, []string{"BTCUSDT"})
+				}()
+				p.fetchPrices(ctx, []string{"BTCUSDT"})
+				<-p.fetching
+				<-done
+			},
+			wantRequests: 5,
+		},
+	}
+
+	for _, step := range steps {
+		step.do()
+		if got := fake.requests.Load(); got != step.wantRequests {
+			t.Errorf("%s: server requests = %d, want %d", step.name, got, step.wantRequests)
+		}
+	}
+}
+
+func TestFetchPricesFallsBackToStaleCache(t *testing.T) {
+	fake, srv := newFakeBinance(t)
+	// A tiny TTL expires the cache immediately so the second call goes live
+	p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client(), CacheTTL: time.Nanosecond})
+	ctx := context.Background()
+
+	if _, err := p.FetchPrices(ctx, []string{"BTCUSDT"}); err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+
+	fake.failing.Store(true)
+	prices, err := p.FetchPrices(ctx, []string{"BTCUSDT"})
+	if err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+	if len(prices) != 1 {
+		t.Fatalf("got %d prices, want 1", len(prices))
+	}
+	if !prices[0].Stale {
+		t.Error("price is fresh, want stale")
+	}
+	if prices[0].Price != 50000.50 {
+		t.Errorf("price = %v, want cached 50000.50", prices[0].Price)
+	}
+}
+
+func TestFetchPricesMarksDelisted(t *testing.T) {
+	fake, srv := newFakeBinance(t)
+	p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client(), CacheTTL: time.Nanosecond})
+	ctx := context.Background()
+
+	if _, err := p.FetchPrices(ctx, []string{"BTCUSDT"}); err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+
+	// Binance drops the pair: it is served stale until rejected delistedAfter times in a row
+	delete(fake.tickers, "BTCUSDT")
+	for i := 1; i <= delistedAfter; i++ {
+		prices, err := p.FetchPrices(ctx, []string{"BTCUSDT", "NOPEUSDT"})
+		if err != nil {
+			t.Fatalf("FetchPrices() error = %v", err)
+		}
+		wantDelisted := i == delistedAfter
+		got := make(map[string]bool, len(prices))
+		for _, price := range prices {
+			got[price.Symbol] = price.Delisted
+			if price.Symbol == "BTCUSDT" && (price.Price != 50000.50 || !price.Stale) {
+				t.Errorf("fetch %d: BTCUSDT = %+v, want stale cached 50000.50", i, price)
+			}
+		}
+		if delisted, ok := got["BTCUSDT"]; !ok || delisted != wantDelisted {
+			t.Errorf("fetch %d: BTCUSDT delisted = %v (returned %v), want %v", i, delisted, ok, wantDelisted)
+		}
+		if delisted, ok := got["NOPEUSDT"]; ok != wantDelisted || delisted != wantDelisted {
+			t.Errorf("fetch %d: NOPEUSDT delisted = %v (returned %v), want %v", i, delisted, ok, wantDelisted)
+		}
+	}
+
+	// A relisted pair recovers on its next successful fetch
+	fake.tickers["BTCUSDT"] = tickerResponse{Symbol: "BTCUSDT", LastPrice: "51000"}
+	prices, err := p.FetchPrices(ctx, []string{"BTCUSDT"})
+	if err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+	if len(prices) != 1 || prices[0].Delisted || prices[0].Stale || prices[0].Price != 51000 {
+		t.Errorf("prices = %+v, want fresh 51000 after relisting", prices)
+	}
+}
+
+func TestFetchHistoricalPrices(t *testing.T) {
+	_, srv := newFakeBinance(t)
+	p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client()})
+	date := time.Date(2024, 3, 15, 18, 30, 0, 0, time.UTC)
+
+	prices, err := p.FetchHistoricalPrices(context.Background(), date, []string{"BTCUSDT", "NOPEUSDT"})
+	if err != nil {
+		t.Fatalf("FetchHistoricalPrices() error = %v", err)
+	}
+	if len(prices) != 1 {
+		t.Fatalf("got %d prices, want 1 (unknown symbol omitted)", len(prices))
+	}
+	if prices[0].Price != 50000.50 {
+		t.Errorf("price = %v, want 50000.50", prices[0].Price)
+	}
+	if want := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC); !prices[0].LastUpdated.Equal(want) {
+		t.Errorf("last updated = %v, want %v", prices[0].LastUpdated, want)
+	}
+}
+
+func TestFetchKlines(t *testing.T) {
+	fake, srv := newFakeBinance(t)
+	p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client(), CacheTTL: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		candles, err := p.FetchKlines(ctx, "ETHUSDT", "1d", 30)
+		if err != nil {
+			t.Fatalf("FetchKlines() error = %v", err)
+		}
+		want := providers.Candle{OpenTime: time.UnixMilli(0).UTC(), Open: 1, High: 2, Low: 0.5, Close: 3000, Volume: 10}
+		if len(candles) != 1 || candles[0] != want {
+			t.Fatalf("candles = %+v, want [%+v]", candles, want)
+		}
+	}
+	if got := fake.requests.Load(); got != 1 {
+		t.Errorf("server requests = %d, want 1 (second call cached)", got)
+	}
+
+	if _, err := p.FetchKlines(ctx, "ETHUSDT", "2d", 30); !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("FetchKlines(2d) error = %v, want ErrInvalidInterval", err)
+	}
+	if _, err := p.FetchKlines(ctx, "NOPEUSDT", "1d", 30); !errors.Is(err, providers.ErrSymbolNotFound) {
+		t.Errorf("FetchKlines(NOPEUSDT) error = %v, want ErrSymbolNotFound", err)
+	}
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := []struct {
+		input   string
+		quote   string
+		want    string
+		wantErr bool
+	}{
+		{"BTCUSDT", "", "BTCUSDT", false},
+		{"btc", "", "BTCUSDT", false},
+		{"btc", "TRY", "BTCTRY", false},
+		{"ETHUSDT", "TRY", "ETHUSDT", false},
+		{" Eth ", "", "ETHUSDT", false},
+		{"ETH-USDT", "", "ETHUSDT", false},
+		{"sol/try", "", "SOLTRY", false},
+		{"eth/btc", "", "ETHBTC", false},
+		{"BTCTRY", "", "BTCTRY", false},
+		{"WBTC", "", "WBTCUSDT", false},
+		{"1000satsusdt", "", "1000SATSUSDT", false},
+		{"", "", "", true},
+		{"USDT", "", "", true},
+		{"BTC-USDT-PERP", "", "", true},
+		{"BTC$", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+"/"+tt.quote, func(t *testing.T) {
+			got, err := NormalizeSymbol(tt.input, tt.quote)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSymbol) {
+					t.Errorf("NormalizeSymbol(%q) error = %v, want ErrInvalidSymbol", tt.input, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeSymbol(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package binance
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// minRefreshWait keeps the background refresh from spinning when the cache
+// is already due
+const minRefreshWait = time.Second
+
+// RunRefresher keeps the price cache warm so requests don't wait on Binance:
+// a fifth of the cache TTL before the cached prices expire, it fetches the
+// configured symbols and those last requested again. A fetch a request
+// triggered in the meantime pushes the refresh back rather than being
+// repeated. It blocks until ctx is cancelled.
+func (p *Provider) RunRefresher(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		p.refresh(ctx)
+		timer.Reset(max(time.Until(p.refreshAt()), minRefreshWait))
+	}
+}
+
+// refresh fetches the symbols to keep warm, unless the cache was refreshed
+// while waiting for the fetching token
+func (p *Provider) refresh(ctx context.Context) {
+	symbols := p.refreshSymbols()
+	if len(symbols) == 0 {
+		return
+	}
+
+	select {
+	case p.fetching <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-p.fetching }()
+	if time.Now().Before(p.refreshAt()) {
+		return
+	}
+
+	slog.Debug("refreshing Binance prices", "symbols", symbols)
+	p.fetchPrices(ctx, symbols)
+}
+
+// refreshAt returns when the cache is next due a background refresh
+func (p *Provider) refreshAt() time.Time {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+	return p.cacheExp.Add(-p.cacheTTL / 5)
+}
+
+// refreshSymbols returns the configured symbols and those last requested,
+// without duplicates
+func (p *Provider) refreshSymbols() []string {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+	symbols := slices.Concat(p.symbols, p.lastRequested)
+	slices.Sort(symbols)
+	return slices.Compact(symbols)
+}
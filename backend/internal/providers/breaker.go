@@ -0,0 +1,217 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, without calling the provider, while its
+// circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Fetches go through
+	CircuitOpen     CircuitState = "open"      // Fetches fail at once until the cooldown is over
+	CircuitHalfOpen CircuitState = "half_open" // One probe fetch is in flight; others fail at once
+)
+
+// BreakerConfig holds circuit breaker thresholds
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failed fetches that open the circuit; zero or negative disables breakers
+	Cooldown         time.Duration // How long the circuit stays open before a probe
+}
+
+// CircuitReporter is implemented by providers that guard calls with circuit
+// breakers, so their state can be shown alongside health
+type CircuitReporter interface {
+	// Circuits returns the state of each breaker, keyed by provider name
+	Circuits() map[string]CircuitState
+}
+
+// CircuitBreaker wraps a provider so that, once its fetches of prices,
+// exchange rates or candles fail FailureThreshold times in a row, further
+// fetches fail at once with ErrCircuitOpen for the cooldown instead of
+// waiting on a dead upstream. A fallback then answers without delay. After
+// the cooldown one fetch is let through as a probe: success closes the
+// circuit, failure reopens it.
+//
+// Providers tend to hide upstream failures behind cached prices, so a
+// price fetch also counts as failed when it returns no fresh price at all.
+// Answers the upstream gave, such as an unknown symbol, count as success.
+type CircuitBreaker struct {
+	provider Provider
+	cfg      BreakerConfig
+	now      func() time.Time // Swapped out in tests
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int       // Consecutive failed fetches
+	openUntil time.Time // End of the cooldown while open
+}
+
+// NewCircuitBreaker wraps provider in a circuit breaker
+func NewCircuitBreaker(provider Provider, cfg BreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{provider: provider, cfg: cfg, now: time.Now, state: CircuitClosed}
+}
+
+// Name returns the wrapped provider's name
+func (b *CircuitBreaker) Name() string {
+	return b.provider.Name()
+}
+
+// FetchPrices fetches from the wrapped provider unless the circuit is open
+func (b *CircuitBreaker) FetchPrices(ctx context.Context, symbols []string) ([]Price, error) {
+	if !b.allow() {
+		return nil, fmt.Errorf("%s: %w", b.Name(), ErrCircuitOpen)
+	}
+	prices, err := b.provider.FetchPrices(ctx, symbols)
+	b.record(err, len(symbols) == 0 || anyFresh(prices))
+	return prices, err
+}
+
+// allow reports whether a fetch may go through, turning an open circuit
+// whose cooldown is over half-open with the caller as its probe
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Before(b.openUntil) {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		slog.Info("circuit breaker probing provider", "provider", b.Name())
+		return true
+	case CircuitHalfOpen:
+		return false
+	}
+	return true
+}
+
+// record updates the circuit with the outcome of a fetch: its error, and
+// whether it returned live data
+func (b *CircuitBreaker) record(err error, fresh bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A caller giving up says nothing about the provider; a cancelled probe
+	// leaves the next fetch to probe instead
+	if errors.Is(err, context.Canceled) {
+		if b.state == CircuitHalfOpen {
+			b.state, b.openUntil = CircuitOpen, b.now()
+		}
+		return
+	}
+
+	if answered(err) && fresh {
+		if b.state != CircuitClosed {
+			slog.Info("circuit breaker closed", "provider", b.Name())
+		}
+		b.state, b.failures = CircuitClosed, 0
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state, b.openUntil = CircuitOpen, b.now().Add(b.cfg.Cooldown)
+		slog.Warn("circuit breaker opened", "provider", b.Name(), "consecutive_failures", b.failures, "cooldown", b.cfg.Cooldown)
+	}
+}
+
+// answered reports whether err, if any, is an answer from the upstream
+// rather than a failure to reach it
+func answered(err error) bool {
+	return err == nil || errors.Is(err, ErrSymbolNotFound) || errors.Is(err, ErrUnsupportedCurrency)
+}
+
+// anyFresh reports whether any of prices isn't stale
+func anyFresh(prices []Price) bool {
+	for _, price := range prices {
+		if !price.Stale {
+			return true
+		}
+	}
+	return false
+}
+
+// State returns the current state of the circuit
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Circuits reports the state of this breaker
+func (b *CircuitBreaker) Circuits() map[string]CircuitState {
+	return map[string]CircuitState{b.Name(): b.State()}
+}
+
+// IsHealthy reports the wrapped provider unhealthy while the circuit is open
+func (b *CircuitBreaker) IsHealthy(ctx context.Context) bool {
+	return b.State() != CircuitOpen && b.provider.IsHealthy(ctx)
+}
+
+// Status returns the wrapped provider's status, if it reports one
+func (b *CircuitBreaker) Status() ProviderStatus {
+	if sr, ok := b.provider.(StatusReporter); ok {
+		return sr.Status()
+	}
+	return ProviderStatus{}
+}
+
+// FlushCache flushes the wrapped provider's cache, if it has one
+func (b *CircuitBreaker) FlushCache() {
+	if f, ok := b.provider.(CacheFlusher); ok {
+		f.FlushCache()
+	}
+}
+
+// Close closes the wrapped provider
+func (b *CircuitBreaker) Close() error {
+	return b.provider.Close()
+}
+
+// FetchExchangeRate asks the wrapped provider, if it serves exchange rates,
+// unless the circuit is open
+func (b *CircuitBreaker) FetchExchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
+	erp, ok := b.provider.(ExchangeRateProvider)
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("%s exchange rates: %w", b.Name(), ErrNotSupported)
+	}
+	if !b.allow() {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", b.Name(), ErrCircuitOpen)
+	}
+	rate, asOf, err := erp.FetchExchangeRate(ctx, from, to)
+	b.record(err, true)
+	return rate, asOf, err
+}
+
+// FetchKlines asks the wrapped provider, if it serves candles, unless the
+// circuit is open
+func (b *CircuitBreaker) FetchKlines(ctx context.Context, symbol, interval string, limit int) ([]Candle, error) {
+	kp, ok := b.provider.(KlineProvider)
+	if !ok {
+		return nil, fmt.Errorf("%s candles: %w", b.Name(), ErrNotSupported)
+	}
+	if !b.allow() {
+		return nil, fmt.Errorf("%s: %w", b.Name(), ErrCircuitOpen)
+	}
+	candles, err := kp.FetchKlines(ctx, symbol, interval, limit)
+	b.record(err, true)
+	return candles, err
+}
+
+// FetchHistoricalPrices asks the wrapped provider, if it serves past prices
+func (b *CircuitBreaker) FetchHistoricalPrices(ctx context.Context, date time.Time, symbols []string) ([]Price, error) {
+	if hp, ok := b.provider.(HistoricalPriceProvider); ok {
+		return hp.FetchHistoricalPrices(ctx, date, symbols)
+	}
+	return nil, fmt.Errorf("%s historical prices: %w", b.Name(), ErrNotSupported)
+}
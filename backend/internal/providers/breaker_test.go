@@ -0,0 +1,174 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	down := errors.New("upstream down")
+	stub := &stubProvider{name: "binance", known: map[string]float64{"BTCUSDT": 65000}, err: down}
+	b := NewCircuitBreaker(stub, BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+	symbols := []string{"BTCUSDT"}
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		err       error
+		wantCall  bool
+		wantState CircuitState
+	}{
+		{name: "first failure", err: down, wantCall: true, wantState: CircuitClosed},
+		{name: "second failure opens", err: down, wantCall: true, wantState: CircuitOpen},
+		{name: "short-circuited while open", advance: 30 * time.Second, wantState: CircuitOpen},
+		{name: "failed probe reopens", advance: 30 * time.Second, err: down, wantCall: true, wantState: CircuitOpen},
+		{name: "cooldown restarted", advance: 30 * time.Second, wantState: CircuitOpen},
+		{name: "successful probe closes", advance: 30 * time.Second, wantCall: true, wantState: CircuitClosed},
+		{name: "cancelled fetch doesn't count", err: context.Canceled, wantCall: true, wantState: CircuitClosed},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		stub.err = step.err
+		calls := len(stub.requested)
+
+		_, err := b.FetchPrices(ctx, symbols)
+		if called := len(stub.requested) > calls; called != step.wantCall {
+			t.Errorf("%s: provider called = %v, want %v", step.name, called, step.wantCall)
+		}
+		if !step.wantCall && !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("%s: error = %v, want ErrCircuitOpen", step.name, err)
+		}
+		if got := b.State(); got != step.wantState {
+			t.Errorf("%s: state = %s, want %s", step.name, got, step.wantState)
+		}
+	}
+}
+
+func TestCircuitBreakerCountsStaleAnswersAsFailures(t *testing.T) {
+	stale := &staleProvider{stubProvider{name: "binance", known: map[string]float64{"BTCUSDT": 65000}}}
+	b := NewCircuitBreaker(stale, BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+
+	prices, err := b.FetchPrices(context.Background(), []string{"BTCUSDT"})
+	if err != nil || len(prices) != 1 {
+		t.Fatalf("FetchPrices() = %v, %v; want the stale price", prices, err)
+	}
+	if got := b.State(); got != CircuitOpen {
+		t.Errorf("state after a stale-only answer = %s, want open", got)
+	}
+	if b.IsHealthy(context.Background()) {
+		t.Error("IsHealthy() = true while open")
+	}
+}
+
+// staleProvider answers only with stale prices, as providers do when they
+// fall back on their cache
+type staleProvider struct{ stubProvider }
+
+func (s *staleProvider) FetchPrices(ctx context.Context, symbols []string) ([]Price, error) {
+	prices, err := s.stubProvider.FetchPrices(ctx, symbols)
+	return MarkStale(prices, time.Nanosecond, time.Now().Add(time.Hour)), err
+}
+
+func TestCircuitBreakerTriggersFallback(t *testing.T) {
+	primary := &stubProvider{name: "binance", err: errors.New("upstream down")}
+	backup := &stubProvider{name: "coingecko", known: map[string]float64{"BTCUSDT": 64000}}
+
+	reg := NewRegistry()
+	reg.SetCircuitBreaker(BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	reg.Register(ProviderTypeBinance, RoleCrypto, func(ctx context.Context) (any, error) { return primary, nil })
+	reg.Register(ProviderTypeCoinGecko, RoleCrypto, func(ctx context.Context) (any, error) { return backup, nil })
+	built, err := reg.Build(context.Background(), []ProviderType{ProviderTypeBinance, ProviderTypeCoinGecko})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	for range 3 {
+		prices, err := built.Crypto.FetchPrices(context.Background(), []string{"BTCUSDT"})
+		if err != nil || len(prices) != 1 || prices[0].Price != 64000 {
+			t.Fatalf("FetchPrices() = %v, %v; want the backup's price", prices, err)
+		}
+	}
+	if len(primary.requested) != 1 {
+		t.Errorf("primary asked %d times, want once before its circuit opened", len(primary.requested))
+	}
+
+	circuits := built.Crypto.(CircuitReporter).Circuits()
+	if len(circuits) != 1 || circuits["binance"] != CircuitOpen {
+		t.Errorf("Circuits() = %v, want only binance, open", circuits)
+	}
+}
+
+// marketStub serves exchange rates and candles, failing with err, and counts
+// the calls made to it
+type marketStub struct {
+	stubProvider
+	calls int
+}
+
+func (m *marketStub) FetchExchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
+	m.calls++
+	return 40, time.Now(), m.err
+}
+
+func (m *marketStub) FetchKlines(ctx context.Context, symbol, interval string, limit int) ([]Candle, error) {
+	m.calls++
+	return []Candle{{Close: 65000}}, m.err
+}
+
+func TestCircuitBreakerGuardsRatesAndCandles(t *testing.T) {
+	down := errors.New("upstream down")
+	tests := []struct {
+		name  string
+		fetch func(b *CircuitBreaker) error
+	}{
+		{"exchange rate", func(b *CircuitBreaker) error {
+			_, _, err := b.FetchExchangeRate(context.Background(), "USD", "TRY")
+			return err
+		}},
+		{"candles", func(b *CircuitBreaker) error {
+			_, err := b.FetchKlines(context.Background(), "BTCUSDT", "1d", 30)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &marketStub{stubProvider: stubProvider{name: "binance"}}
+			b := NewCircuitBreaker(stub, BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+			now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+			b.now = func() time.Time { return now }
+
+			// Answers from the upstream keep the circuit closed
+			stub.err = ErrSymbolNotFound
+			for range 2 {
+				tt.fetch(b)
+			}
+			if got := b.State(); got != CircuitClosed {
+				t.Fatalf("state after not-found answers = %s, want closed", got)
+			}
+
+			stub.err = down
+			for range 2 {
+				tt.fetch(b)
+			}
+			if got := b.State(); got != CircuitOpen {
+				t.Fatalf("state after two failures = %s, want open", got)
+			}
+			if err := tt.fetch(b); !errors.Is(err, ErrCircuitOpen) || stub.calls != 4 {
+				t.Errorf("fetch while open: error = %v after %d calls, want ErrCircuitOpen without a call", err, stub.calls)
+			}
+
+			now = now.Add(time.Minute)
+			stub.err = nil
+			if err := tt.fetch(b); err != nil || b.State() != CircuitClosed {
+				t.Errorf("probe: error = %v, state = %s; want success, closed", err, b.State())
+			}
+		})
+	}
+}
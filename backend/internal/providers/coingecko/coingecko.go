@@ -6,64 +6,171 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/ferhatkunduraci/prism/internal/metrics"
 	"github.com/ferhatkunduraci/prism/internal/providers"
 )
 
 const (
-	baseURL = "https://api.coingecko.com/api/v3"
+	// defaultBaseURL is the CoinGecko API root used when Config.BaseURL is empty
+	defaultBaseURL = "https://api.coingecko.com/api/v3"
+
+	// defaultCacheTTL is used when no TTL is configured
+	defaultCacheTTL = 60 * time.Second // CoinGecko has rate limits
+
+	// defaultTimeout is used when no fetch timeout is configured
+	defaultTimeout = 10 * time.Second
 )
 
 // Provider implements the CoinGecko data provider (fallback for Binance)
 type Provider struct {
 	client   *http.Client
+	baseURL  string
 	apiKey   string
 	cache    map[string]providers.Price
 	cacheMu  sync.RWMutex
 	cacheExp time.Time
 	cacheTTL time.Duration
+	store    providers.PriceStore
+	timeout  time.Duration
 
-	// Exchange rate cache
-	exchangeRate    float64
-	exchangeRateExp time.Time
+	staleAfter  time.Duration // Age at which prices are flagged stale; 0 follows cacheTTL (guarded by cacheMu)
+	lastSuccess time.Time     // Last successful price fetch (guarded by cacheMu)
+
+	// Exchange rate cache, keyed by "FROM/TO"
+	exchangeRates   map[string]cachedRate
 	exchangeRateMu  sync.RWMutex
 	exchangeRateTTL time.Duration
 }
 
+// cachedRate is one cached exchange rate
+type cachedRate struct {
+	rate    float64
+	updated time.Time
+	exp     time.Time
+}
+
+// stablecoinProxies maps a currency to a coin pegged to it. Its price in the
+// quote currency is used as the rate, matching what crypto holdings trade at.
+// Other base currencies are cross-rated through bitcoin.
+var stablecoinProxies = map[string]string{
+	"USD": "tether",
+}
+
 // Config holds CoinGecko provider configuration
 type Config struct {
-	APIKey string // Optional, for higher rate limits
-}
+	APIKey   string               // Optional, for higher rate limits
+	CacheTTL time.Duration        // Optional, defaults to 60s
+	Store    providers.PriceStore // Optional, persists last-known prices
+	Timeout  time.Duration        // Optional, deadline for one fetch, defaults to 10s
 
-// priceResponse represents CoinGecko simple price response
-type priceResponse map[string]struct {
-	USD          float64 `json:"usd"`
-	USD24HChange float64 `json:"usd_24h_change"`
+	StaleAfter time.Duration // Optional, age at which prices are flagged stale, defaults to CacheTTL
+
+	BaseURL    string       // Optional, defaults to the public CoinGecko API
+	HTTPClient *http.Client // Optional, replaces the default client (e.g. in tests)
+	Proxy      *url.URL     // Optional, routes requests through a proxy; nil uses HTTPS_PROXY/HTTP_PROXY
 }
 
+// priceResponse represents CoinGecko simple price response: coin ID to
+// quotes keyed by currency ("usd") and change ("usd_24h_change")
+type priceResponse map[string]map[string]float64
+
 // NewProvider creates a new CoinGecko provider
 func NewProvider(cfg Config) *Provider {
+	timeout := providers.OrDefault(cfg.Timeout, defaultTimeout)
+	client := cfg.HTTPClient
+	if client == nil {
+		client = providers.NewHTTPClient(timeout, cfg.Proxy)
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
 	return &Provider{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client:          client,
+		baseURL:         baseURL,
 		apiKey:          cfg.APIKey,
 		cache:           make(map[string]providers.Price),
-		cacheTTL:        60 * time.Second, // CoinGecko has rate limits
-		exchangeRateTTL: 5 * time.Minute,  // Exchange rate cached for 5 minutes
+		cacheTTL:        providers.OrDefault(cfg.CacheTTL, defaultCacheTTL),
+		store:           cfg.Store,
+		timeout:         timeout,
+		staleAfter:      cfg.StaleAfter,
+		exchangeRates:   make(map[string]cachedRate),
+		exchangeRateTTL: 5 * time.Minute, // Exchange rate cached for 5 minutes
 	}
 }
 
+// LoadCache fills the cache with prices persisted by a previous run
+func (p *Provider) LoadCache(ctx context.Context) error {
+	if p.store == nil {
+		return nil
+	}
+
+	saved, err := p.store.LoadPrices(ctx, p.Name())
+	if err != nil {
+		return err
+	}
+
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	restored, exp := providers.RestorePrices(saved, p.cacheTTL, p.staleThreshold(), time.Now())
+	for _, price := range restored {
+		p.cache[price.Symbol] = price
+	}
+	p.cacheExp = exp
+	return nil
+}
+
+// SetCacheTTL changes how long fetched prices are cached (safe for concurrent use)
+func (p *Provider) SetCacheTTL(ttl time.Duration) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.cacheTTL = providers.OrDefault(ttl, defaultCacheTTL)
+}
+
+// SetStaleAfter changes the age at which prices are flagged stale; zero
+// follows the cache TTL (safe for concurrent use)
+func (p *Provider) SetStaleAfter(staleAfter time.Duration) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.staleAfter = staleAfter
+}
+
+// staleThreshold returns the age at which prices are flagged stale (callers
+// hold cacheMu)
+func (p *Provider) staleThreshold() time.Duration {
+	if p.staleAfter <= 0 {
+		return p.cacheTTL
+	}
+	return p.staleAfter
+}
+
+// FlushCache expires cached prices and drops cached exchange rates. Prices
+// are kept as a stale fallback in case the next fetch fails.
+func (p *Provider) FlushCache() {
+	p.cacheMu.Lock()
+	p.cacheExp = time.Time{}
+	p.cacheMu.Unlock()
+
+	p.exchangeRateMu.Lock()
+	clear(p.exchangeRates)
+	p.exchangeRateMu.Unlock()
+}
+
 // Name returns the provider name
 func (p *Provider) Name() string {
 	return "coingecko"
 }
 
 // FetchPrices retrieves prices for the given symbols
-// Note: CoinGecko uses coin IDs like "bitcoin", not trading pairs like "BTCUSDT"
+// Note: CoinGecko uses coin IDs like "bitcoin", not trading pairs like "BTCUSDT";
+// each symbol is priced in its quote currency (USD for stablecoin pairs)
 func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
 	// Check cache first
 	p.cacheMu.RLock()
@@ -71,8 +178,7 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 		prices := make([]providers.Price, 0, len(symbols))
 		allCached := true
 		for _, s := range symbols {
-			coinID := symbolToCoinID(s)
-			if price, ok := p.cache[coinID]; ok {
+			if price, ok := p.cache[s]; ok {
 				prices = append(prices, price)
 			} else {
 				allCached = false
@@ -80,21 +186,37 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 			}
 		}
 		if allCached {
+			staleAfter := p.staleThreshold()
 			p.cacheMu.RUnlock()
-			return prices, nil
+			metrics.ObserveCache(p.Name(), true)
+			return providers.MarkStale(prices, staleAfter, time.Now()), nil
 		}
 	}
 	p.cacheMu.RUnlock()
+	metrics.ObserveCache(p.Name(), false)
 
-	// Convert symbols to CoinGecko IDs
+	// Convert symbols to CoinGecko IDs and the currencies to quote them in.
+	// Pairs of one coin (BTCUSDT, BTCTRY) share an ID, which is requested
+	// once; results and the cache stay keyed by symbol.
 	coinIDs := make([]string, 0, len(symbols))
+	var currencies []string
 	for _, s := range symbols {
-		coinIDs = append(coinIDs, symbolToCoinID(s))
+		if id := symbolToCoinID(s); !slices.Contains(coinIDs, id) {
+			coinIDs = append(coinIDs, id)
+		}
+		if currency := strings.ToLower(providers.QuoteCurrency(s)); !slices.Contains(currencies, currency) {
+			currencies = append(currencies, currency)
+		}
 	}
 
-	slog.Info("fetching CoinGecko data", "coins", coinIDs)
+	slog.Info("fetching CoinGecko data", "coins", coinIDs, "currencies", currencies)
+
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
 
-	priceData, err := p.fetchPrices(ctx, coinIDs)
+	start := time.Now()
+	priceData, err := p.fetchPrices(fetchCtx, coinIDs, currencies)
+	metrics.ObserveFetch(p.Name(), start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -102,9 +224,10 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 	now := time.Now()
 	prices := make([]providers.Price, 0, len(symbols))
 
-	for i, symbol := range symbols {
-		coinID := coinIDs[i]
-		data, ok := priceData[coinID]
+	for _, symbol := range symbols {
+		coinID := symbolToCoinID(symbol)
+		currency := strings.ToLower(providers.QuoteCurrency(symbol))
+		quote, ok := priceData[coinID][currency]
 		if !ok {
 			continue
 		}
@@ -112,9 +235,9 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 		price := providers.Price{
 			Symbol:      symbol,
 			Name:        coinIDToName(coinID),
-			Price:       data.USD,
+			Price:       quote,
 			DailyChange: 0, // CoinGecko doesn't provide absolute change in simple API
-			DailyPct:    data.USD24HChange,
+			DailyPct:    priceData[coinID][currency+"_24h_change"],
 			LastUpdated: now,
 			Stale:       false,
 		}
@@ -124,19 +247,21 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 	// Update cache
 	p.cacheMu.Lock()
 	for _, price := range prices {
-		coinID := symbolToCoinID(price.Symbol)
-		p.cache[coinID] = price
+		p.cache[price.Symbol] = price
 	}
 	p.cacheExp = time.Now().Add(p.cacheTTL)
+	p.lastSuccess = now
 	p.cacheMu.Unlock()
 
+	providers.PersistPrices(ctx, p.store, p.Name(), prices)
+
 	return prices, nil
 }
 
-// fetchPrices fetches prices from CoinGecko API
-func (p *Provider) fetchPrices(ctx context.Context, coinIDs []string) (priceResponse, error) {
-	url := fmt.Sprintf("%s/simple/price?ids=%s&vs_currencies=usd&include_24hr_change=true",
-		baseURL, strings.Join(coinIDs, ","))
+// fetchPrices fetches prices in the given (lower-case) currencies from CoinGecko API
+func (p *Provider) fetchPrices(ctx context.Context, coinIDs, currencies []string) (priceResponse, error) {
+	url := fmt.Sprintf("%s/simple/price?ids=%s&vs_currencies=%s&include_24hr_change=true",
+		p.baseURL, strings.Join(coinIDs, ","), strings.Join(currencies, ","))
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -165,9 +290,21 @@ func (p *Provider) fetchPrices(ctx context.Context, coinIDs []string) (priceResp
 	return prices, nil
 }
 
+// Status reports the last successful fetch and the state of the price cache
+func (p *Provider) Status() providers.ProviderStatus {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+	age, cached := providers.CacheAge(p.cache, time.Now())
+	return providers.ProviderStatus{
+		LastSuccess:    p.lastSuccess,
+		CacheAge:       age,
+		StaleAvailable: cached,
+	}
+}
+
 // IsHealthy checks if the provider is operational
 func (p *Provider) IsHealthy(ctx context.Context) bool {
-	url := fmt.Sprintf("%s/ping", baseURL)
+	url := fmt.Sprintf("%s/ping", p.baseURL)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return false
@@ -187,23 +324,34 @@ func (p *Provider) Close() error {
 	return nil
 }
 
-// FetchExchangeRate gets the USD/TRY exchange rate using USDT price in TRY
-func (p *Provider) FetchExchangeRate(ctx context.Context) (float64, time.Time, error) {
+// FetchExchangeRate returns how many units of to one unit of from buys.
+// Currencies are ISO 4217 codes (e.g. "USD", "TRY") that CoinGecko quotes.
+func (p *Provider) FetchExchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, time.Now(), nil
+	}
+	pair := from + "/" + to
+
 	// Check cache first
 	p.exchangeRateMu.RLock()
-	if time.Now().Before(p.exchangeRateExp) && p.exchangeRate > 0 {
-		rate := p.exchangeRate
-		exp := p.exchangeRateExp
-		p.exchangeRateMu.RUnlock()
-		return rate, exp, nil
-	}
+	cached, ok := p.exchangeRates[pair]
 	p.exchangeRateMu.RUnlock()
+	if ok && time.Now().Before(cached.exp) {
+		return cached.rate, cached.updated, nil
+	}
 
-	slog.Info("fetching USD/TRY exchange rate from CoinGecko")
+	slog.Info("fetching exchange rate from CoinGecko", "pair", pair)
 
-	// Use USDT (Tether) price in TRY as USD/TRY proxy
-	// CoinGecko endpoint: /simple/price?ids=tether&vs_currencies=try
-	url := fmt.Sprintf("%s/simple/price?ids=tether&vs_currencies=try", baseURL)
+	// A stablecoin's price in the quote currency is the rate directly;
+	// otherwise price bitcoin in both currencies and divide
+	coinID, direct := stablecoinProxies[from]
+	vsCurrencies := []string{strings.ToLower(to)}
+	if !direct {
+		coinID = "bitcoin"
+		vsCurrencies = append(vsCurrencies, strings.ToLower(from))
+	}
+	url := fmt.Sprintf("%s/simple/price?ids=%s&vs_currencies=%s", p.baseURL, coinID, strings.Join(vsCurrencies, ","))
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -224,50 +372,55 @@ func (p *Provider) FetchExchangeRate(ctx context.Context) (float64, time.Time, e
 		return 0, time.Time{}, fmt.Errorf("unexpected status: %d", resp.StatusCode)
 	}
 
-	var result map[string]struct {
-		TRY float64 `json:"try"`
-	}
+	var result map[string]map[string]float64
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return 0, time.Time{}, err
 	}
 
-	tetherData, ok := result["tether"]
-	if !ok || tetherData.TRY <= 0 {
-		return 0, time.Time{}, fmt.Errorf("invalid exchange rate response")
+	quotes := result[coinID]
+	rate := quotes[strings.ToLower(to)]
+	if !direct {
+		if base := quotes[strings.ToLower(from)]; base > 0 {
+			rate /= base
+		} else {
+			rate = 0
+		}
+	}
+	if rate <= 0 {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", pair, providers.ErrUnsupportedCurrency)
 	}
 
-	rate := tetherData.TRY
 	now := time.Now()
 
 	// Update cache
 	p.exchangeRateMu.Lock()
-	p.exchangeRate = rate
-	p.exchangeRateExp = now.Add(p.exchangeRateTTL)
+	p.exchangeRates[pair] = cachedRate{rate: rate, updated: now, exp: now.Add(p.exchangeRateTTL)}
 	p.exchangeRateMu.Unlock()
 
-	slog.Info("fetched USD/TRY exchange rate", "rate", rate)
+	slog.Info("fetched exchange rate", "pair", pair, "rate", rate)
 	return rate, now, nil
 }
 
-// symbolToCoinID converts Binance symbol to CoinGecko ID
+// symbolToCoinID converts Binance symbol to CoinGecko ID, whatever its quote
 func symbolToCoinID(symbol string) string {
 	mapping := map[string]string{
-		"BTCUSDT":   "bitcoin",
-		"ETHUSDT":   "ethereum",
-		"SOLUSDT":   "solana",
-		"BNBUSDT":   "binancecoin",
-		"XRPUSDT":   "ripple",
-		"ADAUSDT":   "cardano",
-		"DOGEUSDT":  "dogecoin",
-		"DOTUSDT":   "polkadot",
-		"MATICUSDT": "matic-network",
-		"AVAXUSDT":  "avalanche-2",
-	}
-
-	if id, ok := mapping[symbol]; ok {
+		"BTC":   "bitcoin",
+		"ETH":   "ethereum",
+		"SOL":   "solana",
+		"BNB":   "binancecoin",
+		"XRP":   "ripple",
+		"ADA":   "cardano",
+		"DOGE":  "dogecoin",
+		"DOT":   "polkadot",
+		"MATIC": "matic-network",
+		"AVAX":  "avalanche-2",
+	}
+
+	base, _ := providers.SplitPair(symbol)
+	if id, ok := mapping[base]; ok {
 		return id
 	}
-	return strings.ToLower(strings.TrimSuffix(symbol, "USDT"))
+	return strings.ToLower(base)
 }
 
 // coinIDToName returns a human-readable name for a coin ID
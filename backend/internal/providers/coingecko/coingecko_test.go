@@ -0,0 +1,211 @@
+package coingecko
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+)
+
+// fakeCoinGecko serves /simple/price from a fixed table and counts requests
+type fakeCoinGecko struct {
+	requests atomic.Int32
+	ids      atomic.Value // ids parameter of the last request
+}
+
+func (f *fakeCoinGecko) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	f.ids.Store(r.URL.Query().Get("ids"))
+	if r.URL.Path != "/simple/price" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	currencies := strings.Split(r.URL.Query().Get("vs_currencies"), ",")
+	if r.URL.Query().Get("include_24hr_change") != "true" {
+		f.serveQuotes(w, r.URL.Query().Get("ids"), currencies)
+		return
+	}
+
+	entries := map[string]map[string]float64{
+		"bitcoin":  {"usd": 50000.5, "usd_24h_change": -1.5, "try": 1712500, "try_24h_change": -1.25},
+		"ethereum": {"usd": 3000, "usd_24h_change": 2.25},
+	}
+	out := map[string]map[string]float64{}
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		entry, ok := entries[id]
+		if !ok {
+			continue
+		}
+		out[id] = map[string]float64{}
+		for _, c := range currencies {
+			for _, key := range []string{c, c + "_24h_change"} {
+				if v, ok := entry[key]; ok {
+					out[id][key] = v
+				}
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// serveQuotes answers exchange-rate lookups, omitting unknown currencies
+// like the real API does
+func (f *fakeCoinGecko) serveQuotes(w http.ResponseWriter, coinID string, currencies []string) {
+	quotes := map[string]map[string]float64{
+		"tether":  {"try": 34.25},
+		"bitcoin": {"try": 3425000, "eur": 92000},
+	}
+	out := map[string]map[string]float64{coinID: {}}
+	for _, c := range currencies {
+		if q, ok := quotes[coinID][c]; ok {
+			out[coinID][c] = q
+		}
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+func newFakeCoinGecko(t *testing.T) (*fakeCoinGecko, *httptest.Server) {
+	t.Helper()
+	fake := &fakeCoinGecko{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func TestFetchPricesParsesResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		symbols    []string
+		wantPrices map[string]float64
+		wantPct    map[string]float64
+		wantIDs    string
+	}{
+		{
+			name:       "trading pair maps to coin ID",
+			symbols:    []string{"BTCUSDT"},
+			wantPrices: map[string]float64{"BTCUSDT": 50000.5},
+			wantPct:    map[string]float64{"BTCUSDT": -1.5},
+		},
+		{
+			name:       "multiple symbols",
+			symbols:    []string{"BTCUSDT", "ETHUSDT"},
+			wantPrices: map[string]float64{"BTCUSDT": 50000.5, "ETHUSDT": 3000},
+			wantPct:    map[string]float64{"BTCUSDT": -1.5, "ETHUSDT": 2.25},
+		},
+		{
+			name:       "TRY pair is priced in TRY",
+			symbols:    []string{"BTCTRY", "ETHUSDT"},
+			wantPrices: map[string]float64{"BTCTRY": 1712500, "ETHUSDT": 3000},
+			wantPct:    map[string]float64{"BTCTRY": -1.25, "ETHUSDT": 2.25},
+		},
+		{
+			name:       "pairs of one coin are priced separately",
+			symbols:    []string{"BTCUSDT", "BTCTRY", "ETHUSDT"},
+			wantPrices: map[string]float64{"BTCUSDT": 50000.5, "BTCTRY": 1712500, "ETHUSDT": 3000},
+			wantPct:    map[string]float64{"BTCUSDT": -1.5, "BTCTRY": -1.25, "ETHUSDT": 2.25},
+			wantIDs:    "bitcoin,ethereum",
+		},
+		{
+			name:       "unknown coin is skipped",
+			symbols:    []string{"BTCUSDT", "NOPEUSDT"},
+			wantPrices: map[string]float64{"BTCUSDT": 50000.5},
+			wantPct:    map[string]float64{"BTCUSDT": -1.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, srv := newFakeCoinGecko(t)
+			p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client()})
+
+			prices, err := p.FetchPrices(context.Background(), tt.symbols)
+			if err != nil {
+				t.Fatalf("FetchPrices() error = %v", err)
+			}
+			if ids := fake.ids.Load(); tt.wantIDs != "" && ids != tt.wantIDs {
+				t.Errorf("requested ids %v, want %s", ids, tt.wantIDs)
+			}
+			if len(prices) != len(tt.wantPrices) {
+				t.Fatalf("got %d prices, want %d", len(prices), len(tt.wantPrices))
+			}
+			for _, price := range prices {
+				if price.Price != tt.wantPrices[price.Symbol] {
+					t.Errorf("%s price = %v, want %v", price.Symbol, price.Price, tt.wantPrices[price.Symbol])
+				}
+				if price.DailyPct != tt.wantPct[price.Symbol] {
+					t.Errorf("%s daily pct = %v, want %v", price.Symbol, price.DailyPct, tt.wantPct[price.Symbol])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchPricesUsesCache(t *testing.T) {
+	fake, srv := newFakeCoinGecko(t)
+	p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client(), CacheTTL: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.FetchPrices(ctx, []string{"BTCUSDT", "ETHUSDT"}); err != nil {
+			t.Fatalf("FetchPrices() error = %v", err)
+		}
+	}
+
+	if got := fake.requests.Load(); got != 1 {
+		t.Errorf("server requests = %d, want 1", got)
+	}
+}
+
+func TestFetchExchangeRate(t *testing.T) {
+	tests := []struct {
+		name         string
+		from, to     string
+		want         float64
+		wantErr      error
+		wantRequests int32
+	}{
+		{name: "USD uses tether", from: "USD", to: "TRY", want: 34.25, wantRequests: 1},
+		{name: "lowercase codes", from: "usd", to: "try", want: 34.25, wantRequests: 1},
+		{name: "EUR is cross-rated via bitcoin", from: "EUR", to: "TRY", want: 3425000.0 / 92000, wantRequests: 1},
+		{name: "same currency needs no request", from: "TRY", to: "TRY", want: 1, wantRequests: 0},
+		{name: "unknown quote currency", from: "USD", to: "XXX", wantErr: providers.ErrUnsupportedCurrency, wantRequests: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, srv := newFakeCoinGecko(t)
+			p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client()})
+			ctx := context.Background()
+
+			// The second call must be served from the per-pair cache
+			for i := 0; i < 2; i++ {
+				rate, _, err := p.FetchExchangeRate(ctx, tt.from, tt.to)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("FetchExchangeRate() error = %v, want %v", err, tt.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("FetchExchangeRate() error = %v", err)
+				}
+				if rate != tt.want {
+					t.Errorf("rate = %v, want %v", rate, tt.want)
+				}
+			}
+
+			if got := fake.requests.Load(); got != tt.wantRequests {
+				t.Errorf("server requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package frankfurter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/metrics"
+	"github.com/ferhatkunduraci/prism/internal/providers"
+)
+
+const (
+	// defaultBaseURL is the Frankfurter API root used when Config.BaseURL is empty
+	defaultBaseURL = "https://api.frankfurter.app"
+
+	// defaultCacheTTL is used when no TTL is configured
+	defaultCacheTTL = time.Hour // The ECB publishes reference rates once per working day
+
+	// defaultTimeout is used when no fetch timeout is configured
+	defaultTimeout = 10 * time.Second
+)
+
+// Provider fetches fiat exchange rates from the Frankfurter API, which serves
+// the European Central Bank's daily reference rates. Unlike a stablecoin
+// proxy, these carry no crypto market premium.
+type Provider struct {
+	client   *http.Client
+	baseURL  string
+	timeout  time.Duration
+	cache    map[string]cachedRate // Keyed by "FROM/TO"
+	cacheMu  sync.RWMutex
+	cacheTTL time.Duration
+}
+
+// cachedRate is one cached exchange rate
+type cachedRate struct {
+	rate      float64
+	published time.Time
+	exp       time.Time
+}
+
+// Config holds Frankfurter provider configuration
+type Config struct {
+	CacheTTL time.Duration // Optional, defaults to 1h
+	Timeout  time.Duration // Optional, deadline for one fetch, defaults to 10s
+
+	BaseURL    string       // Optional, defaults to the public Frankfurter API
+	HTTPClient *http.Client // Optional, replaces the default client (e.g. in tests)
+}
+
+// latestResponse represents the Frankfurter /latest response
+type latestResponse struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// NewProvider creates a new Frankfurter provider
+func NewProvider(cfg Config) *Provider {
+	timeout := providers.OrDefault(cfg.Timeout, defaultTimeout)
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: timeout}
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	return &Provider{
+		client:   client,
+		baseURL:  baseURL,
+		timeout:  timeout,
+		cache:    make(map[string]cachedRate),
+		cacheTTL: providers.OrDefault(cfg.CacheTTL, defaultCacheTTL),
+	}
+}
+
+// SetCacheTTL changes how long fetched rates are cached (safe for concurrent use)
+func (p *Provider) SetCacheTTL(ttl time.Duration) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.cacheTTL = providers.OrDefault(ttl, defaultCacheTTL)
+}
+
+// FlushCache drops cached rates so the next request goes to Frankfurter
+func (p *Provider) FlushCache() {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	clear(p.cache)
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return "frankfurter"
+}
+
+// FetchExchangeRate returns the ECB reference rate for one unit of from in
+// to. The returned time is the publication date of the rate.
+func (p *Provider) FetchExchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, time.Now(), nil
+	}
+	pair := from + "/" + to
+
+	// Check cache first
+	p.cacheMu.RLock()
+	cached, ok := p.cache[pair]
+	ttl := p.cacheTTL
+	p.cacheMu.RUnlock()
+	if ok && time.Now().Before(cached.exp) {
+		metrics.ObserveCache(p.Name(), true)
+		return cached.rate, cached.published, nil
+	}
+	metrics.ObserveCache(p.Name(), false)
+
+	slog.Info("fetching exchange rate from Frankfurter", "pair", pair)
+
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	latest, err := p.fetchLatest(fetchCtx, from, to)
+	metrics.ObserveFetch(p.Name(), start, err)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", pair, err)
+	}
+
+	rate := latest.Rates[to]
+	if rate <= 0 {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", pair, providers.ErrUnsupportedCurrency)
+	}
+	published, err := time.Parse(time.DateOnly, latest.Date)
+	if err != nil {
+		published = time.Now()
+	}
+
+	p.cacheMu.Lock()
+	p.cache[pair] = cachedRate{rate: rate, published: published, exp: time.Now().Add(ttl)}
+	p.cacheMu.Unlock()
+
+	slog.Info("fetched exchange rate", "pair", pair, "rate", rate, "date", latest.Date)
+	return rate, published, nil
+}
+
+// fetchLatest fetches the latest reference rate for a pair
+func (p *Provider) fetchLatest(ctx context.Context, from, to string) (*latestResponse, error) {
+	url := fmt.Sprintf("%s/latest?from=%s&to=%s", p.baseURL, from, to)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusUnprocessableEntity:
+		// Currencies the ECB doesn't publish are reported as not found
+		return nil, providers.ErrUnsupportedCurrency
+	default:
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var latest latestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return nil, err
+	}
+
+	return &latest, nil
+}
@@ -0,0 +1,86 @@
+package frankfurter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+)
+
+// fakeFrankfurter serves /latest from a table of rates per USD and counts requests
+type fakeFrankfurter struct {
+	requests atomic.Int32
+}
+
+func (f *fakeFrankfurter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	perUSD := map[string]float64{"USD": 1, "EUR": 0.9, "TRY": 32.4}
+
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	fromRate, okFrom := perUSD[from]
+	toRate, okTo := perUSD[to]
+	if r.URL.Path != "/latest" || !okFrom || !okTo {
+		http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(latestResponse{
+		Base:  from,
+		Date:  "2024-03-15",
+		Rates: map[string]float64{to: toRate / fromRate},
+	})
+}
+
+func TestFetchExchangeRate(t *testing.T) {
+	tests := []struct {
+		name         string
+		from, to     string
+		want         float64
+		wantErr      error
+		wantRequests int32
+	}{
+		{name: "USD/TRY", from: "USD", to: "TRY", want: 32.4, wantRequests: 1},
+		{name: "EUR/TRY", from: "eur", to: "try", want: 32.4 / 0.9, wantRequests: 1},
+		{name: "same currency", from: "TRY", to: "TRY", want: 1, wantRequests: 0},
+		{name: "unsupported currency", from: "USD", to: "XXX", wantErr: providers.ErrUnsupportedCurrency, wantRequests: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeFrankfurter{}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+			p := NewProvider(Config{BaseURL: srv.URL, HTTPClient: srv.Client()})
+
+			// The second call must be served from the per-pair cache
+			for i := 0; i < 2; i++ {
+				rate, published, err := p.FetchExchangeRate(context.Background(), tt.from, tt.to)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("FetchExchangeRate() error = %v, want %v", err, tt.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("FetchExchangeRate() error = %v", err)
+				}
+				if rate != tt.want {
+					t.Errorf("rate = %v, want %v", rate, tt.want)
+				}
+				if tt.wantRequests > 0 && !published.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("published = %v, want 2024-03-15", published)
+				}
+			}
+
+			if got := fake.requests.Load(); got != tt.wantRequests {
+				t.Errorf("server requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package providers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// NewHTTPClient returns a client for provider APIs with the given timeout.
+// Requests go through proxy when it is set, and otherwise through the proxy
+// named by HTTPS_PROXY, HTTP_PROXY and NO_PROXY, if any.
+func NewHTTPClient(timeout time.Duration, proxy *url.URL) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
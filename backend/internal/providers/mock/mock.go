@@ -0,0 +1,207 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+)
+
+const (
+	// baseExchangeRate is the starting USD/TRY rate
+	baseExchangeRate = 32.5
+
+	// defaultVolatility is the standard deviation of each step, in percent
+	defaultVolatility = 1.0
+)
+
+// usdRates are fixed units per USD for the simulated currencies. TRY is not
+// listed; it follows the random walk started at baseExchangeRate.
+var usdRates = map[string]float64{
+	"USD": 1,
+	"EUR": 0.92,
+	"GBP": 0.79,
+}
+
+// Provider returns synthetic prices for any symbol, for development and
+// offline demos. Each symbol starts at a price derived from its name and
+// takes a random step on every fetch, so the same seed always produces the
+// same sequence.
+type Provider struct {
+	name       string
+	seed       int64
+	volatility float64
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	prices   map[string]providers.Price
+	rate     float64
+	rateTime time.Time
+}
+
+// Config holds mock provider configuration
+type Config struct {
+	Name       string  // Provider name reported by Name(), defaults to "mock"
+	Seed       int64   // Random seed; the same seed yields the same price sequence
+	Volatility float64 // Std. deviation of each step in percent; zero uses 1%, negative freezes prices
+}
+
+// NewProvider creates a new mock provider
+func NewProvider(cfg Config) *Provider {
+	name := cfg.Name
+	if name == "" {
+		name = "mock"
+	}
+	volatility := cfg.Volatility
+	if volatility == 0 {
+		volatility = defaultVolatility
+	}
+
+	return &Provider{
+		name:       name,
+		seed:       cfg.Seed,
+		volatility: math.Max(volatility, 0),
+		rng:        rand.New(rand.NewSource(cfg.Seed)),
+		prices:     make(map[string]providers.Price),
+		rate:       baseExchangeRate,
+	}
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// FetchPrices returns the next simulated price for each symbol
+func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	prices := make([]providers.Price, 0, len(symbols))
+	for _, symbol := range symbols {
+		prev, ok := p.prices[symbol]
+		if !ok {
+			prev = providers.Price{
+				Symbol: symbol,
+				Name:   symbol,
+				Price:  basePrice(symbol),
+			}
+		}
+
+		next := prev.Price * (1 + p.step()/100)
+		price := providers.Price{
+			Symbol:      symbol,
+			Name:        prev.Name,
+			Price:       next,
+			DailyChange: next - prev.Price,
+			DailyPct:    (next - prev.Price) / prev.Price * 100,
+			LastUpdated: now,
+		}
+		p.prices[symbol] = price
+		prices = append(prices, price)
+	}
+
+	return prices, nil
+}
+
+// FetchExchangeRate returns a simulated rate between USD, EUR, GBP and TRY.
+// The USD/TRY rate takes a random step on every call; the others are fixed.
+func (p *Provider) FetchExchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rate *= 1 + p.step()/100
+	p.rateTime = time.Now()
+
+	perUSD := func(currency string) (float64, bool) {
+		if currency == "TRY" {
+			return p.rate, true
+		}
+		rate, ok := usdRates[currency]
+		return rate, ok
+	}
+	fromRate, ok := perUSD(strings.ToUpper(from))
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", from, providers.ErrUnsupportedCurrency)
+	}
+	toRate, ok := perUSD(strings.ToUpper(to))
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", to, providers.ErrUnsupportedCurrency)
+	}
+	return toRate / fromRate, p.rateTime, nil
+}
+
+// FetchHistoricalPrices returns a simulated price for each symbol on date.
+// Prices scatter around the symbol's base price and depend only on the seed,
+// symbol and date, so repeated calls agree.
+func (p *Provider) FetchHistoricalPrices(ctx context.Context, date time.Time, symbols []string) ([]providers.Price, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	day := date.Format(time.DateOnly)
+	prices := make([]providers.Price, 0, len(symbols))
+	for _, symbol := range symbols {
+		h := fnv.New64a()
+		h.Write([]byte(symbol + "@" + day))
+		rng := rand.New(rand.NewSource(p.seed ^ int64(h.Sum64())))
+		prices = append(prices, providers.Price{
+			Symbol:      symbol,
+			Name:        symbol,
+			Price:       basePrice(symbol) * (1 + rng.NormFloat64()*p.volatility/100),
+			LastUpdated: date,
+		})
+	}
+
+	return prices, nil
+}
+
+// Status reports the last simulated fetch as the last success
+func (p *Provider) Status() providers.ProviderStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	age, cached := providers.CacheAge(p.prices, now)
+	status := providers.ProviderStatus{CacheAge: age, StaleAvailable: cached}
+	if cached {
+		status.LastSuccess = now.Add(-age)
+	}
+	return status
+}
+
+// IsHealthy always reports true
+func (p *Provider) IsHealthy(ctx context.Context) bool {
+	return true
+}
+
+// Close releases no resources
+func (p *Provider) Close() error {
+	return nil
+}
+
+// step returns a random percentage move; p.mu must be held
+func (p *Provider) step() float64 {
+	return p.rng.NormFloat64() * p.volatility
+}
+
+// basePrice derives a stable starting price (1-1000) from the symbol
+func basePrice(symbol string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(symbol))
+	return 1 + float64(h.Sum32()%100000)/100
+}
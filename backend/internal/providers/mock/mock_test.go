@@ -0,0 +1,54 @@
+package mock
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFetchPricesIsReproducible(t *testing.T) {
+	tests := []struct {
+		name       string
+		seedA      int64
+		seedB      int64
+		volatility float64
+		wantEqual  bool
+	}{
+		{"same seed", 42, 42, 0, true},
+		{"different seed", 42, 43, 0, false},
+		{"frozen prices ignore seed", 42, 43, -1, true},
+	}
+
+	symbols := []string{"KUT", "BTCUSDT"}
+	ctx := context.Background()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewProvider(Config{Seed: tt.seedA, Volatility: tt.volatility})
+			b := NewProvider(Config{Seed: tt.seedB, Volatility: tt.volatility})
+
+			equal := true
+			for round := 0; round < 5; round++ {
+				pa, err := a.FetchPrices(ctx, symbols)
+				if err != nil {
+					t.Fatalf("FetchPrices() error = %v", err)
+				}
+				pb, err := b.FetchPrices(ctx, symbols)
+				if err != nil {
+					t.Fatalf("FetchPrices() error = %v", err)
+				}
+				for i := range pa {
+					if pa[i].Price <= 0 {
+						t.Fatalf("price for %s = %v, want positive", pa[i].Symbol, pa[i].Price)
+					}
+					if pa[i].Price != pb[i].Price {
+						equal = false
+					}
+				}
+			}
+
+			if equal != tt.wantEqual {
+				t.Errorf("sequences equal = %v, want %v", equal, tt.wantEqual)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package providers
+
+import "strings"
+
+// QuoteAssets are the quote assets recognized at the end of a crypto trading
+// pair such as "BTCUSDT" or "BTCTRY", longest first where one ends another
+var QuoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "TRY", "EUR"}
+
+// cryptoQuoteAssets are quotes only recognized once a pair is known to be
+// canonical: on their own they would also split coins such as WBTC
+var cryptoQuoteAssets = []string{"BTC", "ETH", "BNB"}
+
+// usdStablecoins are quote assets priced as US dollars
+var usdStablecoins = map[string]bool{"FDUSD": true, "USDT": true, "USDC": true, "BUSD": true}
+
+// SplitPair splits a canonical trading pair into base and quote assets. The
+// quote is empty if none is recognized.
+func SplitPair(symbol string) (base, quote string) {
+	for _, quotes := range [][]string{QuoteAssets, cryptoQuoteAssets} {
+		for _, q := range quotes {
+			if len(symbol) > len(q) && strings.HasSuffix(symbol, q) {
+				return strings.TrimSuffix(symbol, q), q
+			}
+		}
+	}
+	return symbol, ""
+}
+
+// QuoteCurrency returns the currency prices of a pair are denominated in:
+// "USD" for dollar stablecoins, otherwise the quote asset itself ("TRY").
+// Pairs without a recognized quote are assumed to be in USD.
+func QuoteCurrency(symbol string) string {
+	_, quote := SplitPair(symbol)
+	if quote == "" || usdStablecoins[quote] {
+		return "USD"
+	}
+	return quote
+}
@@ -0,0 +1,32 @@
+package providers
+
+import "testing"
+
+func TestSplitPair(t *testing.T) {
+	tests := []struct {
+		symbol       string
+		wantBase     string
+		wantQuote    string
+		wantCurrency string
+	}{
+		{"BTCUSDT", "BTC", "USDT", "USD"},
+		{"BTCFDUSD", "BTC", "FDUSD", "USD"},
+		{"BTCTRY", "BTC", "TRY", "TRY"},
+		{"ETHEUR", "ETH", "EUR", "EUR"},
+		{"ETHBTC", "ETH", "BTC", "BTC"},
+		{"USDT", "USDT", "", "USD"},
+		{"MYCOIN", "MYCOIN", "", "USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			base, quote := SplitPair(tt.symbol)
+			if base != tt.wantBase || quote != tt.wantQuote {
+				t.Errorf("SplitPair(%q) = %q, %q, want %q, %q", tt.symbol, base, quote, tt.wantBase, tt.wantQuote)
+			}
+			if got := QuoteCurrency(tt.symbol); got != tt.wantCurrency {
+				t.Errorf("QuoteCurrency(%q) = %q, want %q", tt.symbol, got, tt.wantCurrency)
+			}
+		})
+	}
+}
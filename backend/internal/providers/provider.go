@@ -3,6 +3,11 @@ package providers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -14,7 +19,11 @@ type Price struct {
 	DailyChange float64   `json:"daily_change"`
 	DailyPct    float64   `json:"daily_pct"`
 	LastUpdated time.Time `json:"last_updated"`
-	Stale       bool      `json:"stale"` // True if data might be outdated (weekends, holidays)
+	Stale       bool      `json:"stale"`                // True if data might be outdated (old fetch, weekends, holidays)
+	Delisted    bool      `json:"delisted,omitempty"`   // True if the upstream keeps rejecting the symbol as unknown
+	PriceDate   string    `json:"price_date,omitempty"` // Day a daily price was published (YYYY-MM-DD); LastUpdated is when it was fetched
+	Category    string    `json:"category,omitempty"`   // Fund category as the upstream names it (e.g. "Hisse Senedi Fonu"); empty if not reported
+	Source      string    `json:"source,omitempty"`     // Provider that quoted the price, set by FallbackProvider; empty means the provider asked
 }
 
 // Provider defines the interface for all data providers
@@ -32,25 +41,233 @@ type Provider interface {
 	Close() error
 }
 
+// ErrUpstreamBlocked is returned when the upstream source refuses automated
+// requests (e.g. a web application firewall)
+var ErrUpstreamBlocked = errors.New("upstream blocked the request")
+
+// ErrSymbolNotFound is returned when a provider has no data for a symbol
+var ErrSymbolNotFound = errors.New("symbol not found")
+
+// ErrUnsupportedCurrency is returned when a provider can't quote a currency pair
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// ErrNotSupported is returned by composite providers when none of the
+// providers they wrap supports the operation
+var ErrNotSupported = errors.New("not supported by the configured providers")
+
+// FundInfo identifies a fund in a provider's fund universe
+type FundInfo struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// FundSearcher defines the interface for providers that can search their fund universe
+type FundSearcher interface {
+	// SearchFunds returns funds whose code or name matches query
+	SearchFunds(ctx context.Context, query string) ([]FundInfo, error)
+}
+
+// FundDetails holds fund metadata reported alongside the price
+type FundDetails struct {
+	Price             Price
+	PortfolioSize     float64 // Total fund assets, in TRY
+	Investors         int     // Number of investors holding the fund
+	SharesOutstanding float64 // Number of shares in circulation
+}
+
+// FundDetailer defines the interface for providers that expose fund metadata
+type FundDetailer interface {
+	// FetchFundDetails returns price and metadata for a single fund
+	FetchFundDetails(ctx context.Context, code string) (*FundDetails, error)
+}
+
+// Candle is one OHLC bar of a price chart
+type Candle struct {
+	OpenTime time.Time `json:"open_time"`
+	Open     float64   `json:"open"`
+	High     float64   `json:"high"`
+	Low      float64   `json:"low"`
+	Close    float64   `json:"close"`
+	Volume   float64   `json:"volume"` // Traded amount of the base asset
+}
+
+// KlineProvider defines the interface for providers that serve candlestick
+// price history
+type KlineProvider interface {
+	// FetchKlines returns the last limit candles of the given interval
+	// (e.g. "1d"), oldest first; the last one may still be open
+	FetchKlines(ctx context.Context, symbol, interval string, limit int) ([]Candle, error)
+}
+
+// HistoricalPriceProvider defines the interface for providers that can
+// return prices for a past date
+type HistoricalPriceProvider interface {
+	// FetchHistoricalPrices returns the closing price of each symbol on date.
+	// Symbols without data for that day are omitted from the result.
+	FetchHistoricalPrices(ctx context.Context, date time.Time, symbols []string) ([]Price, error)
+}
+
+// PriceHistoryProvider is implemented by providers that can return a
+// symbol's daily prices over a date range in one request
+type PriceHistoryProvider interface {
+	// FetchPriceHistory returns symbol's price on each day within [from, to]
+	// that has one, oldest first, with PriceDate set
+	FetchPriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]Price, error)
+}
+
+// ProviderStatus describes what a provider can currently serve, so clients
+// can tell "down" apart from "serving cached prices"
+type ProviderStatus struct {
+	LastSuccess    time.Time     // Last successful upstream fetch; zero if none yet
+	CacheAge       time.Duration // Age of the oldest cached price; zero if the cache is empty
+	StaleAvailable bool          // Cached prices exist to fall back on if the upstream fails
+	Serving        string        // For composite providers, the provider that answered the last fetch
+}
+
+// StatusReporter is implemented by providers that can describe their state
+// beyond IsHealthy
+type StatusReporter interface {
+	// Status returns the provider's current state without calling upstream
+	Status() ProviderStatus
+}
+
+// SubHealthReporter is implemented by providers that combine others, so the
+// health of each can be told apart
+type SubHealthReporter interface {
+	// SubHealth reports whether each underlying provider is healthy, keyed
+	// by provider name
+	SubHealth(ctx context.Context) map[string]bool
+}
+
+// CacheAge returns the age of the oldest price in cache and whether the
+// cache holds any prices
+func CacheAge(cache map[string]Price, now time.Time) (time.Duration, bool) {
+	var oldest time.Time
+	for _, price := range cache {
+		if oldest.IsZero() || price.LastUpdated.Before(oldest) {
+			oldest = price.LastUpdated
+		}
+	}
+	if oldest.IsZero() {
+		return 0, false
+	}
+	return now.Sub(oldest), true
+}
+
+// FundNameStore persists fund display names learned from provider responses
+type FundNameStore interface {
+	// SaveFundNames upserts names keyed by fund code
+	SaveFundNames(ctx context.Context, names map[string]string) error
+
+	// LoadFundNames returns every saved name keyed by fund code
+	LoadFundNames(ctx context.Context) (map[string]string, error)
+}
+
+// FundNamer is implemented by providers that can name a fund without
+// fetching it, e.g. when building placeholders after a failed fetch
+type FundNamer interface {
+	// FundName returns the display name for code, never empty
+	FundName(code string) string
+}
+
+// CacheFlusher is implemented by providers that can drop cached data on
+// demand, so the next fetch goes upstream regardless of the cache TTL
+type CacheFlusher interface {
+	// FlushCache expires everything cached. Last-known prices may be kept
+	// as a stale fallback, but are no longer served as fresh.
+	FlushCache()
+}
+
 // ExchangeRateProvider defines the interface for providers that can fetch exchange rates
 type ExchangeRateProvider interface {
-	// FetchExchangeRate returns the USD/TRY exchange rate
-	FetchExchangeRate(ctx context.Context) (rate float64, lastUpdated time.Time, err error)
+	// FetchExchangeRate returns the price of one unit of from in to, for
+	// ISO 4217 currency codes such as "USD" and "TRY"
+	FetchExchangeRate(ctx context.Context, from, to string) (rate float64, lastUpdated time.Time, err error)
+}
+
+// PriceStore persists last-known prices so provider caches survive restarts
+type PriceStore interface {
+	// SavePrices stores the latest prices fetched by a provider
+	SavePrices(ctx context.Context, provider string, prices []Price) error
+
+	// LoadPrices returns the prices last saved for a provider
+	LoadPrices(ctx context.Context, provider string) ([]Price, error)
+}
+
+// OrDefault returns d, or def when d is not positive, as for unset
+// provider timeouts and cache TTLs
+func OrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// RestorePrices prepares persisted prices for a provider cache. Prices older
+// than staleAfter are marked stale. The returned expiry is when the oldest
+// restored price leaves the cache; it is in the past if any of them is
+// already older than ttl.
+func RestorePrices(prices []Price, ttl, staleAfter time.Duration, now time.Time) ([]Price, time.Time) {
+	var oldest time.Time
+	restored := make([]Price, 0, len(prices))
+	for _, price := range prices {
+		price.Stale = Outdated(price, staleAfter, now)
+		if oldest.IsZero() || price.LastUpdated.Before(oldest) {
+			oldest = price.LastUpdated
+		}
+		restored = append(restored, price)
+	}
+
+	if oldest.IsZero() {
+		return restored, time.Time{}
+	}
+	return restored, oldest.Add(ttl)
+}
+
+// Outdated reports whether price was fetched more than staleAfter before now
+func Outdated(price Price, staleAfter time.Duration, now time.Time) bool {
+	return now.Sub(price.LastUpdated) > staleAfter
+}
+
+// MarkStale flags the prices fetched more than staleAfter before now as
+// stale. Prices already flagged, e.g. for a weekend price date, stay stale.
+func MarkStale(prices []Price, staleAfter time.Duration, now time.Time) []Price {
+	for i := range prices {
+		if Outdated(prices[i], staleAfter, now) {
+			prices[i].Stale = true
+		}
+	}
+	return prices
+}
+
+// PersistPrices saves freshly fetched prices to store, if one is configured.
+// Failures are logged only; persistence must never fail a fetch.
+func PersistPrices(ctx context.Context, store PriceStore, provider string, prices []Price) {
+	if store == nil || len(prices) == 0 {
+		return
+	}
+	if err := store.SavePrices(ctx, provider, prices); err != nil {
+		slog.Warn("failed to persist prices", "provider", provider, "error", err)
+	}
 }
 
 // ProviderType represents the type of data provider
 type ProviderType string
 
 const (
-	ProviderTypeTEFAS     ProviderType = "tefas"
-	ProviderTypeBinance   ProviderType = "binance"
-	ProviderTypeCoinGecko ProviderType = "coingecko"
+	ProviderTypeTEFAS       ProviderType = "tefas"
+	ProviderTypeBinance     ProviderType = "binance"
+	ProviderTypeCoinGecko   ProviderType = "coingecko"
+	ProviderTypeFrankfurter ProviderType = "frankfurter"
 )
 
 // FallbackProvider wraps multiple providers with fallback logic
 type FallbackProvider struct {
 	primary  Provider
 	fallback Provider
+
+	mu      sync.Mutex
+	serving Provider // Provider that supplied prices on the last fetch
 }
 
 // NewFallbackProvider creates a provider that tries primary first, then fallback
@@ -66,15 +283,124 @@ func (p *FallbackProvider) Name() string {
 	return p.primary.Name() + "+" + p.fallback.Name()
 }
 
-// FetchPrices tries primary provider first, falls back on error
+// FetchPrices tries primary provider first and asks the fallback only for
+// symbols the primary did not return or reported as delisted. Results from
+// both are concatenated, the fallback's replacing delisted entries.
 func (p *FallbackProvider) FetchPrices(ctx context.Context, symbols []string) ([]Price, error) {
-	prices, err := p.primary.FetchPrices(ctx, symbols)
-	if err == nil {
+	// Keep whatever the primary managed to return, even alongside an error
+	prices, primaryErr := p.primary.FetchPrices(ctx, symbols)
+	prices = withSource(prices, p.primary.Name())
+
+	missing := missingSymbols(symbols, prices)
+	if len(missing) == 0 {
+		p.setServing(p.primary)
 		return prices, nil
 	}
 
-	// Try fallback
-	return p.fallback.FetchPrices(ctx, symbols)
+	// Try fallback for the remainder only
+	fallbackPrices, err := p.fallback.FetchPrices(ctx, missing)
+	if err != nil {
+		if len(prices) > 0 {
+			// Partial data is more useful than none
+			p.setServing(p.primary)
+			return prices, nil
+		}
+		if primaryErr != nil {
+			return nil, fmt.Errorf("%s: %v; %s: %w", p.primary.Name(), primaryErr, p.fallback.Name(), err)
+		}
+		return nil, err
+	}
+
+	if len(fallbackPrices) > 0 {
+		p.setServing(p.fallback)
+	}
+	fallbackPrices = withSource(fallbackPrices, p.fallback.Name())
+	return append(withoutSymbols(prices, fallbackPrices), fallbackPrices...), nil
+}
+
+// withSource returns a copy of prices with Source set to source where a
+// nested FallbackProvider hasn't already set it
+func withSource(prices []Price, source string) []Price {
+	sourced := slices.Clone(prices)
+	for i := range sourced {
+		if sourced[i].Source == "" {
+			sourced[i].Source = source
+		}
+	}
+	return sourced
+}
+
+// setServing records which provider answered the last fetch
+func (p *FallbackProvider) setServing(provider Provider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.serving = provider
+}
+
+// Status reports the state of the provider that answered the last fetch
+// (the primary before any fetch). Stale data counts as available if either
+// provider has a cache.
+func (p *FallbackProvider) Status() ProviderStatus {
+	p.mu.Lock()
+	serving := p.serving
+	p.mu.Unlock()
+	if serving == nil {
+		serving = p.primary
+	}
+
+	var status ProviderStatus
+	if sr, ok := serving.(StatusReporter); ok {
+		status = sr.Status()
+	}
+	for _, provider := range []Provider{p.primary, p.fallback} {
+		if sr, ok := provider.(StatusReporter); ok && sr.Status().StaleAvailable {
+			status.StaleAvailable = true
+		}
+	}
+	status.Serving = serving.Name()
+	return status
+}
+
+// missingSymbols returns the requested symbols that are absent from prices
+// or only present as delisted, which another provider may still quote
+func missingSymbols(symbols []string, prices []Price) []string {
+	returned := make(map[string]bool, len(prices))
+	for _, price := range prices {
+		returned[price.Symbol] = !price.Delisted
+	}
+
+	var missing []string
+	for _, s := range symbols {
+		if !returned[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// withoutSymbols returns prices minus the symbols present in replacements
+func withoutSymbols(prices, replacements []Price) []Price {
+	replaced := make(map[string]bool, len(replacements))
+	for _, price := range replacements {
+		replaced[price.Symbol] = true
+	}
+
+	kept := make([]Price, 0, len(prices))
+	for _, price := range prices {
+		if !replaced[price.Symbol] {
+			kept = append(kept, price)
+		}
+	}
+	return kept
+}
+
+// FlushCache flushes both providers
+func (p *FallbackProvider) FlushCache() {
+	for _, provider := range []Provider{p.primary, p.fallback} {
+		if f, ok := provider.(CacheFlusher); ok {
+			f.FlushCache()
+		}
+	}
 }
 
 // IsHealthy returns true if either provider is healthy
@@ -82,6 +408,31 @@ func (p *FallbackProvider) IsHealthy(ctx context.Context) bool {
 	return p.primary.IsHealthy(ctx) || p.fallback.IsHealthy(ctx)
 }
 
+// SubHealth reports the health of both providers, flattening nested
+// fallback chains into one entry per provider
+func (p *FallbackProvider) SubHealth(ctx context.Context) map[string]bool {
+	health := make(map[string]bool)
+	for _, provider := range []Provider{p.primary, p.fallback} {
+		if sub, ok := provider.(SubHealthReporter); ok {
+			maps.Copy(health, sub.SubHealth(ctx))
+			continue
+		}
+		health[provider.Name()] = provider.IsHealthy(ctx)
+	}
+	return health
+}
+
+// Circuits reports the circuit breakers of both providers, if they have any
+func (p *FallbackProvider) Circuits() map[string]CircuitState {
+	circuits := make(map[string]CircuitState)
+	for _, provider := range []Provider{p.primary, p.fallback} {
+		if cr, ok := provider.(CircuitReporter); ok {
+			maps.Copy(circuits, cr.Circuits())
+		}
+	}
+	return circuits
+}
+
 // Close closes both providers
 func (p *FallbackProvider) Close() error {
 	err1 := p.primary.Close()
@@ -93,10 +444,10 @@ func (p *FallbackProvider) Close() error {
 }
 
 // FetchExchangeRate tries to get exchange rate from underlying providers
-func (p *FallbackProvider) FetchExchangeRate(ctx context.Context) (float64, time.Time, error) {
+func (p *FallbackProvider) FetchExchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
 	// Try primary first
 	if erp, ok := p.primary.(ExchangeRateProvider); ok {
-		rate, updated, err := erp.FetchExchangeRate(ctx)
+		rate, updated, err := erp.FetchExchangeRate(ctx, from, to)
 		if err == nil {
 			return rate, updated, nil
 		}
@@ -104,8 +455,56 @@ func (p *FallbackProvider) FetchExchangeRate(ctx context.Context) (float64, time
 
 	// Try fallback
 	if erp, ok := p.fallback.(ExchangeRateProvider); ok {
-		return erp.FetchExchangeRate(ctx)
+		return erp.FetchExchangeRate(ctx, from, to)
 	}
 
 	return 0, time.Time{}, errors.New("no provider supports exchange rates")
 }
+
+// FetchKlines asks the first provider that serves candles, without falling
+// back on other errors: another source's candles wouldn't line up with the
+// first's
+func (p *FallbackProvider) FetchKlines(ctx context.Context, symbol, interval string, limit int) ([]Candle, error) {
+	for _, provider := range []Provider{p.primary, p.fallback} {
+		if kp, ok := provider.(KlineProvider); ok {
+			candles, err := kp.FetchKlines(ctx, symbol, interval, limit)
+			if errors.Is(err, ErrNotSupported) {
+				continue // A wrapper around a provider without candles
+			}
+			return candles, err
+		}
+	}
+	return nil, fmt.Errorf("candles: %w", ErrNotSupported)
+}
+
+// FetchHistoricalPrices asks the primary provider first and the fallback for
+// whatever symbols the primary had no data for
+func (p *FallbackProvider) FetchHistoricalPrices(ctx context.Context, date time.Time, symbols []string) ([]Price, error) {
+	var prices []Price
+	var primaryErr error
+	if hp, ok := p.primary.(HistoricalPriceProvider); ok {
+		prices, primaryErr = hp.FetchHistoricalPrices(ctx, date, symbols)
+	}
+
+	missing := missingSymbols(symbols, prices)
+	hp, ok := p.fallback.(HistoricalPriceProvider)
+	if len(missing) == 0 || !ok {
+		if len(prices) == 0 && primaryErr != nil {
+			return nil, primaryErr
+		}
+		return prices, nil
+	}
+
+	fallbackPrices, err := hp.FetchHistoricalPrices(ctx, date, missing)
+	if err != nil {
+		if len(prices) > 0 {
+			return prices, nil
+		}
+		if primaryErr != nil {
+			return nil, fmt.Errorf("%s: %v; %s: %w", p.primary.Name(), primaryErr, p.fallback.Name(), err)
+		}
+		return nil, err
+	}
+
+	return append(prices, fallbackPrices...), nil
+}
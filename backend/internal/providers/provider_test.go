@@ -0,0 +1,224 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// stubProvider returns prices only for the symbols it knows
+type stubProvider struct {
+	name      string
+	known     map[string]float64
+	delisted  map[string]bool
+	err       error
+	requested [][]string
+}
+
+func (s *stubProvider) Name() string { return s.name }
+
+func (s *stubProvider) FetchPrices(ctx context.Context, symbols []string) ([]Price, error) {
+	s.requested = append(s.requested, symbols)
+	var prices []Price
+	for _, sym := range symbols {
+		if price, ok := s.known[sym]; ok {
+			prices = append(prices, Price{Symbol: sym, Price: price, Delisted: s.delisted[sym]})
+		}
+	}
+	return prices, s.err
+}
+
+func (s *stubProvider) IsHealthy(ctx context.Context) bool { return s.err == nil }
+
+func (s *stubProvider) Close() error { return nil }
+
+func TestFallbackProviderFetchPrices(t *testing.T) {
+	tests := []struct {
+		name             string
+		primary          *stubProvider
+		fallback         *stubProvider
+		symbols          []string
+		want             map[string]float64
+		wantErr          bool
+		wantFallbackCall []string
+		wantServing      string
+	}{
+		{
+			name:             "primary knows BTC but not custom coin",
+			primary:          &stubProvider{name: "primary", known: map[string]float64{"BTCUSDT": 65000}},
+			fallback:         &stubProvider{name: "fallback", known: map[string]float64{"BTCUSDT": 64000, "MYCOINUSDT": 1.5}},
+			symbols:          []string{"BTCUSDT", "MYCOINUSDT"},
+			want:             map[string]float64{"BTCUSDT": 65000, "MYCOINUSDT": 1.5},
+			wantFallbackCall: []string{"MYCOINUSDT"},
+			wantServing:      "fallback",
+		},
+		{
+			name:        "primary returns everything",
+			primary:     &stubProvider{name: "primary", known: map[string]float64{"BTCUSDT": 65000}},
+			fallback:    &stubProvider{name: "fallback"},
+			symbols:     []string{"BTCUSDT"},
+			want:        map[string]float64{"BTCUSDT": 65000},
+			wantServing: "primary",
+		},
+		{
+			name:             "primary partial with error keeps partial",
+			primary:          &stubProvider{name: "primary", known: map[string]float64{"BTCUSDT": 65000}, err: errors.New("boom")},
+			fallback:         &stubProvider{name: "fallback", known: map[string]float64{"ETHUSDT": 3000}},
+			symbols:          []string{"BTCUSDT", "ETHUSDT"},
+			want:             map[string]float64{"BTCUSDT": 65000, "ETHUSDT": 3000},
+			wantFallbackCall: []string{"ETHUSDT"},
+			wantServing:      "fallback",
+		},
+		{
+			name:             "fallback replaces delisted",
+			primary:          &stubProvider{name: "primary", known: map[string]float64{"BTCUSDT": 65000, "LUNAUSDT": 0.5}, delisted: map[string]bool{"LUNAUSDT": true}},
+			fallback:         &stubProvider{name: "fallback", known: map[string]float64{"LUNAUSDT": 0.4}},
+			symbols:          []string{"BTCUSDT", "LUNAUSDT"},
+			want:             map[string]float64{"BTCUSDT": 65000, "LUNAUSDT": 0.4},
+			wantFallbackCall: []string{"LUNAUSDT"},
+			wantServing:      "fallback",
+		},
+		{
+			name:             "delisted kept when fallback lacks it",
+			primary:          &stubProvider{name: "primary", known: map[string]float64{"LUNAUSDT": 0.5}, delisted: map[string]bool{"LUNAUSDT": true}},
+			fallback:         &stubProvider{name: "fallback"},
+			symbols:          []string{"LUNAUSDT"},
+			want:             map[string]float64{"LUNAUSDT": 0.5},
+			wantFallbackCall: []string{"LUNAUSDT"},
+			wantServing:      "primary",
+		},
+		{
+			name:             "fallback fails after partial primary",
+			primary:          &stubProvider{name: "primary", known: map[string]float64{"BTCUSDT": 65000}},
+			fallback:         &stubProvider{name: "fallback", err: errors.New("down")},
+			symbols:          []string{"BTCUSDT", "ETHUSDT"},
+			want:             map[string]float64{"BTCUSDT": 65000},
+			wantFallbackCall: []string{"ETHUSDT"},
+			wantServing:      "primary",
+		},
+		{
+			name:             "both fail",
+			primary:          &stubProvider{name: "primary", err: errors.New("boom")},
+			fallback:         &stubProvider{name: "fallback", err: errors.New("down")},
+			symbols:          []string{"BTCUSDT"},
+			wantErr:          true,
+			wantFallbackCall: []string{"BTCUSDT"},
+			wantServing:      "primary", // Nothing answered; reports the default
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFallbackProvider(tt.primary, tt.fallback)
+			prices, err := p.FetchPrices(context.Background(), tt.symbols)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchPrices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got := make(map[string]float64, len(prices))
+			for _, price := range prices {
+				got[price.Symbol] = price.Price
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for sym, want := range tt.want {
+				if got[sym] != want {
+					t.Errorf("price for %s = %v, want %v", sym, got[sym], want)
+				}
+			}
+			if serving := p.Status().Serving; serving != tt.wantServing {
+				t.Errorf("serving = %q, want %q", serving, tt.wantServing)
+			}
+
+			if tt.wantFallbackCall == nil {
+				if len(tt.fallback.requested) != 0 {
+					t.Errorf("fallback called with %v, want no call", tt.fallback.requested)
+				}
+				return
+			}
+			if len(tt.fallback.requested) != 1 || !equalStrings(tt.fallback.requested[0], tt.wantFallbackCall) {
+				t.Errorf("fallback called with %v, want %v", tt.fallback.requested, tt.wantFallbackCall)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFallbackProviderSubHealth(t *testing.T) {
+	binance := &stubProvider{name: "binance", err: errors.New("451 Unavailable For Legal Reasons")}
+	coingecko := &stubProvider{name: "coingecko"}
+	other := &stubProvider{name: "other", err: errors.New("timeout")}
+
+	chain := NewFallbackProvider(NewFallbackProvider(binance, coingecko), other)
+	want := map[string]bool{"binance": false, "coingecko": true, "other": false}
+	if got := chain.SubHealth(context.Background()); !maps.Equal(got, want) {
+		t.Errorf("SubHealth() = %v, want %v", got, want)
+	}
+	if !chain.IsHealthy(context.Background()) {
+		t.Error("IsHealthy() = false, want true while coingecko is healthy")
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.example:3128")
+	client := NewHTTPClient(5*time.Second, proxy)
+
+	req := httptest.NewRequest(http.MethodGet, "https://api.binance.com/api/v3/ping", nil)
+	got, err := client.Transport.(*http.Transport).Proxy(req)
+	if err != nil || got == nil || got.String() != proxy.String() {
+		t.Errorf("proxy for %s = %v, %v; want %s", req.URL, got, err, proxy)
+	}
+	if client.Timeout != 5*time.Second {
+		t.Errorf("timeout = %v, want 5s", client.Timeout)
+	}
+}
+
+func TestMarkStale(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	prices := []Price{
+		{Symbol: "FRESH", LastUpdated: now.Add(-4 * time.Minute)},
+		{Symbol: "OLD", LastUpdated: now.Add(-6 * time.Minute)},
+		{Symbol: "WEEKEND", LastUpdated: now.Add(-time.Minute), Stale: true},
+	}
+
+	got := MarkStale(prices, 5*time.Minute, now)
+	want := map[string]bool{"FRESH": false, "OLD": true, "WEEKEND": true}
+	for _, p := range got {
+		if p.Stale != want[p.Symbol] {
+			t.Errorf("%s: Stale = %v, want %v", p.Symbol, p.Stale, want[p.Symbol])
+		}
+	}
+}
+
+func TestOrDefault(t *testing.T) {
+	tests := []struct {
+		d, want time.Duration
+	}{
+		{0, time.Minute},
+		{-time.Second, time.Minute},
+		{time.Second, time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := OrDefault(tt.d, time.Minute); got != tt.want {
+			t.Errorf("OrDefault(%v, 1m) = %v, want %v", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Role is what a provider is used for once built
+type Role string
+
+const (
+	RoleFunds  Role = "funds"  // Prices fund holdings
+	RoleCrypto Role = "crypto" // Prices crypto holdings; later ones back up earlier ones
+	RoleFX     Role = "fx"     // Supplies fiat exchange rates
+)
+
+// Factory builds a provider. The result must implement Provider for the
+// funds and crypto roles, and ExchangeRateProvider for the fx role.
+type Factory func(ctx context.Context) (any, error)
+
+// registration is a registered factory and the role of what it builds
+type registration struct {
+	role    Role
+	factory Factory
+}
+
+// Registry maps provider types to the factories that build them, so a new
+// provider is added by registering one rather than by rewiring startup
+type Registry struct {
+	registrations map[ProviderType]registration
+	breaker       BreakerConfig
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{registrations: make(map[ProviderType]registration)}
+}
+
+// Register adds the factory for providerType. Registering a type twice
+// replaces the earlier factory.
+func (r *Registry) Register(providerType ProviderType, role Role, factory Factory) {
+	r.registrations[providerType] = registration{role: role, factory: factory}
+}
+
+// SetCircuitBreaker puts every crypto provider that another one backs up
+// behind a circuit breaker with cfg, so the backup answers at once while
+// the first keeps failing. The last provider of the chain is never
+// short-circuited, so its cached prices stay available.
+func (r *Registry) SetCircuitBreaker(cfg BreakerConfig) {
+	r.breaker = cfg
+}
+
+// Built holds the providers a Registry built, routed by role
+type Built struct {
+	Funds  Provider             // Nil if no funds provider is enabled
+	Crypto Provider             // Nil if none is enabled; chained through FallbackProvider when several are
+	FX     ExchangeRateProvider // Nil if no fx provider is enabled
+}
+
+// Build builds the enabled providers in order and routes them by role.
+// Crypto providers back each other up in the order given; for the funds
+// and fx roles, the first enabled provider is used.
+func (r *Registry) Build(ctx context.Context, enabled []ProviderType) (Built, error) {
+	var built Built
+	var crypto []Provider
+	for _, providerType := range enabled {
+		reg, ok := r.registrations[providerType]
+		if !ok {
+			return Built{}, fmt.Errorf("provider %q: no factory registered", providerType)
+		}
+		p, err := reg.factory(ctx)
+		if err != nil {
+			return Built{}, fmt.Errorf("provider %q: %w", providerType, err)
+		}
+
+		switch reg.role {
+		case RoleFunds:
+			priced, ok := p.(Provider)
+			if !ok {
+				return Built{}, fmt.Errorf("provider %q: %T doesn't implement Provider", providerType, p)
+			}
+			if built.Funds != nil {
+				slog.Warn("more than one funds provider enabled; using the first", "ignored", providerType)
+				continue
+			}
+			built.Funds = priced
+		case RoleCrypto:
+			priced, ok := p.(Provider)
+			if !ok {
+				return Built{}, fmt.Errorf("provider %q: %T doesn't implement Provider", providerType, p)
+			}
+			crypto = append(crypto, priced)
+		case RoleFX:
+			fx, ok := p.(ExchangeRateProvider)
+			if !ok {
+				return Built{}, fmt.Errorf("provider %q: %T doesn't implement ExchangeRateProvider", providerType, p)
+			}
+			if built.FX != nil {
+				slog.Warn("more than one fx provider enabled; using the first", "ignored", providerType)
+				continue
+			}
+			built.FX = fx
+		default:
+			return Built{}, fmt.Errorf("provider %q: unknown role %q", providerType, reg.role)
+		}
+	}
+	built.Crypto = r.chain(crypto)
+	return built, nil
+}
+
+// chain links crypto providers into a fallback chain in order, each but the
+// last behind a circuit breaker if one is configured. It returns nil for none.
+func (r *Registry) chain(crypto []Provider) Provider {
+	if len(crypto) == 0 {
+		return nil
+	}
+	if r.breaker.FailureThreshold > 0 {
+		for i := range crypto[:len(crypto)-1] {
+			crypto[i] = NewCircuitBreaker(crypto[i], r.breaker)
+		}
+	}
+	chained := crypto[0]
+	for _, p := range crypto[1:] {
+		chained = NewFallbackProvider(chained, p)
+	}
+	return chained
+}
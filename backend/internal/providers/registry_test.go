@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// stubRates is an ExchangeRateProvider returning a fixed rate
+type stubRates struct{ rate float64 }
+
+func (s stubRates) FetchExchangeRate(ctx context.Context, from, to string) (float64, time.Time, error) {
+	return s.rate, time.Time{}, nil
+}
+
+func TestRegistryBuild(t *testing.T) {
+	funds := &stubProvider{name: "tefas"}
+	primary := &stubProvider{name: "binance"}
+	backup := &stubProvider{name: "coingecko"}
+
+	reg := NewRegistry()
+	register := func(providerType ProviderType, role Role, p any) {
+		reg.Register(providerType, role, func(ctx context.Context) (any, error) { return p, nil })
+	}
+	register(ProviderTypeTEFAS, RoleFunds, funds)
+	register(ProviderTypeBinance, RoleCrypto, primary)
+	register(ProviderTypeCoinGecko, RoleCrypto, backup)
+	register(ProviderTypeFrankfurter, RoleFX, stubRates{rate: 32})
+
+	tests := []struct {
+		name       string
+		enabled    []ProviderType
+		wantFunds  string
+		wantCrypto string
+		wantFX     bool
+	}{
+		{"everything", []ProviderType{ProviderTypeTEFAS, ProviderTypeBinance, ProviderTypeCoinGecko, ProviderTypeFrankfurter}, "tefas", "binance+coingecko", true},
+		{"backup only", []ProviderType{ProviderTypeCoinGecko}, "", "coingecko", false},
+		{"funds only", []ProviderType{ProviderTypeTEFAS}, "tefas", "", false},
+		{"nothing", nil, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			built, err := reg.Build(context.Background(), tt.enabled)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got := providerName(built.Funds); got != tt.wantFunds {
+				t.Errorf("funds provider = %q, want %q", got, tt.wantFunds)
+			}
+			if got := providerName(built.Crypto); got != tt.wantCrypto {
+				t.Errorf("crypto provider = %q, want %q", got, tt.wantCrypto)
+			}
+			if (built.FX != nil) != tt.wantFX {
+				t.Errorf("fx provider = %v, want one: %v", built.FX, tt.wantFX)
+			}
+		})
+	}
+
+	if _, err := reg.Build(context.Background(), []ProviderType{"kraken"}); err == nil {
+		t.Error("Build() with an unregistered type succeeded, want an error")
+	}
+	register("broken", RoleFX, funds)
+	if _, err := reg.Build(context.Background(), []ProviderType{"broken"}); err == nil {
+		t.Error("Build() with a provider not fit for its role succeeded, want an error")
+	}
+}
+
+// providerName returns p's name, or "" for no provider
+func providerName(p Provider) string {
+	if p == nil {
+		return ""
+	}
+	return p.Name()
+}
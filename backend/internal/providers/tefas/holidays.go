@@ -0,0 +1,74 @@
+package tefas
+
+import "time"
+
+// fixedHolidays are the Turkish public holidays falling on the same date
+// every year, as "MM-DD"; Borsa Istanbul and TEFAS publish no prices on them
+var fixedHolidays = map[string]bool{
+	"01-01": true, // New Year's Day
+	"04-23": true, // National Sovereignty and Children's Day
+	"05-01": true, // Labour and Solidarity Day
+	"05-19": true, // Atatürk Commemoration, Youth and Sports Day
+	"07-15": true, // Democracy and National Unity Day
+	"08-30": true, // Victory Day
+	"10-29": true, // Republic Day
+}
+
+// religiousHolidays are the Ramadan and Sacrifice Feast days, which follow
+// the lunar calendar. Later years are added through Config.Holidays until
+// they are listed here.
+var religiousHolidays = map[string]bool{
+	"2024-04-10": true, "2024-04-11": true, "2024-04-12": true,
+	"2024-06-16": true, "2024-06-17": true, "2024-06-18": true, "2024-06-19": true,
+	"2025-03-30": true, "2025-03-31": true, "2025-04-01": true,
+	"2025-06-06": true, "2025-06-07": true, "2025-06-08": true, "2025-06-09": true,
+	"2026-03-20": true, "2026-03-21": true, "2026-03-22": true,
+	"2026-05-27": true, "2026-05-28": true, "2026-05-29": true, "2026-05-30": true,
+}
+
+// holidaySet returns the configured extra holidays keyed by date
+func holidaySet(days []time.Time) map[string]bool {
+	set := make(map[string]bool, len(days))
+	for _, d := range days {
+		set[d.Format(time.DateOnly)] = true
+	}
+	return set
+}
+
+// isBusinessDay reports whether TEFAS publishes prices for t: a weekday
+// that is neither a built-in nor a configured holiday
+func (p *Provider) isBusinessDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	date := t.Format(time.DateOnly)
+	return !fixedHolidays[date[5:]] && !religiousHolidays[date] && !p.holidays[date]
+}
+
+// lastBusinessDay returns today in the provider's time zone as of now, or
+// the last business day before it
+func (p *Provider) lastBusinessDay(now time.Time) time.Time {
+	return p.businessDayOnOrBefore(now.In(p.location))
+}
+
+// previousBusinessDay returns the business day before the date t
+func (p *Provider) previousBusinessDay(t time.Time) time.Time {
+	return p.businessDayOnOrBefore(t.AddDate(0, 0, -1))
+}
+
+// businessDayOnOrBefore returns the date t, or the last business day before it
+func (p *Provider) businessDayOnOrBefore(t time.Time) time.Time {
+	for !p.isBusinessDay(t) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// isStaleDate reports whether prices published for priceDate may be
+// outdated: they are older than today's in the provider's time zone, as
+// before the day's prices are out, or today is a weekend or holiday, when
+// TEFAS repeats the last prices
+func (p *Provider) isStaleDate(priceDate, now time.Time) bool {
+	now = now.In(p.location)
+	return priceDate.Format(time.DateOnly) != now.Format(time.DateOnly) || !p.isBusinessDay(now)
+}
@@ -0,0 +1,72 @@
+package tefas
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// RunKeepAlive reloads the TEFAS page every interval while no API call has
+// succeeded for that long, so the firewall cookies don't expire and the
+// first fetch after an idle spell doesn't have to earn them again within its
+// deadline. It blocks until ctx is cancelled.
+func (p *Provider) RunKeepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if p.pingDue(time.Now(), interval) {
+			p.ping()
+		}
+	}
+}
+
+// pingDue reports whether the session has been idle for interval as of now:
+// no API call has succeeded since then
+func (p *Provider) pingDue(now time.Time, interval time.Duration) bool {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+	return now.Sub(p.lastSuccess) >= interval
+}
+
+// ping reloads the TEFAS page under p.mu while no fetch is in flight, so it
+// never overlaps one. A browser that isn't running is left for the next
+// fetch to start; a failed reload counts towards the automatic restart like
+// a failed API call.
+func (p *Provider) ping() {
+	p.mu.Lock()
+	relaunch := false
+	defer func() {
+		p.mu.Unlock()
+		if relaunch {
+			p.relaunch()
+		}
+	}()
+
+	if !p.started || p.page == nil || p.inFlight > 0 {
+		return
+	}
+
+	resp, err := p.page.Goto(baseURL+"/TarihselVeriler.aspx", playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+		Timeout:   playwright.Float(float64(p.timeout.Milliseconds())),
+	})
+	if err == nil && resp != nil && (resp.Status() == 403 || resp.Status() == 429) {
+		err = fmt.Errorf("%w (status %d)", ErrWAFBlocked, resp.Status())
+	}
+	if err != nil {
+		slog.Warn("TEFAS keep-alive failed", "error", err)
+		p.trackFailures(err)
+		relaunch = p.restartIfIdleLocked()
+		return
+	}
+	slog.Debug("TEFAS keep-alive succeeded")
+}
@@ -0,0 +1,41 @@
+package tefas
+
+import "context"
+
+// defaultFetchConcurrency keeps API calls one at a time, as a browser
+// session firing several requests at once looks more like a bot to the
+// TEFAS firewall
+const defaultFetchConcurrency = 1
+
+// fetchPool bounds how many TEFAS API calls are in flight at once, across
+// every caller. The calls share one browser page, so fetches spanning
+// several dates or fund types run side by side instead of queuing for it.
+type fetchPool struct {
+	slots chan struct{}
+}
+
+// newFetchPool creates a pool running up to size calls at once (at least one)
+func newFetchPool(size int) *fetchPool {
+	return &fetchPool{slots: make(chan struct{}, max(size, 1))}
+}
+
+// run calls fn once a slot is free, or returns ctx's error if ctx is done
+// first
+func (fp *fetchPool) run(ctx context.Context, fn func()) error {
+	select {
+	case fp.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-fp.slots }()
+	fn()
+	return nil
+}
+
+// orDefaultFetchConcurrency returns n, or defaultFetchConcurrency if n is zero
+func orDefaultFetchConcurrency(n int) int {
+	if n == 0 {
+		return defaultFetchConcurrency
+	}
+	return n
+}
@@ -3,20 +3,93 @@ package tefas
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 
+	"github.com/ferhatkunduraci/prism/internal/metrics"
 	"github.com/ferhatkunduraci/prism/internal/providers"
 	"github.com/playwright-community/playwright-go"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	baseURL = "https://www.tefas.gov.tr"
+
+	// defaultCacheTTL is used when no TTL is configured
+	defaultCacheTTL = 5 * time.Minute // TEFAS data doesn't change frequently
+
+	// defaultTimeout is used when no fetch timeout is configured
+	defaultTimeout = 20 * time.Second // Playwright round-trips are slow
+
+	// fundListTTL is how long the fund universe used for search is cached
+	fundListTTL = 24 * time.Hour // Funds are added or closed rarely
+
+	// defaultUserAgent is the browser user agent when none are configured
+	defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"
+
+	// defaultRestartAfter is the number of consecutive failed API calls that
+	// triggers a browser restart when no threshold is configured
+	defaultRestartAfter = 3
+
+	// defaultLookbackDays is how many earlier business days are tried when
+	// the last one has no prices yet, if no limit is configured
+	defaultLookbackDays = 5 // Covers the longest public holidays
+
+	// Backoff between automatic browser restarts, doubled after each restart
+	// that doesn't bring back a successful call
+	minRestartBackoff = time.Minute
+	maxRestartBackoff = 30 * time.Minute
+
+	// defaultAbortGrace is how long a cancelled page call gets to stop
+	// before the browser is closed as hung
+	defaultAbortGrace = 2 * time.Second
+
+	// maxSearchResults caps the number of funds returned by SearchFunds
+	maxSearchResults = 20
 )
 
+// ErrWAFBlocked is returned when the TEFAS web application firewall answers
+// instead of the API (block page, captcha, or a refusal status). It wraps
+// providers.ErrUpstreamBlocked.
+var ErrWAFBlocked = fmt.Errorf("TEFAS firewall blocked the request: %w", providers.ErrUpstreamBlocked)
+
+// ErrBrowserNotInstalled is returned by Start when Playwright or its Chromium
+// build is missing. Fund prices are unavailable, apart from cached ones,
+// until it is installed and the server restarted.
+var ErrBrowserNotInstalled = errors.New("playwright chromium is not installed; run " +
+	"\"go run github.com/playwright-community/playwright-go/cmd/playwright install --with-deps chromium\" " +
+	"or set PLAYWRIGHT_CHROMIUM_EXECUTABLE_PATH to a system Chromium")
+
+// browserMissingMarkers are lower-cased phrases in Playwright launch errors
+// caused by a missing browser install
+var browserMissingMarkers = []string{
+	"executable doesn't exist",
+	"please run the following command to download new browsers",
+	"playwright install",
+}
+
+// wafMarkers are lower-cased phrases seen on TEFAS firewall block and
+// captcha pages
+var wafMarkers = []string{
+	"erişim engellendi",
+	"web application firewall",
+	"request rejected",
+	"the requested url was rejected",
+	"your support id is",
+	"captcha",
+	"access denied",
+}
+
 // FundType represents TEFAS fund types
 type FundType string
 
@@ -34,6 +107,9 @@ type RawFundData struct {
 	TedPaySayisi    float64 `json:"TEDPAYSAYISI"`
 	KisiSayisi      int     `json:"KISISAYISI"`
 	PortfoyBuyukluk float64 `json:"PORTFOYBUYUKLUK"`
+	FonTurAciklama  string  `json:"FONTURACIKLAMA"` // Fund category, when the response includes it
+
+	FundType FundType `json:"-"` // Type the fund was queried as (not part of the response)
 }
 
 // APIResponse represents the TEFAS API response structure
@@ -46,12 +122,62 @@ type APIResponse struct {
 
 // Provider implements the TEFAS data provider using Playwright
 type Provider struct {
-	headless bool
-	funds    []string
-	cache    map[string]providers.Price
-	cacheMu  sync.RWMutex
-	cacheExp time.Time
-	cacheTTL time.Duration
+	headless  bool
+	funds     []string
+	fundTypes map[string]FundType // guarded by cacheMu
+	cache     map[string]providers.Price
+	cacheMu   sync.RWMutex
+	cacheTTL  time.Duration
+	store     providers.PriceStore
+	timeout   time.Duration
+
+	staleAfter time.Duration // Age at which prices are flagged stale; 0 follows cacheTTL (guarded by cacheMu)
+
+	// Cached prices are fresh for cacheTTL after they were fetched, unless
+	// fetched before pricesFlushed (guarded by cacheMu)
+	pricesFlushed time.Time
+
+	// Fund universe for search (guarded by cacheMu)
+	fundList    []providers.FundInfo
+	fundListExp time.Time
+
+	// Fund metadata, cached with the same TTL as prices (guarded by cacheMu).
+	// Details fetched before detailsFlushed are no longer fresh.
+	details        map[string]providers.FundDetails
+	detailsFlushed time.Time
+
+	// When codes were last found missing from a successful details fetch,
+	// so unknown codes are answered from here for the cache TTL rather than
+	// sent to TEFAS on every request (guarded by cacheMu)
+	detailMisses map[string]time.Time
+
+	// Display names seen in TEFAS responses, persisted to nameStore (guarded by cacheMu)
+	names     map[string]string
+	nameStore providers.FundNameStore
+
+	// Outcome of recent API calls, for health checks (guarded by cacheMu)
+	lastSuccess time.Time
+	lastFailure time.Time
+
+	// ready mirrors started so health checks don't wait on an in-flight call
+	ready atomic.Bool
+
+	// Browser launch settings
+	proxy      Proxy
+	launchArgs []string
+	userAgents []string
+
+	// nextAgent indexes the user agent the next browser start uses (guarded by mu)
+	nextAgent int
+
+	// lookbackDays is how many earlier business days fetchLatest tries
+	lookbackDays int
+
+	// holidays are market holidays beyond the built-in ones, keyed by date
+	holidays map[string]bool
+
+	// location is the time zone business days and TEFAS dates are in
+	location *time.Location
 
 	// Playwright resources
 	pw      *playwright.Playwright
@@ -59,22 +185,260 @@ type Provider struct {
 	page    playwright.Page
 	started bool
 	mu      sync.Mutex
+
+	// notInstalled is the ErrBrowserNotInstalled failure of the first start,
+	// returned by later starts without retrying (guarded by mu)
+	notInstalled error
+
+	// Automatic restart after repeated failures (guarded by mu)
+	restartAfter        int
+	consecutiveFailures int
+	restartBackoff      time.Duration
+	nextRestart         time.Time
+
+	// Concurrent fetches of one day's prices share a single API call per
+	// fund type, rather than queuing behind mu to repeat it
+	dayFetches singleflight.Group
+
+	// callSeq numbers in-page fetches so a cancelled one can be aborted (guarded by mu)
+	callSeq int64
+
+	// inFlight counts in-page fetches under way, which run without holding
+	// mu (guarded by mu)
+	inFlight int
+
+	// restartDone is set while a due restart waits for the in-flight
+	// fetches to finish, so none has the page closed under it, and closed
+	// once the browser is replaced. Calls arriving meanwhile wait for it
+	// rather than start on the old page. relaunching is set once the old
+	// browser is closed and the new one launches, without holding mu.
+	// closes counts calls to Close, so a browser launched across one is
+	// discarded. (guarded by mu)
+	restartDone chan struct{}
+	relaunching bool
+	closes      int
+
+	// pool bounds how many API calls run at once
+	pool *fetchPool
+
+	// abortGrace is how long a cancelled page call gets to stop once its
+	// in-page fetch is aborted, before the page is considered hung
+	abortGrace time.Duration
+
+	// query makes one API call, in a pool slot; callRangeAPI, swapped out in tests
+	query func(ctx context.Context, fundType FundType, code, from, to string) ([]RawFundData, error)
+
+	// launch starts a browser presenting a user agent; launchBrowser, swapped out in tests
+	launch func(userAgent string) (browserSession, error)
 }
 
 // Config holds TEFAS provider configuration
 type Config struct {
-	Headless bool
-	Funds    []string
+	Headless   bool
+	Funds      []string
+	FundTypes  map[string]FundType     // Optional, fund code -> type; unknown codes are detected
+	CacheTTL   time.Duration           // Optional, defaults to 5m
+	StaleAfter time.Duration           // Optional, age at which prices are flagged stale, defaults to CacheTTL
+	Store      providers.PriceStore    // Optional, persists last-known prices
+	Timeout    time.Duration           // Optional, deadline for one fetch, defaults to 20s
+	NameStore  providers.FundNameStore // Optional, persists fund names seen in responses
+	Proxy      Proxy                   // Optional, routes browser traffic through a proxy
+	LaunchArgs []string                // Optional, replaces the default Chromium flags
+	UserAgents []string                // Optional, rotated through on each browser start; defaults to a recent desktop Chrome
+
+	// RestartAfter is the number of consecutive failed API calls after which
+	// the browser is restarted; a firewall block restarts it at once. Zero
+	// uses the default (3); negative disables restarts.
+	RestartAfter int
+
+	// LookbackDays is how many earlier business days are tried when TEFAS
+	// has no prices for the last one yet, as on mornings before NAVs are
+	// published. Zero uses the default (5); negative disables the lookback.
+	LookbackDays int
+
+	// Holidays are extra days without prices on top of the built-in Turkish
+	// public holidays, e.g. religious holidays of years not built in yet
+	Holidays []time.Time
+
+	// Location is the time zone business days are computed in, so a server
+	// running in UTC doesn't ask for a date TEFAS hasn't reached. Nil uses
+	// Europe/Istanbul.
+	Location *time.Location
+
+	// FetchConcurrency is how many API calls may run at once, e.g. one per
+	// fund type of a fetch. Zero uses the default (1).
+	FetchConcurrency int
+}
+
+// Proxy routes the browser through a proxy server, e.g. when the TEFAS
+// firewall blocks the host's network
+type Proxy struct {
+	Server   string // e.g. "http://proxy.example:3128" or "socks5://proxy.example:1080"; empty disables
+	Username string // Optional
+	Password string // Optional
 }
 
 // NewProvider creates a new TEFAS provider
 func NewProvider(cfg Config) *Provider {
-	return &Provider{
-		headless: cfg.Headless,
-		funds:    cfg.Funds,
-		cache:    make(map[string]providers.Price),
-		cacheTTL: 5 * time.Minute, // TEFAS data doesn't change frequently
+	fundTypes := make(map[string]FundType, len(cfg.FundTypes))
+	for code, t := range cfg.FundTypes {
+		fundTypes[code] = t
+	}
+
+	p := &Provider{
+		headless:  cfg.Headless,
+		funds:     cfg.Funds,
+		fundTypes: fundTypes,
+		cache:     make(map[string]providers.Price),
+		details:   make(map[string]providers.FundDetails),
+		names:     make(map[string]string),
+		nameStore: cfg.NameStore,
+		cacheTTL:  providers.OrDefault(cfg.CacheTTL, defaultCacheTTL),
+		store:     cfg.Store,
+		timeout:   providers.OrDefault(cfg.Timeout, defaultTimeout),
+
+		detailMisses: make(map[string]time.Time),
+
+		staleAfter: cfg.StaleAfter,
+		proxy:      cfg.Proxy,
+		launchArgs: cfg.LaunchArgs,
+		userAgents: orDefaultUserAgents(cfg.UserAgents),
+
+		lookbackDays: max(orDefaultLookbackDays(cfg.LookbackDays), 0),
+		holidays:     holidaySet(cfg.Holidays),
+		location:     orDefaultLocation(cfg.Location),
+
+		restartAfter:   orDefaultRestartAfter(cfg.RestartAfter),
+		restartBackoff: minRestartBackoff,
+		abortGrace:     defaultAbortGrace,
+
+		pool: newFetchPool(orDefaultFetchConcurrency(cfg.FetchConcurrency)),
+	}
+	p.query = p.callRangeAPI
+	p.launch = p.launchBrowser
+	return p
+}
+
+// orDefaultUserAgents returns agents, or just defaultUserAgent when empty
+func orDefaultUserAgents(agents []string) []string {
+	if len(agents) == 0 {
+		return []string{defaultUserAgent}
+	}
+	return agents
+}
+
+// orDefaultRestartAfter returns n, or defaultRestartAfter when n is zero
+func orDefaultRestartAfter(n int) int {
+	if n == 0 {
+		return defaultRestartAfter
+	}
+	return n
+}
+
+// orDefaultLocation returns loc, or Europe/Istanbul when it is nil. Without
+// the time zone database that falls back to a fixed UTC+3, which Turkey
+// has kept all year since 2016.
+func orDefaultLocation(loc *time.Location) *time.Location {
+	if loc != nil {
+		return loc
 	}
+	if istanbul, err := time.LoadLocation("Europe/Istanbul"); err == nil {
+		return istanbul
+	}
+	return time.FixedZone("TRT", 3*60*60)
+}
+
+// orDefaultLookbackDays returns n, or defaultLookbackDays when n is zero
+func orDefaultLookbackDays(n int) int {
+	if n == 0 {
+		return defaultLookbackDays
+	}
+	return n
+}
+
+// LoadCache fills the cache with prices and fund names persisted by a
+// previous run
+func (p *Provider) LoadCache(ctx context.Context) error {
+	if p.nameStore != nil {
+		names, err := p.nameStore.LoadFundNames(ctx)
+		if err != nil {
+			return err
+		}
+		p.cacheMu.Lock()
+		for code, name := range names {
+			p.names[code] = name
+		}
+		p.cacheMu.Unlock()
+	}
+
+	if p.store == nil {
+		return nil
+	}
+
+	saved, err := p.store.LoadPrices(ctx, p.Name())
+	if err != nil {
+		return err
+	}
+
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	restored, _ := providers.RestorePrices(saved, p.cacheTTL, p.staleThreshold(), time.Now())
+	for _, price := range restored {
+		p.cache[price.Symbol] = price
+	}
+	return nil
+}
+
+// SetSymbols replaces the configured fund list (safe for concurrent use)
+func (p *Provider) SetSymbols(funds []string) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.funds = funds
+}
+
+// SetFundTypes records the type of each given fund code, keeping types
+// already known for other codes (safe for concurrent use)
+func (p *Provider) SetFundTypes(types map[string]FundType) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	for code, t := range types {
+		p.fundTypes[code] = t
+	}
+}
+
+// SetCacheTTL changes how long fetched prices are cached (safe for concurrent use)
+func (p *Provider) SetCacheTTL(ttl time.Duration) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.cacheTTL = providers.OrDefault(ttl, defaultCacheTTL)
+}
+
+// SetStaleAfter changes the age at which prices are flagged stale; zero
+// follows the cache TTL (safe for concurrent use)
+func (p *Provider) SetStaleAfter(staleAfter time.Duration) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.staleAfter = staleAfter
+}
+
+// staleThreshold returns the age at which prices are flagged stale (callers
+// hold cacheMu)
+func (p *Provider) staleThreshold() time.Duration {
+	if p.staleAfter <= 0 {
+		return p.cacheTTL
+	}
+	return p.staleAfter
+}
+
+// FlushCache expires cached prices, fund details and the fund list so the
+// next requests go to TEFAS. Cached data is kept as a stale fallback in
+// case they fail.
+func (p *Provider) FlushCache() {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.pricesFlushed = time.Now()
+	p.fundListExp = time.Time{}
+	p.detailsFlushed = time.Now()
 }
 
 // Name returns the provider name
@@ -82,16 +446,141 @@ func (p *Provider) Name() string {
 	return "tefas"
 }
 
-// Start initializes the Playwright browser
+// Start initializes the Playwright browser, waiting for a restart under
+// way to finish
 func (p *Provider) Start() error {
 	p.mu.Lock()
+	for p.restartDone != nil {
+		done := p.restartDone
+		p.mu.Unlock()
+		<-done
+		p.mu.Lock()
+	}
 	defer p.mu.Unlock()
+	return p.startLocked()
+}
+
+// nextUserAgent returns the user agent for a browser start and moves the
+// rotation on, so each restart presents the next one; p.mu must be held
+func (p *Provider) nextUserAgent() string {
+	userAgent := p.userAgents[p.nextAgent%len(p.userAgents)]
+	p.nextAgent++
+	return userAgent
+}
+
+// browserArgs returns the Chromium flags: the configured ones, or defaults
+// that make the browser look less automated to the TEFAS WAF, presenting
+// userAgent when headless
+func (p *Provider) browserArgs(userAgent string) []string {
+	if len(p.launchArgs) > 0 {
+		return p.launchArgs
+	}
 
+	args := []string{
+		"--no-sandbox",
+		"--disable-dev-shm-usage",
+		"--disable-blink-features=AutomationControlled",
+		"--disable-infobars",
+		"--window-size=1920,1080",
+	}
+	if p.headless {
+		// Add args that help headless mode look more like a real browser
+		args = append(args,
+			"--disable-gpu",
+			"--user-agent="+userAgent,
+		)
+	}
+	return args
+}
+
+// browserProxy returns the Playwright proxy settings, or nil if no proxy
+// is configured
+func (p *Provider) browserProxy() *playwright.Proxy {
+	if p.proxy.Server == "" {
+		return nil
+	}
+	proxy := &playwright.Proxy{Server: p.proxy.Server}
+	if p.proxy.Username != "" {
+		proxy.Username = playwright.String(p.proxy.Username)
+	}
+	if p.proxy.Password != "" {
+		proxy.Password = playwright.String(p.proxy.Password)
+	}
+	return proxy
+}
+
+// isBrowserMissing reports whether a browser launch failed because the
+// browser isn't installed
+func isBrowserMissing(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, marker := range browserMissingMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLine returns s up to its first line break; Playwright follows launch
+// errors with a boxed banner that doesn't belong in a log line
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// browserSession is a running browser with the TEFAS page open
+type browserSession struct {
+	pw      *playwright.Playwright
+	browser playwright.Browser
+	page    playwright.Page
+}
+
+// close releases the session's resources
+func (s browserSession) close() {
+	if s.page != nil {
+		s.page.Close()
+	}
+	if s.browser != nil {
+		s.browser.Close()
+	}
+	if s.pw != nil {
+		s.pw.Stop()
+	}
+}
+
+// startLocked initializes the Playwright browser; p.mu must be held
+func (p *Provider) startLocked() error {
 	if p.started {
 		return nil
 	}
+	if p.notInstalled != nil {
+		return p.notInstalled
+	}
+
+	session, err := p.launch(p.nextUserAgent())
+	if err != nil {
+		if errors.Is(err, ErrBrowserNotInstalled) {
+			p.notInstalled = err
+		}
+		return err
+	}
+	p.useSessionLocked(session)
+	return nil
+}
+
+// useSessionLocked makes session the provider's browser; p.mu must be held
+func (p *Provider) useSessionLocked(session browserSession) {
+	p.pw, p.browser, p.page = session.pw, session.browser, session.page
+	p.started = true
+	p.ready.Store(true)
+	slog.Info("TEFAS provider started successfully")
+}
 
-	slog.Info("starting TEFAS provider", "headless", p.headless)
+// launchBrowser starts Playwright and a browser presenting userAgent, and
+// opens the TEFAS page to get its cookies. It touches no provider state
+// guarded by p.mu, so a restart can launch without holding it.
+func (p *Provider) launchBrowser(userAgent string) (browserSession, error) {
+	slog.Info("starting TEFAS provider", "headless", p.headless, "user_agent", userAgent)
 
 	// Initialize Playwright
 	slog.Debug("initializing Playwright runtime")
@@ -101,40 +590,26 @@ func (p *Provider) Start() error {
 		// Try to install the driver automatically
 		if installErr := playwright.Install(); installErr != nil {
 			slog.Error("failed to install Playwright driver", "error", installErr)
-			return fmt.Errorf("could not start playwright: %w (also failed to install: %v)", err, installErr)
+			return browserSession{}, fmt.Errorf("%w (starting the driver: %v; installing it: %v)", ErrBrowserNotInstalled, err, installErr)
 		}
 		slog.Info("Playwright driver installed, retrying...")
 		pw, err = playwright.Run()
 		if err != nil {
 			slog.Error("failed to start Playwright after install", "error", err)
-			return fmt.Errorf("could not start playwright: %w", err)
+			return browserSession{}, fmt.Errorf("could not start playwright: %w", err)
 		}
 	}
-	p.pw = pw
+	session := browserSession{pw: pw}
 	slog.Debug("Playwright runtime initialized successfully")
 
-	// Launch browser with anti-detection settings
-	// Note: TEFAS WAF often blocks headless browsers
-	// Using extra args to better evade detection
-	args := []string{
-		"--no-sandbox",
-		"--disable-dev-shm-usage",
-		"--disable-blink-features=AutomationControlled",
-		"--disable-infobars",
-		"--window-size=1920,1080",
-	}
-	if p.headless {
-		// Add args that help headless mode look more like a real browser
-		args = append(args,
-			"--disable-gpu",
-			"--user-agent=Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36",
-		)
-	}
-
 	// Build launch options
 	launchOpts := playwright.BrowserTypeLaunchOptions{
 		Headless: playwright.Bool(p.headless),
-		Args:     args,
+		Args:     p.browserArgs(userAgent),
+		Proxy:    p.browserProxy(),
+	}
+	if p.proxy.Server != "" {
+		slog.Info("routing TEFAS browser through proxy", "server", p.proxy.Server)
 	}
 
 	// Use system Chromium if PLAYWRIGHT_CHROMIUM_EXECUTABLE_PATH is set
@@ -146,40 +621,42 @@ func (p *Provider) Start() error {
 
 	browser, err := pw.Chromium.Launch(launchOpts)
 	if err != nil {
-		p.pw.Stop()
+		session.close()
+		if isBrowserMissing(err) {
+			return browserSession{}, fmt.Errorf("%w (%v)", ErrBrowserNotInstalled, firstLine(err.Error()))
+		}
 		slog.Error("failed to launch browser", "error", err, "headless", p.headless)
-		return fmt.Errorf("could not launch browser: %w", err)
+		return browserSession{}, fmt.Errorf("could not launch browser: %w", err)
 	}
-	p.browser = browser
+	session.browser = browser
 
 	// Create browser context with realistic settings
 	contextOptions := playwright.BrowserNewContextOptions{
-		UserAgent: playwright.String("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36"),
+		UserAgent: playwright.String(userAgent),
 		Viewport: &playwright.Size{
 			Width:  1920,
 			Height: 1080,
 		},
 		Locale: playwright.String("tr-TR"),
+		Proxy:  p.browserProxy(),
 	}
 	context, err := browser.NewContext(contextOptions)
 	if err != nil {
-		p.browser.Close()
-		p.pw.Stop()
-		return fmt.Errorf("could not create context: %w", err)
+		session.close()
+		return browserSession{}, fmt.Errorf("could not create context: %w", err)
 	}
 
 	// Create page from context
 	page, err := context.NewPage()
 	if err != nil {
 		context.Close()
-		p.browser.Close()
-		p.pw.Stop()
-		return fmt.Errorf("could not create page: %w", err)
+		session.close()
+		return browserSession{}, fmt.Errorf("could not create page: %w", err)
 	}
-	p.page = page
+	session.page = page
 
 	// Remove webdriver property that exposes automation
-	p.page.AddInitScript(playwright.Script{
+	page.AddInitScript(playwright.Script{
 		Content: playwright.String(`
 			Object.defineProperty(navigator, 'webdriver', {
 				get: () => undefined
@@ -188,98 +665,100 @@ func (p *Provider) Start() error {
 	})
 
 	// Set headers
-	p.page.SetExtraHTTPHeaders(map[string]string{
+	page.SetExtraHTTPHeaders(map[string]string{
 		"Accept-Language": "tr-TR,tr;q=0.9,en;q=0.8",
 	})
 
 	// Navigate to TEFAS to get cookies
-	_, err = p.page.Goto(baseURL+"/TarihselVeriler.aspx", playwright.PageGotoOptions{
+	_, err = page.Goto(baseURL+"/TarihselVeriler.aspx", playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
 	})
 	if err != nil {
-		p.Close()
-		return fmt.Errorf("could not navigate to TEFAS: %w", err)
+		session.close()
+		return browserSession{}, fmt.Errorf("could not navigate to TEFAS: %w", err)
 	}
 
 	// Wait for page to load and any JavaScript challenges to complete
 	time.Sleep(2 * time.Second)
-
-	p.started = true
-	slog.Info("TEFAS provider started successfully")
-	return nil
+	return session, nil
 }
 
 // FetchPrices retrieves prices for the given fund codes
 func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]providers.Price, error) {
-	// Check cache first
-	p.cacheMu.RLock()
-	if time.Now().Before(p.cacheExp) && len(p.cache) > 0 {
-		prices := make([]providers.Price, 0, len(symbols))
-		allCached := true
-		for _, s := range symbols {
-			if price, ok := p.cache[s]; ok {
-				prices = append(prices, price)
-			} else {
-				allCached = false
-				break
-			}
-		}
-		if allCached && len(prices) == len(symbols) {
-			p.cacheMu.RUnlock()
-			slog.Debug("returning cached TEFAS prices", "count", len(prices))
-			return prices, nil
-		}
+	// Serve what is cached and fresh, and fetch only the rest
+	cached, missing := p.freshCached(symbols)
+	if len(missing) == 0 {
+		metrics.ObserveCache(p.Name(), true)
+		slog.Debug("returning cached TEFAS prices", "count", len(symbols))
+		return inOrder(symbols, cached), nil
 	}
-	p.cacheMu.RUnlock()
+	metrics.ObserveCache(p.Name(), false)
 
 	// Ensure provider is started
 	if err := p.Start(); err != nil {
+		if prices := p.staleCached(symbols); len(prices) > 0 {
+			slog.Warn("returning stale cache; TEFAS provider not started", "error", err)
+			return prices, nil
+		}
 		slog.Error("failed to start TEFAS provider", "error", err)
 		return nil, fmt.Errorf("failed to start provider: %w", err)
 	}
 
-	slog.Info("fetching TEFAS data", "funds", symbols)
+	slog.Info("fetching TEFAS data", "funds", missing, "cached", len(cached))
 
-	// Get last business day
-	targetDate := getLastBusinessDay()
-	dateStr := formatDate(targetDate)
+	// Fetch all funds data for the last business day with prices
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
 
-	// Fetch all funds data
-	rawFunds, err := p.callAPI(ctx, dateStr)
+	start := time.Now()
+	rawFunds, priceDate, err := p.fetchLatest(fetchCtx, func(dateStr string) ([]RawFundData, error) {
+		return p.fetchFunds(fetchCtx, dateStr, missing)
+	})
+	metrics.ObserveFetch(p.Name(), start, err)
 	if err != nil {
 		// Return stale cache if available
-		p.cacheMu.RLock()
-		if len(p.cache) > 0 {
-			prices := make([]providers.Price, 0, len(symbols))
-			for _, s := range symbols {
-				if price, ok := p.cache[s]; ok {
-					price.Stale = true
-					prices = append(prices, price)
-				}
-			}
-			p.cacheMu.RUnlock()
-			if len(prices) > 0 {
-				slog.Warn("returning stale cache due to API error", "error", err)
-				return prices, nil
-			}
+		if prices := p.staleCached(symbols); len(prices) > 0 {
+			slog.Warn("returning stale cache due to API error", "error", err)
+			return prices, nil
 		}
-		p.cacheMu.RUnlock()
 		return nil, fmt.Errorf("failed to fetch TEFAS data: %w", err)
 	}
 
-	// Build a map of fund data
+	// The response covers every fund of the queried types, so refresh metadata for free
+	p.updateDetails(rawFunds, priceDate)
+
+	// Build a map of fund data, merged across fund types
 	fundMap := make(map[string]RawFundData)
+	fetchedTypes := make(map[FundType]bool)
 	for _, f := range rawFunds {
 		fundMap[f.FonKodu] = f
+		fetchedTypes[f.FundType] = true
+	}
+
+	// A fund whose type failed to fetch keeps its last price, if it has
+	// one, instead of being reported missing
+	var unfetched []string
+	for _, symbol := range missing {
+		if _, ok := fundMap[symbol]; !ok && !p.typeFetched(symbol, fetchedTypes) {
+			unfetched = append(unfetched, symbol)
+		}
+	}
+	fallback := make(map[string]providers.Price, len(unfetched))
+	for _, price := range p.staleCached(unfetched) {
+		fallback[price.Symbol] = price
 	}
 
-	// Build prices for requested symbols
+	// Build prices for the fetched symbols
 	now := time.Now()
-	isWeekend := now.Weekday() == time.Saturday || now.Weekday() == time.Sunday
-	prices := make([]providers.Price, 0, len(symbols))
+	prices := make([]providers.Price, 0, len(missing))
 
-	for _, symbol := range symbols {
+	for _, symbol := range missing {
 		var price providers.Price
+		if last, ok := fallback[symbol]; ok {
+			slog.Warn("keeping last TEFAS price; its fund type failed to fetch", "symbol", symbol)
+			cached[symbol] = last
+			continue
+		}
 		if fund, ok := fundMap[symbol]; ok {
 			price = providers.Price{
 				Symbol:      fund.FonKodu,
@@ -288,13 +767,15 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 				DailyChange: 0, // TEFAS doesn't provide daily change directly
 				DailyPct:    0,
 				LastUpdated: now,
-				Stale:       isWeekend,
+				Stale:       p.isStaleDate(priceDate, now),
+				PriceDate:   priceDate.Format(time.DateOnly),
+				Category:    strings.TrimSpace(fund.FonTurAciklama),
 			}
 		} else {
 			// Fund not found - return placeholder
 			price = providers.Price{
 				Symbol:      symbol,
-				Name:        getFundName(symbol),
+				Name:        p.FundName(symbol),
 				Price:       0,
 				DailyChange: 0,
 				DailyPct:    0,
@@ -310,88 +791,840 @@ func (p *Provider) FetchPrices(ctx context.Context, symbols []string) ([]provide
 	p.cacheMu.Lock()
 	for _, price := range prices {
 		p.cache[price.Symbol] = price
+		cached[price.Symbol] = price
 	}
-	p.cacheExp = time.Now().Add(p.cacheTTL)
 	p.cacheMu.Unlock()
 
+	providers.PersistPrices(ctx, p.store, p.Name(), prices)
+
+	return inOrder(symbols, cached), nil
+}
+
+// freshCached splits symbols into those with a cached price younger than
+// the cache TTL and fetched since the last flush, returned by symbol and
+// marked stale past the stale threshold, and the missing ones to fetch
+func (p *Provider) freshCached(symbols []string) (map[string]providers.Price, []string) {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+	now := time.Now()
+	staleAfter := p.staleThreshold()
+	cached := make(map[string]providers.Price, len(symbols))
+	var missing []string
+	for _, s := range symbols {
+		price, ok := p.cache[s]
+		if ok && now.Sub(price.LastUpdated) < p.cacheTTL && price.LastUpdated.After(p.pricesFlushed) {
+			if providers.Outdated(price, staleAfter, now) {
+				price.Stale = true
+			}
+			cached[s] = price
+		} else if !slices.Contains(missing, s) {
+			missing = append(missing, s)
+		}
+	}
+	return cached, missing
+}
+
+// inOrder lists the prices of symbols in the order given, skipping any
+// without one
+func inOrder(symbols []string, prices map[string]providers.Price) []providers.Price {
+	ordered := make([]providers.Price, 0, len(symbols))
+	for _, s := range symbols {
+		if price, ok := prices[s]; ok {
+			ordered = append(ordered, price)
+		}
+	}
+	return ordered
+}
+
+// staleCached returns the cached prices of symbols to fall back on, marked
+// stale once they are older than the stale threshold
+func (p *Provider) staleCached(symbols []string) []providers.Price {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+	prices := make([]providers.Price, 0, len(symbols))
+	for _, s := range symbols {
+		if price, ok := p.cache[s]; ok {
+			prices = append(prices, price)
+		}
+	}
+	return providers.MarkStale(prices, p.staleThreshold(), time.Now())
+}
+
+// FetchHistoricalPrices returns fund prices published for date. TEFAS has no
+// prices for weekends and holidays, so those days return an empty result.
+// Results are not cached.
+func (p *Provider) FetchHistoricalPrices(ctx context.Context, date time.Time, symbols []string) ([]providers.Price, error) {
+	if err := p.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start provider: %w", err)
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	rawFunds, err := p.fetchFunds(fetchCtx, formatDate(date), symbols)
+	metrics.ObserveFetch(p.Name(), start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch TEFAS history for %s: %w", date.Format(time.DateOnly), err)
+	}
+
+	wanted := make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		wanted[s] = true
+	}
+
+	prices := make([]providers.Price, 0, len(symbols))
+	for _, f := range rawFunds {
+		if !wanted[f.FonKodu] || f.Fiyat <= 0 {
+			continue
+		}
+		wanted[f.FonKodu] = false
+		prices = append(prices, providers.Price{
+			Symbol:      f.FonKodu,
+			Name:        f.FonUnvan,
+			Price:       f.Fiyat,
+			LastUpdated: date,
+			PriceDate:   date.Format(time.DateOnly),
+			Category:    strings.TrimSpace(f.FonTurAciklama),
+		})
+	}
+
 	return prices, nil
 }
 
-// callAPI makes the actual API call via Playwright
-func (p *Provider) callAPI(ctx context.Context, dateStr string) ([]RawFundData, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// FetchPriceHistory returns the prices TEFAS published for one fund between
+// from and to, oldest first, in a single API call. A code of unknown type is
+// looked up as YAT first and then EMK.
+func (p *Provider) FetchPriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]providers.Price, error) {
+	if err := p.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start provider: %w", err)
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	p.cacheMu.RLock()
+	fundType, known := p.fundTypes[symbol]
+	p.cacheMu.RUnlock()
+	if !known {
+		fundType = FundTypeYAT
+	}
 
+	start := time.Now()
+	rawFunds, err := p.call(fetchCtx, fundType, symbol, formatDate(from), formatDate(to))
+	if err == nil && len(rawFunds) == 0 && !known {
+		rawFunds, err = p.call(fetchCtx, FundTypeEMK, symbol, formatDate(from), formatDate(to))
+	}
+	metrics.ObserveFetch(p.Name(), start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch TEFAS history for %s: %w", symbol, err)
+	}
+
+	prices := make([]providers.Price, 0, len(rawFunds))
+	for _, f := range rawFunds {
+		if f.FonKodu != symbol || f.Fiyat <= 0 {
+			continue
+		}
+		date, err := parseTarih(f.Tarih, p.location)
+		if err != nil {
+			slog.Warn("skipping TEFAS record with an unreadable date", "symbol", symbol, "date", f.Tarih)
+			continue
+		}
+		prices = append(prices, providers.Price{
+			Symbol:      f.FonKodu,
+			Name:        f.FonUnvan,
+			Price:       f.Fiyat,
+			LastUpdated: date,
+			PriceDate:   date.Format(time.DateOnly),
+			Category:    strings.TrimSpace(f.FonTurAciklama),
+		})
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].PriceDate < prices[j].PriceDate })
+	return prices, nil
+}
+
+// parseTarih parses a record's TARIH, milliseconds since the epoch at
+// midnight in Turkey, as a time in loc
+func parseTarih(s string, loc *time.Location) (time.Time, error) {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing TARIH %q: %w", s, err)
+	}
+	return time.UnixMilli(ms).In(loc), nil
+}
+
+// fetchFunds fetches data covering symbols, with one API call per fund type
+// involved. Codes of unknown type are looked up as YAT first and then EMK;
+// the type that answers is remembered for later fetches.
+func (p *Provider) fetchFunds(ctx context.Context, dateStr string, symbols []string) ([]RawFundData, error) {
+	var unknown []string
+	types := make(map[FundType]bool)
+	p.cacheMu.RLock()
+	for _, s := range symbols {
+		if t, ok := p.fundTypes[s]; ok {
+			types[t] = true
+		} else {
+			unknown = append(unknown, s)
+		}
+	}
+	p.cacheMu.RUnlock()
+	if len(unknown) > 0 {
+		types[FundTypeYAT] = true
+	}
+
+	queried := make([]FundType, 0, 2)
+	for _, t := range []FundType{FundTypeYAT, FundTypeEMK} {
+		if types[t] {
+			queried = append(queried, t)
+		}
+	}
+
+	rawFunds, err := p.callAPIs(ctx, dateStr, queried...)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]FundType, len(rawFunds))
+	for _, f := range rawFunds {
+		found[f.FonKodu] = f.FundType
+	}
+
+	// Unknown codes missing from the YAT response may be pension funds
+	if !types[FundTypeEMK] && anyMissing(unknown, found) {
+		emk, err := p.callAPI(ctx, dateStr, FundTypeEMK)
+		if err != nil {
+			slog.Warn("failed to look up unknown funds as EMK", "error", err)
+		}
+		rawFunds = append(rawFunds, emk...)
+		for _, f := range emk {
+			found[f.FonKodu] = f.FundType
+		}
+	}
+
+	learned := make(map[string]FundType)
+	for _, s := range unknown {
+		if t, ok := found[s]; ok {
+			learned[s] = t
+		}
+	}
+	if len(learned) > 0 {
+		p.SetFundTypes(learned)
+	}
+
+	return rawFunds, nil
+}
+
+// typeFetched reports whether the fund type of code is among fetched. Codes
+// of unknown type count as fetched, since every type was tried for them.
+func (p *Provider) typeFetched(code string, fetched map[FundType]bool) bool {
+	p.cacheMu.RLock()
+	t, known := p.fundTypes[code]
+	p.cacheMu.RUnlock()
+	return !known || fetched[t]
+}
+
+// anyMissing reports whether any of codes is absent from found
+func anyMissing(codes []string, found map[string]FundType) bool {
+	for _, c := range codes {
+		if _, ok := found[c]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// callAPIs calls the API once per fund type, concurrently as far as the
+// pool allows, and merges the results in the order of types. It only fails
+// when every call fails.
+func (p *Provider) callAPIs(ctx context.Context, dateStr string, types ...FundType) ([]RawFundData, error) {
+	results := make([][]RawFundData, len(types))
+	callErrs := make([]error, len(types))
+	var wg sync.WaitGroup
+	for i, t := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], callErrs[i] = p.callAPI(ctx, dateStr, t)
+		}()
+	}
+	wg.Wait()
+
+	var rawFunds []RawFundData
+	var errs []error
+	for i, t := range types {
+		if callErrs[i] != nil {
+			errs = append(errs, fmt.Errorf("%s funds: %w", t, callErrs[i]))
+			continue
+		}
+		rawFunds = append(rawFunds, results[i]...)
+	}
+
+	if len(errs) == len(types) && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		slog.Warn("partial TEFAS fetch", "error", errors.Join(errs...))
+	}
+	return rawFunds, nil
+}
+
+// callAPI fetches every fund of one type for a date. Concurrent callers for
+// the same date and type share one call, which runs under the provider
+// timeout rather than the deadline of whichever caller started it.
+func (p *Provider) callAPI(ctx context.Context, dateStr string, fundType FundType) ([]RawFundData, error) {
+	ch := p.dayFetches.DoChan(dateStr+"/"+string(fundType), func() (any, error) {
+		callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.timeout)
+		defer cancel()
+		return p.call(callCtx, fundType, "", dateStr, dateStr)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		funds := res.Val.([]RawFundData)
+		if res.Shared {
+			funds = slices.Clone(funds)
+		}
+		return funds, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// call makes one API call with query once the pool has a free slot
+func (p *Provider) call(ctx context.Context, fundType FundType, code, from, to string) (funds []RawFundData, err error) {
+	if poolErr := p.pool.run(ctx, func() {
+		funds, err = p.query(ctx, fundType, code, from, to)
+	}); poolErr != nil {
+		return nil, poolErr
+	}
+	return funds, err
+}
+
+// callRangeAPI fetches the records of one fund type published between the
+// from and to dates (DD.MM.YYYY), limited to one fund when code is set. The
+// in-page fetch runs without holding p.mu, so pooled calls share the page.
+func (p *Provider) callRangeAPI(ctx context.Context, fundType FundType, code, from, to string) (funds []RawFundData, err error) {
+	p.mu.Lock()
+	for p.restartDone != nil {
+		done := p.restartDone
+		p.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		p.mu.Lock()
+	}
 	if !p.started || p.page == nil {
+		p.mu.Unlock()
 		return nil, fmt.Errorf("provider not started")
 	}
 
-	// JavaScript to execute in the browser context
+	// page.Evaluate takes neither a context nor a timeout, so the deadline
+	// is passed to the in-page fetch and cancellation is handled by evaluate
+	if err := ctx.Err(); err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+	page := p.page
+	p.callSeq++
+	callID := p.callSeq
+	p.inFlight++
+	p.mu.Unlock()
+
+	defer func() {
+		p.recordCall(err)
+
+		p.mu.Lock()
+		p.inFlight--
+		// A call on a browser that has since been closed says nothing about
+		// the one that replaced it
+		if p.page == page {
+			p.trackFailures(err)
+		}
+		relaunch := p.restartIfIdleLocked()
+		p.mu.Unlock()
+		if relaunch {
+			p.relaunch()
+		}
+	}()
+
+	timeoutMs := p.timeout.Milliseconds()
+	if deadline, ok := ctx.Deadline(); ok {
+		timeoutMs = time.Until(deadline).Milliseconds()
+	}
+
+	// JavaScript to execute in the browser context. The fetch is registered
+	// under the call number so evaluate can abort it.
 	jsCode := fmt.Sprintf(`
 		async () => {
-			const params = new URLSearchParams({
-				fontip: 'YAT',
-				sfontur: '',
-				fonkod: '',
-				fongrup: '',
-				bastarih: '%s',
-				bittarih: '%s',
-				fonturkod: '',
-				fonunvantip: '',
-				kurucukod: ''
-			});
+			const controller = new AbortController();
+			const aborts = (window.__prismAborts ??= {});
+			aborts[%[6]d] = controller;
+			try {
+				const params = new URLSearchParams({
+					fontip: '%[1]s',
+					sfontur: '',
+					fonkod: %[2]s,
+					fongrup: '',
+					bastarih: '%[3]s',
+					bittarih: '%[4]s',
+					fonturkod: '',
+					fonunvantip: '',
+					kurucukod: ''
+				});
 
-			const response = await fetch('/api/DB/BindHistoryInfo', {
-				method: 'POST',
-				headers: {
-					'Content-Type': 'application/x-www-form-urlencoded',
-					'X-Requested-With': 'XMLHttpRequest'
-				},
-				body: params.toString()
-			});
+				const response = await fetch('/api/DB/BindHistoryInfo', {
+					method: 'POST',
+					headers: {
+						'Content-Type': 'application/x-www-form-urlencoded',
+						'X-Requested-With': 'XMLHttpRequest'
+					},
+					body: params.toString(),
+					signal: AbortSignal.any([controller.signal, AbortSignal.timeout(%[5]d)])
+				});
 
-			const text = await response.text();
-			
-			if (text.includes('Erişim Engellendi') || text.includes('Web Application Firewall')) {
-				throw new Error('WAF_BLOCKED');
+				// Parsed in Go so firewall pages can be told apart from bad JSON
+				return {
+					status: response.status,
+					contentType: response.headers.get('content-type') || '',
+					body: await response.text()
+				};
+			} finally {
+				delete aborts[%[6]d];
 			}
-
-			return JSON.parse(text);
 		}
-	`, dateStr, dateStr)
+	`, fundType, jsString(code), from, to, timeoutMs, callID)
 
-	result, err := p.page.Evaluate(jsCode)
+	result, err := p.evaluate(ctx, page, jsCode, callID)
 	if err != nil {
 		return nil, fmt.Errorf("API call failed: %w", err)
 	}
 
-	// Parse the result
-	jsonBytes, err := json.Marshal(result)
+	raw, ok := result.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected API call result %T", result)
+	}
+	status, _ := raw["status"].(int)
+	contentType, _ := raw["contentType"].(string)
+	body, _ := raw["body"].(string)
+
+	response, err := parseAPIResponse(status, contentType, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %w", err)
+		return nil, err
 	}
 
-	var response APIResponse
-	if err := json.Unmarshal(jsonBytes, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	for i := range response.Data {
+		response.Data[i].FundType = fundType
 	}
+	p.learnNames(ctx, response.Data)
 
-	slog.Info("fetched TEFAS data", "total_funds", response.RecordsTotal, "returned", len(response.Data))
+	slog.Info("fetched TEFAS data", "type", fundType, "total_funds", response.RecordsTotal, "returned", len(response.Data))
 	return response.Data, nil
 }
 
-// IsHealthy checks if the provider is operational
-func (p *Provider) IsHealthy(ctx context.Context) bool {
+// abortScript aborts the in-page fetch registered under a call number
+const abortScript = `id => window.__prismAborts?.[id]?.abort()`
+
+// evaluate runs jsCode on page, returning early with ctx's error once ctx
+// is done. The abandoned call's in-page fetch, registered under callID, is
+// then aborted; if the page doesn't finish the call within abortGrace it is
+// hung, and the browser is closed so the call ends rather than leaking.
+// The next fetch starts a new browser. p.mu must not be held.
+func (p *Provider) evaluate(ctx context.Context, page playwright.Page, jsCode string, callID int64) (any, error) {
+	type evalResult struct {
+		value any
+		err   error
+	}
+	done := make(chan evalResult, 1)
+	go func() {
+		value, err := page.Evaluate(jsCode)
+		done <- evalResult{value, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+	}
+
+	// A hung page won't run this either; closing it ends both calls
+	go page.Evaluate(abortScript, callID)
+	select {
+	case <-done:
+	case <-time.After(p.abortGrace):
+		p.mu.Lock()
+		if p.page == page {
+			slog.Warn("TEFAS page didn't stop after its call was cancelled; closing the browser", "grace", p.abortGrace)
+			p.closeLocked()
+		}
+		p.mu.Unlock()
+	}
+	return nil, ctx.Err()
+}
+
+// jsString quotes s as a JavaScript string literal, so user-supplied fund
+// codes can't break out of the in-page script
+func jsString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// parseAPIResponse decodes a BindHistoryInfo response. It returns
+// ErrWAFBlocked for firewall answers, which can come with status 200 and
+// HTML, or with a JSON-looking body, rather than the API's JSON.
+func parseAPIResponse(status int, contentType, body string) (APIResponse, error) {
+	lower := strings.ToLower(body)
+	for _, marker := range wafMarkers {
+		if strings.Contains(lower, marker) {
+			return APIResponse{}, fmt.Errorf("%w (page mentions %q)", ErrWAFBlocked, marker)
+		}
+	}
+
+	switch {
+	case status == 403 || status == 429:
+		return APIResponse{}, fmt.Errorf("%w (status %d)", ErrWAFBlocked, status)
+	case status != 200:
+		return APIResponse{}, fmt.Errorf("API returned status %d", status)
+	case strings.Contains(contentType, "html") || strings.HasPrefix(strings.TrimSpace(body), "<"):
+		// The API only serves JSON; HTML is a firewall or maintenance page
+		return APIResponse{}, fmt.Errorf("%w (got %s instead of JSON)", ErrWAFBlocked, contentTypeOrHTML(contentType))
+	}
+
+	var response APIResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return APIResponse{}, fmt.Errorf("parsing API response (%s, body %q): %w", contentType, snippet(body), err)
+	}
+	return response, nil
+}
+
+// contentTypeOrHTML names the content type of an HTML response for errors
+func contentTypeOrHTML(contentType string) string {
+	if contentType == "" {
+		return "HTML"
+	}
+	return contentType
+}
+
+// snippet returns the start of body for error messages
+func snippet(body string) string {
+	const max = 80
+	if len(body) <= max {
+		return body
+	}
+	return body[:max] + "..."
+}
+
+// SearchFunds returns funds whose code starts with, or whose name contains,
+// query (case-insensitive, Turkish casing). Code matches are listed first.
+func (p *Provider) SearchFunds(ctx context.Context, query string) ([]providers.FundInfo, error) {
+	funds, err := p.fundUniverse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := normalizeSearch(query)
+	var codeMatches, nameMatches []providers.FundInfo
+	for _, f := range funds {
+		switch {
+		case strings.HasPrefix(normalizeSearch(f.Code), q):
+			codeMatches = append(codeMatches, f)
+		case strings.Contains(normalizeSearch(f.Name), q):
+			nameMatches = append(nameMatches, f)
+		}
+	}
+
+	results := append(codeMatches, nameMatches...)
+	if len(results) > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
+	return results, nil
+}
+
+// fundUniverse returns the cached fund list, refreshing it once it expires
+func (p *Provider) fundUniverse(ctx context.Context) ([]providers.FundInfo, error) {
+	p.cacheMu.RLock()
+	if time.Now().Before(p.fundListExp) {
+		funds := p.fundList
+		p.cacheMu.RUnlock()
+		return funds, nil
+	}
+	p.cacheMu.RUnlock()
+
+	if err := p.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start provider: %w", err)
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	rawFunds, _, err := p.fetchLatest(fetchCtx, func(dateStr string) ([]RawFundData, error) {
+		return p.callAPIs(fetchCtx, dateStr, FundTypeYAT, FundTypeEMK)
+	})
+	if err != nil {
+		// An expired list is still far better than nothing
+		p.cacheMu.RLock()
+		funds := p.fundList
+		p.cacheMu.RUnlock()
+		if len(funds) > 0 {
+			slog.Warn("returning stale fund list due to API error", "error", err)
+			return funds, nil
+		}
+		return nil, fmt.Errorf("failed to fetch TEFAS fund list: %w", err)
+	}
+
+	return p.updateFundList(rawFunds), nil
+}
+
+// updateFundList replaces the cached fund universe with rawFunds, sorted by code
+func (p *Provider) updateFundList(rawFunds []RawFundData) []providers.FundInfo {
+	funds := make([]providers.FundInfo, 0, len(rawFunds))
+	for _, f := range rawFunds {
+		funds = append(funds, providers.FundInfo{Code: f.FonKodu, Name: f.FonUnvan})
+	}
+	sort.Slice(funds, func(i, j int) bool { return funds[i].Code < funds[j].Code })
+
+	p.cacheMu.Lock()
+	p.fundList = funds
+	p.fundListExp = time.Now().Add(fundListTTL)
+	p.cacheMu.Unlock()
+
+	return funds
+}
+
+// FetchFundDetails returns price and metadata (portfolio size, investor
+// count, shares outstanding) for a single fund. A code TEFAS didn't list is
+// reported as not found without asking again until the cache TTL passes.
+func (p *Provider) FetchFundDetails(ctx context.Context, code string) (*providers.FundDetails, error) {
+	p.cacheMu.RLock()
+	d, ok := p.details[code]
+	fresh := time.Since(d.Price.LastUpdated) < p.cacheTTL && d.Price.LastUpdated.After(p.detailsFlushed)
+	missedAt, missed := p.detailMisses[code]
+	knownMissing := missed && time.Since(missedAt) < p.cacheTTL && missedAt.After(p.detailsFlushed)
+	staleAfter := p.staleThreshold()
+	p.cacheMu.RUnlock()
+	if ok && providers.Outdated(d.Price, staleAfter, time.Now()) {
+		d.Price.Stale = true
+	}
+	if (ok && fresh) || (!ok && knownMissing) {
+		metrics.ObserveCache(p.Name(), true)
+		if !ok {
+			return nil, fmt.Errorf("fund %s: %w", code, providers.ErrSymbolNotFound)
+		}
+		return &d, nil
+	}
+	metrics.ObserveCache(p.Name(), false)
+
+	if err := p.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start provider: %w", err)
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	rawFunds, priceDate, err := p.fetchLatest(fetchCtx, func(dateStr string) ([]RawFundData, error) {
+		return p.fetchFunds(fetchCtx, dateStr, []string{code})
+	})
+	metrics.ObserveFetch(p.Name(), start, err)
+	if err != nil {
+		if ok {
+			slog.Warn("returning cached fund details due to API error", "code", code, "error", err)
+			return &d, nil
+		}
+		return nil, fmt.Errorf("failed to fetch TEFAS data: %w", err)
+	}
+
+	p.updateDetails(rawFunds, priceDate)
+
+	p.cacheMu.Lock()
+	d, ok = p.details[code]
+	if !ok {
+		p.recordDetailMissLocked(code, time.Now())
+	}
+	p.cacheMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fund %s: %w", code, providers.ErrSymbolNotFound)
+	}
+	return &d, nil
+}
+
+// recordDetailMissLocked remembers that code was missing from a details
+// fetch at now, dropping misses older than the cache TTL on the way so
+// made-up codes can't grow the map without bound; p.cacheMu must be held
+func (p *Provider) recordDetailMissLocked(code string, now time.Time) {
+	for c, at := range p.detailMisses {
+		if now.Sub(at) >= p.cacheTTL {
+			delete(p.detailMisses, c)
+		}
+	}
+	p.detailMisses[code] = now
+}
+
+// updateDetails merges rawFunds, published for priceDate, into the cached
+// fund metadata
+func (p *Provider) updateDetails(rawFunds []RawFundData, priceDate time.Time) {
+	now := time.Now()
+
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	for _, f := range rawFunds {
+		delete(p.detailMisses, f.FonKodu)
+		p.details[f.FonKodu] = providers.FundDetails{
+			Price: providers.Price{
+				Symbol:      f.FonKodu,
+				Name:        f.FonUnvan,
+				Price:       f.Fiyat,
+				LastUpdated: now,
+				Stale:       p.isStaleDate(priceDate, now),
+				PriceDate:   priceDate.Format(time.DateOnly),
+				Category:    strings.TrimSpace(f.FonTurAciklama),
+			},
+			PortfolioSize:     f.PortfoyBuyukluk,
+			Investors:         f.KisiSayisi,
+			SharesOutstanding: f.TedPaySayisi,
+		}
+	}
+}
+
+// normalizeSearch upper-cases s using Turkish rules so "i" matches "İ"
+func normalizeSearch(s string) string {
+	return strings.ToUpperSpecial(unicode.TurkishCase, strings.TrimSpace(s))
+}
+
+// trackFailures counts consecutive failed API calls and makes a browser
+// restart due once the threshold is reached, backing off between restarts.
+// The caller then runs restartIfIdleLocked. p.mu must be held.
+func (p *Provider) trackFailures(err error) {
+	if err == nil {
+		p.consecutiveFailures = 0
+		p.restartBackoff = minRestartBackoff
+		return
+	}
+	// A caller giving up says nothing about the browser's state
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	// A firewall block is tied to the browser session, so a fresh one is
+	// worth trying without waiting for the threshold
+	blocked := errors.Is(err, ErrWAFBlocked)
+
+	p.consecutiveFailures++
+	if p.restartAfter < 0 || (p.consecutiveFailures < p.restartAfter && !blocked) || time.Now().Before(p.nextRestart) || p.restartDone != nil {
+		return
+	}
+
+	slog.Warn("restarting TEFAS browser after repeated failures",
+		"consecutive_failures", p.consecutiveFailures, "blocked", blocked, "in_flight", p.inFlight, "next_backoff", p.restartBackoff)
+
+	p.restartDone = make(chan struct{})
+	p.consecutiveFailures = 0
+	p.nextRestart = time.Now().Add(p.restartBackoff)
+	p.restartBackoff = min(p.restartBackoff*2, maxRestartBackoff)
+}
+
+// restartIfIdleLocked closes the browser if a restart is due and no call is
+// in flight. It reports whether the caller must then relaunch, once it has
+// released p.mu. p.mu must be held.
+func (p *Provider) restartIfIdleLocked() bool {
+	if p.restartDone == nil || p.inFlight > 0 || p.relaunching {
+		return false
+	}
+	p.closeLocked()
+	p.relaunching = true
+	return true
+}
+
+// relaunch starts the browser replacing the one a restart closed, without
+// holding p.mu while it launches, then lets the calls waiting on the
+// restart go. p.mu must not be held.
+func (p *Provider) relaunch() {
+	p.mu.Lock()
+	userAgent, err, closes := p.nextUserAgent(), p.notInstalled, p.closes
+	p.mu.Unlock()
+
+	var session browserSession
+	if err == nil {
+		session, err = p.launch(userAgent)
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	return p.started && p.browser != nil && p.page != nil
+	switch {
+	case err != nil:
+		slog.Error("failed to restart TEFAS browser", "error", err)
+		if errors.Is(err, ErrBrowserNotInstalled) {
+			p.notInstalled = err
+		}
+	case p.closes != closes:
+		// Shut down while launching
+		session.close()
+	default:
+		p.useSessionLocked(session)
+	}
+	close(p.restartDone)
+	p.restartDone = nil
+	p.relaunching = false
+}
+
+// recordCall tracks the outcome of an API call for IsHealthy
+func (p *Provider) recordCall(err error) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	if err != nil {
+		p.lastFailure = time.Now()
+	} else {
+		p.lastSuccess = time.Now()
+	}
+}
+
+// Status reports the last successful fetch and the state of the price cache
+func (p *Provider) Status() providers.ProviderStatus {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+	age, cached := providers.CacheAge(p.cache, time.Now())
+	return providers.ProviderStatus{
+		LastSuccess:    p.lastSuccess,
+		CacheAge:       age,
+		StaleAvailable: cached,
+	}
+}
+
+// IsHealthy reports whether TEFAS data can actually be retrieved: the
+// browser must be up and the most recent API call must have succeeded within
+// twice the cache TTL. It never calls TEFAS itself, so it is cheap to poll.
+func (p *Provider) IsHealthy(ctx context.Context) bool {
+	if !p.ready.Load() {
+		return false
+	}
+
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+
+	if p.lastSuccess.IsZero() {
+		// Started but not queried yet; a failure means we never got through
+		return p.lastFailure.IsZero()
+	}
+	if p.lastFailure.After(p.lastSuccess) {
+		return false
+	}
+	return time.Since(p.lastSuccess) <= 2*p.cacheTTL
 }
 
 // Close releases all resources
 func (p *Provider) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.closes++
+	return p.closeLocked()
+}
 
+// closeLocked releases all resources; p.mu must be held
+func (p *Provider) closeLocked() error {
 	slog.Info("closing TEFAS provider")
 
 	if p.page != nil {
@@ -408,21 +1641,23 @@ func (p *Provider) Close() error {
 	}
 
 	p.started = false
+	p.ready.Store(false)
 	return nil
 }
 
-// getLastBusinessDay returns the last business day (skips weekends)
-func getLastBusinessDay() time.Time {
-	now := time.Now()
-	dayOfWeek := now.Weekday()
-
-	switch dayOfWeek {
-	case time.Saturday:
-		return now.AddDate(0, 0, -1) // Friday
-	case time.Sunday:
-		return now.AddDate(0, 0, -2) // Friday
-	default:
-		return now
+// fetchLatest calls fetch for the last business day and, while it returns
+// no records because that day's prices aren't published yet, for up to
+// lookbackDays earlier business days. It returns the records and the day
+// they were published for.
+func (p *Provider) fetchLatest(ctx context.Context, fetch func(dateStr string) ([]RawFundData, error)) ([]RawFundData, time.Time, error) {
+	day := p.lastBusinessDay(time.Now())
+	for i := 0; ; i++ {
+		rawFunds, err := fetch(formatDate(day))
+		if err != nil || len(rawFunds) > 0 || i >= p.lookbackDays || ctx.Err() != nil {
+			return rawFunds, day, err
+		}
+		slog.Info("no TEFAS data for date, trying the previous business day", "date", day.Format(time.DateOnly))
+		day = p.previousBusinessDay(day)
 	}
 }
 
@@ -431,20 +1666,50 @@ func formatDate(t time.Time) string {
 	return fmt.Sprintf("%02d.%02d.%d", t.Day(), t.Month(), t.Year())
 }
 
-// getFundName returns a human-readable name for a fund code
-func getFundName(code string) string {
-	names := map[string]string{
-		"KUT": "Kuveyt Türk Portföy Kısa Vadeli Kira Sertifikaları Katılım Fonu",
-		"TI2": "TEB Portföy İkinci Değişken Fon",
-		"AFT": "Ak Portföy Amerikan Doları Fon Sepeti Fonu",
-		"YZG": "Yapı Kredi Portföy Gümüş Fonu",
-		"KTV": "Kuveyt Türk Portföy Altın Katılım Fonu",
-		"HKH": "Halk Portföy Kısa Vadeli Borçlanma Araçları Fonu",
-		"IOG": "İş Portföy Orta Vadeli Borçlanma Araçları Fonu",
-	}
+// knownFundNames names common funds before TEFAS has been reached
+var knownFundNames = map[string]string{
+	"KUT": "Kuveyt Türk Portföy Kısa Vadeli Kira Sertifikaları Katılım Fonu",
+	"TI2": "TEB Portföy İkinci Değişken Fon",
+	"AFT": "Ak Portföy Amerikan Doları Fon Sepeti Fonu",
+	"YZG": "Yapı Kredi Portföy Gümüş Fonu",
+	"KTV": "Kuveyt Türk Portföy Altın Katılım Fonu",
+	"HKH": "Halk Portföy Kısa Vadeli Borçlanma Araçları Fonu",
+	"IOG": "İş Portföy Orta Vadeli Borçlanma Araçları Fonu",
+	"KGM": "Kuveyt Türk Portföy Gümüş Katılım Fonu",
+}
 
-	if name, ok := names[code]; ok {
+// FundName returns the display name for a fund code: the name last seen in a
+// TEFAS response, then a built-in name, then "<code> Fund"
+func (p *Provider) FundName(code string) string {
+	p.cacheMu.RLock()
+	name, ok := p.names[code]
+	p.cacheMu.RUnlock()
+	if ok {
+		return name
+	}
+	if name, ok := knownFundNames[code]; ok {
 		return name
 	}
 	return fmt.Sprintf("%s Fund", code)
 }
+
+// learnNames records fund names from an API response and persists the ones
+// that are new or changed. Failures are logged only.
+func (p *Provider) learnNames(ctx context.Context, rawFunds []RawFundData) {
+	changed := make(map[string]string)
+	p.cacheMu.Lock()
+	for _, f := range rawFunds {
+		if f.FonUnvan != "" && p.names[f.FonKodu] != f.FonUnvan {
+			p.names[f.FonKodu] = f.FonUnvan
+			changed[f.FonKodu] = f.FonUnvan
+		}
+	}
+	p.cacheMu.Unlock()
+
+	if p.nameStore == nil || len(changed) == 0 {
+		return
+	}
+	if err := p.nameStore.SaveFundNames(ctx, changed); err != nil {
+		slog.Warn("failed to persist fund names", "count", len(changed), "error", err)
+	}
+}
@@ -0,0 +1,868 @@
+package tefas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+	"github.com/playwright-community/playwright-go"
+)
+
+// memNameStore is an in-memory providers.FundNameStore
+type memNameStore struct {
+	names map[string]string
+	saved []map[string]string
+}
+
+func (m *memNameStore) SaveFundNames(ctx context.Context, names map[string]string) error {
+	m.saved = append(m.saved, names)
+	return nil
+}
+
+func (m *memNameStore) LoadFundNames(ctx context.Context) (map[string]string, error) {
+	return m.names, nil
+}
+
+func TestFundName(t *testing.T) {
+	store := &memNameStore{names: map[string]string{"KUT": "Stored KUT Name", "ABC": "Stored ABC Name"}}
+	p := NewProvider(Config{NameStore: store})
+	if err := p.LoadCache(context.Background()); err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"KUT", "Stored KUT Name"},                        // stored name beats the built-in one
+		{"ABC", "Stored ABC Name"},                        // stored only
+		{"KTV", "Kuveyt Türk Portföy Altın Katılım Fonu"}, // built-in only
+		{"XYZ", "XYZ Fund"},                               // unknown
+	}
+	for _, tt := range tests {
+		if got := p.FundName(tt.code); got != tt.want {
+			t.Errorf("FundName(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestLearnNamesPersistsOnlyChanges(t *testing.T) {
+	store := &memNameStore{names: map[string]string{"KUT": "Old Name"}}
+	p := NewProvider(Config{NameStore: store})
+	if err := p.LoadCache(context.Background()); err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+
+	p.learnNames(context.Background(), []RawFundData{
+		{FonKodu: "KUT", FonUnvan: "New Name"},
+		{FonKodu: "ABC", FonUnvan: "Abc Fonu"},
+	})
+	p.learnNames(context.Background(), []RawFundData{{FonKodu: "ABC", FonUnvan: "Abc Fonu"}})
+
+	if len(store.saved) != 1 || len(store.saved[0]) != 2 {
+		t.Fatalf("saved = %v, want one save with both names", store.saved)
+	}
+	if got := p.FundName("KUT"); got != "New Name" {
+		t.Errorf("FundName(KUT) = %q, want %q", got, "New Name")
+	}
+}
+
+func TestParseAPIResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantFunds   int
+		wantBlocked bool
+		wantErr     bool
+	}{
+		{"fund data", 200, "application/json; charset=utf-8", `{"recordsTotal":1,"data":[{"FONKODU":"KUT","FIYAT":1.5}]}`, 1, false, false},
+		{"no funds", 200, "application/json", `{"recordsTotal":0,"data":[]}`, 0, false, false},
+		{"turkish block page", 200, "text/html", `<html><h1>Erişim Engellendi</h1></html>`, 0, true, true},
+		{"json-looking block", 200, "application/json", `{"message":"Request Rejected. Your support ID is: 1234"}`, 0, true, true},
+		{"captcha page", 200, "text/html; charset=utf-8", `<html><div class="g-recaptcha"></div></html>`, 0, true, true},
+		{"unknown html page", 200, "text/html", `<html><body>Bakım çalışması</body></html>`, 0, true, true},
+		{"html without content type", 200, "", `  <!DOCTYPE html><html></html>`, 0, true, true},
+		{"forbidden", 403, "text/plain", `forbidden`, 0, true, true},
+		{"server error", 500, "application/json", `{}`, 0, false, true},
+		{"truncated json", 200, "application/json", `{"data":[{"FONKODU":`, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := parseAPIResponse(tt.status, tt.contentType, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if blocked := errors.Is(err, ErrWAFBlocked); blocked != tt.wantBlocked {
+				t.Errorf("errors.Is(%v, ErrWAFBlocked) = %v, want %v", err, blocked, tt.wantBlocked)
+			}
+			if tt.wantBlocked && !errors.Is(err, providers.ErrUpstreamBlocked) {
+				t.Errorf("error %v doesn't wrap providers.ErrUpstreamBlocked", err)
+			}
+			if len(resp.Data) != tt.wantFunds {
+				t.Errorf("got %d funds, want %d", len(resp.Data), tt.wantFunds)
+			}
+		})
+	}
+}
+
+func TestBrowserLaunchSettings(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantArgs  []string
+		wantProxy *string // Proxy server, nil for none
+	}{
+		{
+			name:     "defaults",
+			wantArgs: []string{"--no-sandbox", "--disable-dev-shm-usage", "--disable-blink-features=AutomationControlled", "--disable-infobars", "--window-size=1920,1080"},
+		},
+		{
+			name:     "headless presents the user agent",
+			cfg:      Config{Headless: true},
+			wantArgs: []string{"--no-sandbox", "--disable-dev-shm-usage", "--disable-blink-features=AutomationControlled", "--disable-infobars", "--window-size=1920,1080", "--disable-gpu", "--user-agent=UA"},
+		},
+		{
+			name:      "configured args and proxy",
+			cfg:       Config{Headless: true, LaunchArgs: []string{"--no-sandbox"}, Proxy: Proxy{Server: "socks5://proxy:1080", Username: "u", Password: "p"}},
+			wantArgs:  []string{"--no-sandbox"},
+			wantProxy: playwright.String("socks5://proxy:1080"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProvider(tt.cfg)
+			if got := p.browserArgs("UA"); !slices.Equal(got, tt.wantArgs) {
+				t.Errorf("browserArgs() = %v, want %v", got, tt.wantArgs)
+			}
+
+			proxy := p.browserProxy()
+			switch {
+			case tt.wantProxy == nil && proxy != nil:
+				t.Errorf("browserProxy() = %+v, want nil", proxy)
+			case tt.wantProxy != nil && (proxy == nil || proxy.Server != *tt.wantProxy):
+				t.Errorf("browserProxy() = %+v, want server %s", proxy, *tt.wantProxy)
+			case proxy != nil && (*proxy.Username != tt.cfg.Proxy.Username || *proxy.Password != tt.cfg.Proxy.Password):
+				t.Errorf("proxy credentials = %s/%s, want %s/%s", *proxy.Username, *proxy.Password, tt.cfg.Proxy.Username, tt.cfg.Proxy.Password)
+			}
+		})
+	}
+}
+
+func TestIsBrowserMissing(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("BrowserType.launch: Executable doesn't exist at /root/.cache/ms-playwright/chromium-1091/chrome-linux/chrome"), true},
+		{errors.New("please install the driver (v1.49.1) first: playwright install"), true},
+		{errors.New("BrowserType.launch: Target page, context or browser has been closed"), false},
+		{errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isBrowserMissing(tt.err); got != tt.want {
+			t.Errorf("isBrowserMissing(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFetchPricesBrowserNotInstalled(t *testing.T) {
+	p := NewProvider(Config{})
+	p.notInstalled = ErrBrowserNotInstalled
+
+	if _, err := p.FetchPrices(context.Background(), []string{"AAK"}); !errors.Is(err, ErrBrowserNotInstalled) {
+		t.Fatalf("FetchPrices() error = %v, want ErrBrowserNotInstalled", err)
+	}
+
+	p.cache["AAK"] = providers.Price{Symbol: "AAK", Price: 1.5}
+	prices, err := p.FetchPrices(context.Background(), []string{"AAK"})
+	if err != nil {
+		t.Fatalf("FetchPrices() error = %v, want stale cache", err)
+	}
+	if len(prices) != 1 || prices[0].Price != 1.5 || !prices[0].Stale {
+		t.Errorf("FetchPrices() = %+v, want stale AAK at 1.5", prices)
+	}
+}
+
+func TestNextUserAgent(t *testing.T) {
+	p := NewProvider(Config{})
+	if got := p.nextUserAgent(); got != defaultUserAgent {
+		t.Errorf("nextUserAgent() = %q, want the default", got)
+	}
+
+	p = NewProvider(Config{UserAgents: []string{"a", "b", "c"}})
+	var got []string
+	for range 4 {
+		got = append(got, p.nextUserAgent())
+	}
+	if want := []string{"a", "b", "c", "a"}; !slices.Equal(got, want) {
+		t.Errorf("user agents over four starts = %v, want %v", got, want)
+	}
+}
+
+func TestFreshCached(t *testing.T) {
+	p := NewProvider(Config{CacheTTL: time.Hour})
+	now := time.Now()
+	p.cache["AAK"] = providers.Price{Symbol: "AAK", Price: 1.5, LastUpdated: now.Add(-time.Minute)}
+	p.cache["KUT"] = providers.Price{Symbol: "KUT", Price: 2.5, LastUpdated: now.Add(-2 * time.Hour)}
+	p.cache["TTE"] = providers.Price{Symbol: "TTE", Price: 3.5, LastUpdated: now.Add(-time.Minute)}
+
+	cached, missing := p.freshCached([]string{"TTE", "KUT", "AAK", "YAY", "YAY"})
+	if got := inOrder([]string{"TTE", "KUT", "AAK", "YAY"}, cached); len(got) != 2 || got[0].Symbol != "TTE" || got[1].Symbol != "AAK" {
+		t.Errorf("cached = %+v, want TTE and AAK in request order", got)
+	}
+	if !slices.Equal(missing, []string{"KUT", "YAY"}) {
+		t.Errorf("missing = %v, want [KUT YAY]", missing)
+	}
+
+	// Everything cached is served without fetching
+	prices, err := p.FetchPrices(context.Background(), []string{"AAK", "TTE"})
+	if err != nil || len(prices) != 2 || prices[0].Price != 1.5 || prices[1].Price != 3.5 {
+		t.Errorf("FetchPrices() = %+v, %v; want cached AAK and TTE", prices, err)
+	}
+
+	p.FlushCache()
+	if _, missing := p.freshCached([]string{"AAK"}); !slices.Equal(missing, []string{"AAK"}) {
+		t.Errorf("missing after FlushCache = %v, want [AAK]", missing)
+	}
+}
+
+func TestPreviousBusinessDay(t *testing.T) {
+	extra, _ := time.Parse(time.DateOnly, "2027-03-10")
+	p := NewProvider(Config{Holidays: []time.Time{extra}})
+	tests := []struct {
+		day  string
+		want string
+	}{
+		{"2024-03-13", "2024-03-12"}, // Wednesday
+		{"2024-03-11", "2024-03-08"}, // Monday skips the weekend
+		{"2024-03-10", "2024-03-08"}, // Sunday
+		{"2025-10-30", "2025-10-28"}, // Skips Republic Day
+		{"2026-03-23", "2026-03-19"}, // Skips the Ramadan Feast and the weekend
+		{"2027-03-11", "2027-03-09"}, // Skips a configured holiday
+	}
+
+	for _, tt := range tests {
+		day, _ := time.Parse(time.DateOnly, tt.day)
+		if got := p.previousBusinessDay(day).Format(time.DateOnly); got != tt.want {
+			t.Errorf("previousBusinessDay(%s) = %s, want %s", tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestIsStaleDate(t *testing.T) {
+	p := NewProvider(Config{})
+	tests := []struct {
+		name      string
+		priceDate string
+		now       string
+		want      bool
+	}{
+		{name: "today's prices", priceDate: "2024-03-13", now: "2024-03-13", want: false},
+		{name: "before today's prices are out", priceDate: "2024-03-12", now: "2024-03-13", want: true},
+		{name: "weekend", priceDate: "2024-03-08", now: "2024-03-09", want: true},
+		{name: "repeated on a holiday", priceDate: "2024-10-29", now: "2024-10-29", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priceDate, _ := time.Parse(time.DateOnly, tt.priceDate)
+			now, _ := time.Parse(time.DateOnly, tt.now)
+			if got := p.isStaleDate(priceDate, now); got != tt.want {
+				t.Errorf("isStaleDate(%s, %s) = %v, want %v", tt.priceDate, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBusinessDayAroundMidnight(t *testing.T) {
+	istanbul := NewProvider(Config{})
+	utc := NewProvider(Config{Location: time.UTC})
+	tests := []struct {
+		name      string
+		p         *Provider
+		now       string // UTC
+		wantDay   string
+		wantStale bool // For prices published on wantDay
+	}{
+		{name: "Sunday 23:59 in Istanbul", p: istanbul, now: "2024-03-17T20:59:00Z", wantDay: "2024-03-15", wantStale: true},
+		{name: "Monday 00:00 in Istanbul", p: istanbul, now: "2024-03-17T21:00:00Z", wantDay: "2024-03-18"},
+		{name: "Wednesday 00:30 in Istanbul", p: istanbul, now: "2024-03-12T21:30:00Z", wantDay: "2024-03-13"},
+		{name: "Wednesday 23:30 in Istanbul", p: istanbul, now: "2024-03-13T20:30:00Z", wantDay: "2024-03-13"},
+		{name: "still Sunday in UTC", p: utc, now: "2024-03-17T21:00:00Z", wantDay: "2024-03-15", wantStale: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tt.now)
+			day := tt.p.lastBusinessDay(now)
+			if got := day.Format(time.DateOnly); got != tt.wantDay {
+				t.Errorf("lastBusinessDay(%s) = %s, want %s", tt.now, got, tt.wantDay)
+			}
+			if got := tt.p.isStaleDate(day, now); got != tt.wantStale {
+				t.Errorf("isStaleDate(%s, %s) = %v, want %v", tt.wantDay, tt.now, got, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestFetchLatestStepsBack(t *testing.T) {
+	record := []RawFundData{{FonKodu: "KUT", Fiyat: 1.5}}
+	tests := []struct {
+		name         string
+		lookbackDays int
+		publishedOn  int // Calls answered with no data before one returns records
+		wantCalls    int
+		wantRecords  bool
+	}{
+		{name: "last business day has data", publishedOn: 0, wantCalls: 1, wantRecords: true},
+		{name: "steps back until data", publishedOn: 2, wantCalls: 3, wantRecords: true},
+		{name: "gives up after the limit", lookbackDays: 2, publishedOn: 5, wantCalls: 3},
+		{name: "disabled", lookbackDays: -1, publishedOn: 1, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProvider(Config{LookbackDays: tt.lookbackDays})
+			var dates []string
+			rawFunds, day, err := p.fetchLatest(context.Background(), func(dateStr string) ([]RawFundData, error) {
+				dates = append(dates, dateStr)
+				if len(dates) > tt.publishedOn {
+					return record, nil
+				}
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatalf("fetchLatest() error = %v", err)
+			}
+
+			if len(dates) != tt.wantCalls {
+				t.Fatalf("fetch called for %v, want %d calls", dates, tt.wantCalls)
+			}
+			if (len(rawFunds) > 0) != tt.wantRecords {
+				t.Errorf("records = %v, want records %v", rawFunds, tt.wantRecords)
+			}
+			if got := formatDate(day); got != dates[len(dates)-1] {
+				t.Errorf("reported date = %s, want the last date tried %s", got, dates[len(dates)-1])
+			}
+			for i := 1; i < len(dates); i++ {
+				prev, _ := time.Parse("02.01.2006", dates[i-1])
+				if want := formatDate(p.previousBusinessDay(prev)); dates[i] != want {
+					t.Errorf("call %d for %s, want %s", i, dates[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeepAlivePingDue(t *testing.T) {
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		lastSuccess time.Time
+		want        bool
+	}{
+		{name: "never fetched", want: true},
+		{name: "idle for the interval", lastSuccess: now.Add(-5 * time.Minute), want: true},
+		{name: "fetched recently", lastSuccess: now.Add(-time.Minute), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProvider(Config{})
+			p.lastSuccess = tt.lastSuccess
+			if got := p.pingDue(now, 5*time.Minute); got != tt.want {
+				t.Errorf("pingDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTarih(t *testing.T) {
+	// Midnight in Turkey is still the previous day in UTC
+	got, err := parseTarih("1710277200000", orDefaultLocation(nil))
+	if err != nil {
+		t.Fatalf("parseTarih() error = %v", err)
+	}
+	if date := got.Format(time.DateOnly); date != "2024-03-13" {
+		t.Errorf("parseTarih() date = %s, want 2024-03-13", date)
+	}
+
+	if _, err := parseTarih("13.03.2024", time.UTC); err == nil {
+		t.Error("parseTarih() accepted a non-numeric date")
+	}
+}
+
+func TestFetchPricesSharesConcurrentFetches(t *testing.T) {
+	p := NewProvider(Config{FundTypes: map[string]FundType{"KUT": FundTypeYAT, "TI2": FundTypeYAT}})
+	p.started = true
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	p.query = func(ctx context.Context, fundType FundType, code, from, to string) ([]RawFundData, error) {
+		calls.Add(1)
+		<-release
+		return []RawFundData{{FonKodu: "KUT", Fiyat: 1.5}, {FonKodu: "TI2", Fiyat: 2.5}}, nil
+	}
+
+	const callers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := range callers {
+		symbols := []string{"KUT"}
+		if i%2 == 1 {
+			symbols = []string{"TI2", "KUT"}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prices, err := p.FetchPrices(context.Background(), symbols)
+			if err == nil && len(prices) != len(symbols) {
+				err = fmt.Errorf("got %d prices for %v", len(prices), symbols)
+			}
+			errs <- err
+		}()
+	}
+
+	// Give every caller time to join the in-flight call
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("FetchPrices() error = %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("API called %d times for %d concurrent fetches, want 1", got, callers)
+	}
+}
+
+func TestFetchPricesByFundType(t *testing.T) {
+	p := NewProvider(Config{FundTypes: map[string]FundType{"KUT": FundTypeYAT, "AEM": FundTypeEMK, "AVE": FundTypeEMK}})
+	p.started = true
+	last := providers.Price{Symbol: "AEM", Price: 0.5, LastUpdated: time.Now().Add(-time.Hour)}
+	p.cache["AEM"] = last
+
+	var queried []FundType
+	var mu sync.Mutex
+	p.query = func(ctx context.Context, fundType FundType, code, from, to string) ([]RawFundData, error) {
+		mu.Lock()
+		queried = append(queried, fundType)
+		mu.Unlock()
+		if fundType == FundTypeEMK {
+			return nil, errors.New("EMK unavailable")
+		}
+		return []RawFundData{{FonKodu: "KUT", Fiyat: 1.5}}, nil
+	}
+
+	prices, err := p.FetchPrices(context.Background(), []string{"AEM", "KUT", "AVE"})
+	if err != nil {
+		t.Fatalf("FetchPrices() error = %v", err)
+	}
+	slices.Sort(queried)
+	if !slices.Equal(queried, []FundType{FundTypeEMK, FundTypeYAT}) {
+		t.Errorf("queried types %v, want one call each for EMK and YAT", queried)
+	}
+
+	got := make(map[string]float64, len(prices))
+	for _, price := range prices {
+		got[price.Symbol] = price.Price
+	}
+	// AEM keeps its last price and AVE, never fetched, is missing
+	if want := map[string]float64{"AEM": 0.5, "KUT": 1.5, "AVE": 0}; !maps.Equal(got, want) {
+		t.Errorf("prices = %v, want %v", got, want)
+	}
+	if cached := p.cache["AEM"]; !cached.LastUpdated.Equal(last.LastUpdated) {
+		t.Errorf("cached AEM updated at %v, want untouched so it is fetched again", cached.LastUpdated)
+	}
+}
+
+// blockingPage is a playwright.Page whose Evaluate blocks until the call is
+// aborted, if abortable, or the page is closed
+type blockingPage struct {
+	playwright.Page
+	abortable bool
+	stop      chan struct{}
+	stopOnce  sync.Once
+	closed    atomic.Bool
+}
+
+func (b *blockingPage) Evaluate(expression string, arg ...any) (any, error) {
+	if expression == abortScript {
+		if b.abortable {
+			b.stopOnce.Do(func() { close(b.stop) })
+		}
+		return nil, nil
+	}
+	<-b.stop
+	return nil, errors.New("aborted")
+}
+
+func (b *blockingPage) Close(options ...playwright.PageCloseOptions) error {
+	b.closed.Store(true)
+	b.stopOnce.Do(func() { close(b.stop) })
+	return nil
+}
+
+func TestEvaluateHonorsContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		abortable   bool
+		wantStarted bool
+	}{
+		{name: "aborted fetch", abortable: true, wantStarted: true},
+		{name: "hung page", abortable: false, wantStarted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &blockingPage{abortable: tt.abortable, stop: make(chan struct{})}
+			p := NewProvider(Config{})
+			p.page, p.started = page, true
+			p.abortGrace = 20 * time.Millisecond
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			_, err := p.evaluate(ctx, page, "async () => {}", 1)
+
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("evaluate() error = %v, want context.DeadlineExceeded", err)
+			}
+			if p.started != tt.wantStarted || page.closed.Load() == tt.wantStarted {
+				t.Errorf("started = %v, page closed = %v; want started %v", p.started, page.closed.Load(), tt.wantStarted)
+			}
+		})
+	}
+}
+
+// scriptedPage is a playwright.Page whose first API call waits for release
+// and succeeds, while later ones fail at once
+type scriptedPage struct {
+	playwright.Page
+	release chan struct{}
+	calls   atomic.Int32
+	closed  atomic.Bool
+}
+
+func (s *scriptedPage) Evaluate(expression string, arg ...any) (any, error) {
+	if s.calls.Add(1) > 1 {
+		return nil, errors.New("page crashed")
+	}
+	<-s.release
+	return map[string]any{"status": 200, "contentType": "application/json", "body": `{"data":[]}`}, nil
+}
+
+func (s *scriptedPage) Close(options ...playwright.PageCloseOptions) error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestRestartWaitsForInFlightCalls(t *testing.T) {
+	p := NewProvider(Config{RestartAfter: 1, FetchConcurrency: 3})
+	page := &scriptedPage{release: make(chan struct{})}
+	p.page, p.started = page, true
+	p.notInstalled = ErrBrowserNotInstalled // The restart closes the browser without launching one
+	ctx := context.Background()
+	dateStr := formatDate(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := p.callRangeAPI(ctx, FundTypeYAT, "", dateStr, dateStr)
+		slow <- err
+	}()
+	for page.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A failure on another pooled call makes a restart due
+	if _, err := p.callRangeAPI(ctx, FundTypeEMK, "", dateStr, dateStr); err == nil {
+		t.Fatal("failing call succeeded")
+	}
+	if page.closed.Load() {
+		t.Fatal("page closed under a call in flight")
+	}
+
+	// Calls made meanwhile wait for the new browser instead of using the old page
+	waiting := make(chan error, 1)
+	go func() {
+		_, err := p.callRangeAPI(ctx, FundTypeYAT, "KUT", dateStr, dateStr)
+		waiting <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if got := page.calls.Load(); got != 2 {
+		t.Errorf("old page called %d times, want 2", got)
+	}
+
+	close(page.release)
+	if err := <-slow; err != nil {
+		t.Errorf("call in flight during the failure: error = %v", err)
+	}
+	if err := <-waiting; err == nil {
+		t.Error("call waiting on the restart succeeded without a browser")
+	}
+	if !page.closed.Load() {
+		t.Error("page not closed once the in-flight call finished")
+	}
+	if got := page.calls.Load(); got != 2 {
+		t.Errorf("old page called %d times, want 2", got)
+	}
+}
+
+// answerPage is a playwright.Page whose API calls all get answer
+type answerPage struct {
+	playwright.Page
+	answer func() (any, error)
+	closed atomic.Bool
+}
+
+func (a *answerPage) Evaluate(expression string, arg ...any) (any, error) { return a.answer() }
+
+func (a *answerPage) Close(options ...playwright.PageCloseOptions) error {
+	a.closed.Store(true)
+	return nil
+}
+
+// Answers for answerPage
+var (
+	answerOK = func() (any, error) {
+		return map[string]any{"status": 200, "contentType": "application/json", "body": `{"data":[]}`}, nil
+	}
+	answerDown    = func() (any, error) { return nil, errors.New("page crashed") }
+	answerBlocked = func() (any, error) {
+		return map[string]any{"status": 403, "contentType": "text/html", "body": "denied"}, nil
+	}
+)
+
+func TestRestartThresholdAndBackoff(t *testing.T) {
+	p := NewProvider(Config{RestartAfter: 3})
+	first := &answerPage{answer: answerDown}
+	p.page, p.started = first, true
+
+	var launched []*answerPage
+	p.launch = func(userAgent string) (browserSession, error) {
+		page := &answerPage{answer: answerDown}
+		launched = append(launched, page)
+		return browserSession{page: page}, nil
+	}
+	call := func() {
+		dateStr := formatDate(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+		p.callRangeAPI(context.Background(), FundTypeYAT, "", dateStr, dateStr)
+	}
+	backoffOver := func() {
+		p.mu.Lock()
+		p.nextRestart = time.Now().Add(-time.Second)
+		p.mu.Unlock()
+	}
+
+	steps := []struct {
+		name         string
+		answer       func() (any, error)
+		before       func()
+		wantLaunches int
+		wantBackoff  time.Duration
+	}{
+		{name: "first failure", answer: answerDown, wantLaunches: 0, wantBackoff: minRestartBackoff},
+		{name: "second failure", answer: answerDown, wantLaunches: 0, wantBackoff: minRestartBackoff},
+		{name: "threshold reached", answer: answerDown, wantLaunches: 1, wantBackoff: 2 * minRestartBackoff},
+		{name: "failures within the backoff", answer: answerDown, wantLaunches: 1, wantBackoff: 2 * minRestartBackoff},
+		{name: "block within the backoff", answer: answerBlocked, wantLaunches: 1, wantBackoff: 2 * minRestartBackoff},
+		{name: "failure after the backoff", answer: answerDown, before: backoffOver, wantLaunches: 2, wantBackoff: 4 * minRestartBackoff},
+		{name: "success resets", answer: answerOK, wantLaunches: 2, wantBackoff: minRestartBackoff},
+		{name: "cancelled call doesn't count", answer: func() (any, error) { return nil, context.Canceled }, before: backoffOver, wantLaunches: 2, wantBackoff: minRestartBackoff},
+		{name: "block restarts at once", answer: answerBlocked, wantLaunches: 3, wantBackoff: 2 * minRestartBackoff},
+	}
+
+	for _, step := range steps {
+		if step.before != nil {
+			step.before()
+		}
+		p.mu.Lock()
+		page := p.page.(*answerPage)
+		p.mu.Unlock()
+		page.answer = step.answer
+
+		call()
+
+		if len(launched) != step.wantLaunches {
+			t.Fatalf("%s: %d restarts, want %d", step.name, len(launched), step.wantLaunches)
+		}
+		if p.restartBackoff != step.wantBackoff {
+			t.Errorf("%s: backoff = %v, want %v", step.name, p.restartBackoff, step.wantBackoff)
+		}
+		if step.wantLaunches > 0 {
+			if p.page != launched[step.wantLaunches-1] || !p.started {
+				t.Errorf("%s: provider isn't using the relaunched browser", step.name)
+			}
+		}
+	}
+	if !first.closed.Load() || !launched[0].closed.Load() || launched[2].closed.Load() {
+		t.Error("replaced browsers not closed, or the current one closed")
+	}
+}
+
+func TestRestartDisabled(t *testing.T) {
+	p := NewProvider(Config{RestartAfter: -1})
+	page := &answerPage{answer: answerBlocked}
+	p.page, p.started = page, true
+	p.launch = func(userAgent string) (browserSession, error) {
+		t.Error("browser restarted with restarts disabled")
+		return browserSession{}, errors.New("unexpected launch")
+	}
+
+	dateStr := formatDate(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	for range 5 {
+		p.callRangeAPI(context.Background(), FundTypeYAT, "", dateStr, dateStr)
+	}
+	if page.closed.Load() {
+		t.Error("page closed with restarts disabled")
+	}
+}
+
+func TestRestartLaunchesWithoutLock(t *testing.T) {
+	p := NewProvider(Config{RestartAfter: 1})
+	p.page, p.started = &answerPage{answer: answerDown}, true
+
+	launching := make(chan struct{})
+	release := make(chan struct{})
+	relaunched := &answerPage{answer: answerOK}
+	p.launch = func(userAgent string) (browserSession, error) {
+		close(launching)
+		<-release
+		return browserSession{page: relaunched}, nil
+	}
+	ctx := context.Background()
+	dateStr := formatDate(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+
+	failed := make(chan struct{})
+	go func() {
+		defer close(failed)
+		p.callRangeAPI(ctx, FundTypeYAT, "", dateStr, dateStr)
+	}()
+	<-launching
+
+	if !p.mu.TryLock() {
+		t.Fatal("p.mu held while the browser launches")
+	}
+	p.mu.Unlock()
+	if p.IsHealthy(ctx) {
+		t.Error("IsHealthy() = true while the browser restarts")
+	}
+
+	// A call made meanwhile waits for the new browser
+	waited := make(chan error, 1)
+	go func() {
+		_, err := p.callRangeAPI(ctx, FundTypeYAT, "KUT", dateStr, dateStr)
+		waited <- err
+	}()
+	select {
+	case err := <-waited:
+		t.Fatalf("call during the restart returned early: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-failed
+	if err := <-waited; err != nil {
+		t.Errorf("call waiting on the restart: error = %v", err)
+	}
+	if p.page != relaunched {
+		t.Error("provider isn't using the relaunched browser")
+	}
+}
+
+func TestFetchConcurrencyLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		want        int32
+	}{
+		{name: "default", concurrency: 0, want: 1},
+		{name: "three", concurrency: 3, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProvider(Config{FetchConcurrency: tt.concurrency})
+			p.started = true
+
+			var inFlight, peak atomic.Int32
+			p.query = func(ctx context.Context, fundType FundType, code, from, to string) ([]RawFundData, error) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					old := peak.Load()
+					if n <= old || peak.CompareAndSwap(old, n) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				return []RawFundData{{FonKodu: "KUT", Fiyat: 1.5}}, nil
+			}
+
+			// Days and fund types of a backfill, none shared between calls
+			var wg sync.WaitGroup
+			for day := range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					dateStr := formatDate(time.Date(2024, 3, 11+day, 0, 0, 0, 0, time.UTC))
+					if _, err := p.callAPIs(context.Background(), dateStr, FundTypeYAT, FundTypeEMK); err != nil {
+						t.Errorf("callAPIs(%s) error = %v", dateStr, err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := peak.Load(); got != tt.want {
+				t.Errorf("peak concurrent calls = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchFundDetailsCachesMisses(t *testing.T) {
+	p := NewProvider(Config{})
+	p.started = true
+
+	var calls atomic.Int32
+	p.query = func(ctx context.Context, fundType FundType, code, from, to string) ([]RawFundData, error) {
+		calls.Add(1)
+		return []RawFundData{{FonKodu: "KUT", Fiyat: 1.5}}, nil
+	}
+
+	for i := range 3 {
+		if _, err := p.FetchFundDetails(context.Background(), "NOPE"); !errors.Is(err, providers.ErrSymbolNotFound) {
+			t.Fatalf("lookup %d: FetchFundDetails() error = %v, want ErrSymbolNotFound", i+1, err)
+		}
+	}
+	afterMisses := calls.Load()
+	if afterMisses == 0 {
+		t.Fatal("unknown code never looked up")
+	}
+	if d, err := p.FetchFundDetails(context.Background(), "KUT"); err != nil || d.Price.Price != 1.5 {
+		t.Errorf("FetchFundDetails(KUT) = %v, %v; want the price from the earlier response", d, err)
+	}
+	if got := calls.Load(); got != afterMisses {
+		t.Errorf("API called %d times, want %d: repeated lookups should be answered from the cache", got, afterMisses)
+	}
+
+	p.FlushCache()
+	p.FetchFundDetails(context.Background(), "NOPE")
+	if calls.Load() == afterMisses {
+		t.Error("miss still cached after FlushCache")
+	}
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	// backupStepPages is how many pages a backup copies at a time, releasing
+	// the database in between so writers aren't held up for long
+	backupStepPages = 256
+
+	// backupPrefix and backupSuffix frame the timestamp in the names of
+	// rotated backups
+	backupPrefix = "prism-"
+	backupSuffix = ".db"
+
+	// backupTimeFormat sorts lexically in time order
+	backupTimeFormat = "20060102T150405Z"
+)
+
+// BackupPolicy says where rotated backups go and how many are kept
+type BackupPolicy struct {
+	Dir  string // Created if missing
+	Keep int    // Newest backups kept; older ones are deleted (0 keeps all)
+}
+
+// Backup copies the database to destPath with SQLite's online backup API,
+// which is consistent even while the database is being written to. The copy
+// is written beside destPath and renamed into place once complete, so
+// destPath never holds a partial backup.
+func (s *Storage) Backup(ctx context.Context, destPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("creating backup directory: %w", err)
+	}
+	tmpPath := destPath + ".tmp"
+	os.Remove(tmpPath) // Left over from an interrupted backup
+
+	if err := s.backupTo(ctx, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("moving backup into place: %w", err)
+	}
+	return nil
+}
+
+// backupTo runs the online backup of the main database into a new database
+// file at path
+func (s *Storage) backupTo(ctx context.Context, path string) error {
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("opening backup database: %w", err)
+	}
+	defer dest.Close()
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("opening backup database: %w", err)
+	}
+	defer destConn.Close()
+	srcConn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver any) error {
+		return srcConn.Raw(func(srcDriver any) error {
+			backup, err := destDriver.(*sqlite3.SQLiteConn).Backup("main", srcDriver.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return fmt.Errorf("starting backup: %w", err)
+			}
+			defer backup.Close()
+
+			for done := false; !done; {
+				if err := ctx.Err(); err != nil {
+					return fmt.Errorf("backing up database: %w", err)
+				}
+				if done, err = backup.Step(backupStepPages); err != nil {
+					return fmt.Errorf("backing up database: %w", err)
+				}
+			}
+			if err := backup.Finish(); err != nil {
+				return fmt.Errorf("finishing backup: %w", err)
+			}
+			return nil
+		})
+	})
+}
+
+// BackupRotated writes a timestamped backup into policy.Dir and deletes all
+// but the newest policy.Keep there. It returns the new backup's path.
+func (s *Storage) BackupRotated(ctx context.Context, policy BackupPolicy, now time.Time) (string, error) {
+	path := filepath.Join(policy.Dir, backupPrefix+now.UTC().Format(backupTimeFormat)+backupSuffix)
+	if err := s.Backup(ctx, path); err != nil {
+		return "", err
+	}
+
+	if policy.Keep > 0 {
+		backups, err := listBackups(policy.Dir)
+		if err != nil {
+			return path, err
+		}
+		for _, old := range backups[:max(len(backups)-policy.Keep, 0)] {
+			if err := os.Remove(old); err != nil {
+				return path, fmt.Errorf("removing old backup: %w", err)
+			}
+		}
+	}
+	return path, nil
+}
+
+// listBackups returns the paths of the rotated backups in dir, oldest first
+func listBackups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing backups: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			backups = append(backups, filepath.Join(dir, name))
+		}
+	}
+	slices.Sort(backups)
+	return backups, nil
+}
+
+// lastBackup returns when the newest rotated backup in dir was taken, or the
+// zero time if there is none
+func lastBackup(dir string) time.Time {
+	backups, err := listBackups(dir)
+	if err != nil || len(backups) == 0 {
+		return time.Time{}
+	}
+	name := filepath.Base(backups[len(backups)-1])
+	taken, _ := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix))
+	return taken
+}
+
+// RunBackups writes a rotated backup every interval, the first as soon as
+// the newest one in policy.Dir is interval old, so restarts don't put
+// backups off. It blocks until ctx is cancelled.
+func (s *Storage) RunBackups(ctx context.Context, policy BackupPolicy, interval time.Duration) {
+	timer := time.NewTimer(time.Until(lastBackup(policy.Dir).Add(interval)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if path, err := s.BackupRotated(ctx, policy, time.Now()); err != nil {
+			slog.Warn("failed to back up database", "error", err)
+		} else {
+			slog.Info("backed up database", "path", path, "keep", policy.Keep)
+		}
+		timer.Reset(interval)
+	}
+}
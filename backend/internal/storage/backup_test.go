@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupRotated(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(filepath.Join(dir, "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if _, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 1}); err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+
+	policy := BackupPolicy{Dir: filepath.Join(dir, "backups"), Keep: 2}
+	start := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+	var newest string
+	for day := range 3 {
+		if newest, err = s.BackupRotated(ctx, policy, start.AddDate(0, 0, day)); err != nil {
+			t.Fatalf("BackupRotated() error = %v", err)
+		}
+	}
+
+	backups, err := listBackups(policy.Dir)
+	if err != nil {
+		t.Fatalf("listBackups() error = %v", err)
+	}
+	if len(backups) != 2 || backups[1] != newest {
+		t.Errorf("backups = %v, want the newest 2 ending with %s", backups, newest)
+	}
+	if got, want := lastBackup(policy.Dir), start.AddDate(0, 0, 2); !got.Equal(want) {
+		t.Errorf("lastBackup() = %v, want %v", got, want)
+	}
+
+	// The backup is a complete database holding what was written
+	restored, err := New(newest, Options{})
+	if err != nil {
+		t.Fatalf("opening backup: %v", err)
+	}
+	defer restored.Close()
+	holdings, err := restored.GetAllHoldings(ctx)
+	if err != nil {
+		t.Fatalf("GetAllHoldings() error = %v", err)
+	}
+	if len(holdings) != 1 || holdings[0].Symbol != "KUT" {
+		t.Errorf("backed up holdings = %+v, want KUT", holdings)
+	}
+}
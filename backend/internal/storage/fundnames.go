@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// SaveFundNames upserts fund display names keyed by code
+func (s *Storage) SaveFundNames(ctx context.Context, names map[string]string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fund_names (code, name, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(code) DO UPDATE SET
+			name = excluded.name,
+			updated_at = excluded.updated_at
+	`)
+	if err != nil {
+		return fmt.Errorf("preparing statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for code, name := range names {
+		if code == "" || name == "" {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, code, name); err != nil {
+			return fmt.Errorf("saving fund name %s: %w", code, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// LoadFundNames returns every saved fund display name keyed by code
+func (s *Storage) LoadFundNames(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT code, name FROM fund_names`)
+	if err != nil {
+		return nil, fmt.Errorf("querying fund names: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	for rows.Next() {
+		var code, name string
+		if err := rows.Scan(&code, &name); err != nil {
+			return nil, fmt.Errorf("scanning fund name: %w", err)
+		}
+		names[code] = name
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating fund names: %w", err)
+	}
+
+	return names, nil
+}
@@ -3,9 +3,15 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 var (
@@ -13,15 +19,55 @@ var (
 	ErrHoldingNotFound = errors.New("holding not found")
 	// ErrHoldingExists is returned when trying to create a duplicate holding
 	ErrHoldingExists = errors.New("holding already exists")
+	// ErrTypeChangeRequiresCostBasis is returned when changing a holding's type
+	// without restating its cost basis, which is denominated per type
+	ErrTypeChangeRequiresCostBasis = errors.New("changing holding type requires cost_basis")
+	// ErrHoldingInTrash is returned when a duplicate holding exists but is soft-deleted
+	ErrHoldingInTrash = errors.New("holding exists in trash")
+	// ErrInvalidPosition is returned when a holding's quantity and cost basis
+	// break the sign conventions checked by PositionProblem
+	ErrInvalidPosition = errors.New("invalid position")
 )
 
-// GetAllHoldings returns all holdings
-func (s *Storage) GetAllHoldings(ctx context.Context) ([]Holding, error) {
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, type, symbol, quantity, cost_basis, created_at, updated_at
-		FROM holdings
-		ORDER BY type, symbol
-	`)
+// holdingColumns is the column list shared by all holding queries, in scan order
+const holdingColumns = `id, type, symbol, quantity, cost_basis, target_pct, fund_type, cost_currency, tags, notes, created_at, updated_at, deleted_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanHolding scans a row selected with holdingColumns
+func scanHolding(row rowScanner) (Holding, error) {
+	var h Holding
+	var targetPct sql.NullFloat64
+	var fundType, costCurrency, tags, notes sql.NullString
+	var deletedAt sql.NullTime
+	if err := row.Scan(&h.ID, &h.Type, &h.Symbol, &h.Quantity, &h.CostBasis, &targetPct, &fundType, &costCurrency, &tags, &notes, &h.CreatedAt, &h.UpdatedAt, &deletedAt); err != nil {
+		return h, err
+	}
+	h.FundType = fundType.String
+	h.CostCurrency = costCurrency.String
+	h.Notes = notes.String
+	h.Tags = []string{}
+	if tags.Valid {
+		if err := json.Unmarshal([]byte(tags.String), &h.Tags); err != nil {
+			return h, fmt.Errorf("decoding tags: %w", err)
+		}
+	}
+	h.AvgPrice = AvgPrice(h.CostBasis, h.Quantity)
+	if targetPct.Valid {
+		h.TargetPct = &targetPct.Float64
+	}
+	if deletedAt.Valid {
+		h.DeletedAt = &deletedAt.Time
+	}
+	return h, nil
+}
+
+// queryHoldings runs a holdings query and scans every row
+func (s *Storage) queryHoldings(ctx context.Context, query string, args ...any) ([]Holding, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying holdings: %w", err)
 	}
@@ -29,8 +75,8 @@ func (s *Storage) GetAllHoldings(ctx context.Context) ([]Holding, error) {
 
 	var holdings []Holding
 	for rows.Next() {
-		var h Holding
-		if err := rows.Scan(&h.ID, &h.Type, &h.Symbol, &h.Quantity, &h.CostBasis, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		h, err := scanHolding(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scanning holding: %w", err)
 		}
 		holdings = append(holdings, h)
@@ -43,43 +89,43 @@ func (s *Storage) GetAllHoldings(ctx context.Context) ([]Holding, error) {
 	return holdings, nil
 }
 
-// GetHoldingsByType returns all holdings of a specific type
+// GetAllHoldings returns all holdings that are not in the trash
+func (s *Storage) GetAllHoldings(ctx context.Context) ([]Holding, error) {
+	return s.queryHoldings(ctx, `
+		SELECT `+holdingColumns+`
+		FROM holdings
+		WHERE deleted_at IS NULL
+		ORDER BY type, symbol
+	`)
+}
+
+// GetHoldingsByType returns all holdings of a specific type that are not in the trash
 func (s *Storage) GetHoldingsByType(ctx context.Context, holdingType HoldingType) ([]Holding, error) {
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, type, symbol, quantity, cost_basis, created_at, updated_at
+	return s.queryHoldings(ctx, `
+		SELECT `+holdingColumns+`
 		FROM holdings
-		WHERE type = ?
+		WHERE type = ? AND deleted_at IS NULL
 		ORDER BY symbol
 	`, holdingType)
-	if err != nil {
-		return nil, fmt.Errorf("querying holdings by type: %w", err)
-	}
-	defer rows.Close()
-
-	var holdings []Holding
-	for rows.Next() {
-		var h Holding
-		if err := rows.Scan(&h.ID, &h.Type, &h.Symbol, &h.Quantity, &h.CostBasis, &h.CreatedAt, &h.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("scanning holding: %w", err)
-		}
-		holdings = append(holdings, h)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating holdings: %w", err)
-	}
+}
 
-	return holdings, nil
+// GetDeletedHoldings returns all soft-deleted holdings, most recently deleted first
+func (s *Storage) GetDeletedHoldings(ctx context.Context) ([]Holding, error) {
+	return s.queryHoldings(ctx, `
+		SELECT `+holdingColumns+`
+		FROM holdings
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`)
 }
 
 // GetHoldingByID returns a holding by its ID
 func (s *Storage) GetHoldingByID(ctx context.Context, id int64) (*Holding, error) {
-	var h Holding
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, type, symbol, quantity, cost_basis, created_at, updated_at
+	h, err := scanHolding(s.db.QueryRowContext(ctx, `
+		SELECT `+holdingColumns+`
 		FROM holdings
-		WHERE id = ?
-	`, id).Scan(&h.ID, &h.Type, &h.Symbol, &h.Quantity, &h.CostBasis, &h.CreatedAt, &h.UpdatedAt)
+		WHERE id = ? AND deleted_at IS NULL
+	`, id))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -93,12 +139,11 @@ func (s *Storage) GetHoldingByID(ctx context.Context, id int64) (*Holding, error
 
 // GetHoldingBySymbol returns a holding by type and symbol
 func (s *Storage) GetHoldingBySymbol(ctx context.Context, holdingType HoldingType, symbol string) (*Holding, error) {
-	var h Holding
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, type, symbol, quantity, cost_basis, created_at, updated_at
+	h, err := scanHolding(s.db.QueryRowContext(ctx, `
+		SELECT `+holdingColumns+`
 		FROM holdings
-		WHERE type = ? AND symbol = ?
-	`, holdingType, symbol).Scan(&h.ID, &h.Type, &h.Symbol, &h.Quantity, &h.CostBasis, &h.CreatedAt, &h.UpdatedAt)
+		WHERE type = ? AND symbol = ? AND deleted_at IS NULL
+	`, holdingType, symbol))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -114,15 +159,26 @@ func (s *Storage) GetHoldingBySymbol(ctx context.Context, holdingType HoldingTyp
 func (s *Storage) CreateHolding(ctx context.Context, req CreateHoldingRequest) (*Holding, error) {
 	now := time.Now()
 
+	if req.AvgPrice != nil {
+		if problem := avgPriceProblem(req); problem != "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPosition, problem)
+		}
+		req.CostBasis = req.Quantity * *req.AvgPrice
+	}
+	if problem := PositionProblem(req.Type, req.Quantity, req.CostBasis); problem != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPosition, problem)
+	}
+	tags := NormalizeTags(req.Tags)
+
 	result, err := s.db.ExecContext(ctx, `
-		INSERT INTO holdings (type, symbol, quantity, cost_basis, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, req.Type, req.Symbol, req.Quantity, req.CostBasis, now, now)
+		INSERT INTO holdings (type, symbol, quantity, cost_basis, target_pct, fund_type, cost_currency, tags, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, req.Type, req.Symbol, req.Quantity, req.CostBasis, req.TargetPct, nullString(req.FundType), nullString(req.CostCurrency), tagsColumn(tags), nullString(req.Notes), now, now)
 
 	if err != nil {
 		// Check for unique constraint violation
 		if isUniqueConstraintError(err) {
-			return nil, ErrHoldingExists
+			return nil, s.duplicateHoldingError(ctx, req.Type, req.Symbol)
 		}
 		return nil, fmt.Errorf("creating holding: %w", err)
 	}
@@ -133,13 +189,19 @@ func (s *Storage) CreateHolding(ctx context.Context, req CreateHoldingRequest) (
 	}
 
 	return &Holding{
-		ID:        id,
-		Type:      req.Type,
-		Symbol:    req.Symbol,
-		Quantity:  req.Quantity,
-		CostBasis: req.CostBasis,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:           id,
+		Type:         req.Type,
+		Symbol:       req.Symbol,
+		Quantity:     req.Quantity,
+		CostBasis:    req.CostBasis,
+		AvgPrice:     AvgPrice(req.CostBasis, req.Quantity),
+		TargetPct:    req.TargetPct,
+		FundType:     req.FundType,
+		CostCurrency: req.CostCurrency,
+		Tags:         tags,
+		Notes:        req.Notes,
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}, nil
 }
 
@@ -151,35 +213,165 @@ func (s *Storage) UpdateHolding(ctx context.Context, id int64, req UpdateHolding
 		return nil, err
 	}
 
+	// Cost basis is denominated in the type's currency (TRY for funds, USD for
+	// crypto), so moving a holding across types must restate it
+	if req.Type != nil && *req.Type != existing.Type && existing.CostBasis != 0 && req.CostBasis == nil {
+		return nil, ErrTypeChangeRequiresCostBasis
+	}
+
 	// Apply updates
+	if req.Type != nil {
+		existing.Type = *req.Type
+	}
+	if req.Symbol != nil {
+		existing.Symbol = *req.Symbol
+	}
 	if req.Quantity != nil {
 		existing.Quantity = *req.Quantity
 	}
 	if req.CostBasis != nil {
 		existing.CostBasis = *req.CostBasis
 	}
+	if req.TargetPct != nil {
+		existing.TargetPct = req.TargetPct
+	}
+	if req.FundType != nil {
+		existing.FundType = *req.FundType
+	}
+	if req.CostCurrency != nil {
+		existing.CostCurrency = *req.CostCurrency
+	}
+	if req.Tags != nil {
+		existing.Tags = NormalizeTags(*req.Tags)
+	}
+	if req.Notes != nil {
+		existing.Notes = *req.Notes
+	}
+	// Fund type only applies to TEFAS funds
+	if existing.Type != HoldingTypeFund {
+		existing.FundType = ""
+	}
+	if problem := PositionProblem(existing.Type, existing.Quantity, existing.CostBasis); problem != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPosition, problem)
+	}
+	existing.AvgPrice = AvgPrice(existing.CostBasis, existing.Quantity)
 	existing.UpdatedAt = time.Now()
 
 	_, err = s.db.ExecContext(ctx, `
 		UPDATE holdings
-		SET quantity = ?, cost_basis = ?, updated_at = ?
+		SET type = ?, symbol = ?, quantity = ?, cost_basis = ?, target_pct = ?, fund_type = ?, cost_currency = ?, tags = ?, notes = ?, updated_at = ?
 		WHERE id = ?
-	`, existing.Quantity, existing.CostBasis, existing.UpdatedAt, id)
+	`, existing.Type, existing.Symbol, existing.Quantity, existing.CostBasis, existing.TargetPct, nullString(existing.FundType), nullString(existing.CostCurrency),
+		tagsColumn(existing.Tags), nullString(existing.Notes), existing.UpdatedAt, id)
 
 	if err != nil {
+		// Renaming onto an existing (type, symbol) pair
+		if isUniqueConstraintError(err) {
+			return nil, s.duplicateHoldingError(ctx, existing.Type, existing.Symbol)
+		}
 		return nil, fmt.Errorf("updating holding: %w", err)
 	}
 
 	return existing, nil
 }
 
-// DeleteHolding deletes a holding by ID
+// BulkUpdateHoldings applies updates in one transaction and returns the
+// outcome of each, in order. A holding that doesn't exist or is in the trash
+// is reported as BulkNotFound and the rest still apply; any other error
+// rolls back the whole batch.
+func (s *Storage) BulkUpdateHoldings(ctx context.Context, updates []BulkUpdate) ([]BulkUpdateResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]BulkUpdateResult, 0, len(updates))
+	for _, u := range updates {
+		h, err := scanHolding(tx.QueryRowContext(ctx, `
+			SELECT `+holdingColumns+`
+			FROM holdings
+			WHERE id = ? AND deleted_at IS NULL
+		`, u.ID))
+		if errors.Is(err, sql.ErrNoRows) {
+			results = append(results, BulkUpdateResult{ID: u.ID, Status: BulkNotFound})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("querying holding %d: %w", u.ID, err)
+		}
+
+		if u.Quantity != nil {
+			h.Quantity = *u.Quantity
+		}
+		if u.CostBasis != nil {
+			h.CostBasis = *u.CostBasis
+		}
+		if problem := PositionProblem(h.Type, h.Quantity, h.CostBasis); problem != "" {
+			return nil, fmt.Errorf("holding %d: %w: %s", u.ID, ErrInvalidPosition, problem)
+		}
+		h.AvgPrice = AvgPrice(h.CostBasis, h.Quantity)
+		h.UpdatedAt = time.Now()
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE holdings SET quantity = ?, cost_basis = ?, updated_at = ? WHERE id = ?
+		`, h.Quantity, h.CostBasis, h.UpdatedAt, h.ID); err != nil {
+			return nil, fmt.Errorf("updating holding %d: %w", u.ID, err)
+		}
+		results = append(results, BulkUpdateResult{ID: u.ID, Status: BulkUpdated, Holding: &h})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+	return results, nil
+}
+
+// DeleteHolding moves a holding to the trash by setting deleted_at
 func (s *Storage) DeleteHolding(ctx context.Context, id int64) error {
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE holdings
+		SET deleted_at = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`, now, now, id)
+	if err != nil {
+		return fmt.Errorf("deleting holding: %w", err)
+	}
+
+	return checkRowsAffected(result)
+}
+
+// HardDeleteHolding permanently removes a holding, whether or not it is in the trash
+func (s *Storage) HardDeleteHolding(ctx context.Context, id int64) error {
 	result, err := s.db.ExecContext(ctx, "DELETE FROM holdings WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("deleting holding: %w", err)
 	}
 
+	return checkRowsAffected(result)
+}
+
+// RestoreHolding moves a holding out of the trash
+func (s *Storage) RestoreHolding(ctx context.Context, id int64) (*Holding, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE holdings
+		SET deleted_at = NULL, updated_at = ?
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`, time.Now(), id)
+	if err != nil {
+		return nil, fmt.Errorf("restoring holding: %w", err)
+	}
+
+	if err := checkRowsAffected(result); err != nil {
+		return nil, err
+	}
+
+	return s.GetHoldingByID(ctx, id)
+}
+
+// checkRowsAffected returns ErrHoldingNotFound when a statement matched no rows
+func checkRowsAffected(result sql.Result) error {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("checking rows affected: %w", err)
@@ -192,6 +384,18 @@ func (s *Storage) DeleteHolding(ctx context.Context, id int64) error {
 	return nil
 }
 
+// duplicateHoldingError distinguishes a live duplicate from one sitting in the trash
+func (s *Storage) duplicateHoldingError(ctx context.Context, holdingType HoldingType, symbol string) error {
+	var deletedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT deleted_at FROM holdings WHERE type = ? AND symbol = ?
+	`, holdingType, symbol).Scan(&deletedAt)
+	if err == nil && deletedAt.Valid {
+		return ErrHoldingInTrash
+	}
+	return ErrHoldingExists
+}
+
 // BulkCreateHoldings creates multiple holdings at once (for initial migration)
 func (s *Storage) BulkCreateHoldings(ctx context.Context, holdings []CreateHoldingRequest) error {
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -200,47 +404,177 @@ func (s *Storage) BulkCreateHoldings(ctx context.Context, holdings []CreateHoldi
 	}
 	defer tx.Rollback()
 
+	if _, err := insertHoldings(ctx, tx, holdings); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// insertHoldings inserts holdings within tx, skipping ones already stored,
+// and returns how many it inserted
+func insertHoldings(ctx context.Context, tx *sql.Tx, holdings []CreateHoldingRequest) (int64, error) {
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR IGNORE INTO holdings (type, symbol, quantity, cost_basis, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT OR IGNORE INTO holdings (type, symbol, quantity, cost_basis, fund_type, cost_currency, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
-		return fmt.Errorf("preparing statement: %w", err)
+		return 0, fmt.Errorf("preparing statement: %w", err)
 	}
 	defer stmt.Close()
 
+	var inserted int64
 	now := time.Now()
 	for _, h := range holdings {
-		_, err := stmt.ExecContext(ctx, h.Type, h.Symbol, h.Quantity, h.CostBasis, now, now)
+		result, err := stmt.ExecContext(ctx, h.Type, h.Symbol, h.Quantity, h.CostBasis, nullString(h.FundType), nullString(h.CostCurrency), now, now)
+		if err != nil {
+			return 0, fmt.Errorf("inserting holding %s: %w", h.Symbol, err)
+		}
+		n, err := result.RowsAffected()
 		if err != nil {
-			return fmt.Errorf("inserting holding %s: %w", h.Symbol, err)
+			return 0, fmt.Errorf("inserting holding %s: %w", h.Symbol, err)
 		}
+		inserted += n
 	}
+	return inserted, nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("committing transaction: %w", err)
+// ValidateHoldings reports, per holding and in order, whether it could be
+// created: rejecting unknown types, empty symbols, bad amounts and repeats
+// within the batch, and flagging holdings already stored. It only reads
+// the database.
+func (s *Storage) ValidateHoldings(ctx context.Context, holdings []CreateHoldingRequest) ([]HoldingValidation, error) {
+	results := CheckHoldings(holdings)
+	for i, r := range results {
+		if r.Status != ValidationNew {
+			continue
+		}
+		var deletedAt sql.NullTime
+		err := s.db.QueryRowContext(ctx, `
+			SELECT deleted_at FROM holdings WHERE type = ? AND symbol = ?
+		`, r.Type, r.Symbol).Scan(&deletedAt)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return nil, fmt.Errorf("looking up holding %s: %w", r.Symbol, err)
+		case deletedAt.Valid:
+			results[i].Status = ValidationInTrash
+		default:
+			results[i].Status = ValidationExists
+		}
 	}
+	return results, nil
+}
 
-	return nil
+// CheckHoldings validates holdings on their own, without the database: each
+// comes back ValidationNew or ValidationInvalid
+func CheckHoldings(holdings []CreateHoldingRequest) []HoldingValidation {
+	results := make([]HoldingValidation, len(holdings))
+	seen := make(map[string]int, len(holdings))
+	for i, h := range holdings {
+		var problems []string
+		if h.Type != HoldingTypeFund && h.Type != HoldingTypeCrypto {
+			problems = append(problems, "type must be 'fund' or 'crypto'")
+		}
+		if h.Symbol == "" {
+			problems = append(problems, "symbol must not be empty")
+		}
+		if problem := PositionProblem(h.Type, h.Quantity, h.CostBasis); problem != "" {
+			problems = append(problems, problem)
+		}
+		if h.FundType != "" && h.Type != HoldingTypeFund {
+			problems = append(problems, "fund_type only applies to fund holdings")
+		}
+		key := string(h.Type) + ":" + h.Symbol
+		if first, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("duplicate of holding %d", first))
+		} else {
+			seen[key] = i
+		}
+
+		results[i] = HoldingValidation{Index: i, Type: h.Type, Symbol: h.Symbol, Status: ValidationNew, Problems: problems}
+		if len(problems) > 0 {
+			results[i].Status = ValidationInvalid
+		}
+	}
+	return results
 }
 
-// isUniqueConstraintError checks if the error is a unique constraint violation
-func isUniqueConstraintError(err error) bool {
-	return err != nil && (
-	// SQLite error codes
-	contains(err.Error(), "UNIQUE constraint failed") ||
-		contains(err.Error(), "constraint failed"))
+// PositionProblem says what is wrong with a holding's quantity and cost
+// basis, or returns "" when they are valid. Only crypto can be held short,
+// with a negative quantity; cost basis takes the sign of the quantity, so a
+// short's is minus what the sale brought in and value - cost_basis is its
+// P&L like any other holding's.
+func PositionProblem(holdingType HoldingType, quantity, costBasis float64) string {
+	switch {
+	case math.IsNaN(quantity) || math.IsInf(quantity, 0):
+		return "quantity must be a number"
+	case math.IsNaN(costBasis) || math.IsInf(costBasis, 0):
+		return "cost_basis must be a number"
+	case quantity < 0 && holdingType != HoldingTypeCrypto:
+		return "quantity must not be negative; only crypto can be held short"
+	case quantity < 0 && costBasis > 0:
+		return "cost_basis of a short position must not be positive"
+	case quantity >= 0 && costBasis < 0:
+		return "cost_basis must not be negative"
+	}
+	return ""
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
+// avgPriceProblem says why req's avg_price can't give its cost basis, or
+// returns "" when it can
+func avgPriceProblem(req CreateHoldingRequest) string {
+	switch {
+	case req.CostBasis != 0:
+		return "provide either cost_basis or avg_price, not both"
+	case req.Quantity == 0:
+		return "avg_price needs a non-zero quantity"
+	}
+	return ""
 }
 
-func containsHelper(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+// NormalizeTags trims and lowercases tags, dropping empty ones and repeats.
+// The result is never nil.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
 		}
 	}
+	return normalized
+}
+
+// NormalizeTag returns tag in the form holdings store it
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// tagsColumn encodes tags as the JSON array stored in holdings.tags, or
+// NULL when there are none
+func tagsColumn(tags []string) sql.NullString {
+	if len(tags) == 0 {
+		return sql.NullString{}
+	}
+	encoded, _ := json.Marshal(tags) // A []string always encodes
+	return sql.NullString{String: string(encoded), Valid: true}
+}
+
+// nullString maps an empty string to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// isUniqueConstraintError checks if the error is a unique constraint violation
+func isUniqueConstraintError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
 	return false
 }
@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBulkUpdateHoldings(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	kut, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20})
+	if err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+	eth, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeCrypto, Symbol: "ETHUSDT", Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+	if err := s.DeleteHolding(ctx, eth.ID); err != nil {
+		t.Fatalf("DeleteHolding() error = %v", err)
+	}
+
+	quantity, costBasis := 12.0, 30.0
+	results, err := s.BulkUpdateHoldings(ctx, []BulkUpdate{
+		{ID: kut.ID, Quantity: &quantity, CostBasis: &costBasis},
+		{ID: eth.ID, Quantity: &quantity}, // In the trash
+		{ID: 999, Quantity: &quantity},
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdateHoldings() error = %v", err)
+	}
+
+	wantStatuses := []string{BulkUpdated, BulkNotFound, BulkNotFound}
+	if len(results) != len(wantStatuses) {
+		t.Fatalf("results = %+v, want %d", results, len(wantStatuses))
+	}
+	for i, want := range wantStatuses {
+		if results[i].Status != want {
+			t.Errorf("results[%d].status = %q, want %q", i, results[i].Status, want)
+		}
+	}
+	if h := results[0].Holding; h == nil || h.Quantity != 12 || h.AvgPrice != 2.5 {
+		t.Errorf("updated holding = %+v, want quantity 12 at avg price 2.5", h)
+	}
+
+	stored, err := s.GetHoldingByID(ctx, kut.ID)
+	if err != nil {
+		t.Fatalf("GetHoldingByID() error = %v", err)
+	}
+	if stored.Quantity != 12 || stored.CostBasis != 30 {
+		t.Errorf("stored KUT = %v/%v, want 12/30", stored.Quantity, stored.CostBasis)
+	}
+}
+
+func TestValidateHoldings(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if _, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 10}); err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+	eth, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeCrypto, Symbol: "ETHUSDT", Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+	if err := s.DeleteHolding(ctx, eth.ID); err != nil {
+		t.Fatalf("DeleteHolding() error = %v", err)
+	}
+
+	holdings := []CreateHoldingRequest{
+		{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 5},
+		{Type: HoldingTypeCrypto, Symbol: "ETHUSDT", Quantity: 1},
+		{Type: HoldingTypeFund, Symbol: "TI2", Quantity: 3},
+		{Type: HoldingTypeFund, Symbol: "TI2", Quantity: 4},
+		{Type: "stock", Symbol: "AAPL", Quantity: 1},
+		{Type: HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: -1, CostBasis: 5, FundType: "YAT"},
+		{Type: HoldingTypeFund, Symbol: "", Quantity: 1},
+	}
+	results, err := s.ValidateHoldings(ctx, holdings)
+	if err != nil {
+		t.Fatalf("ValidateHoldings() error = %v", err)
+	}
+
+	want := []struct {
+		status   string
+		problems int
+	}{
+		{ValidationExists, 0},
+		{ValidationInTrash, 0},
+		{ValidationNew, 0},
+		{ValidationInvalid, 1}, // Duplicate of TI2 above
+		{ValidationInvalid, 1},
+		{ValidationInvalid, 2},
+		{ValidationInvalid, 1},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if results[i].Index != i || results[i].Status != w.status || len(results[i].Problems) != w.problems {
+			t.Errorf("results[%d] = %+v, want status %s with %d problems", i, results[i], w.status, w.problems)
+		}
+	}
+
+	all, err := s.GetAllHoldings(ctx)
+	if err != nil || len(all) != 1 {
+		t.Errorf("GetAllHoldings() = %d holdings, %v; want validation to write nothing", len(all), err)
+	}
+}
+
+func TestPositionProblem(t *testing.T) {
+	tests := []struct {
+		name                string
+		holdingType         HoldingType
+		quantity, costBasis float64
+		wantValid           bool
+	}{
+		{"long", HoldingTypeFund, 10, 20, true},
+		{"closed", HoldingTypeFund, 0, 0, true},
+		{"negative cost", HoldingTypeFund, 10, -20, false},
+		{"short fund", HoldingTypeFund, -10, -20, false},
+		{"short crypto", HoldingTypeCrypto, -1, -50, true},
+		{"short crypto without cost", HoldingTypeCrypto, -1, 0, true},
+		{"short with positive cost", HoldingTypeCrypto, -1, 50, false},
+		{"NaN quantity", HoldingTypeCrypto, math.NaN(), 0, false},
+		{"infinite cost", HoldingTypeCrypto, 1, math.Inf(1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := PositionProblem(tt.holdingType, tt.quantity, tt.costBasis)
+			if (problem == "") != tt.wantValid {
+				t.Errorf("PositionProblem() = %q, want valid %v", problem, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestHoldingTagsAndNotes(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	created, err := s.CreateHolding(ctx, CreateHoldingRequest{
+		Type: HoldingTypeFund, Symbol: "KUT", Quantity: 10,
+		Tags: []string{" Retirement", "gold", "retirement", ""}, Notes: "Monthly top-up",
+	})
+	if err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+	stored, err := s.GetHoldingByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetHoldingByID() error = %v", err)
+	}
+	if !slices.Equal(stored.Tags, []string{"retirement", "gold"}) || stored.Notes != "Monthly top-up" {
+		t.Errorf("stored tags/notes = %v/%q, want [retirement gold]/\"Monthly top-up\"", stored.Tags, stored.Notes)
+	}
+
+	empty, notes := []string{}, ""
+	updated, err := s.UpdateHolding(ctx, created.ID, UpdateHoldingRequest{Tags: &empty, Notes: &notes})
+	if err != nil {
+		t.Fatalf("UpdateHolding() error = %v", err)
+	}
+	stored, err = s.GetHoldingByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetHoldingByID() error = %v", err)
+	}
+	if len(updated.Tags) != 0 || stored.Tags == nil || len(stored.Tags) != 0 || stored.Notes != "" {
+		t.Errorf("cleared tags/notes = %v/%q, want an empty list and no notes", stored.Tags, stored.Notes)
+	}
+}
+
+func TestHoldingTrash(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	kut, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 10, CostBasis: 20})
+	if err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+	btc, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+
+	// Moving to the trash hides the holding everywhere but the trash
+	if err := s.DeleteHolding(ctx, kut.ID); err != nil {
+		t.Fatalf("DeleteHolding() error = %v", err)
+	}
+	if err := s.DeleteHolding(ctx, kut.ID); !errors.Is(err, ErrHoldingNotFound) {
+		t.Errorf("DeleteHolding() of a trashed holding error = %v, want ErrHoldingNotFound", err)
+	}
+	if _, err := s.GetHoldingByID(ctx, kut.ID); !errors.Is(err, ErrHoldingNotFound) {
+		t.Errorf("GetHoldingByID() of a trashed holding error = %v, want ErrHoldingNotFound", err)
+	}
+	if _, err := s.GetHoldingBySymbol(ctx, HoldingTypeFund, "KUT"); !errors.Is(err, ErrHoldingNotFound) {
+		t.Errorf("GetHoldingBySymbol() of a trashed holding error = %v, want ErrHoldingNotFound", err)
+	}
+	live, err := s.GetAllHoldings(ctx)
+	if err != nil || len(live) != 1 || live[0].ID != btc.ID {
+		t.Errorf("GetAllHoldings() = %+v, %v; want only BTCUSDT", live, err)
+	}
+	trash, err := s.GetDeletedHoldings(ctx)
+	if err != nil || len(trash) != 1 || trash[0].ID != kut.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("GetDeletedHoldings() = %+v, %v; want KUT with deleted_at", trash, err)
+	}
+
+	// The symbol stays taken while in the trash
+	_, err = s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 5})
+	if !errors.Is(err, ErrHoldingInTrash) {
+		t.Errorf("CreateHolding() of a trashed symbol error = %v, want ErrHoldingInTrash", err)
+	}
+	_, err = s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 5})
+	if !errors.Is(err, ErrHoldingExists) {
+		t.Errorf("CreateHolding() of a live symbol error = %v, want ErrHoldingExists", err)
+	}
+
+	// Restoring brings the holding back as it was
+	restored, err := s.RestoreHolding(ctx, kut.ID)
+	if err != nil {
+		t.Fatalf("RestoreHolding() error = %v", err)
+	}
+	if restored.DeletedAt != nil || restored.Quantity != 10 || restored.CostBasis != 20 {
+		t.Errorf("restored holding = %+v, want KUT 10/20 out of the trash", restored)
+	}
+	if _, err := s.RestoreHolding(ctx, kut.ID); !errors.Is(err, ErrHoldingNotFound) {
+		t.Errorf("RestoreHolding() of a live holding error = %v, want ErrHoldingNotFound", err)
+	}
+
+	// Hard deletes work from the trash or not, and free the symbol
+	if err := s.DeleteHolding(ctx, kut.ID); err != nil {
+		t.Fatalf("DeleteHolding() error = %v", err)
+	}
+	for _, id := range []int64{kut.ID, btc.ID} {
+		if err := s.HardDeleteHolding(ctx, id); err != nil {
+			t.Errorf("HardDeleteHolding(%d) error = %v", id, err)
+		}
+	}
+	if err := s.HardDeleteHolding(ctx, kut.ID); !errors.Is(err, ErrHoldingNotFound) {
+		t.Errorf("HardDeleteHolding() of a deleted holding error = %v, want ErrHoldingNotFound", err)
+	}
+	if trash, err := s.GetDeletedHoldings(ctx); err != nil || len(trash) != 0 {
+		t.Errorf("GetDeletedHoldings() = %+v, %v; want empty", trash, err)
+	}
+	if _, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 5}); err != nil {
+		t.Errorf("CreateHolding() after a hard delete error = %v", err)
+	}
+}
+
+func TestCreateHoldingAvgPrice(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	price := func(p float64) *float64 { return &p }
+
+	tests := []struct {
+		name          string
+		req           CreateHoldingRequest
+		wantErr       error
+		wantCostBasis float64
+		wantAvgPrice  float64
+	}{
+		{
+			name:          "derives cost basis",
+			req:           CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 10, AvgPrice: price(2.5)},
+			wantCostBasis: 25,
+			wantAvgPrice:  2.5,
+		},
+		{
+			name:          "short position",
+			req:           CreateHoldingRequest{Type: HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: -2, AvgPrice: price(100)},
+			wantCostBasis: -200,
+			wantAvgPrice:  100,
+		},
+		{
+			name:    "both fields",
+			req:     CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "TI2", Quantity: 10, CostBasis: 25, AvgPrice: price(2.5)},
+			wantErr: ErrInvalidPosition,
+		},
+		{
+			name:    "zero quantity",
+			req:     CreateHoldingRequest{Type: HoldingTypeFund, Symbol: "AH5", AvgPrice: price(2.5)},
+			wantErr: ErrInvalidPosition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := s.CreateHolding(ctx, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateHolding() error = %v, want %v", err, tt.wantErr)
+				}
+				if _, err := s.GetHoldingBySymbol(ctx, tt.req.Type, tt.req.Symbol); !errors.Is(err, ErrHoldingNotFound) {
+					t.Errorf("rejected holding was stored: error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateHolding() error = %v", err)
+			}
+			if h.CostBasis != tt.wantCostBasis || h.AvgPrice != tt.wantAvgPrice {
+				t.Errorf("cost basis/avg price = %v/%v, want %v/%v", h.CostBasis, h.AvgPrice, tt.wantCostBasis, tt.wantAvgPrice)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrIdempotencyKeyNotFound is returned when no unexpired response is
+// recorded under a key
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+// IdempotentResponse is a response recorded under an Idempotency-Key, to be
+// replayed when a client retries the same request. RequestHash identifies
+// that request; it is empty for responses recorded before it was kept.
+type IdempotentResponse struct {
+	Status      int
+	Body        []byte
+	RequestHash string
+}
+
+// GetIdempotentResponse returns the unexpired response recorded under key,
+// or ErrIdempotencyKeyNotFound
+func (s *Storage) GetIdempotentResponse(ctx context.Context, key string) (*IdempotentResponse, error) {
+	var resp IdempotentResponse
+	err := s.db.QueryRowContext(ctx, `
+		SELECT status, response, request_hash FROM idempotency_keys
+		WHERE key = ? AND expires_at > ?
+	`, key, time.Now().UTC()).Scan(&resp.Status, &resp.Body, &resp.RequestHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying idempotency key: %w", err)
+	}
+	return &resp, nil
+}
+
+// SaveIdempotentResponse records resp under key until ttl elapses. Expired
+// keys are pruned on the way.
+func (s *Storage) SaveIdempotentResponse(ctx context.Context, key string, resp IdempotentResponse, ttl time.Duration) error {
+	now := time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, now); err != nil {
+		return fmt.Errorf("pruning idempotency keys: %w", err)
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (key, status, response, request_hash, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			status = excluded.status,
+			response = excluded.response,
+			request_hash = excluded.request_hash,
+			expires_at = excluded.expires_at
+	`, key, resp.Status, resp.Body, resp.RequestHash, now.Add(ttl))
+	if err != nil {
+		return fmt.Errorf("saving idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIdempotentResponses(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if _, err := s.GetIdempotentResponse(ctx, "k1"); !errors.Is(err, ErrIdempotencyKeyNotFound) {
+		t.Fatalf("unknown key error = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+
+	saved := IdempotentResponse{Status: 201, Body: []byte(`{"id":1}`), RequestHash: "abc123"}
+	if err := s.SaveIdempotentResponse(ctx, "k1", saved, time.Hour); err != nil {
+		t.Fatalf("SaveIdempotentResponse() error = %v", err)
+	}
+	if err := s.SaveIdempotentResponse(ctx, "k2", saved, -time.Second); err != nil {
+		t.Fatalf("SaveIdempotentResponse() error = %v", err)
+	}
+
+	got, err := s.GetIdempotentResponse(ctx, "k1")
+	if err != nil {
+		t.Fatalf("GetIdempotentResponse() error = %v", err)
+	}
+	if got.Status != saved.Status || string(got.Body) != string(saved.Body) || got.RequestHash != saved.RequestHash {
+		t.Errorf("response = %d %s (request %q), want %d %s (request %q)",
+			got.Status, got.Body, got.RequestHash, saved.Status, saved.Body, saved.RequestHash)
+	}
+	if _, err := s.GetIdempotentResponse(ctx, "k2"); !errors.Is(err, ErrIdempotencyKeyNotFound) {
+		t.Errorf("expired key error = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migration is a single, ordered schema change
+type migration struct {
+	version     int
+	description string
+	apply       func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change in order. Append new steps with the
+// next version number; never edit or reorder steps that have shipped.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "initial schema",
+		apply: execStatements(
+			// Holdings table
+			`CREATE TABLE IF NOT EXISTS holdings (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				type TEXT NOT NULL CHECK (type IN ('fund', 'crypto')),
+				symbol TEXT NOT NULL,
+				quantity REAL NOT NULL DEFAULT 0,
+				cost_basis REAL NOT NULL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(type, symbol)
+			)`,
+			// Portfolio snapshots table (for future history feature)
+			`CREATE TABLE IF NOT EXISTS portfolio_snapshots (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				date DATE NOT NULL UNIQUE,
+				total_value REAL NOT NULL,
+				total_cost_basis REAL NOT NULL,
+				tefas_value REAL DEFAULT 0,
+				crypto_value REAL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			// Index for faster lookups
+			`CREATE INDEX IF NOT EXISTS idx_holdings_type ON holdings(type)`,
+			`CREATE INDEX IF NOT EXISTS idx_holdings_symbol ON holdings(symbol)`,
+		),
+	},
+	{
+		version:     2,
+		description: "soft-delete for holdings",
+		// Databases created before versioning may already have this column
+		apply: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "holdings", "deleted_at", "DATETIME")
+		},
+	},
+	{
+		version:     3,
+		description: "target allocation for holdings",
+		apply:       execStatements(`ALTER TABLE holdings ADD COLUMN target_pct REAL`),
+	},
+	{
+		version:     4,
+		description: "persisted provider price cache",
+		apply: execStatements(
+			`CREATE TABLE IF NOT EXISTS price_cache (
+				provider TEXT NOT NULL,
+				symbol TEXT NOT NULL,
+				name TEXT NOT NULL DEFAULT '',
+				price REAL NOT NULL,
+				daily_change REAL NOT NULL DEFAULT 0,
+				daily_pct REAL NOT NULL DEFAULT 0,
+				last_updated DATETIME NOT NULL,
+				PRIMARY KEY (provider, symbol)
+			)`,
+		),
+	},
+	{
+		version:     5,
+		description: "TEFAS fund type for fund holdings",
+		apply:       execStatements(`ALTER TABLE holdings ADD COLUMN fund_type TEXT`),
+	},
+	{
+		version:     6,
+		description: "fund display names learned from TEFAS",
+		apply: execStatements(
+			`CREATE TABLE IF NOT EXISTS fund_names (
+				code TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+		),
+	},
+	{
+		version:     7,
+		description: "responses recorded under Idempotency-Key headers",
+		apply: execStatements(
+			`CREATE TABLE IF NOT EXISTS idempotency_keys (
+				key TEXT PRIMARY KEY,
+				status INTEGER NOT NULL,
+				response BLOB NOT NULL,
+				expires_at DATETIME NOT NULL
+			)`,
+		),
+	},
+	{
+		version:     8,
+		description: "watchlist of symbols followed without holding them",
+		apply: execStatements(
+			`CREATE TABLE IF NOT EXISTS watchlist (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				type TEXT NOT NULL CHECK (type IN ('fund', 'crypto')),
+				symbol TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(type, symbol)
+			)`,
+		),
+	},
+	{
+		version:     9,
+		description: "currency a holding's cost basis was paid in",
+		apply:       execStatements(`ALTER TABLE holdings ADD COLUMN cost_currency TEXT`),
+	},
+	{
+		version:     10,
+		description: "tags and notes for holdings",
+		apply: execStatements(
+			`ALTER TABLE holdings ADD COLUMN tags TEXT`,
+			`ALTER TABLE holdings ADD COLUMN notes TEXT`,
+		),
+	},
+	{
+		version:     11,
+		description: "currency of portfolio snapshot totals",
+		apply:       execStatements(`ALTER TABLE portfolio_snapshots ADD COLUMN currency TEXT NOT NULL DEFAULT ''`),
+	},
+	{
+		version:     12,
+		description: "hash of the request recorded under an Idempotency-Key",
+		apply:       execStatements(`ALTER TABLE idempotency_keys ADD COLUMN request_hash TEXT NOT NULL DEFAULT ''`),
+	},
+}
+
+// migrate applies every migration newer than the stored schema version
+func (s *Storage) migrate(backup *BackupPolicy) error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if backup != nil && migrations[len(migrations)-1].version > current {
+		if err := s.backupBeforeMigrating(*backup); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := s.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.description, err)
+		}
+		slog.Info("applied migration", "version", m.version, "description", m.description)
+	}
+
+	return nil
+}
+
+// backupBeforeMigrating writes a rotated backup of a database that holds
+// data, so a failed or unwanted migration can be rolled back by hand. A new
+// database has nothing to lose and isn't backed up.
+func (s *Storage) backupBeforeMigrating(policy BackupPolicy) error {
+	var tables int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'holdings'").Scan(&tables); err != nil {
+		return fmt.Errorf("checking for existing data: %w", err)
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	path, err := s.BackupRotated(context.Background(), policy, time.Now())
+	if err != nil {
+		return fmt.Errorf("backing up before migrating: %w", err)
+	}
+	slog.Info("backed up database before migrating", "path", path)
+	return nil
+}
+
+// applyMigration runs a migration and records its version in one transaction
+func (s *Storage) applyMigration(m migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.apply(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", m.version); err != nil {
+		return fmt.Errorf("recording version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SchemaVersion returns the highest applied migration version (0 if none)
+func (s *Storage) SchemaVersion() (int, error) {
+	var version int
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, nil
+}
+
+// execStatements returns a migration step that executes each statement in order
+func execStatements(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("executing statement: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("reading %s schema: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return fmt.Errorf("scanning %s schema: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading %s schema: %w", table, err)
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("adding column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
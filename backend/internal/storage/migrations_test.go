@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "prism.db")
+
+	// Open twice against the same file; the second run must be a no-op
+	for run := 1; run <= 2; run++ {
+		s, err := New(dbPath, Options{})
+		if err != nil {
+			t.Fatalf("run %d: New() error = %v", run, err)
+		}
+
+		version, err := s.SchemaVersion()
+		if err != nil {
+			t.Fatalf("run %d: SchemaVersion() error = %v", run, err)
+		}
+		if want := migrations[len(migrations)-1].version; version != want {
+			t.Errorf("run %d: version = %d, want %d", run, version, want)
+		}
+
+		var applied int
+		if err := s.DB().QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied); err != nil {
+			t.Fatalf("run %d: counting migrations: %v", run, err)
+		}
+		if applied != len(migrations) {
+			t.Errorf("run %d: applied migrations = %d, want %d", run, applied, len(migrations))
+		}
+
+		if err := s.migrate(nil); err != nil {
+			t.Errorf("run %d: re-running migrate() error = %v", run, err)
+		}
+
+		s.Close()
+	}
+}
+
+func TestMigrationVersionsAreOrdered(t *testing.T) {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version <= migrations[i-1].version {
+			t.Errorf("migration %d has version %d, not greater than %d",
+				i, migrations[i].version, migrations[i-1].version)
+		}
+	}
+}
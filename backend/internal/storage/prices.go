@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+)
+
+// SavePrices upserts the last-known prices for a provider
+func (s *Storage) SavePrices(ctx context.Context, provider string, prices []providers.Price) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO price_cache (provider, symbol, name, price, daily_change, daily_pct, last_updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(provider, symbol) DO UPDATE SET
+			name = excluded.name,
+			price = excluded.price,
+			daily_change = excluded.daily_change,
+			daily_pct = excluded.daily_pct,
+			last_updated = excluded.last_updated
+	`)
+	if err != nil {
+		return fmt.Errorf("preparing statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range prices {
+		// Placeholders for unknown symbols carry no information worth keeping
+		if p.Price <= 0 {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, provider, p.Symbol, p.Name, p.Price, p.DailyChange, p.DailyPct, p.LastUpdated); err != nil {
+			return fmt.Errorf("saving price %s: %w", p.Symbol, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// LoadPrices returns the last-known prices saved for a provider
+func (s *Storage) LoadPrices(ctx context.Context, provider string) ([]providers.Price, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT symbol, name, price, daily_change, daily_pct, last_updated
+		FROM price_cache
+		WHERE provider = ?
+	`, provider)
+	if err != nil {
+		return nil, fmt.Errorf("querying price cache: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []providers.Price
+	for rows.Next() {
+		var p providers.Price
+		if err := rows.Scan(&p.Symbol, &p.Name, &p.Price, &p.DailyChange, &p.DailyPct, &p.LastUpdated); err != nil {
+			return nil, fmt.Errorf("scanning price: %w", err)
+		}
+		prices = append(prices, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating price cache: %w", err)
+	}
+
+	return prices, nil
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSnapshotConverted is returned when a snapshot whose totals are in no
+// currency would replace one whose totals are converted
+var ErrSnapshotConverted = errors.New("a snapshot with converted totals exists for this date")
+
+// Snapshot is the portfolio value recorded for one day. Currency is the
+// currency the totals are in; it is empty when they add TRY and other
+// amounts as they are, as snapshots taken without exchange rates and
+// before display_currency do, so they can't be compared with converted
+// totals.
+type Snapshot struct {
+	Date           time.Time `json:"date"`
+	TotalValue     float64   `json:"total_value"`
+	TotalCostBasis float64   `json:"total_cost_basis"`
+	TEFASValue     float64   `json:"tefas_value"`
+	CryptoValue    float64   `json:"crypto_value"`
+	Currency       string    `json:"currency,omitempty"`
+}
+
+// UpsertSnapshot stores a snapshot, replacing any existing one for the same
+// day. A snapshot without a currency never replaces one with converted
+// totals; that returns ErrSnapshotConverted.
+func (s *Storage) UpsertSnapshot(ctx context.Context, snap Snapshot) error {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO portfolio_snapshots (date, total_value, total_cost_basis, tefas_value, crypto_value, currency)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(date) DO UPDATE SET
+			total_value = excluded.total_value,
+			total_cost_basis = excluded.total_cost_basis,
+			tefas_value = excluded.tefas_value,
+			crypto_value = excluded.crypto_value,
+			currency = excluded.currency
+		WHERE excluded.currency != '' OR portfolio_snapshots.currency = ''
+	`, snap.Date.Format(time.DateOnly), snap.TotalValue, snap.TotalCostBasis, snap.TEFASValue, snap.CryptoValue, snap.Currency)
+	if err != nil {
+		return fmt.Errorf("saving snapshot %s: %w", snap.Date.Format(time.DateOnly), err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("saving snapshot %s: %w", snap.Date.Format(time.DateOnly), ErrSnapshotConverted)
+	}
+	return nil
+}
+
+// GetSnapshots returns snapshots between from and to (inclusive), oldest first
+func (s *Storage) GetSnapshots(ctx context.Context, from, to time.Time) ([]Snapshot, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT date, total_value, total_cost_basis, tefas_value, crypto_value, currency
+		FROM portfolio_snapshots
+		WHERE date BETWEEN ? AND ?
+		ORDER BY date
+	`, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		var snap Snapshot
+		if err := rows.Scan(&snap.Date, &snap.TotalValue, &snap.TotalCostBasis, &snap.TEFASValue, &snap.CryptoValue, &snap.Currency); err != nil {
+			return nil, fmt.Errorf("scanning snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// GetPeakSnapshot returns the snapshot with totals in currency with the
+// highest total value, the earliest one on a tie, and how many snapshots
+// have totals in currency. With none, it returns a zero Snapshot and a count
+// of 0.
+func (s *Storage) GetPeakSnapshot(ctx context.Context, currency string) (Snapshot, int, error) {
+	var peak Snapshot
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT date, total_value, total_cost_basis, tefas_value, crypto_value, currency,
+			(SELECT COUNT(*) FROM portfolio_snapshots WHERE currency = ?)
+		FROM portfolio_snapshots
+		WHERE currency = ?
+		ORDER BY total_value DESC, date
+		LIMIT 1
+	`, currency, currency).Scan(&peak.Date, &peak.TotalValue, &peak.TotalCostBasis, &peak.TEFASValue, &peak.CryptoValue, &peak.Currency, &count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Snapshot{}, 0, nil
+	}
+	if err != nil {
+		return Snapshot{}, 0, fmt.Errorf("querying peak snapshot: %w", err)
+	}
+	return peak, count, nil
+}
+
+// PruneSnapshots deletes snapshots dated before before and returns how many
+// were deleted. With keepMonthEnds, the last snapshot of each month is kept
+// for long-term history.
+func (s *Storage) PruneSnapshots(ctx context.Context, before time.Time, keepMonthEnds bool) (int64, error) {
+	query := `DELETE FROM portfolio_snapshots WHERE date < ?`
+	if keepMonthEnds {
+		query += ` AND date NOT IN (SELECT MAX(date) FROM portfolio_snapshots GROUP BY ` + snapshotBuckets[GranularityMonthly] + `)`
+	}
+
+	result, err := s.db.ExecContext(ctx, query, before.Format(time.DateOnly))
+	if err != nil {
+		return 0, fmt.Errorf("pruning snapshots before %s: %w", before.Format(time.DateOnly), err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("counting pruned snapshots: %w", err)
+	}
+	return deleted, nil
+}
+
+// Granularity is the bucket size for snapshot history
+type Granularity string
+
+const (
+	GranularityDaily   Granularity = "daily"
+	GranularityWeekly  Granularity = "weekly"
+	GranularityMonthly Granularity = "monthly"
+)
+
+// snapshotBuckets maps each granularity to the SQL expression naming a
+// snapshot's bucket. Weeks start on Monday.
+var snapshotBuckets = map[Granularity]string{
+	GranularityDaily:   `date`,
+	GranularityWeekly:  `date(date, '-' || ((CAST(strftime('%w', date) AS INTEGER) + 6) % 7) || ' days')`,
+	GranularityMonthly: `strftime('%Y-%m', date)`,
+}
+
+// ValidGranularity reports whether g is a supported granularity
+func ValidGranularity(g Granularity) bool {
+	_, ok := snapshotBuckets[g]
+	return ok
+}
+
+// SnapshotQuery selects snapshot history. Limit keeps only the most recent
+// buckets; zero means no limit.
+type SnapshotQuery struct {
+	From        time.Time
+	To          time.Time
+	Granularity Granularity
+	Limit       int
+}
+
+// GetSnapshotHistory returns the last snapshot of each bucket between q.From
+// and q.To (inclusive), oldest first
+func (s *Storage) GetSnapshotHistory(ctx context.Context, q SnapshotQuery) ([]Snapshot, error) {
+	bucket, ok := snapshotBuckets[q.Granularity]
+	if !ok {
+		return nil, fmt.Errorf("unknown granularity %q", q.Granularity)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT date, total_value, total_cost_basis, tefas_value, crypto_value, currency
+		FROM portfolio_snapshots
+		WHERE date IN (
+			SELECT MAX(date)
+			FROM portfolio_snapshots
+			WHERE date BETWEEN ? AND ?
+			GROUP BY `+bucket+`
+			ORDER BY MAX(date) DESC
+			LIMIT ?
+		)
+		ORDER BY date
+	`, q.From.Format(time.DateOnly), q.To.Format(time.DateOnly), limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying snapshot history: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		var snap Snapshot
+		if err := rows.Scan(&snap.Date, &snap.TotalValue, &snap.TotalCostBasis, &snap.TEFASValue, &snap.CryptoValue, &snap.Currency); err != nil {
+			return nil, fmt.Errorf("scanning snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
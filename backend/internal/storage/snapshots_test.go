@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetSnapshotHistory(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+
+	// Daily snapshots from Monday 2024-01-29 through Tuesday 2024-02-06
+	ctx := context.Background()
+	start := time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 9; i++ {
+		snap := Snapshot{Date: start.AddDate(0, 0, i), TotalValue: float64(i)}
+		if err := s.UpsertSnapshot(ctx, snap); err != nil {
+			t.Fatalf("UpsertSnapshot() error = %v", err)
+		}
+	}
+	end := start.AddDate(0, 0, 8)
+	day := func(date string) time.Time {
+		d, _ := time.Parse(time.DateOnly, date)
+		return d
+	}
+
+	tests := []struct {
+		name  string
+		query SnapshotQuery
+		want  []string
+	}{
+		{"daily range", SnapshotQuery{From: day("2024-02-01"), To: day("2024-02-03"), Granularity: GranularityDaily},
+			[]string{"2024-02-01", "2024-02-02", "2024-02-03"}},
+		{"daily limit keeps latest", SnapshotQuery{From: start, To: end, Granularity: GranularityDaily, Limit: 3},
+			[]string{"2024-02-04", "2024-02-05", "2024-02-06"}},
+		{"weekly takes last of each week", SnapshotQuery{From: start, To: end, Granularity: GranularityWeekly},
+			[]string{"2024-02-04", "2024-02-06"}},
+		{"weekly with limit", SnapshotQuery{From: start, To: end, Granularity: GranularityWeekly, Limit: 1},
+			[]string{"2024-02-06"}},
+		{"monthly takes last of each month", SnapshotQuery{From: start, To: end, Granularity: GranularityMonthly},
+			[]string{"2024-01-31", "2024-02-06"}},
+		{"empty range", SnapshotQuery{From: day("2023-01-01"), To: day("2023-12-31"), Granularity: GranularityDaily},
+			[]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshots, err := s.GetSnapshotHistory(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("GetSnapshotHistory() error = %v", err)
+			}
+			got := make([]string, 0, len(snapshots))
+			for _, snap := range snapshots {
+				got = append(got, snap.Date.Format(time.DateOnly))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertSnapshotKeepsConvertedTotals(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	day := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		snap    Snapshot
+		wantErr error
+		want    Snapshot
+	}{
+		{Snapshot{Date: day, TotalValue: 100}, nil, Snapshot{Date: day, TotalValue: 100}},
+		{Snapshot{Date: day, TotalValue: 200, Currency: "TRY"}, nil, Snapshot{Date: day, TotalValue: 200, Currency: "TRY"}},
+		{Snapshot{Date: day, TotalValue: 300}, ErrSnapshotConverted, Snapshot{Date: day, TotalValue: 200, Currency: "TRY"}},
+		{Snapshot{Date: day, TotalValue: 10, Currency: "USD"}, nil, Snapshot{Date: day, TotalValue: 10, Currency: "USD"}},
+	}
+
+	for i, step := range steps {
+		if err := s.UpsertSnapshot(ctx, step.snap); !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d: UpsertSnapshot() error = %v, want %v", i+1, err, step.wantErr)
+		}
+		snapshots, err := s.GetSnapshots(ctx, day, day)
+		if err != nil {
+			t.Fatalf("GetSnapshots() error = %v", err)
+		}
+		if len(snapshots) != 1 || !snapshots[0].Date.Equal(step.want.Date) ||
+			snapshots[0].TotalValue != step.want.TotalValue || snapshots[0].Currency != step.want.Currency {
+			t.Errorf("step %d: stored %+v, want %+v", i+1, snapshots, step.want)
+		}
+	}
+}
+
+func TestGetPeakSnapshot(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if _, count, err := s.GetPeakSnapshot(ctx, "TRY"); err != nil || count != 0 {
+		t.Fatalf("GetPeakSnapshot() on empty table = %d, %v; want 0, nil", count, err)
+	}
+
+	start := time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC)
+	for i, value := range []float64{10, 30, 20, 30, 5} {
+		if err := s.UpsertSnapshot(ctx, Snapshot{Date: start.AddDate(0, 0, i), TotalValue: value, Currency: "TRY"}); err != nil {
+			t.Fatalf("UpsertSnapshot() error = %v", err)
+		}
+	}
+	// Unconverted and USD totals are in other units, so never the peak
+	if err := s.UpsertSnapshot(ctx, Snapshot{Date: start.AddDate(0, 0, -2), TotalValue: 100}); err != nil {
+		t.Fatalf("UpsertSnapshot() error = %v", err)
+	}
+	if err := s.UpsertSnapshot(ctx, Snapshot{Date: start.AddDate(0, 0, -1), TotalValue: 50, Currency: "USD"}); err != nil {
+		t.Fatalf("UpsertSnapshot() error = %v", err)
+	}
+
+	peak, count, err := s.GetPeakSnapshot(ctx, "TRY")
+	if err != nil {
+		t.Fatalf("GetPeakSnapshot() error = %v", err)
+	}
+	if count != 5 {
+		t.Errorf("count = %d, want 5", count)
+	}
+	// 30 is reached twice; the first time counts
+	if peak.TotalValue != 30 || peak.Date.Format(time.DateOnly) != "2024-01-30" {
+		t.Errorf("peak = %v on %s, want 30 on 2024-01-30", peak.TotalValue, peak.Date.Format(time.DateOnly))
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	// Daily snapshots from 2024-01-29 through 2024-03-05
+	start := time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		keepMonthEnds bool
+		wantDeleted   int64
+		wantBefore    []string // Snapshots left before the cutoff
+	}{
+		{"delete everything before", false, 32, []string{}},
+		{"keep month ends", true, 30, []string{"2024-01-31", "2024-02-29"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer s.Close()
+
+			ctx := context.Background()
+			for d := start; !d.After(before.AddDate(0, 0, 4)); d = d.AddDate(0, 0, 1) {
+				if err := s.UpsertSnapshot(ctx, Snapshot{Date: d, TotalValue: 1}); err != nil {
+					t.Fatalf("UpsertSnapshot() error = %v", err)
+				}
+			}
+
+			deleted, err := s.PruneSnapshots(ctx, before, tt.keepMonthEnds)
+			if err != nil {
+				t.Fatalf("PruneSnapshots() error = %v", err)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("deleted = %d, want %d", deleted, tt.wantDeleted)
+			}
+
+			left, err := s.GetSnapshots(ctx, start, before.AddDate(0, 0, -1))
+			if err != nil {
+				t.Fatalf("GetSnapshots() error = %v", err)
+			}
+			got := make([]string, 0, len(left))
+			for _, snap := range left {
+				got = append(got, snap.Date.Format(time.DateOnly))
+			}
+			if !reflect.DeepEqual(got, tt.wantBefore) {
+				t.Errorf("snapshots before cutoff = %v, want %v", got, tt.wantBefore)
+			}
+
+			kept, err := s.GetSnapshots(ctx, before, before.AddDate(0, 0, 4))
+			if err != nil {
+				t.Fatalf("GetSnapshots() error = %v", err)
+			}
+			if len(kept) != 5 {
+				t.Errorf("%d snapshots from the cutoff on, want 5", len(kept))
+			}
+		})
+	}
+}
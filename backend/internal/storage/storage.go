@@ -27,41 +27,148 @@ const (
 
 // Holding represents a portfolio holding
 type Holding struct {
-	ID        int64       `json:"id"`
-	Type      HoldingType `json:"type"`
-	Symbol    string      `json:"symbol"`
-	Quantity  float64     `json:"quantity"`
-	CostBasis float64     `json:"cost_basis"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
+	ID           int64       `json:"id"`
+	Type         HoldingType `json:"type"`
+	Symbol       string      `json:"symbol"`
+	Quantity     float64     `json:"quantity"`                // Negative for a short crypto position
+	CostBasis    float64     `json:"cost_basis"`              // Signed like quantity: a short's is minus the proceeds of the sale
+	AvgPrice     float64     `json:"avg_price"`               // Derived: cost_basis / quantity (0 when quantity is 0)
+	TargetPct    *float64    `json:"target_pct"`              // Optional target allocation (0-100)
+	FundType     string      `json:"fund_type,omitempty"`     // TEFAS fund type for funds: "YAT" or "EMK" (empty = detect)
+	CostCurrency string      `json:"cost_currency,omitempty"` // Currency cost_basis was paid in, when not the one the holding is priced in
+	Tags         []string    `json:"tags"`                    // Lowercase labels such as "retirement"; empty when untagged
+	Notes        string      `json:"notes,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+	DeletedAt    *time.Time  `json:"deleted_at,omitempty"` // Set when the holding is in the trash
 }
 
 // CreateHoldingRequest represents the request to create a holding
 type CreateHoldingRequest struct {
-	Type      HoldingType `json:"type" binding:"required,oneof=fund crypto"`
-	Symbol    string      `json:"symbol" binding:"required"`
-	Quantity  float64     `json:"quantity" binding:"required,gte=0"`
-	CostBasis float64     `json:"cost_basis" binding:"gte=0"`
+	Type         HoldingType `json:"type" binding:"omitempty,oneof=fund crypto"` // Inferred from the symbol by POST /api/holdings when empty
+	Symbol       string      `json:"symbol" binding:"required"`
+	Quantity     float64     `json:"quantity" binding:"required"` // Signs are checked by PositionProblem
+	CostBasis    float64     `json:"cost_basis"`
+	AvgPrice     *float64    `json:"avg_price,omitempty" binding:"omitempty,gte=0"` // Alternative to cost_basis: cost_basis = quantity * avg_price
+	TargetPct    *float64    `json:"target_pct,omitempty" binding:"omitempty,gte=0,lte=100"`
+	FundType     string      `json:"fund_type,omitempty" binding:"omitempty,oneof=YAT EMK"`
+	CostCurrency string      `json:"cost_currency,omitempty" binding:"omitempty,len=3,alpha"`
+	Tags         []string    `json:"tags,omitempty" binding:"max=20,dive,max=50"`
+	Notes        string      `json:"notes,omitempty" binding:"max=2000"`
+
+	// InferCostBasis values a holding given without cost_basis or avg_price
+	// at the current price; nil uses the infer_cost_basis config setting
+	InferCostBasis *bool `json:"infer_cost_basis,omitempty"`
 }
 
-// UpdateHoldingRequest represents the request to update a holding
+// UpdateHoldingRequest represents the request to update a holding.
+// Only non-nil fields are applied (PATCH semantics).
 type UpdateHoldingRequest struct {
+	Type         *HoldingType `json:"type,omitempty"`
+	Symbol       *string      `json:"symbol,omitempty"`
+	Quantity     *float64     `json:"quantity,omitempty"`
+	CostBasis    *float64     `json:"cost_basis,omitempty"`
+	TargetPct    *float64     `json:"target_pct,omitempty" binding:"omitempty,gte=0,lte=100"`
+	FundType     *string      `json:"fund_type,omitempty" binding:"omitempty,oneof=YAT EMK"`
+	CostCurrency *string      `json:"cost_currency,omitempty" binding:"omitempty,len=3,alpha"` // Empty resets it
+	Tags         *[]string    `json:"tags,omitempty" binding:"omitempty,max=20,dive,max=50"`   // Replaces the tags; empty removes them
+	Notes        *string      `json:"notes,omitempty" binding:"omitempty,max=2000"`            // Empty removes them
+}
+
+// BulkUpdate is one holding's change in a bulk update. Only non-nil fields
+// are applied.
+type BulkUpdate struct {
+	ID        int64    `json:"id" binding:"required"`
 	Quantity  *float64 `json:"quantity,omitempty"`
 	CostBasis *float64 `json:"cost_basis,omitempty"`
 }
 
+// Bulk update outcomes
+const (
+	BulkUpdated  = "updated"
+	BulkNotFound = "not_found"
+)
+
+// BulkUpdateResult is the outcome of one BulkUpdate
+type BulkUpdateResult struct {
+	ID      int64    `json:"id"`
+	Status  string   `json:"status"`            // BulkUpdated or BulkNotFound
+	Holding *Holding `json:"holding,omitempty"` // The updated holding
+}
+
+// Validation outcomes of a holding to create
+const (
+	ValidationNew     = "new"      // Can be created
+	ValidationExists  = "exists"   // A live holding has the same type and symbol
+	ValidationInTrash = "in_trash" // A holding in the trash has the same type and symbol
+	ValidationInvalid = "invalid"  // Rejected; see Problems
+)
+
+// HoldingValidation is the outcome of validating one holding to create
+type HoldingValidation struct {
+	Index    int         `json:"index"` // Position in the validated batch
+	Type     HoldingType `json:"type"`
+	Symbol   string      `json:"symbol"`
+	Status   string      `json:"status"`             // One of the Validation constants
+	Problems []string    `json:"problems,omitempty"` // Why an invalid holding was rejected
+}
+
+// AvgPrice returns the average buy price for a holding, guarding against
+// zero quantity
+func AvgPrice(costBasis, quantity float64) float64 {
+	if quantity == 0 {
+		return 0
+	}
+	return costBasis / quantity
+}
+
+// Connection pool defaults, used for zero Options fields
+const (
+	defaultMaxOpenConns = 4
+	defaultBusyTimeout  = 5 * time.Second
+)
+
+// Options tunes the database connection pool. Zero values use the defaults.
+//
+// The database runs in WAL mode, so readers never block the writer or each
+// other, but SQLite still allows only one writer at a time. Writers queue on
+// the lock for up to BusyTimeout before failing with "database is locked",
+// and transactions start IMMEDIATE so a writer claims the lock up front
+// rather than failing when a read transaction tries to upgrade.
+type Options struct {
+	MaxOpenConns int           // Open connections, readers and writers together (default 4)
+	MaxIdleConns int           // Connections kept open when idle (default MaxOpenConns)
+	BusyTimeout  time.Duration // How long to wait for a lock (default 5s)
+
+	BackupBeforeMigrate *BackupPolicy // Optional: back up an existing database before applying migrations
+}
+
 // New creates a new Storage instance with the given database path
-func New(dbPath string) (*Storage, error) {
+func New(dbPath string, opts Options) (*Storage, error) {
 	// Ensure parent directory exists
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("creating database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_foreign_keys=ON")
+	if opts.MaxOpenConns <= 0 {
+		opts.MaxOpenConns = defaultMaxOpenConns
+	}
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = opts.MaxOpenConns
+	}
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = defaultBusyTimeout
+	}
+
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_foreign_keys=ON&_busy_timeout=%d&_txlock=immediate",
+		dbPath, opts.BusyTimeout.Milliseconds())
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
 
 	// Test connection
 	if err := db.Ping(); err != nil {
@@ -71,11 +178,11 @@ func New(dbPath string) (*Storage, error) {
 	s := &Storage{db: db}
 
 	// Run migrations
-	if err := s.migrate(); err != nil {
+	if err := s.migrate(opts.BackupBeforeMigrate); err != nil {
 		return nil, fmt.Errorf("running migrations: %w", err)
 	}
 
-	slog.Info("storage initialized", "path", dbPath)
+	slog.Info("storage initialized", "path", dbPath, "max_open_conns", opts.MaxOpenConns, "busy_timeout", opts.BusyTimeout)
 	return s, nil
 }
 
@@ -87,41 +194,11 @@ func (s *Storage) Close() error {
 	return nil
 }
 
-// migrate runs database migrations
-func (s *Storage) migrate() error {
-	migrations := []string{
-		// Holdings table
-		`CREATE TABLE IF NOT EXISTS holdings (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			type TEXT NOT NULL CHECK (type IN ('fund', 'crypto')),
-			symbol TEXT NOT NULL,
-			quantity REAL NOT NULL DEFAULT 0,
-			cost_basis REAL NOT NULL DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(type, symbol)
-		)`,
-		// Portfolio snapshots table (for future history feature)
-		`CREATE TABLE IF NOT EXISTS portfolio_snapshots (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			date DATE NOT NULL UNIQUE,
-			total_value REAL NOT NULL,
-			total_cost_basis REAL NOT NULL,
-			tefas_value REAL DEFAULT 0,
-			crypto_value REAL DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-		// Index for faster lookups
-		`CREATE INDEX IF NOT EXISTS idx_holdings_type ON holdings(type)`,
-		`CREATE INDEX IF NOT EXISTS idx_holdings_symbol ON holdings(symbol)`,
-	}
-
-	for _, m := range migrations {
-		if _, err := s.db.Exec(m); err != nil {
-			return fmt.Errorf("executing migration: %w", err)
-		}
+// Ping checks that the database is reachable
+func (s *Storage) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("pinging database: %w", err)
 	}
-
 	return nil
 }
 
@@ -135,6 +212,53 @@ func (s *Storage) IsEmpty(ctx context.Context) (bool, error) {
 	return count == 0, nil
 }
 
+// ResetResult counts the rows a Reset removed and seeded
+type ResetResult struct {
+	HoldingsRemoved  int64 `json:"holdings_removed"`
+	SnapshotsRemoved int64 `json:"snapshots_removed"`
+	HoldingsSeeded   int64 `json:"holdings_seeded"`
+}
+
+// Reset deletes every holding, including those in the trash, and every
+// snapshot, then creates the seed holdings, all in one transaction. Recorded
+// idempotent responses go too, so a retried create cannot replay a holding
+// that no longer exists.
+func (s *Storage) Reset(ctx context.Context, seed []CreateHoldingRequest) (ResetResult, error) {
+	var result ResetResult
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	tables := []struct {
+		name    string
+		removed *int64
+	}{
+		{"holdings", &result.HoldingsRemoved},
+		{"portfolio_snapshots", &result.SnapshotsRemoved},
+		{"idempotency_keys", new(int64)},
+	}
+	for _, table := range tables {
+		deleted, err := tx.ExecContext(ctx, "DELETE FROM "+table.name)
+		if err != nil {
+			return result, fmt.Errorf("clearing %s: %w", table.name, err)
+		}
+		if *table.removed, err = deleted.RowsAffected(); err != nil {
+			return result, fmt.Errorf("clearing %s: %w", table.name, err)
+		}
+	}
+
+	if result.HoldingsSeeded, err = insertHoldings(ctx, tx, seed); err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("committing transaction: %w", err)
+	}
+	return result, nil
+}
+
 // DB returns the underlying database connection for advanced queries
 func (s *Storage) DB() *sql.DB {
 	return s.db
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ferhatkunduraci/prism/internal/providers"
+)
+
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+
+	const workers = 8
+	const iterations = 25
+	ctx := context.Background()
+	errs := make(chan error, 6*workers*iterations)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				h, err := s.CreateHolding(ctx, CreateHoldingRequest{
+					Type:     HoldingTypeCrypto,
+					Symbol:   fmt.Sprintf("W%dI%dUSDT", w, i),
+					Quantity: 1,
+				})
+				if err != nil {
+					errs <- fmt.Errorf("create: %w", err)
+					continue
+				}
+				quantity := 2.0
+				if _, err := s.UpdateHolding(ctx, h.ID, UpdateHoldingRequest{Quantity: &quantity}); err != nil {
+					errs <- fmt.Errorf("update: %w", err)
+				}
+				// A transaction that reads before writing must upgrade its lock;
+				// with deferred transactions two of these deadlock and one fails
+				// immediately, regardless of the busy timeout
+				if err := readThenWrite(ctx, s, h.ID); err != nil {
+					errs <- fmt.Errorf("read-then-write: %w", err)
+				}
+				price := providers.Price{Symbol: h.Symbol, Price: 1, LastUpdated: time.Now()}
+				if err := s.SavePrices(ctx, "test", []providers.Price{price}); err != nil {
+					errs <- fmt.Errorf("save prices: %w", err)
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if _, err := s.GetAllHoldings(ctx); err != nil {
+					errs <- fmt.Errorf("read: %w", err)
+				}
+				if _, err := s.LoadPrices(ctx, "test"); err != nil {
+					errs <- fmt.Errorf("read prices: %w", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	holdings, err := s.GetAllHoldings(ctx)
+	if err != nil {
+		t.Fatalf("GetHoldings() error = %v", err)
+	}
+	if len(holdings) != workers*iterations {
+		t.Errorf("got %d holdings, want %d", len(holdings), workers*iterations)
+	}
+}
+
+// readThenWrite bumps a holding's quantity inside one transaction
+func readThenWrite(ctx context.Context, s *Storage, id int64) error {
+	tx, err := s.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var quantity float64
+	if err := tx.QueryRowContext(ctx, "SELECT quantity FROM holdings WHERE id = ?", id).Scan(&quantity); err != nil {
+		return err
+	}
+	// Give other writers a chance to commit in between, as real work would
+	time.Sleep(time.Millisecond)
+	if _, err := tx.ExecContext(ctx, "UPDATE holdings SET quantity = ? WHERE id = ?", quantity+1, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func TestReset(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	for _, symbol := range []string{"KUT", "TI2"} {
+		if _, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeFund, Symbol: symbol, Quantity: 1}); err != nil {
+			t.Fatalf("CreateHolding() error = %v", err)
+		}
+	}
+	trashed, err := s.CreateHolding(ctx, CreateHoldingRequest{Type: HoldingTypeCrypto, Symbol: "BTCUSDT", Quantity: 1})
+	if err != nil {
+		t.Fatalf("CreateHolding() error = %v", err)
+	}
+	if err := s.DeleteHolding(ctx, trashed.ID); err != nil {
+		t.Fatalf("DeleteHolding() error = %v", err)
+	}
+	if err := s.UpsertSnapshot(ctx, Snapshot{Date: time.Now(), TotalValue: 10}); err != nil {
+		t.Fatalf("UpsertSnapshot() error = %v", err)
+	}
+	if err := s.SaveIdempotentResponse(ctx, "create-btc", IdempotentResponse{Status: 201, Body: []byte(`{}`)}, time.Hour); err != nil {
+		t.Fatalf("SaveIdempotentResponse() error = %v", err)
+	}
+
+	seed := []CreateHoldingRequest{{Type: HoldingTypeFund, Symbol: "KUT", Quantity: 5}}
+	result, err := s.Reset(ctx, seed)
+	if err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if want := (ResetResult{HoldingsRemoved: 3, SnapshotsRemoved: 1, HoldingsSeeded: 1}); result != want {
+		t.Errorf("Reset() = %+v, want %+v", result, want)
+	}
+
+	holdings, err := s.GetAllHoldings(ctx)
+	if err != nil {
+		t.Fatalf("GetAllHoldings() error = %v", err)
+	}
+	if len(holdings) != 1 || holdings[0].Symbol != "KUT" || holdings[0].Quantity != 5 {
+		t.Errorf("holdings after reset = %+v, want only the seeded KUT", holdings)
+	}
+	if deleted, _ := s.GetDeletedHoldings(ctx); len(deleted) != 0 {
+		t.Errorf("trash after reset = %+v, want empty", deleted)
+	}
+	if snapshots, _ := s.GetSnapshots(ctx, time.Time{}, time.Now()); len(snapshots) != 0 {
+		t.Errorf("%d snapshots after reset, want 0", len(snapshots))
+	}
+	if _, err := s.GetIdempotentResponse(ctx, "create-btc"); !errors.Is(err, ErrIdempotencyKeyNotFound) {
+		t.Errorf("GetIdempotentResponse() after reset error = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+}
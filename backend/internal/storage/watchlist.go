@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrWatchItemNotFound is returned when a watchlist item is not found
+	ErrWatchItemNotFound = errors.New("watchlist item not found")
+	// ErrWatchItemExists is returned when a symbol is already watched
+	ErrWatchItemExists = errors.New("symbol already watched")
+)
+
+// WatchItem is a symbol whose price is followed without holding it
+type WatchItem struct {
+	ID        int64       `json:"id"`
+	Type      HoldingType `json:"type"`
+	Symbol    string      `json:"symbol"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// WatchRequest represents the request to watch a symbol
+type WatchRequest struct {
+	Type   HoldingType `json:"type" binding:"required,oneof=fund crypto"`
+	Symbol string      `json:"symbol" binding:"required"`
+}
+
+// GetWatchlist returns every watched symbol, oldest first
+func (s *Storage) GetWatchlist(ctx context.Context) ([]WatchItem, error) {
+	return s.queryWatchlist(ctx, `SELECT id, type, symbol, created_at FROM watchlist ORDER BY id`)
+}
+
+// GetWatchlistByType returns the watched symbols of one type, oldest first
+func (s *Storage) GetWatchlistByType(ctx context.Context, holdingType HoldingType) ([]WatchItem, error) {
+	return s.queryWatchlist(ctx, `SELECT id, type, symbol, created_at FROM watchlist WHERE type = ? ORDER BY id`, holdingType)
+}
+
+// queryWatchlist runs a watchlist query and scans every row
+func (s *Storage) queryWatchlist(ctx context.Context, query string, args ...any) ([]WatchItem, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying watchlist: %w", err)
+	}
+	defer rows.Close()
+
+	items := []WatchItem{}
+	for rows.Next() {
+		var item WatchItem
+		if err := rows.Scan(&item.ID, &item.Type, &item.Symbol, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning watchlist item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating watchlist: %w", err)
+	}
+
+	return items, nil
+}
+
+// AddWatchItem starts watching a symbol, or returns ErrWatchItemExists
+func (s *Storage) AddWatchItem(ctx context.Context, req WatchRequest) (*WatchItem, error) {
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO watchlist (type, symbol, created_at)
+		VALUES (?, ?, ?)
+	`, req.Type, req.Symbol, now)
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return nil, ErrWatchItemExists
+		}
+		return nil, fmt.Errorf("adding watchlist item: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("getting last insert id: %w", err)
+	}
+
+	return &WatchItem{ID: id, Type: req.Type, Symbol: req.Symbol, CreatedAt: now}, nil
+}
+
+// RemoveWatchItem stops watching a symbol
+func (s *Storage) RemoveWatchItem(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM watchlist WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("removing watchlist item: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrWatchItemNotFound
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatchlist(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "prism.db"), Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	kut, err := s.AddWatchItem(ctx, WatchRequest{Type: HoldingTypeFund, Symbol: "KUT"})
+	if err != nil {
+		t.Fatalf("AddWatchItem() error = %v", err)
+	}
+	if _, err := s.AddWatchItem(ctx, WatchRequest{Type: HoldingTypeCrypto, Symbol: "KUT"}); err != nil {
+		t.Fatalf("same symbol, other type: AddWatchItem() error = %v", err)
+	}
+	if _, err := s.AddWatchItem(ctx, WatchRequest{Type: HoldingTypeFund, Symbol: "KUT"}); !errors.Is(err, ErrWatchItemExists) {
+		t.Errorf("duplicate error = %v, want ErrWatchItemExists", err)
+	}
+
+	funds, err := s.GetWatchlistByType(ctx, HoldingTypeFund)
+	if err != nil {
+		t.Fatalf("GetWatchlistByType() error = %v", err)
+	}
+	if len(funds) != 1 || funds[0].Symbol != "KUT" {
+		t.Errorf("watched funds = %+v, want [KUT]", funds)
+	}
+
+	if err := s.RemoveWatchItem(ctx, kut.ID); err != nil {
+		t.Fatalf("RemoveWatchItem() error = %v", err)
+	}
+	if err := s.RemoveWatchItem(ctx, kut.ID); !errors.Is(err, ErrWatchItemNotFound) {
+		t.Errorf("second remove error = %v, want ErrWatchItemNotFound", err)
+	}
+	all, err := s.GetWatchlist(ctx)
+	if err != nil {
+		t.Fatalf("GetWatchlist() error = %v", err)
+	}
+	if len(all) != 1 || all[0].Type != HoldingTypeCrypto {
+		t.Errorf("watchlist = %+v, want the crypto KUT only", all)
+	}
+}
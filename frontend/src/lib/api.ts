@@ -17,6 +17,20 @@ interface RequestOptions {
   body?: unknown;
 }
 
+// Error thrown for non-2xx responses. code is the stable error code from
+// the backend's error envelope (e.g. HOLDING_NOT_FOUND), when present.
+export class ApiError extends Error {
+  constructor(
+    message: string,
+    public status: number,
+    public code?: string,
+    public details?: unknown,
+  ) {
+    super(message);
+    this.name = 'ApiError';
+  }
+}
+
 // Generic fetch wrapper with error handling
 async function fetchApi<T>(endpoint: string, options: RequestOptions = {}): Promise<T> {
   const { method = 'GET', body } = options;
@@ -36,7 +50,13 @@ async function fetchApi<T>(endpoint: string, options: RequestOptions = {}): Prom
   
   if (!response.ok) {
     const errorData = await response.json().catch(() => ({}));
-    throw new Error(errorData.error || `API error: ${response.status} ${response.statusText}`);
+    const error = errorData.error ?? {};
+    throw new ApiError(
+      error.message || `API error: ${response.status} ${response.statusText}`,
+      response.status,
+      error.code,
+      error.details,
+    );
   }
   
   // Handle empty responses (like DELETE)